@@ -0,0 +1,119 @@
+package mailgun
+
+import (
+	"sync"
+	"time"
+)
+
+// Anomaly describes a spike in events detected by an AnomalyDetector
+type Anomaly struct {
+	// The name of the event which spiked, e.g. "failed"
+	Event string
+	// The number of events seen in the detection window
+	Count int
+	// The number of events expected in the detection window given the trailing baseline
+	Expected float64
+	// The timestamp of the event which triggered the anomaly
+	Timestamp time.Time
+}
+
+// AnomalyDetector compares the rate of an event, such as "failed" or "complained", over a short
+// window with its rate over a trailing baseline and calls OnAnomaly when the rate spikes.
+// OnAnomaly is called once per spike, the detector re-arms once the rate drops back below
+// the threshold. Attach detectors to an EventPoller with AddAnomalyDetector(), or feed
+// events to Observe() directly.
+//
+//	poller := mg.PollEvents(&mailgun.ListEventOptions{})
+//	poller.AddAnomalyDetector(&mailgun.AnomalyDetector{
+//		Event:     events.EventFailed,
+//		Window:    5 * time.Minute,
+//		Baseline:  time.Hour,
+//		Factor:    3,
+//		MinEvents: 20,
+//		OnAnomaly: func(a mailgun.Anomaly) {
+//			log.Printf("%d %s events in 5 minutes, expected %.1f", a.Count, a.Event, a.Expected)
+//		},
+//	})
+type AnomalyDetector struct {
+	// The name of the event to watch
+	Event string
+	// The length of the window whose event count is compared with the baseline
+	Window time.Duration
+	// The length of the trailing period preceding the window used to compute the baseline.
+	// If zero, no events are expected and a spike is reported once the window holds MinEvents
+	Baseline time.Duration
+	// How many times the baseline rate the window must exceed to be reported
+	Factor float64
+	// The minimum number of events in the window before a spike is reported,
+	// this avoids reports when the baseline is close to zero
+	MinEvents int
+	// Called when a spike is detected
+	OnAnomaly func(Anomaly)
+
+	mutex      sync.Mutex
+	timestamps []time.Time
+	triggered  bool
+}
+
+// Observe records the events matching the detector, calling OnAnomaly if they cause a spike.
+// Events are expected in ascending timestamp order, as returned by an EventPoller.
+// OnAnomaly is called once the events are recorded, it may use the detector.
+func (ad *AnomalyDetector) Observe(events []Event) {
+	var anomalies []Anomaly
+	ad.mutex.Lock()
+	for _, e := range events {
+		if e.GetName() != ad.Event {
+			continue
+		}
+		ad.timestamps = append(ad.timestamps, e.GetTimestamp())
+		if a, ok := ad.check(e.GetTimestamp()); ok {
+			anomalies = append(anomalies, a)
+		}
+	}
+	onAnomaly := ad.OnAnomaly
+	ad.mutex.Unlock()
+
+	if onAnomaly == nil {
+		return
+	}
+	for _, a := range anomalies {
+		onAnomaly(a)
+	}
+}
+
+// check returns the anomaly caused by the event at now, if any
+func (ad *AnomalyDetector) check(now time.Time) (Anomaly, bool) {
+	windowStart := now.Add(-ad.Window)
+	baselineStart := windowStart.Add(-ad.Baseline)
+
+	// Forget events older than the baseline
+	i := 0
+	for i < len(ad.timestamps) && !ad.timestamps[i].After(baselineStart) {
+		i++
+	}
+	ad.timestamps = ad.timestamps[i:]
+
+	var current, baseline int
+	for _, ts := range ad.timestamps {
+		if ts.After(windowStart) {
+			current++
+		} else {
+			baseline++
+		}
+	}
+
+	// Without a baseline period the expected rate would be NaN, which never spikes
+	var expected float64
+	if ad.Baseline > 0 {
+		expected = float64(baseline) * float64(ad.Window) / float64(ad.Baseline)
+	}
+	spiking := current >= ad.MinEvents && float64(current) > expected*ad.Factor
+	triggered := spiking && !ad.triggered
+	ad.triggered = spiking
+	return Anomaly{
+		Event:     ad.Event,
+		Count:     current,
+		Expected:  expected,
+		Timestamp: now,
+	}, triggered
+}
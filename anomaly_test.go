@@ -0,0 +1,120 @@
+package mailgun_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+func newTestEvent(name string, ts time.Time) mailgun.Event {
+	var e mailgun.Event
+	switch name {
+	case events.EventFailed:
+		e = &events.Failed{}
+	default:
+		e = &events.Delivered{}
+	}
+	e.SetName(name)
+	e.SetTimestamp(ts)
+	return e
+}
+
+func TestAnomalyDetector(t *testing.T) {
+	var anomalies []mailgun.Anomaly
+	ad := mailgun.AnomalyDetector{
+		Event:     events.EventFailed,
+		Window:    5 * time.Minute,
+		Baseline:  time.Hour,
+		Factor:    3,
+		MinEvents: 5,
+		OnAnomaly: func(a mailgun.Anomaly) {
+			anomalies = append(anomalies, a)
+		},
+	}
+
+	start := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	var page []mailgun.Event
+
+	// Baseline of one failure every 5 minutes for an hour, mixed with deliveries
+	for i := 0; i < 12; i++ {
+		ts := start.Add(time.Duration(i) * 5 * time.Minute)
+		page = append(page, newTestEvent(events.EventFailed, ts), newTestEvent(events.EventDelivered, ts))
+	}
+	ad.Observe(page)
+	ensure.DeepEqual(t, len(anomalies), 0)
+
+	// A spike of failures within a minute
+	page = nil
+	spike := start.Add(time.Hour)
+	for i := 0; i < 8; i++ {
+		page = append(page, newTestEvent(events.EventFailed, spike.Add(time.Duration(i)*time.Second)))
+	}
+	ad.Observe(page)
+
+	// Only reported once per spike
+	ensure.DeepEqual(t, len(anomalies), 1)
+	ensure.DeepEqual(t, anomalies[0].Event, events.EventFailed)
+	ensure.DeepEqual(t, anomalies[0].Count, 5)
+	ensure.DeepEqual(t, anomalies[0].Timestamp, spike.Add(4*time.Second))
+
+	// Once the spike subsides the detector re-arms
+	quiet := spike.Add(30 * time.Minute)
+	ad.Observe([]mailgun.Event{newTestEvent(events.EventFailed, quiet)})
+	ensure.DeepEqual(t, len(anomalies), 1)
+
+	page = nil
+	for i := 0; i < 10; i++ {
+		page = append(page, newTestEvent(events.EventFailed, quiet.Add(time.Duration(i)*time.Second)))
+	}
+	ad.Observe(page)
+	ensure.DeepEqual(t, len(anomalies), 2)
+}
+
+func TestAnomalyDetectorZeroBaseline(t *testing.T) {
+	var anomalies []mailgun.Anomaly
+	ad := mailgun.AnomalyDetector{
+		Event:     events.EventFailed,
+		Window:    5 * time.Minute,
+		Factor:    3,
+		MinEvents: 3,
+		OnAnomaly: func(a mailgun.Anomaly) {
+			anomalies = append(anomalies, a)
+		},
+	}
+
+	start := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	var page []mailgun.Event
+	for i := 0; i < 4; i++ {
+		page = append(page, newTestEvent(events.EventFailed, start.Add(time.Duration(i)*time.Second)))
+	}
+	ad.Observe(page)
+	ensure.DeepEqual(t, len(anomalies), 1)
+	ensure.DeepEqual(t, anomalies[0].Count, 3)
+	ensure.DeepEqual(t, anomalies[0].Expected, float64(0))
+}
+
+func TestAnomalyDetectorReentrant(t *testing.T) {
+	var ad *mailgun.AnomalyDetector
+	done := make(chan mailgun.Anomaly, 1)
+	ad = &mailgun.AnomalyDetector{
+		Event:     events.EventFailed,
+		Window:    5 * time.Minute,
+		MinEvents: 1,
+		OnAnomaly: func(a mailgun.Anomaly) {
+			// The detector is not locked while OnAnomaly runs
+			ad.Observe(nil)
+			done <- a
+		},
+	}
+
+	go ad.Observe([]mailgun.Event{newTestEvent(events.EventFailed, time.Now())})
+	select {
+	case a := <-done:
+		ensure.DeepEqual(t, a.Count, 1)
+	case <-time.After(time.Second):
+		t.Fatal("OnAnomaly deadlocked calling the detector")
+	}
+}
@@ -0,0 +1,206 @@
+package mailgun
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// ArchiveWriter writes stored messages to an archive, see NewMboxWriter() and NewMaildirWriter().
+type ArchiveWriter interface {
+	WriteMessage(msg StoredMessageRaw) error
+	Close() error
+}
+
+// Archiver copies stored messages into an archive before Mailgun deletes them at the end of
+// the retention period.
+//
+//	f, err := os.Create("archive.mbox")
+//	...
+//	archiver := mailgun.NewArchiver(mg, mailgun.NewMboxWriter(f))
+//	n, err := archiver.ArchiveEvents(ctx, mg.ListEvents(&mailgun.ListEventOptions{
+//		Filter: map[string]string{"event": "stored"},
+//	}))
+//	...
+//	err = archiver.Close()
+type Archiver struct {
+	mg Mailgun
+	w  ArchiveWriter
+}
+
+// NewArchiver creates an archiver which writes the messages it retrieves to w.
+func NewArchiver(mg Mailgun, w ArchiveWriter) *Archiver {
+	return &Archiver{mg: mg, w: w}
+}
+
+// ArchiveIDs retrieves the raw MIME of the stored messages with the given storage keys and
+// writes them to the archive.
+func (a *Archiver) ArchiveIDs(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		msg, err := a.mg.GetStoredMessageRaw(ctx, id)
+		if err != nil {
+			return fmt.Errorf("while retrieving stored message '%s': %s", id, err)
+		}
+		if err := a.w.WriteMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveEvents writes the stored message of every event returned by the iterator which
+// refers to one. Events without a stored message are skipped. Returns the number of messages
+// archived.
+func (a *Archiver) ArchiveEvents(ctx context.Context, it *EventIterator) (int, error) {
+	var count int
+	var page []Event
+	for it.Next(ctx, &page) {
+		for _, e := range page {
+			url := storageURL(e)
+			if url == "" {
+				continue
+			}
+			msg, err := a.mg.GetStoredMessageRawForURL(ctx, url)
+			if err != nil {
+				return count, fmt.Errorf("while retrieving stored message '%s': %s", url, err)
+			}
+			if err := a.w.WriteMessage(msg); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, it.Err()
+}
+
+// Close closes the archive writer.
+func (a *Archiver) Close() error {
+	return a.w.Close()
+}
+
+func storageURL(e Event) string {
+	switch e := e.(type) {
+	case *events.Stored:
+		return e.Storage.URL
+	case *events.Rejected:
+		return e.Storage.URL
+	}
+	return ""
+}
+
+var mboxFromLine = regexp.MustCompile(`^>*From `)
+
+// MboxWriter writes messages to an mbox file in the mboxrd format, which escapes
+// lines starting with "From " so the archive can be split back into the original messages.
+type MboxWriter struct {
+	w *bufio.Writer
+	c io.Closer
+}
+
+// NewMboxWriter creates an mbox archive which writes to w. If w is an io.Closer
+// it is closed by Close().
+func NewMboxWriter(w io.Writer) *MboxWriter {
+	mw := &MboxWriter{w: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		mw.c = c
+	}
+	return mw
+}
+
+// WriteMessage appends the message to the mbox.
+func (mw *MboxWriter) WriteMessage(msg StoredMessageRaw) error {
+	body := strings.Replace(msg.BodyMime, "\r\n", "\n", -1)
+
+	sender := msg.Sender
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		sender = addr.Address
+	}
+	if sender == "" {
+		sender = "MAILER-DAEMON"
+	}
+	date := time.Now()
+	if m, err := mail.ReadMessage(strings.NewReader(body)); err == nil {
+		if d, err := m.Header.Date(); err == nil {
+			date = d
+		}
+	}
+	fmt.Fprintf(mw.w, "From %s %s\n", sender, date.UTC().Format(time.ANSIC))
+
+	lines := strings.SplitAfter(body, "\n")
+	for _, line := range lines {
+		if mboxFromLine.MatchString(line) {
+			mw.w.WriteString(">")
+		}
+		mw.w.WriteString(line)
+	}
+	if !strings.HasSuffix(body, "\n") {
+		mw.w.WriteString("\n")
+	}
+	// Messages are separated by a blank line
+	mw.w.WriteString("\n")
+	return mw.w.Flush()
+}
+
+// Close flushes the mbox and closes the underlying writer if it is an io.Closer.
+func (mw *MboxWriter) Close() error {
+	if err := mw.w.Flush(); err != nil {
+		return err
+	}
+	if mw.c != nil {
+		return mw.c.Close()
+	}
+	return nil
+}
+
+// MaildirWriter writes messages into a Maildir, one file per message.
+type MaildirWriter struct {
+	dir      string
+	hostname string
+	count    int64
+}
+
+// NewMaildirWriter creates a Maildir archive in dir, creating the tmp, new and cur
+// directories if they don't exist yet.
+func NewMaildirWriter(dir string) (*MaildirWriter, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	// '/' and ':' are not allowed in the unique name of a maildir file
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+	return &MaildirWriter{dir: dir, hostname: hostname}, nil
+}
+
+// WriteMessage delivers the message into the new directory of the Maildir. The message is
+// written to the tmp directory first, so readers never see a partially written message.
+func (mw *MaildirWriter) WriteMessage(msg StoredMessageRaw) error {
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", time.Now().Unix(), time.Now().Nanosecond()/1000,
+		os.Getpid(), atomic.AddInt64(&mw.count, 1), mw.hostname)
+
+	tmp := filepath.Join(mw.dir, "tmp", name)
+	if err := ioutil.WriteFile(tmp, []byte(msg.BodyMime), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(mw.dir, "new", name))
+}
+
+// Close does nothing, messages are complete once WriteMessage() returns.
+func (mw *MaildirWriter) Close() error {
+	return nil
+}
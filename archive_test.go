@@ -0,0 +1,118 @@
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+const archivedMIME = "From: sender@example.com\r\n" +
+	"To: rcpt@example.com\r\n" +
+	"Date: Wed, 02 Jan 2019 03:04:05 +0000\r\n" +
+	"Subject: Hello\r\n" +
+	"\r\n" +
+	"From the desk of the sender\r\n" +
+	">From quoted\r\n"
+
+func newArchiveServer(t *testing.T) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/" + exampleDomain + "/events":
+			items := []map[string]interface{}{}
+			if req.URL.Query().Get("page") == "" {
+				items = []map[string]interface{}{
+					{"event": "accepted", "id": "1", "timestamp": 1546398245.0},
+					{"event": "stored", "id": "2", "timestamp": 1546398245.0,
+						"storage": map[string]string{"key": "key1", "url": srv.URL + "/" + exampleDomain + "/messages/key1"}},
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"items":  items,
+				"paging": map[string]string{"next": srv.URL + "/" + exampleDomain + "/events?page=2"},
+			})
+		case "/" + exampleDomain + "/messages/key1", "/domains/" + exampleDomain + "/messages/key1":
+			ensure.DeepEqual(t, req.Header.Get("Accept"), "message/rfc2822")
+			json.NewEncoder(w).Encode(map[string]string{
+				"sender":    "Sender <sender@example.com>",
+				"body-mime": archivedMIME,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv
+}
+
+func TestArchiveMbox(t *testing.T) {
+	srv := newArchiveServer(t)
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	archiver := NewArchiver(mg, NewMboxWriter(&buf))
+
+	count, err := archiver.ArchiveEvents(ctx, mg.ListEvents(nil))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, count, 1)
+	ensure.Nil(t, archiver.ArchiveIDs(ctx, "key1"))
+	ensure.Nil(t, archiver.Close())
+
+	message := "From sender@example.com Wed Jan  2 03:04:05 2019\n" +
+		"From: sender@example.com\n" +
+		"To: rcpt@example.com\n" +
+		"Date: Wed, 02 Jan 2019 03:04:05 +0000\n" +
+		"Subject: Hello\n" +
+		"\n" +
+		">From the desk of the sender\n" +
+		">>From quoted\n" +
+		"\n"
+	ensure.DeepEqual(t, buf.String(), message+message)
+
+	err = archiver.ArchiveIDs(ctx, "missing")
+	ensure.NotNil(t, err)
+}
+
+func TestArchiveMaildir(t *testing.T) {
+	srv := newArchiveServer(t)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "maildir")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	w, err := NewMaildirWriter(dir)
+	ensure.Nil(t, err)
+	archiver := NewArchiver(mg, w)
+	ensure.Nil(t, archiver.ArchiveIDs(context.Background(), "key1", "key1"))
+	ensure.Nil(t, archiver.Close())
+
+	tmp, err := ioutil.ReadDir(filepath.Join(dir, "tmp"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(tmp), 0)
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "new"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(files), 2)
+	for _, f := range files {
+		ensure.False(t, strings.Contains(f.Name(), ":"))
+		data, err := ioutil.ReadFile(filepath.Join(dir, "new", f.Name()))
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(data), archivedMIME)
+	}
+}
@@ -36,9 +36,12 @@ type bouncesListResponse struct {
 // Note that the length of the slice may be smaller than the total number of bounces.
 func (mg *MailgunImpl) ListBounces(opts *ListOptions) *BouncesIterator {
 	r := newHTTPRequest(generateApiUrl(mg, bouncesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &BouncesIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -142,7 +145,7 @@ func (ci *BouncesIterator) Previous(ctx context.Context, items *[]Bounce) bool {
 
 func (ci *BouncesIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(ci.mg.Client())
+	r.setClient(ci.mg)
 	r.setBasicAuth(basicAuthUser, ci.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &ci.bouncesListResponse)
@@ -151,7 +154,7 @@ func (ci *BouncesIterator) fetch(ctx context.Context, url string) error {
 // GetBounce retrieves a single bounce record, if any exist, for the given recipient address.
 func (mg *MailgunImpl) GetBounce(ctx context.Context, address string) (Bounce, error) {
 	r := newHTTPRequest(generateApiUrl(mg, bouncesEndpoint) + "/" + address)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var response Bounce
@@ -177,7 +180,7 @@ func (mg *MailgunImpl) GetBounce(ctx context.Context, address string) (Bounce, e
 // code will report as a number.
 func (mg *MailgunImpl) AddBounce(ctx context.Context, address, code, error string) error {
 	r := newHTTPRequest(generateApiUrl(mg, bouncesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
@@ -195,7 +198,17 @@ func (mg *MailgunImpl) AddBounce(ctx context.Context, address, code, error strin
 // DeleteBounce removes all bounces associted with the provided e-mail address.
 func (mg *MailgunImpl) DeleteBounce(ctx context.Context, address string) error {
 	r := newHTTPRequest(generateApiUrl(mg, bouncesEndpoint) + "/" + address)
-	r.setClient(mg.Client())
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makeDeleteRequest(ctx, r)
+	return err
+}
+
+// DeleteAllBounces removes all bounces logged against the sender's domain, allowing
+// messages to be delivered to those addresses again.
+func (mg *MailgunImpl) DeleteAllBounces(ctx context.Context) error {
+	r := newHTTPRequest(generateApiUrl(mg, bouncesEndpoint))
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -109,3 +110,17 @@ func TestAddDelBounces(t *testing.T) {
 	_, err = mg.GetBounce(ctx, exampleEmail)
 	ensure.NotNil(t, err)
 }
+
+func TestDeleteAllBounces(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.Method, http.MethodDelete)
+		ensure.DeepEqual(t, req.URL.Path, "/"+exampleDomain+"/bounces")
+		fmt.Fprint(w, `{"message": "Bounced addresses for this domain have been removed"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	ensure.Nil(t, mg.DeleteAllBounces(context.Background()))
+}
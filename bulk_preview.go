@@ -0,0 +1,95 @@
+package mailgun
+
+import "context"
+
+// BulkPreview is a bulk validation preview, which estimates the deliverability of an uploaded
+// list from a sample of its addresses. A preview is free, promote it with PromoteBulkPreview()
+// to validate every address of the list.
+type BulkPreview struct {
+	// The list ID the preview was created with
+	Id string `json:"id"`
+	// The time the preview was created, as a Unix timestamp
+	CreatedAt int64 `json:"created_at"`
+	// The number of addresses in the list
+	Quantity int `json:"quantity"`
+	// One of "preview_processing", "preview_complete" or "failed"
+	Status string `json:"status"`
+	// True if the list could be parsed
+	Valid   bool               `json:"valid"`
+	Summary BulkPreviewSummary `json:"summary"`
+}
+
+// BulkPreviewSummary estimates the percentage of the addresses of a list by result and risk.
+type BulkPreviewSummary struct {
+	Result struct {
+		CatchAll      float64 `json:"catch_all"`
+		Deliverable   float64 `json:"deliverable"`
+		DoNotSend     float64 `json:"do_not_send"`
+		Undeliverable float64 `json:"undeliverable"`
+		Unknown       float64 `json:"unknown"`
+	} `json:"result"`
+	Risk struct {
+		High    float64 `json:"high"`
+		Low     float64 `json:"low"`
+		Medium  float64 `json:"medium"`
+		Unknown float64 `json:"unknown"`
+	} `json:"risk"`
+}
+
+// CreateBulkPreview uploads a CSV file of addresses and starts a preview of their validation,
+// identified by listID. The file has the same format as for CreateBulkValidation().
+func (mg *MailgunImpl) CreateBulkPreview(ctx context.Context, listID, file string) error {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkPreviewEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+
+	p := newFormDataPayload()
+	p.addFile("file", file)
+	_, err := makePostRequest(ctx, r, p)
+	return err
+}
+
+// GetBulkPreview retrieves the status and estimates of a preview.
+func (mg *MailgunImpl) GetBulkPreview(ctx context.Context, listID string) (BulkPreview, error) {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkPreviewEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+
+	var envelope struct {
+		Preview BulkPreview `json:"preview"`
+	}
+	err := getResponseFromJSON(ctx, r, &envelope)
+	return envelope.Preview, err
+}
+
+// ListBulkPreviews returns every preview of the account.
+func (mg *MailgunImpl) ListBulkPreviews(ctx context.Context) ([]BulkPreview, error) {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkPreviewEndpoint))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+
+	var envelope struct {
+		Previews []BulkPreview `json:"previews"`
+	}
+	err := getResponseFromJSON(ctx, r, &envelope)
+	return envelope.Previews, err
+}
+
+// DeleteBulkPreview deletes a preview and its estimates.
+func (mg *MailgunImpl) DeleteBulkPreview(ctx context.Context, listID string) error {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkPreviewEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makeDeleteRequest(ctx, r)
+	return err
+}
+
+// PromoteBulkPreview starts a bulk validation job for every address of the list of a preview.
+// The job has the same list ID and can be followed with GetBulkValidation().
+func (mg *MailgunImpl) PromoteBulkPreview(ctx context.Context, listID string) error {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkPreviewEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makePutRequest(ctx, r, newUrlEncodedPayload())
+	return err
+}
@@ -0,0 +1,380 @@
+package mailgun
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BulkValidationResult is a single row of a bulk validation result file.
+type BulkValidationResult struct {
+	// The address which was validated
+	Address string
+	// One of "deliverable", "undeliverable", "do_not_send", "catch_all" or "unknown"
+	Result string
+	// One of "low", "medium", "high" or "unknown"
+	Risk string
+	// A human readable reason the address is reported as undeliverable
+	Reason string
+	// Mailgun's recommendation in case of a typo, may be empty
+	DidYouMean string
+	// Indicates whether Mailgun thinks the address is from a known
+	// disposable mailbox provider.
+	IsDisposableAddress bool
+	// Indicates whether Mailgun thinks the address is an email distribution list.
+	IsRoleAddress bool
+}
+
+// BulkValidationResultReader parses a bulk validation result file one address at a time,
+// so result files with millions of rows can be processed without loading them into memory.
+// Result files may be plain or gzip compressed CSV, the compression is detected automatically.
+//
+//	rr, err := mailgun.NewBulkValidationResultReader(file)
+//	var result mailgun.BulkValidationResult
+//	for rr.Next(&result) {
+//		fmt.Printf("%s: %s\n", result.Address, result.Result)
+//	}
+//	if rr.Err() != nil {
+//		return rr.Err()
+//	}
+type BulkValidationResultReader struct {
+	csv     *csv.Reader
+	columns map[string]int
+	row     []string
+	err     error
+}
+
+// NewBulkValidationResultReader reads the header of the result file and returns
+// a reader positioned at the first result.
+func NewBulkValidationResultReader(r io.Reader) (*BulkValidationResultReader, error) {
+	src, err := gunzipResult(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rr := BulkValidationResultReader{
+		csv:     csv.NewReader(src),
+		columns: make(map[string]int),
+	}
+	rr.csv.ReuseRecord = true
+	rr.csv.FieldsPerRecord = -1
+
+	header, err := rr.csv.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading result file header")
+	}
+	for i, name := range header {
+		rr.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := rr.columns["address"]; !ok {
+		return nil, errors.New("result file header has no 'address' column")
+	}
+	return &rr, nil
+}
+
+// gunzipResult returns a reader of the uncompressed contents of a result file, which may
+// be gzip compressed
+func gunzipResult(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		src, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "while opening gzip'd result file")
+		}
+		return src, nil
+	}
+	return br, nil
+}
+
+// Next reads the next result into the struct provided. Returns false when
+// there are no more results or when an error occurred, see Err().
+func (rr *BulkValidationResultReader) Next(result *BulkValidationResult) bool {
+	if rr.err != nil {
+		return false
+	}
+
+	rr.row, rr.err = rr.csv.Read()
+	if rr.err != nil {
+		if rr.err == io.EOF {
+			rr.err = nil
+		}
+		return false
+	}
+
+	*result = BulkValidationResult{
+		Address:             rr.field("address"),
+		Result:              rr.field("result"),
+		Risk:                rr.field("risk"),
+		Reason:              rr.field("reason"),
+		DidYouMean:          rr.field("did_you_mean"),
+		IsDisposableAddress: stringToBool(rr.field("is_disposable_address")),
+		IsRoleAddress:       stringToBool(rr.field("is_role_address")),
+	}
+	return true
+}
+
+// Err returns any error which occurred while reading the result file
+func (rr *BulkValidationResultReader) Err() error {
+	return rr.err
+}
+
+func (rr *BulkValidationResultReader) field(name string) string {
+	i, ok := rr.columns[name]
+	if !ok || i >= len(rr.row) {
+		return ""
+	}
+	return rr.row[i]
+}
+
+// BulkValidation is a bulk validation job, which validates every address of an uploaded list.
+type BulkValidation struct {
+	// The list ID the job was created with
+	Id string `json:"id"`
+	// The time the job was created, as a Unix timestamp
+	CreatedAt int64 `json:"created_at"`
+	// Links to the result file once the job has completed
+	DownloadURL BulkValidationDownloadURL `json:"download_url"`
+	// The number of addresses in the list
+	Quantity int `json:"quantity"`
+	// The number of addresses validated so far
+	RecordsProcessed int `json:"records_processed"`
+	// One of "created", "processing", "uploading", "uploaded" or "failed"
+	Status  string                `json:"status"`
+	Summary BulkValidationSummary `json:"summary"`
+}
+
+// BulkValidationDownloadURL links to the result file of a bulk validation job, in either format.
+type BulkValidationDownloadURL struct {
+	CSV  string `json:"csv"`
+	JSON string `json:"json"`
+}
+
+// BulkValidationSummary counts the addresses of a bulk validation job by result and risk.
+type BulkValidationSummary struct {
+	Result struct {
+		CatchAll      int `json:"catch_all"`
+		Deliverable   int `json:"deliverable"`
+		DoNotSend     int `json:"do_not_send"`
+		Undeliverable int `json:"undeliverable"`
+		Unknown       int `json:"unknown"`
+	} `json:"result"`
+	Risk struct {
+		High    int `json:"high"`
+		Low     int `json:"low"`
+		Medium  int `json:"medium"`
+		Unknown int `json:"unknown"`
+	} `json:"risk"`
+}
+
+type bulkValidationsListResponse struct {
+	Jobs   []BulkValidation `json:"jobs"`
+	Paging Paging           `json:"paging"`
+	Total  int              `json:"total"`
+}
+
+// CreateBulkValidation uploads a CSV file of addresses and starts a bulk validation job for
+// them, identified by listID. The file must have a header row with an 'email' column, and may
+// be gzip compressed. Poll GetBulkValidation() until the status of the job is "uploaded" to
+// retrieve the results.
+func (mg *MailgunImpl) CreateBulkValidation(ctx context.Context, listID, file string) error {
+	p := newFormDataPayload()
+	p.addFile("file", file)
+	return mg.createBulkValidation(ctx, listID, p)
+}
+
+// CreateBulkValidationFromReader works as CreateBulkValidation(), but reads the CSV data from
+// an io.Reader, so lists generated in memory or downloaded from elsewhere can be uploaded
+// without writing them to disk first. The data is streamed into the request, the reader is
+// not closed.
+func (mg *MailgunImpl) CreateBulkValidationFromReader(ctx context.Context, listID string, data io.Reader) error {
+	p := newFormDataPayload()
+	p.addReadCloser("file", listID+".csv", ioutil.NopCloser(data))
+	return mg.createBulkValidation(ctx, listID, p)
+}
+
+func (mg *MailgunImpl) createBulkValidation(ctx context.Context, listID string, p *formDataPayload) error {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkValidateEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makePostRequest(ctx, r, p)
+	return err
+}
+
+// GetBulkValidation retrieves the status of a bulk validation job.
+func (mg *MailgunImpl) GetBulkValidation(ctx context.Context, listID string) (BulkValidation, error) {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkValidateEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+
+	var response BulkValidation
+	err := getResponseFromJSON(ctx, r, &response)
+	return response, err
+}
+
+// DeleteBulkValidation cancels a bulk validation job which is in progress, or deletes
+// the results of a completed one.
+func (mg *MailgunImpl) DeleteBulkValidation(ctx context.Context, listID string) error {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkValidateEndpoint) + "/" + listID)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makeDeleteRequest(ctx, r)
+	return err
+}
+
+// DownloadBulkValidationResult downloads the CSV result file of a completed bulk validation
+// job and writes it to w uncompressed. The file is streamed, so results of any size can be
+// written to a file or parsed with NewBulkValidationResultReader() through an io.Pipe.
+func (mg *MailgunImpl) DownloadBulkValidationResult(ctx context.Context, listID string, w io.Writer) error {
+	job, err := mg.GetBulkValidation(ctx, listID)
+	if err != nil {
+		return err
+	}
+	if job.DownloadURL.CSV == "" {
+		return errors.Errorf("bulk validation '%s' has no result to download, its status is '%s'", listID, job.Status)
+	}
+
+	// The download url is signed, and must be requested without the API credentials
+	r := newHTTPRequest(job.DownloadURL.CSV)
+	r.setClient(mg)
+	body, err := getResponseStream(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	src, err := gunzipResult(body)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// ListBulkValidations returns an iterator over the bulk validation jobs of the account.
+func (mg *MailgunImpl) ListBulkValidations(opts *ListOptions) *BulkValidationsIterator {
+	r := newHTTPRequest(generateV4ApiUrl(mg, bulkValidateEndpoint))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &BulkValidationsIterator{mg: mg, err: err}
+		}
+		if opts.Limit != 0 {
+			r.addParameter("limit", strconv.Itoa(opts.Limit))
+		}
+	}
+	url, err := r.generateUrlWithParameters()
+	return &BulkValidationsIterator{
+		mg:                          mg,
+		bulkValidationsListResponse: bulkValidationsListResponse{Paging: Paging{Next: url, First: url}},
+		err:                         err,
+	}
+}
+
+type BulkValidationsIterator struct {
+	bulkValidationsListResponse
+	mg  Mailgun
+	err error
+}
+
+// If an error occurred during iteration `Err()` will return non nil
+func (bi *BulkValidationsIterator) Err() error {
+	return bi.err
+}
+
+// Retrieves the next page of items from the api. Returns false when there
+// no more pages to retrieve or if there was an error. Use `.Err()` to retrieve
+// the error
+func (bi *BulkValidationsIterator) Next(ctx context.Context, items *[]BulkValidation) bool {
+	if bi.err != nil {
+		return false
+	}
+	bi.err = bi.fetch(ctx, bi.Paging.Next)
+	if bi.err != nil {
+		return false
+	}
+	cpy := make([]BulkValidation, len(bi.Jobs))
+	copy(cpy, bi.Jobs)
+	*items = cpy
+	if len(bi.Jobs) == 0 {
+		return false
+	}
+	return true
+}
+
+// Retrieves the first page of items from the api. Returns false if there
+// was an error. It also sets the iterator object to the first page.
+// Use `.Err()` to retrieve the error.
+func (bi *BulkValidationsIterator) First(ctx context.Context, items *[]BulkValidation) bool {
+	if bi.err != nil {
+		return false
+	}
+	bi.err = bi.fetch(ctx, bi.Paging.First)
+	if bi.err != nil {
+		return false
+	}
+	cpy := make([]BulkValidation, len(bi.Jobs))
+	copy(cpy, bi.Jobs)
+	*items = cpy
+	return true
+}
+
+// Retrieves the last page of items from the api.
+// Calling Last() is invalid unless you first call First() or Next()
+// Returns false if there was an error. It also sets the iterator object
+// to the last page. Use `.Err()` to retrieve the error.
+func (bi *BulkValidationsIterator) Last(ctx context.Context, items *[]BulkValidation) bool {
+	if bi.err != nil {
+		return false
+	}
+	bi.err = bi.fetch(ctx, bi.Paging.Last)
+	if bi.err != nil {
+		return false
+	}
+	cpy := make([]BulkValidation, len(bi.Jobs))
+	copy(cpy, bi.Jobs)
+	*items = cpy
+	return true
+}
+
+// Retrieves the previous page of items from the api. Returns false when there
+// no more pages to retrieve or if there was an error. Use `.Err()` to retrieve
+// the error if any
+func (bi *BulkValidationsIterator) Previous(ctx context.Context, items *[]BulkValidation) bool {
+	if bi.err != nil {
+		return false
+	}
+	if bi.Paging.Previous == "" {
+		return false
+	}
+	bi.err = bi.fetch(ctx, bi.Paging.Previous)
+	if bi.err != nil {
+		return false
+	}
+	cpy := make([]BulkValidation, len(bi.Jobs))
+	copy(cpy, bi.Jobs)
+	*items = cpy
+	if len(bi.Jobs) == 0 {
+		return false
+	}
+	return true
+}
+
+func (bi *BulkValidationsIterator) fetch(ctx context.Context, url string) error {
+	r := newHTTPRequest(url)
+	r.setClient(bi.mg)
+	r.setBasicAuth(basicAuthUser, bi.mg.APIKey())
+
+	return getResponseFromJSON(ctx, r, &bi.bulkValidationsListResponse)
+}
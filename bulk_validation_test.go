@@ -0,0 +1,188 @@
+package mailgun_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+const bulkValidationResults = `address,is_role_address,is_disposable_address,did_you_mean,result,reason,risk
+alice@example.com,false,false,,deliverable,,low
+admin@example.com,true,false,,do_not_send,,medium
+bob@gmial.com,false,false,bob@gmail.com,undeliverable,mailbox_does_not_exist,high
+`
+
+func readBulkValidationResults(t *testing.T, rr *mailgun.BulkValidationResultReader) []mailgun.BulkValidationResult {
+	var results []mailgun.BulkValidationResult
+	var result mailgun.BulkValidationResult
+	for rr.Next(&result) {
+		results = append(results, result)
+	}
+	ensure.Nil(t, rr.Err())
+	return results
+}
+
+func TestBulkValidationResultReader(t *testing.T) {
+	rr, err := mailgun.NewBulkValidationResultReader(strings.NewReader(bulkValidationResults))
+	ensure.Nil(t, err)
+
+	results := readBulkValidationResults(t, rr)
+	ensure.DeepEqual(t, len(results), 3)
+	ensure.DeepEqual(t, results[0], mailgun.BulkValidationResult{
+		Address: "alice@example.com",
+		Result:  "deliverable",
+		Risk:    "low",
+	})
+	ensure.True(t, results[1].IsRoleAddress)
+	ensure.DeepEqual(t, results[2].DidYouMean, "bob@gmail.com")
+	ensure.DeepEqual(t, results[2].Reason, "mailbox_does_not_exist")
+}
+
+func TestBulkValidationResultReaderGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(bulkValidationResults))
+	ensure.Nil(t, err)
+	ensure.Nil(t, gz.Close())
+
+	rr, err := mailgun.NewBulkValidationResultReader(&buf)
+	ensure.Nil(t, err)
+
+	results := readBulkValidationResults(t, rr)
+	ensure.DeepEqual(t, len(results), 3)
+	ensure.DeepEqual(t, results[2].Address, "bob@gmial.com")
+}
+
+func TestBulkValidationResultReaderBadHeader(t *testing.T) {
+	_, err := mailgun.NewBulkValidationResultReader(strings.NewReader("email,result\n"))
+	ensure.NotNil(t, err)
+}
+
+func TestBulkValidations(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	f, err := ioutil.TempFile("", "bulk-validation-*.csv")
+	ensure.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("email\nalice@example.com\nbob@example.com\nexample.com\n")
+	ensure.Nil(t, err)
+	ensure.Nil(t, f.Close())
+
+	ensure.Nil(t, mg.CreateBulkValidation(ctx, "newsletter", f.Name()))
+
+	job, err := mg.GetBulkValidation(ctx, "newsletter")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, job.Id, "newsletter")
+	ensure.DeepEqual(t, job.Status, "uploaded")
+	ensure.DeepEqual(t, job.Quantity, 3)
+	ensure.DeepEqual(t, job.Summary.Result.Deliverable, 2)
+	ensure.DeepEqual(t, job.Summary.Result.Undeliverable, 1)
+	ensure.DeepEqual(t, job.Summary.Risk.High, 1)
+
+	it := mg.ListBulkValidations(nil)
+	var page []mailgun.BulkValidation
+	var found bool
+	for it.Next(ctx, &page) {
+		for _, j := range page {
+			if j.Id == "newsletter" {
+				found = true
+			}
+		}
+	}
+	ensure.Nil(t, it.Err())
+	ensure.True(t, found)
+
+	ensure.Nil(t, mg.DeleteBulkValidation(ctx, "newsletter"))
+	_, err = mg.GetBulkValidation(ctx, "newsletter")
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+}
+
+func TestCreateBulkValidationFromReader(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	list := strings.NewReader("name,email\nAlice,alice@example.com\nBob,bob\n")
+	ensure.Nil(t, mg.CreateBulkValidationFromReader(ctx, "in-memory", list))
+	defer mg.DeleteBulkValidation(ctx, "in-memory")
+
+	job, err := mg.GetBulkValidation(ctx, "in-memory")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, job.Quantity, 2)
+	ensure.DeepEqual(t, job.Summary.Result.Deliverable, 1)
+	ensure.DeepEqual(t, job.Summary.Result.Undeliverable, 1)
+}
+
+func TestDownloadBulkValidationResult(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	list := strings.NewReader("email\nalice@example.com\nbob\n")
+	ensure.Nil(t, mg.CreateBulkValidationFromReader(ctx, "download", list))
+	defer mg.DeleteBulkValidation(ctx, "download")
+
+	var buf bytes.Buffer
+	ensure.Nil(t, mg.DownloadBulkValidationResult(ctx, "download", &buf))
+
+	rr, err := mailgun.NewBulkValidationResultReader(&buf)
+	ensure.Nil(t, err)
+	results := readBulkValidationResults(t, rr)
+	ensure.DeepEqual(t, len(results), 2)
+	ensure.DeepEqual(t, results[0].Address, "alice@example.com")
+	ensure.DeepEqual(t, results[0].Result, "deliverable")
+	ensure.DeepEqual(t, results[1].Address, "bob")
+	ensure.DeepEqual(t, results[1].Result, "undeliverable")
+
+	err = mg.DownloadBulkValidationResult(ctx, "unknown", &buf)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+}
+
+func TestBulkPreviews(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	f, err := ioutil.TempFile("", "bulk-preview-*.csv")
+	ensure.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("email\nalice@example.com\nbob@example.com\ncarol@example.com\nexample.com\n")
+	ensure.Nil(t, err)
+	ensure.Nil(t, f.Close())
+
+	ensure.Nil(t, mg.CreateBulkPreview(ctx, "prospects", f.Name()))
+
+	preview, err := mg.GetBulkPreview(ctx, "prospects")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, preview.Id, "prospects")
+	ensure.True(t, preview.Valid)
+	ensure.DeepEqual(t, preview.Quantity, 4)
+	ensure.DeepEqual(t, preview.Summary.Result.Deliverable, 75.0)
+	ensure.DeepEqual(t, preview.Summary.Risk.High, 25.0)
+
+	previews, err := mg.ListBulkPreviews(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(previews), 1)
+	ensure.DeepEqual(t, previews[0].Id, "prospects")
+
+	// Promoting a preview starts a full validation job for the list
+	ensure.Nil(t, mg.PromoteBulkPreview(ctx, "prospects"))
+	job, err := mg.GetBulkValidation(ctx, "prospects")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, job.Summary.Result.Deliverable, 3)
+	ensure.Nil(t, mg.DeleteBulkValidation(ctx, "prospects"))
+
+	ensure.Nil(t, mg.DeleteBulkPreview(ctx, "prospects"))
+	_, err = mg.GetBulkPreview(ctx, "prospects")
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+}
@@ -0,0 +1,53 @@
+package mailgun
+
+import (
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// A Cipher encrypts the values of custom variables before they are sent to Mailgun, and
+// decrypts them when events are retrieved. This keeps sensitive correlation data, such as
+// customer identifiers, out of the Mailgun logs while it is still available on events.
+// See SetVariableCipher().
+//
+// Encrypt must return a string, the ciphertext is typically base64 encoded.
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// DecryptEventVariables decrypts the user variables of an event in place. Use it on events
+// which are not retrieved through an EventIterator, such as those parsed from webhooks.
+// Events without user variables are left unchanged, as are the variables the SDK sets itself in
+// plaintext, such as AttachmentManifestVariable. Variables which fail to decrypt, such as those
+// sent before the Cipher was installed, are left unchanged as well, and the first error is returned.
+func DecryptEventVariables(c Cipher, e Event) error {
+	var vars map[string]string
+	switch e := e.(type) {
+	case *events.Opened:
+		vars = e.UserVariables
+	case *events.Clicked:
+		vars = e.UserVariables
+	case *events.Unsubscribed:
+		vars = e.UserVariables
+	}
+	var firstErr error
+	for k, v := range vars {
+		if reservedVariables[k] {
+			continue
+		}
+		plain, err := c.Decrypt(v)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		vars[k] = plain
+	}
+	return firstErr
+}
+
+// reservedVariables are the custom variables the SDK adds to messages without encrypting them
+var reservedVariables = map[string]bool{
+	AttachmentManifestVariable: true,
+}
@@ -0,0 +1,117 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// base64Cipher is a reversible stand-in for a real cipher
+type base64Cipher struct{}
+
+func (base64Cipher) Encrypt(plaintext string) (string, error) {
+	return "enc:" + base64.StdEncoding.EncodeToString([]byte(plaintext)), nil
+}
+
+func (base64Cipher) Decrypt(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, "enc:") {
+		return "", errors.New("not encrypted")
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, "enc:"))
+	return string(b), err
+}
+
+func TestVariableCipherSend(t *testing.T) {
+	var customerID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		customerID = req.FormValue("v:customer-id")
+		fmt.Fprint(w, `{"message":"Queued", "id":"<id@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetVariableCipher(base64Cipher{})
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	ensure.Nil(t, m.AddVariable("customer-id", "c-1234"))
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, customerID, "enc:Yy0xMjM0")
+}
+
+func TestVariableCipherEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"items": [{"event": "opened", "user-variables": {"customer-id": "enc:Yy0xMjM0"}},`+
+			`{"event": "clicked", "user-variables": {"customer-id": "c-5678", "plan": "enc:cHJv"}}], "paging": {}}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetVariableCipher(base64Cipher{})
+
+	it := mg.ListEvents(nil)
+	var page []Event
+	ensure.True(t, it.First(context.Background(), &page))
+	ensure.Nil(t, it.Err())
+	ensure.DeepEqual(t, len(page), 2)
+	ensure.DeepEqual(t, page[0].(*events.Opened).UserVariables["customer-id"], "c-1234")
+
+	// Values sent before the cipher was installed are left as they are
+	ensure.DeepEqual(t, page[1].(*events.Clicked).UserVariables,
+		map[string]string{"customer-id": "c-5678", "plan": "pro"})
+
+	// Values which fail to decrypt are reported
+	e := &events.Clicked{UserVariables: map[string]string{"customer-id": "c-1234", "plan": "enc:cHJv"}}
+	ensure.NotNil(t, DecryptEventVariables(base64Cipher{}, e))
+	ensure.DeepEqual(t, e.UserVariables, map[string]string{"customer-id": "c-1234", "plan": "pro"})
+}
+
+func TestVariableCipherAttachmentManifest(t *testing.T) {
+	var manifest, customerID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			fmt.Fprintf(w, `{"items": [{"event": "opened", "user-variables": {"customer-id": %q, %q: %q}}], "paging": {}}`,
+				customerID, AttachmentManifestVariable, manifest)
+			return
+		}
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		manifest = req.FormValue("v:" + AttachmentManifestVariable)
+		customerID = req.FormValue("v:customer-id")
+		fmt.Fprint(w, `{"message":"Queued", "id":"<id@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetVariableCipher(base64Cipher{})
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	ensure.Nil(t, m.AddVariable("customer-id", "c-1234"))
+	m.AddBufferAttachment("invoice.txt", []byte("invoice"))
+	m.EnableAttachmentManifest()
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, customerID, "enc:Yy0xMjM0")
+
+	it := mg.ListEvents(nil)
+	var page []Event
+	ensure.True(t, it.First(context.Background(), &page))
+	ensure.Nil(t, it.Err())
+	vars := page[0].(*events.Opened).UserVariables
+	ensure.DeepEqual(t, vars["customer-id"], "c-1234")
+	ensure.DeepEqual(t, vars[AttachmentManifestVariable], manifest)
+	ensure.StringContains(t, manifest, `"filename":"invoice.txt"`)
+}
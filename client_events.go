@@ -0,0 +1,259 @@
+package mailgun
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientEvents publishes the lifecycle events of a client, so applications can log or alert on
+// them without wrapping every call. Handlers are called synchronously by the goroutine making
+// the request, they must be quick and safe for concurrent use.
+//
+//	events := mailgun.NewClientEvents()
+//	events.OnRateLimited(func(e mailgun.RateLimitedEvent) {
+//		log.Printf("rate limited by %s, retry in %s", e.URL, e.RetryAfter)
+//	})
+//	mg.SetClientEvents(events)
+type ClientEvents struct {
+	mu             sync.Mutex
+	requestStart   []func(RequestStartEvent)
+	requestEnd     []func(RequestEndEvent)
+	rateLimited    []func(RateLimitedEvent)
+	retry          []func(RetryEvent)
+	queueDepth     []func(QueueDepthEvent)
+	send           []func(SendEvent)
+	queueDepthSeen map[string]int
+}
+
+// RequestStartEvent is published before a request is sent to Mailgun
+type RequestStartEvent struct {
+	Method string
+	URL    string
+}
+
+// RequestEndEvent is published when the response headers of a request are received, or the
+// request failed
+type RequestEndEvent struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	// The error of the transport, responses with an error status have a nil Err
+	Err error
+}
+
+// RateLimitedEvent is published when Mailgun answers a request with 429 Too Many Requests or
+// 503 Service Unavailable
+type RateLimitedEvent struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// The delay requested by the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+// RetryEvent is published before a failed operation is retried, e.g. by SuppressionSync
+type RetryEvent struct {
+	// The number of the retry, starting at 1
+	Attempt int
+	// How long until the retry
+	Delay time.Duration
+	// The error of the failed attempt
+	Err error
+}
+
+// QueueDepthEvent is published when GetSendingQueues() finds the size of a sending queue
+// changed since the last reading
+type QueueDepthEvent struct {
+	Domain string
+	// "regular" or "scheduled"
+	Queue string
+	// The size of the queue at the last reading, 0 on the first reading
+	Previous int
+	Depth    int
+}
+
+// SendEvent is published when Send() returns, for messages which passed validation
+type SendEvent struct {
+	Domain string
+	// The SLA class and deadline set with Message.SetSLAClass(), if any
+	SLAClass string
+	Deadline time.Duration
+	// How long Send() took until Mailgun accepted the message, including the suppression and
+	// contact lookups
+	Duration time.Duration
+	// Whether the send took longer than Deadline
+	Missed bool
+	// The error returned by Send()
+	Err error
+}
+
+// NewClientEvents creates an event bus without subscribers.
+func NewClientEvents() *ClientEvents {
+	return &ClientEvents{queueDepthSeen: make(map[string]int)}
+}
+
+// OnRequestStart subscribes fn to RequestStartEvent.
+func (e *ClientEvents) OnRequestStart(fn func(RequestStartEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requestStart = append(e.requestStart, fn)
+}
+
+// OnRequestEnd subscribes fn to RequestEndEvent.
+func (e *ClientEvents) OnRequestEnd(fn func(RequestEndEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requestEnd = append(e.requestEnd, fn)
+}
+
+// OnRateLimited subscribes fn to RateLimitedEvent.
+func (e *ClientEvents) OnRateLimited(fn func(RateLimitedEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rateLimited = append(e.rateLimited, fn)
+}
+
+// OnRetry subscribes fn to RetryEvent.
+func (e *ClientEvents) OnRetry(fn func(RetryEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retry = append(e.retry, fn)
+}
+
+// OnQueueDepthChange subscribes fn to QueueDepthEvent.
+func (e *ClientEvents) OnQueueDepthChange(fn func(QueueDepthEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queueDepth = append(e.queueDepth, fn)
+}
+
+// OnSend subscribes fn to SendEvent.
+func (e *ClientEvents) OnSend(fn func(SendEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.send = append(e.send, fn)
+}
+
+// SetClientEvents publishes the lifecycle events of the client to e, pass nil to stop.
+// The events wrap the transport of the HTTP client, call SetClient() first when using both.
+// Responses served by a ResponseCache are not requests to Mailgun and publish no events.
+func (mg *MailgunImpl) SetClientEvents(e *ClientEvents) {
+	client := *mg.Client()
+	transport := client.Transport
+	cache, cached := transport.(*cachingTransport)
+	if cached {
+		transport = cache.base
+	}
+	if t, ok := transport.(*eventsTransport); ok {
+		transport = t.base
+	}
+	if e != nil {
+		transport = &eventsTransport{events: e, base: transport}
+	}
+	if cached {
+		transport = &cachingTransport{cache: cache.cache, base: transport}
+	}
+	client.Transport = transport
+	mg.client = &client
+	mg.events = e
+}
+
+// ClientEvents returns the event bus of the client, or nil if none was set.
+func (mg *MailgunImpl) ClientEvents() *ClientEvents {
+	return mg.events
+}
+
+// The publishing methods accept a nil bus, so callers don't need to check whether one was set
+
+func (e *ClientEvents) publishRetry(ev RetryEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	handlers := e.retry
+	e.mu.Unlock()
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+func (e *ClientEvents) publishQueueDepth(domain, queue string, depth int) {
+	if e == nil {
+		return
+	}
+	key := domain + "/" + queue
+	e.mu.Lock()
+	// A zero value ClientEvents has no map yet
+	if e.queueDepthSeen == nil {
+		e.queueDepthSeen = make(map[string]int)
+	}
+	previous, seen := e.queueDepthSeen[key]
+	e.queueDepthSeen[key] = depth
+	handlers := e.queueDepth
+	e.mu.Unlock()
+	if seen && previous == depth {
+		return
+	}
+	for _, fn := range handlers {
+		fn(QueueDepthEvent{Domain: domain, Queue: queue, Previous: previous, Depth: depth})
+	}
+}
+
+func (e *ClientEvents) publishSend(ev SendEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	handlers := e.send
+	e.mu.Unlock()
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+// eventsTransport publishes the request events of a ClientEvents
+type eventsTransport struct {
+	events *ClientEvents
+	base   http.RoundTripper
+}
+
+func (t *eventsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	e := t.events
+	e.mu.Lock()
+	onStart, onEnd, onRateLimited := e.requestStart, e.requestEnd, e.rateLimited
+	e.mu.Unlock()
+
+	url := req.URL.String()
+	for _, fn := range onStart {
+		fn(RequestStartEvent{Method: req.Method, URL: url})
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	end := RequestEndEvent{Method: req.Method, URL: url, Duration: time.Since(start), Err: err}
+	if resp != nil {
+		end.StatusCode = resp.StatusCode
+	}
+	for _, fn := range onEnd {
+		fn(end)
+	}
+
+	if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		limited := RateLimitedEvent{
+			Method:     req.Method,
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		for _, fn := range onRateLimited {
+			fn(limited)
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,121 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestClientEvents(t *testing.T) {
+	var requests, regular int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, `{"regular": {"size": %d}, "scheduled": {"size": 3}}`, regular)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL + "/v3")
+	events := NewClientEvents()
+	var starts []RequestStartEvent
+	var ends []RequestEndEvent
+	var limited []RateLimitedEvent
+	var depths []QueueDepthEvent
+	events.OnRequestStart(func(e RequestStartEvent) { starts = append(starts, e) })
+	events.OnRequestEnd(func(e RequestEndEvent) { ends = append(ends, e) })
+	events.OnRateLimited(func(e RateLimitedEvent) { limited = append(limited, e) })
+	events.OnQueueDepthChange(func(e QueueDepthEvent) { depths = append(depths, e) })
+	mg.SetClientEvents(events)
+	ensure.True(t, mg.ClientEvents() == events)
+	ctx := context.Background()
+
+	_, err := mg.GetSendingQueues(ctx)
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, len(starts), 1)
+	ensure.DeepEqual(t, starts[0].Method, http.MethodGet)
+	ensure.DeepEqual(t, starts[0].URL, srv.URL+"/v3/domains/"+exampleDomain+"/sending_queues")
+	ensure.DeepEqual(t, len(ends), 1)
+	ensure.DeepEqual(t, ends[0].StatusCode, http.StatusTooManyRequests)
+	ensure.Nil(t, ends[0].Err)
+	ensure.DeepEqual(t, len(limited), 1)
+	ensure.DeepEqual(t, limited[0].RetryAfter, 2*time.Second)
+	ensure.DeepEqual(t, len(depths), 0)
+
+	// The first reading publishes both queues, later readings only the changes
+	regular = 10
+	_, err = mg.GetSendingQueues(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, depths, []QueueDepthEvent{
+		{Domain: exampleDomain, Queue: "regular", Depth: 10},
+		{Domain: exampleDomain, Queue: "scheduled", Depth: 3},
+	})
+	_, err = mg.GetSendingQueues(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(depths), 2)
+	regular = 4
+	_, err = mg.GetSendingQueues(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, depths[2], QueueDepthEvent{Domain: exampleDomain, Queue: "regular", Previous: 10, Depth: 4})
+	ensure.DeepEqual(t, ends[3].StatusCode, http.StatusOK)
+
+	// Cached responses are not requests to Mailgun, whichever is set first
+	mg.SetResponseCache(NewResponseCache(time.Minute))
+	mg.SetClientEvents(events)
+	_, ok := mg.Client().Transport.(*cachingTransport)
+	ensure.True(t, ok)
+	_, err = mg.GetDomainTracking(ctx, exampleDomain)
+	ensure.Nil(t, err)
+	_, err = mg.GetDomainTracking(ctx, exampleDomain)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(starts), 5)
+
+	mg.SetClientEvents(nil)
+	_, err = mg.GetSendingQueues(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(starts), 5)
+}
+
+func TestClientEventsRetry(t *testing.T) {
+	ss := &syncServer{lists: map[string][]map[string]interface{}{
+		"example.com/bounces": {{"address": "bounced@example.com"}},
+	}}
+	srv := httptest.NewServer(ss)
+	defer srv.Close()
+
+	mg := NewMailgun("example.com", exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	events := NewClientEvents()
+	var retries []RetryEvent
+	events.OnRetry(func(e RetryEvent) { retries = append(retries, e) })
+	mg.SetClientEvents(events)
+
+	s := NewSuppressionSync(mg, DomainSuppressions(mg), "news.example.com")
+	s.backoff = time.Millisecond
+	results, err := s.Run(context.Background())
+	ensure.Nil(t, err)
+	ensure.Nil(t, results[0].Err)
+	ensure.DeepEqual(t, len(retries), 1)
+	ensure.DeepEqual(t, retries[0].Attempt, 1)
+	ensure.DeepEqual(t, retries[0].Delay, time.Millisecond)
+	ensure.DeepEqual(t, GetStatusFromErr(retries[0].Err), http.StatusTooManyRequests)
+}
+
+func TestClientEventsZeroValue(t *testing.T) {
+	var depths []QueueDepthEvent
+	var events ClientEvents
+	events.OnQueueDepthChange(func(e QueueDepthEvent) { depths = append(depths, e) })
+
+	events.publishQueueDepth(exampleDomain, "regular", 2)
+	events.publishQueueDepth(exampleDomain, "regular", 2)
+	ensure.DeepEqual(t, depths, []QueueDepthEvent{{Domain: exampleDomain, Queue: "regular", Depth: 2}})
+}
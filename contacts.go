@@ -0,0 +1,184 @@
+package mailgun
+
+import (
+	"context"
+	"net/mail"
+	"sync"
+	"time"
+)
+
+// DefaultContactBatchSize is the number of recipients looked up in a single call to a ContactSource
+const DefaultContactBatchSize = 100
+
+// ContactSource provides the recipient variables of batch messages from an application's own
+// contact data, such as a database or a CRM. See Message.SetContactSource().
+type ContactSource interface {
+	// Contacts returns the variables of each of the given addresses. Addresses unknown
+	// to the source may be left out of the result.
+	Contacts(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error)
+}
+
+// ContactSourceFunc adapts a function to the ContactSource interface
+type ContactSourceFunc func(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error)
+
+// Contacts calls f(ctx, addresses)
+func (f ContactSourceFunc) Contacts(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error) {
+	return f(ctx, addresses)
+}
+
+// SetContactSource has Send() look up the variables of the recipients of a batch message from
+// src, instead of adding them up front with AddRecipientAndVariables(). Recipients are looked up
+// in batches of batchSize addresses, or DefaultContactBatchSize if batchSize is 0. Variables
+// added with AddRecipientAndVariables() take precedence, and recipients removed by
+// SetSuppressionMode() are not looked up. Wrap src with NewCachedContactSource() to reuse
+// the variables across messages.
+func (m *Message) SetContactSource(src ContactSource, batchSize int) {
+	m.contacts = src
+	m.contactBatchSize = batchSize
+}
+
+// loadContacts adds the variables of recipients without variables from the contact source
+func (m *Message) loadContacts(ctx context.Context) error {
+	// Look up bare addresses, but key the variables by recipient as AddRecipientAndVariables() does
+	recipients := make(map[string]string)
+	var addresses []string
+	for _, to := range m.to {
+		if _, ok := m.recipientVariables[to]; ok {
+			continue
+		}
+		address := to
+		if parsed, err := mail.ParseAddress(to); err == nil {
+			address = parsed.Address
+		}
+		if _, ok := recipients[address]; !ok {
+			addresses = append(addresses, address)
+		}
+		recipients[address] = to
+	}
+
+	size := m.contactBatchSize
+	if size <= 0 {
+		size = DefaultContactBatchSize
+	}
+	for start := 0; start < len(addresses); start += size {
+		contacts, err := m.contacts.Contacts(ctx, addresses[start:minInt(start+size, len(addresses))])
+		if err != nil {
+			return err
+		}
+		for address, vars := range contacts {
+			to, ok := recipients[address]
+			if !ok || vars == nil {
+				continue
+			}
+			if m.recipientVariables == nil {
+				m.recipientVariables = make(map[string]map[string]interface{})
+			}
+			m.recipientVariables[to] = vars
+		}
+	}
+	return nil
+}
+
+// CachedContactSource caches the variables returned by a ContactSource, so contacts which
+// receive several messages are looked up once. Addresses unknown to the source are cached too.
+// Expired entries are dropped as the cache grows, so it holds at most about twice the contacts
+// looked up within ttl. A CachedContactSource is safe for concurrent use.
+type CachedContactSource struct {
+	src ContactSource
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedContact
+	// The size of entries at which the expired entries are dropped
+	evictAt int
+}
+
+// minContactEviction is the smallest size of the cache at which expired entries are dropped
+const minContactEviction = 1024
+
+type cachedContact struct {
+	vars    map[string]interface{}
+	expires time.Time
+}
+
+// NewCachedContactSource caches the contacts returned by src for ttl.
+func NewCachedContactSource(src ContactSource, ttl time.Duration) *CachedContactSource {
+	return &CachedContactSource{
+		src:     src,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedContact),
+		evictAt: minContactEviction,
+	}
+}
+
+// Contacts returns the cached variables of the addresses, looking up the addresses which
+// are not cached or have expired with a single call to the underlying source.
+func (c *CachedContactSource) Contacts(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error) {
+	result := make(map[string]map[string]interface{}, len(addresses))
+	var missing []string
+	now := c.now()
+
+	c.mu.Lock()
+	for _, address := range addresses {
+		entry, ok := c.entries[address]
+		if ok && now.After(entry.expires) {
+			delete(c.entries, address)
+			ok = false
+		}
+		if !ok {
+			missing = append(missing, address)
+			continue
+		}
+		if entry.vars != nil {
+			result[address] = entry.vars
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+	contacts, err := c.src.Contacts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now = c.now()
+	expires := now.Add(c.ttl)
+	for _, address := range missing {
+		vars := contacts[address]
+		c.entries[address] = cachedContact{vars: vars, expires: expires}
+		if vars != nil {
+			result[address] = vars
+		}
+	}
+	if len(c.entries) >= c.evictAt {
+		c.evict(now)
+	}
+	return result, nil
+}
+
+// evict drops the expired entries, and waits for the cache to double in size before the next
+// eviction so the cost of walking the entries is spread over the lookups
+func (c *CachedContactSource) evict(now time.Time) {
+	for address, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, address)
+		}
+	}
+	c.evictAt = 2 * len(c.entries)
+	if c.evictAt < minContactEviction {
+		c.evictAt = minContactEviction
+	}
+}
+
+// Forget removes the cached variables of an address, for instance after the contact changed.
+func (c *CachedContactSource) Forget(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, address)
+}
@@ -0,0 +1,107 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSendContactSource(t *testing.T) {
+	var sent map[string]map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		sent = nil
+		ensure.Nil(t, json.Unmarshal([]byte(req.FormValue("recipient-variables")), &sent))
+		fmt.Fprint(w, `{"id": "<20190101000000.1.1@testDomain>", "message": "Queued. Thank you."}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	var lookups [][]string
+	db := ContactSourceFunc(func(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error) {
+		lookups = append(lookups, addresses)
+		contacts := make(map[string]map[string]interface{})
+		for _, a := range addresses {
+			if a != "stranger@example.com" {
+				contacts[a] = map[string]interface{}{"name": a[:len(a)-len("@example.com")]}
+			}
+		}
+		return contacts, nil
+	})
+	cache := NewCachedContactSource(db, time.Hour)
+
+	m := mg.NewMessage(fromUser, exampleSubject, "Hello %recipient.name%")
+	ensure.Nil(t, m.AddRecipient("Alice <alice@example.com>"))
+	ensure.Nil(t, m.AddRecipient("bob@example.com"))
+	ensure.Nil(t, m.AddRecipient("stranger@example.com"))
+	ensure.Nil(t, m.AddRecipientAndVariables("carol@example.com", map[string]interface{}{"name": "Caroline"}))
+	m.SetContactSource(cache, 2)
+
+	_, _, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, lookups, [][]string{
+		{"alice@example.com", "bob@example.com"},
+		{"stranger@example.com"},
+	})
+	ensure.DeepEqual(t, sent, map[string]map[string]interface{}{
+		"Alice <alice@example.com>": {"name": "alice"},
+		"bob@example.com":           {"name": "bob"},
+		"carol@example.com":         {"name": "Caroline"},
+	})
+
+	// Contacts seen by an earlier message, including unknown ones, are served from the cache
+	m = mg.NewMessage(fromUser, exampleSubject, "Hello %recipient.name%")
+	ensure.Nil(t, m.AddRecipient("bob@example.com"))
+	ensure.Nil(t, m.AddRecipient("stranger@example.com"))
+	ensure.Nil(t, m.AddRecipient("dave@example.com"))
+	m.SetContactSource(cache, 0)
+
+	_, _, err = mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(lookups), 3)
+	ensure.DeepEqual(t, lookups[2], []string{"dave@example.com"})
+	ensure.DeepEqual(t, sent["dave@example.com"], map[string]interface{}{"name": "dave"})
+	ensure.DeepEqual(t, len(sent), 2)
+
+	cache.Forget("bob@example.com")
+	_, err = cache.Contacts(ctx, []string{"bob@example.com"})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, lookups[3], []string{"bob@example.com"})
+}
+
+func TestCachedContactSourceEviction(t *testing.T) {
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	var lookups int
+	db := ContactSourceFunc(func(ctx context.Context, addresses []string) (map[string]map[string]interface{}, error) {
+		lookups++
+		return map[string]map[string]interface{}{}, nil
+	})
+	cache := NewCachedContactSource(db, time.Minute)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// An expired entry is looked up again
+	_, err := cache.Contacts(ctx, []string{"alice@example.com"})
+	ensure.Nil(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = cache.Contacts(ctx, []string{"alice@example.com"})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, lookups, 2)
+
+	// Expired entries of addresses which are not looked up again are dropped as the cache grows
+	for i := 0; i < 3*minContactEviction; i++ {
+		now = now.Add(time.Second)
+		_, err := cache.Contacts(ctx, []string{fmt.Sprintf("user%d@example.com", i)})
+		ensure.Nil(t, err)
+	}
+	ensure.True(t, len(cache.entries) < minContactEviction)
+}
@@ -0,0 +1,317 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store is a key/value store shared by several processes, such as a Redis server or a
+// database table, which they use to coordinate work. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value stored under key, or an empty string if the key is not set or expired.
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value under key without an expiry.
+	Set(ctx context.Context, key, value string) error
+	// Acquire atomically stores value under key for ttl if the key is not set, is expired, or
+	// already holds value. Returns true if the key holds value afterwards.
+	Acquire(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+}
+
+// MemoryStore is a Store which keeps its values in memory. It coordinates the users of a
+// single process, and is useful in tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string]memoryValue
+}
+
+type memoryValue struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string]memoryValue)}
+}
+
+func (s *MemoryStore) get(key string) (memoryValue, bool) {
+	v, ok := s.values[key]
+	if ok && !v.expires.IsZero() && time.Now().After(v.expires) {
+		delete(s.values, key)
+		return memoryValue{}, false
+	}
+	return v, ok
+}
+
+// Get returns the value stored under key, or an empty string if the key is not set or expired.
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, _ := s.get(key)
+	return v.value, nil
+}
+
+// Set stores value under key without an expiry.
+func (s *MemoryStore) Set(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = memoryValue{value: value}
+	return nil
+}
+
+// Acquire stores value under key for ttl if the key is not set, is expired, or already holds value.
+func (s *MemoryStore) Acquire(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.get(key); ok && v.value != value {
+		return false, nil
+	}
+	s.values[key] = memoryValue{value: value, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+// DefaultCoordinatorLease is how long the elected poller of an EventCoordinator remains
+// leader without renewing its lease.
+const DefaultCoordinatorLease = time.Minute
+
+// EventCoordinator polls the events of a domain on behalf of several processes, such as
+// the replicas of a service. The processes share a Store, through which they elect a single
+// leader which polls the events API and passes new events to its subscribers. The others stand
+// by and take over, resuming from the last event the leader delivered, if the leader stops.
+//
+//	ec := mg.CoordinateEvents(store, hostname, &mailgun.ListEventOptions{
+//		PollInterval: time.Second * 30,
+//	})
+//	ec.Subscribe(func(events []mailgun.Event) {
+//		...
+//	})
+//	err := ec.Run(ctx)
+//
+// Subscribers are only called in the process which is currently the leader.
+type EventCoordinator struct {
+	mg    Mailgun
+	store Store
+	id    string
+	opts  ListEventOptions
+	lease time.Duration
+
+	mu       sync.Mutex
+	leader   bool
+	handlers []func([]Event)
+}
+
+// CoordinateEvents creates a coordinator which polls the events of the domain with the given
+// options. The id identifies this process in the election, and must be unique among the
+// processes sharing the store.
+func (mg *MailgunImpl) CoordinateEvents(store Store, id string, opts *ListEventOptions) *EventCoordinator {
+	ec := &EventCoordinator{
+		mg:    mg,
+		store: store,
+		id:    id,
+		lease: DefaultCoordinatorLease,
+	}
+	if opts != nil {
+		ec.opts = *opts
+	}
+	// Set a 15 second poll interval if none set, as PollEvents() does
+	if ec.opts.PollInterval == 0 {
+		ec.opts.PollInterval = time.Second * 15
+	}
+	return ec
+}
+
+// SetLease sets how long the leader remains elected without renewing its lease, which is
+// the longest time events go unpolled after the leader stops. Defaults to DefaultCoordinatorLease.
+func (ec *EventCoordinator) SetLease(lease time.Duration) {
+	ec.lease = lease
+}
+
+// Subscribe registers a handler which is called with every page of new events polled by this
+// process while it is the leader. Handlers are called in the order they were registered.
+func (ec *EventCoordinator) Subscribe(handler func([]Event)) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.handlers = append(ec.handlers, handler)
+}
+
+// IsLeader returns true while this process is the elected poller.
+func (ec *EventCoordinator) IsLeader() bool {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	return ec.leader
+}
+
+// Run takes part in the election and polls events while this process is the leader.
+// It blocks until the context is cancelled or an error occurs.
+func (ec *EventCoordinator) Run(ctx context.Context) error {
+	for {
+		elected, err := ec.store.Acquire(ctx, ec.leaderKey(), ec.id, ec.lease)
+		if err != nil {
+			return err
+		}
+		if elected {
+			if err := ec.lead(ctx); err != nil {
+				return err
+			}
+		}
+
+		tick := time.NewTimer(ec.opts.PollInterval)
+		select {
+		case <-ctx.Done():
+			tick.Stop()
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// lead polls events until the context is cancelled or the lease is lost
+func (ec *EventCoordinator) lead(ctx context.Context) error {
+	ec.setLeader(true)
+	defer ec.setLeader(false)
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Renew the lease in the background, Poll() blocks until events arrive
+	lost := make(chan error, 1)
+	go func() {
+		tick := time.NewTicker(ec.lease / 3)
+		defer tick.Stop()
+		for {
+			select {
+			case <-pollCtx.Done():
+				return
+			case <-tick.C:
+				renewed, err := ec.store.Acquire(pollCtx, ec.leaderKey(), ec.id, ec.lease)
+				if err != nil || !renewed {
+					lost <- err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	opts := ec.opts
+	cursor, err := ec.cursor(ctx)
+	if err != nil {
+		return err
+	}
+	if !cursor.Timestamp.IsZero() {
+		opts.Begin = cursor.Timestamp
+	}
+
+	poller := ec.mg.PollEvents(&opts)
+	var page []Event
+	for poller.Poll(pollCtx, &page) {
+		// Begin has a resolution of a second, skip events which were already delivered
+		var fresh []Event
+		for _, e := range page {
+			if !cursor.delivered(e) {
+				fresh = append(fresh, e)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		ec.dispatch(fresh)
+
+		cursor.advance(fresh)
+		value, err := json.Marshal(cursor)
+		if err != nil {
+			return err
+		}
+		if err := ec.store.Set(ctx, ec.cursorKey(), string(value)); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-lost:
+		// A nil error means another process took over, stand by
+		return err
+	default:
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return poller.Err()
+}
+
+func (ec *EventCoordinator) dispatch(events []Event) {
+	ec.mu.Lock()
+	handlers := make([]func([]Event), len(ec.handlers))
+	copy(handlers, ec.handlers)
+	ec.mu.Unlock()
+	for _, h := range handlers {
+		h(events)
+	}
+}
+
+func (ec *EventCoordinator) setLeader(leader bool) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.leader = leader
+}
+
+// eventCursor is the position of the last event delivered by any leader. Events often share
+// a timestamp, the IDs of the events delivered with the last timestamp tell the events which
+// are yet to be delivered apart.
+type eventCursor struct {
+	Timestamp time.Time `json:"timestamp"`
+	IDs       []string  `json:"ids,omitempty"`
+}
+
+// delivered returns true if e was delivered before the cursor was saved
+func (c *eventCursor) delivered(e Event) bool {
+	ts := e.GetTimestamp()
+	if !ts.Equal(c.Timestamp) {
+		return ts.Before(c.Timestamp)
+	}
+	for _, id := range c.IDs {
+		if id == e.GetID() {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the cursor past delivered events
+func (c *eventCursor) advance(delivered []Event) {
+	for _, e := range delivered {
+		ts := e.GetTimestamp()
+		if ts.After(c.Timestamp) {
+			c.Timestamp = ts
+			c.IDs = nil
+		}
+		if ts.Equal(c.Timestamp) {
+			c.IDs = append(c.IDs, e.GetID())
+		}
+	}
+}
+
+// cursor returns the position of the last event delivered by any leader
+func (ec *EventCoordinator) cursor(ctx context.Context) (eventCursor, error) {
+	var cursor eventCursor
+	value, err := ec.store.Get(ctx, ec.cursorKey())
+	if err != nil || value == "" {
+		return cursor, err
+	}
+	if err := json.Unmarshal([]byte(value), &cursor); err != nil {
+		return cursor, fmt.Errorf("while reading the events cursor: %s", err)
+	}
+	return cursor, nil
+}
+
+func (ec *EventCoordinator) leaderKey() string {
+	return "mailgun-events/" + ec.mg.Domain() + "/leader"
+}
+
+func (ec *EventCoordinator) cursorKey() string {
+	return "mailgun-events/" + ec.mg.Domain() + "/cursor"
+}
@@ -0,0 +1,158 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+type coordinatorEvent struct {
+	id string
+	ts time.Time
+}
+
+type coordinatorServer struct {
+	mu     sync.Mutex
+	events []coordinatorEvent
+	keys   map[string]bool
+}
+
+// addEvent adds an event whose id is its unix timestamp, or id if given
+func (cs *coordinatorServer) addEvent(ts time.Time, id ...string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	e := coordinatorEvent{id: fmt.Sprint(ts.Unix()), ts: ts}
+	if len(id) != 0 {
+		e.id = id[0]
+	}
+	cs.events = append(cs.events, e)
+}
+
+func (cs *coordinatorServer) polledBy(key string) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.keys[key]
+}
+
+func (cs *coordinatorServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	_, key, _ := req.BasicAuth()
+	cs.keys[key] = true
+
+	next := fmt.Sprintf("http://%s/%s/events?page=2", req.Host, exampleDomain)
+	if req.FormValue("page") == "2" {
+		fmt.Fprintf(w, `{"items": [], "paging": {"next": %q}}`, next)
+		return
+	}
+	begin, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", req.FormValue("begin"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var items []string
+	for _, e := range cs.events {
+		if !e.ts.Before(begin) {
+			items = append(items, fmt.Sprintf(`{"event": "accepted", "id": %q, "timestamp": %d}`, e.id, e.ts.Unix()))
+		}
+	}
+	fmt.Fprintf(w, `{"items": [%s], "paging": {"next": %q}}`, strings.Join(items, ","), next)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventCoordinator(t *testing.T) {
+	t0 := time.Now().Add(-time.Minute).Truncate(time.Second)
+	cs := &coordinatorServer{keys: make(map[string]bool)}
+	cs.addEvent(t0.Add(time.Second))
+	cs.addEvent(t0.Add(2 * time.Second))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	received := make(chan []Event, 10)
+	newCoordinator := func(id string) *EventCoordinator {
+		mg := NewMailgun(exampleDomain, "key-"+id)
+		mg.SetAPIBase(srv.URL)
+		ec := mg.CoordinateEvents(store, id, &ListEventOptions{
+			Begin:        t0,
+			PollInterval: 20 * time.Millisecond,
+		})
+		ec.SetLease(150 * time.Millisecond)
+		ec.Subscribe(func(events []Event) { received <- events })
+		return ec
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	ecA := newCoordinator("a")
+	doneA := make(chan error, 1)
+	go func() { doneA <- ecA.Run(ctxA) }()
+	waitFor(t, ecA.IsLeader)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	ecB := newCoordinator("b")
+	doneB := make(chan error, 1)
+	go func() { doneB <- ecB.Run(ctxB) }()
+
+	events := <-received
+	ensure.DeepEqual(t, len(events), 2)
+	ensure.DeepEqual(t, events[1].GetID(), fmt.Sprint(t0.Add(2*time.Second).Unix()))
+
+	// Only the leader polls
+	time.Sleep(100 * time.Millisecond)
+	ensure.False(t, ecB.IsLeader())
+	ensure.False(t, cs.polledBy("key-b"))
+
+	// The standby takes over once the lease of the leader expires, and resumes after
+	// the last event the leader delivered, including the events which share its timestamp
+	cs.addEvent(t0.Add(2*time.Second), "same-second")
+	cs.addEvent(t0.Add(3 * time.Second))
+	cancelA()
+	ensure.DeepEqual(t, <-doneA, context.Canceled)
+	waitFor(t, ecB.IsLeader)
+
+	events = <-received
+	ensure.DeepEqual(t, len(events), 2)
+	ensure.DeepEqual(t, events[0].GetID(), "same-second")
+	ensure.DeepEqual(t, events[1].GetID(), fmt.Sprint(t0.Add(3*time.Second).Unix()))
+
+	cancelB()
+	ensure.DeepEqual(t, <-doneB, context.Canceled)
+}
+
+func TestMemoryStoreAcquire(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	ok, err := store.Acquire(ctx, "lock", "a", 50*time.Millisecond)
+	ensure.Nil(t, err)
+	ensure.True(t, ok)
+
+	// Held by a, renewable by a only
+	ok, _ = store.Acquire(ctx, "lock", "b", time.Minute)
+	ensure.False(t, ok)
+	ok, _ = store.Acquire(ctx, "lock", "a", 50*time.Millisecond)
+	ensure.True(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	value, _ := store.Get(ctx, "lock")
+	ensure.DeepEqual(t, value, "")
+	ok, _ = store.Acquire(ctx, "lock", "b", time.Minute)
+	ensure.True(t, ok)
+}
@@ -26,6 +26,9 @@ var ErrEmptyParam = fmt.Errorf("empty or illegal parameter")
 func (mg *MailgunImpl) ListCredentials(opts *ListOptions) *CredentialsIterator {
 	var limit int
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &CredentialsIterator{mg: mg, err: err}
+		}
 		limit = opts.Limit
 	}
 
@@ -162,7 +165,7 @@ func (ri *CredentialsIterator) Previous(ctx context.Context, items *[]Credential
 func (ri *CredentialsIterator) fetch(ctx context.Context, skip, limit int) error {
 	r := newHTTPRequest(ri.url)
 	r.setBasicAuth(basicAuthUser, ri.mg.APIKey())
-	r.setClient(ri.mg.Client())
+	r.setClient(ri.mg)
 
 	if skip != 0 {
 		r.addParameter("skip", strconv.Itoa(skip))
@@ -180,7 +183,7 @@ func (mg *MailgunImpl) CreateCredential(ctx context.Context, login, password str
 		return ErrEmptyParam
 	}
 	r := newHTTPRequest(generateCredentialsUrl(mg, ""))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	p.addValue("login", login)
@@ -195,7 +198,7 @@ func (mg *MailgunImpl) ChangeCredentialPassword(ctx context.Context, id, passwor
 		return ErrEmptyParam
 	}
 	r := newHTTPRequest(generateCredentialsUrl(mg, id))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	p.addValue("password", password)
@@ -209,7 +212,7 @@ func (mg *MailgunImpl) DeleteCredential(ctx context.Context, id string) error {
 		return ErrEmptyParam
 	}
 	r := newHTTPRequest(generateCredentialsUrl(mg, id))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
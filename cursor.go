@@ -0,0 +1,161 @@
+package mailgun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Resumable is a paging iterator whose position can be saved and restored, so a long running
+// job can resume where it left off after a restart, see IteratorCursor.
+type Resumable interface {
+	// Cursor returns the position of the iterator, from which Next() returns the page after
+	// the last page it returned
+	Cursor() string
+	// Resume positions the iterator at a cursor returned by Cursor()
+	Resume(cursor string) error
+}
+
+// IteratorCursor persists the position of a Resumable iterator under a key of a Store, such as
+// the Store of an EventCoordinator.
+//
+//	cursor := mailgun.NewIteratorCursor(store, "bounces-sync")
+//	it := mg.ListBounces(nil)
+//	if _, err := cursor.Restore(ctx, it); err != nil {
+//		return err
+//	}
+//	var page []mailgun.Bounce
+//	for it.Next(ctx, &page) {
+//		process(page)
+//		if err := cursor.Save(ctx, it); err != nil {
+//			return err
+//		}
+//	}
+//
+// Save the cursor once a page is processed, a crash then replays at most the page being
+// processed.
+type IteratorCursor struct {
+	store Store
+	key   string
+}
+
+// NewIteratorCursor creates a cursor stored under key.
+func NewIteratorCursor(store Store, key string) *IteratorCursor {
+	return &IteratorCursor{store: store, key: key}
+}
+
+// Restore positions it at the saved cursor. Returns false if no cursor was saved, in which
+// case it is left at its start.
+func (c *IteratorCursor) Restore(ctx context.Context, it Resumable) (bool, error) {
+	cursor, err := c.store.Get(ctx, c.key)
+	if err != nil || cursor == "" {
+		return false, err
+	}
+	if err := it.Resume(cursor); err != nil {
+		return false, fmt.Errorf("while restoring cursor '%s': %s", c.key, err)
+	}
+	return true, nil
+}
+
+// Save stores the position of it.
+func (c *IteratorCursor) Save(ctx context.Context, it Resumable) error {
+	return c.store.Set(ctx, c.key, it.Cursor())
+}
+
+// Reset forgets the saved cursor, so the next Restore() leaves the iterator at its start.
+func (c *IteratorCursor) Reset(ctx context.Context) error {
+	return c.store.Set(ctx, c.key, "")
+}
+
+// resumePaging points the next page of p at a cursor URL. The cursor must have the scheme and
+// host of the iterator, as it is requested with the credentials of the client.
+func resumePaging(p *Paging, cursor string) error {
+	u, err := url.Parse(cursor)
+	if err != nil {
+		return fmt.Errorf("invalid cursor: %s", err)
+	}
+	current, err := url.Parse(p.Next)
+	if err != nil {
+		return fmt.Errorf("invalid iterator URL: %s", err)
+	}
+	if u.Scheme != current.Scheme || u.Host != current.Host {
+		return errors.New("cursor doesn't belong to the API base of the iterator")
+	}
+	p.Next = cursor
+	return nil
+}
+
+// resumeOffset parses a cursor returned by an iterator paging by offset
+func resumeOffset(offset *int, cursor string) error {
+	n, err := strconv.Atoi(cursor)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid cursor '%s'", cursor)
+	}
+	*offset = n
+	return nil
+}
+
+// Cursor returns the URL of the next page.
+func (ci *BouncesIterator) Cursor() string { return ci.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ci *BouncesIterator) Resume(cursor string) error { return resumePaging(&ci.Paging, cursor) }
+
+// Cursor returns the URL of the next page.
+func (ci *UnsubscribesIterator) Cursor() string { return ci.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ci *UnsubscribesIterator) Resume(cursor string) error {
+	return resumePaging(&ci.Paging, cursor)
+}
+
+// Cursor returns the URL of the next page.
+func (ci *ComplaintsIterator) Cursor() string { return ci.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ci *ComplaintsIterator) Resume(cursor string) error { return resumePaging(&ci.Paging, cursor) }
+
+// Cursor returns the URL of the next page.
+func (li *MemberListIterator) Cursor() string { return li.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (li *MemberListIterator) Resume(cursor string) error { return resumePaging(&li.Paging, cursor) }
+
+// Cursor returns the URL of the next page.
+func (li *ListsIterator) Cursor() string { return li.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (li *ListsIterator) Resume(cursor string) error { return resumePaging(&li.Paging, cursor) }
+
+// Cursor returns the URL of the next page.
+func (ti *TemplatesIterator) Cursor() string { return ti.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ti *TemplatesIterator) Resume(cursor string) error { return resumePaging(&ti.Paging, cursor) }
+
+// Cursor returns the URL of the next page.
+func (ei *EventIterator) Cursor() string { return ei.Paging.Next }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ei *EventIterator) Resume(cursor string) error {
+	p := Paging(ei.Paging)
+	if err := resumePaging(&p, cursor); err != nil {
+		return err
+	}
+	ei.Paging.Next = p.Next
+	return nil
+}
+
+// Cursor returns the offset of the next page.
+func (ri *DomainsIterator) Cursor() string { return strconv.Itoa(ri.offset) }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ri *DomainsIterator) Resume(cursor string) error { return resumeOffset(&ri.offset, cursor) }
+
+// Cursor returns the offset of the next page.
+func (ri *CredentialsIterator) Cursor() string { return strconv.Itoa(ri.offset) }
+
+// Resume positions the iterator at a cursor returned by Cursor().
+func (ri *CredentialsIterator) Resume(cursor string) error { return resumeOffset(&ri.offset, cursor) }
@@ -0,0 +1,86 @@
+package mailgun_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+func TestIteratorCursor(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+	var members []mailgun.Member
+	for i := 0; i < 5; i++ {
+		members = append(members, mailgun.Member{Address: fmt.Sprintf("member%d@example.com", i)})
+	}
+	ensure.Nil(t, mg.CreateMembers(ctx, false, address, members))
+
+	store := mailgun.NewMemoryStore()
+	cursor := mailgun.NewIteratorCursor(store, "members-sync")
+
+	// A job processes the first page, then crashes
+	var page []mailgun.Member
+	it := mg.ListMembers(address, &mailgun.ListMembersOptions{Limit: 2})
+	restored, err := cursor.Restore(ctx, it)
+	ensure.Nil(t, err)
+	ensure.False(t, restored)
+	ensure.True(t, it.Next(ctx, &page))
+	ensure.DeepEqual(t, page[0].Address, "member0@example.com")
+	ensure.Nil(t, cursor.Save(ctx, it))
+
+	// The next job resumes after the first page
+	it = mg.ListMembers(address, &mailgun.ListMembersOptions{Limit: 2})
+	restored, err = cursor.Restore(ctx, it)
+	ensure.Nil(t, err)
+	ensure.True(t, restored)
+	var resumed []string
+	for it.Next(ctx, &page) {
+		for _, m := range page {
+			resumed = append(resumed, m.Address)
+		}
+		ensure.Nil(t, cursor.Save(ctx, it))
+	}
+	ensure.Nil(t, it.Err())
+	ensure.DeepEqual(t, resumed, []string{"member2@example.com", "member3@example.com", "member4@example.com"})
+
+	ensure.Nil(t, cursor.Reset(ctx))
+	restored, err = cursor.Restore(ctx, mg.ListMembers(address, nil))
+	ensure.Nil(t, err)
+	ensure.False(t, restored)
+
+	// Cursors pointing elsewhere than the API base are rejected
+	ensure.Nil(t, store.Set(ctx, "members-sync", "https://attacker.example.com/lists/pages"))
+	_, err = cursor.Restore(ctx, mg.ListMembers(address, nil))
+	ensure.NotNil(t, err)
+}
+
+func TestIteratorCursorOffset(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	var all []mailgun.Domain
+	ensure.True(t, mg.ListDomains(nil).Next(ctx, &all))
+
+	it := mg.ListDomains(nil)
+	ensure.Nil(t, it.Resume("1"))
+	var page []mailgun.Domain
+	it.Next(ctx, &page)
+	ensure.Nil(t, it.Err())
+	ensure.DeepEqual(t, len(page), len(all)-1)
+	ensure.DeepEqual(t, it.Cursor(), fmt.Sprint(len(all)))
+
+	ensure.NotNil(t, it.Resume("-1"))
+	ensure.NotNil(t, it.Resume("next"))
+}
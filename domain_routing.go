@@ -0,0 +1,87 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainRoutingTTL is how long SetDomainRouting() caches the domains of the account
+const DomainRoutingTTL = 10 * time.Minute
+
+// domainRoutingCache holds the active domains of the account, shared by the copies of a client
+type domainRoutingCache struct {
+	mu      sync.Mutex
+	domains []string
+	fetched time.Time
+}
+
+// SetDomainRouting makes Send() post each message to the active domain of the account matching
+// the domain of its From address, so applications sending for several brands don't need a
+// client per domain. A From address matches a domain if it is the domain or a subdomain of it,
+// the most specific domain wins. Messages with a domain set by AddDomain(), MIME messages and
+// messages without a matching domain are sent from the domain of the client.
+//
+// The domains are listed with ListDomains() on the first send and cached for DomainRoutingTTL.
+func (mg *MailgunImpl) SetDomainRouting(enabled bool) {
+	mg.domainRouting = enabled
+}
+
+// routeDomain sets the domain of a plain message to the domain matching its From address
+func (mg *MailgunImpl) routeDomain(ctx context.Context, m *Message) error {
+	plain, ok := m.specific.(*plainMessage)
+	if !ok || m.domain != "" {
+		return nil
+	}
+	from, err := mail.ParseAddress(plain.from)
+	if err != nil {
+		return fmt.Errorf("while routing message: invalid From address: %s", err)
+	}
+	fromDomain := strings.ToLower(from.Address[strings.LastIndex(from.Address, "@")+1:])
+
+	domains, err := mg.routingDomains(ctx)
+	if err != nil {
+		return err
+	}
+	var match string
+	for _, d := range domains {
+		if (fromDomain == d || strings.HasSuffix(fromDomain, "."+d)) && len(d) > len(match) {
+			match = d
+		}
+	}
+	if match != "" {
+		m.domain = match
+	}
+	return nil
+}
+
+// routingDomains returns the names of the active domains of the account, listing them again
+// once the cache expired
+func (mg *MailgunImpl) routingDomains(ctx context.Context) ([]string, error) {
+	c := mg.domainRoutes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetched.IsZero() && time.Since(c.fetched) < DomainRoutingTTL {
+		return c.domains, nil
+	}
+
+	var domains []string
+	var page []Domain
+	it := mg.ListDomains(nil)
+	for it.Next(ctx, &page) {
+		for _, d := range page {
+			if d.State == "active" {
+				domains = append(domains, strings.ToLower(d.Name))
+			}
+		}
+	}
+	if it.Err() != nil {
+		return nil, fmt.Errorf("while listing domains for routing: %s", it.Err())
+	}
+	c.domains = domains
+	c.fetched = time.Now()
+	return domains, nil
+}
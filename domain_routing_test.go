@@ -0,0 +1,71 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDomainRouting(t *testing.T) {
+	var listings int
+	var sentFrom []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/domains" {
+			if req.FormValue("skip") != "" {
+				fmt.Fprint(w, `{"total_count": 3, "items": []}`)
+				return
+			}
+			listings++
+			fmt.Fprint(w, `{"total_count": 3, "items": [
+				{"name": "brand-a.com", "state": "active"},
+				{"name": "mail.brand-b.com", "state": "active"},
+				{"name": "brand-c.com", "state": "unverified"}
+			]}`)
+			return
+		}
+		ensure.True(t, strings.HasSuffix(req.URL.Path, "/messages"))
+		sentFrom = append(sentFrom, strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/messages"))
+		fmt.Fprint(w, `{"message": "Queued. Thank you.", "id": "<1@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun("", exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetDomainRouting(true)
+	ctx := context.Background()
+
+	for _, from := range []string{
+		"Brand A <news@brand-a.com>",
+		"alerts@eu.mail.brand-b.com",
+		"Brand A <support@BRAND-A.COM>",
+	} {
+		_, _, err := mg.Send(ctx, mg.NewMessage(from, exampleSubject, exampleText, "to@example.com"))
+		ensure.Nil(t, err)
+	}
+	ensure.DeepEqual(t, sentFrom, []string{"brand-a.com", "mail.brand-b.com", "brand-a.com"})
+	// The domains are listed once
+	ensure.DeepEqual(t, listings, 1)
+
+	// A domain set on the message wins
+	m := mg.NewMessage("news@brand-a.com", exampleSubject, exampleText, "to@example.com")
+	m.AddDomain("override.com")
+	_, _, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sentFrom[3], "override.com")
+
+	// Unverified domains don't match, and there's no client domain to fall back to
+	_, _, err = mg.Send(ctx, mg.NewMessage("news@brand-c.com", exampleSubject, exampleText, "to@example.com"))
+	ensure.StringContains(t, err.Error(), "no domain of the account matches")
+
+	fallback := NewMailgun(exampleDomain, exampleAPIKey)
+	fallback.SetAPIBase(srv.URL)
+	fallback.SetDomainRouting(true)
+	_, _, err = fallback.Send(ctx, fallback.NewMessage("news@brand-c.com", exampleSubject, exampleText, "to@example.com"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sentFrom[4], exampleDomain)
+}
@@ -2,6 +2,8 @@ package mailgun
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -27,6 +29,7 @@ type Domain struct {
 	Wildcard     bool        `json:"wildcard"`
 	SpamAction   SpamAction  `json:"spam_action"`
 	State        string      `json:"state"`
+	WebScheme    string      `json:"web_scheme"`
 }
 
 // DNSRecord structures describe intended records to properly configure your domain for use with Mailgun.
@@ -79,10 +82,34 @@ type domainTrackingResponse struct {
 	Tracking DomainTracking `json:"tracking"`
 }
 
+// The status of the regular and scheduled sending queues of a domain
+type SendingQueues struct {
+	Regular   SendingQueue `json:"regular"`
+	Scheduled SendingQueue `json:"scheduled"`
+}
+
+// The status of a sending queue. A queue is disabled when Mailgun stops
+// delivering from it, Disabled then holds the reason and until when.
+type SendingQueue struct {
+	// The number of messages waiting in the queue
+	Size       int             `json:"size"`
+	IsDisabled bool            `json:"is_disabled"`
+	Disabled   QueueDisabledBy `json:"disabled"`
+}
+
+// Why and until when a sending queue is disabled
+type QueueDisabledBy struct {
+	Until  string `json:"until"`
+	Reason string `json:"reason"`
+}
+
 // ListDomains retrieves a set of domains from Mailgun.
 func (mg *MailgunImpl) ListDomains(opts *ListOptions) *DomainsIterator {
 	var limit int
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &DomainsIterator{mg: mg, err: err}
+		}
 		limit = opts.Limit
 	}
 
@@ -219,7 +246,7 @@ func (ri *DomainsIterator) Previous(ctx context.Context, items *[]Domain) bool {
 func (ri *DomainsIterator) fetch(ctx context.Context, skip, limit int) error {
 	r := newHTTPRequest(ri.url)
 	r.setBasicAuth(basicAuthUser, ri.mg.APIKey())
-	r.setClient(ri.mg.Client())
+	r.setClient(ri.mg)
 
 	if skip != 0 {
 		r.addParameter("skip", strconv.Itoa(skip))
@@ -234,7 +261,7 @@ func (ri *DomainsIterator) fetch(ctx context.Context, skip, limit int) error {
 // Retrieve detailed information about the named domain.
 func (mg *MailgunImpl) GetDomain(ctx context.Context, domain string) (DomainResponse, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var resp DomainResponse
 	err := getResponseFromJSON(ctx, r, &resp)
@@ -243,7 +270,7 @@ func (mg *MailgunImpl) GetDomain(ctx context.Context, domain string) (DomainResp
 
 func (mg *MailgunImpl) VerifyDomain(ctx context.Context, domain string) (string, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain + "/verify")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
@@ -254,27 +281,66 @@ func (mg *MailgunImpl) VerifyDomain(ctx context.Context, domain string) (string,
 
 // Optional parameters when creating a domain
 type CreateDomainOptions struct {
-	SpamAction         SpamAction
-	Wildcard           bool
+	SpamAction SpamAction
+	// Treat all subdomains of the domain as the domain
+	Wildcard bool
+	// Sign messages with the DKIM key of the domain itself, rather than of its parent domain
 	ForceDKIMAuthority bool
-	DKIMKeySize        int
-	IPS                []string
+	// 1024 or 2048, defaults to 1024
+	DKIMKeySize int
+	// The IP addresses the domain sends from, among the dedicated IPs of the account
+	IPS []string
+	// The scheme of the tracking links, "http" or "https", defaults to "http"
+	WebScheme string
+}
+
+// Validate returns an *OptionError if the options are invalid. A nil *CreateDomainOptions is valid.
+func (o *CreateDomainOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.SpamAction {
+	case "", SpamActionTag, SpamActionDisabled, SpamActionDelete:
+	default:
+		return invalidOption("SpamAction", fmt.Sprintf("unknown spam action '%s'", o.SpamAction))
+	}
+	switch o.DKIMKeySize {
+	case 0, 1024, 2048:
+	default:
+		return invalidOption("DKIMKeySize", "must be 1024 or 2048")
+	}
+	for _, ip := range o.IPS {
+		if net.ParseIP(ip) == nil {
+			return invalidOption("IPS", fmt.Sprintf("invalid IP address '%s'", ip))
+		}
+	}
+	switch o.WebScheme {
+	case "", "http", "https":
+	default:
+		return invalidOption("WebScheme", "must be http or https")
+	}
+	return nil
 }
 
 // CreateDomain instructs Mailgun to create a new domain for your account.
 // The name parameter identifies the domain.
-// The smtpPassword parameter provides an access credential for the domain.
-// The spamAction domain must be one of Delete, Tag, or Disabled.
-// The wildcard parameter instructs Mailgun to treat all subdomains of this domain uniformly if true,
-// and as different domains if false.
+// The password parameter provides the SMTP password of the postmaster@ login of the domain,
+// Mailgun generates one if it is empty.
+// The opts parameter sets the remaining settings of the domain, such as its spam action and
+// sending IPs, as the control panel does.
 func (mg *MailgunImpl) CreateDomain(ctx context.Context, name string, password string, opts *CreateDomainOptions) (DomainResponse, error) {
+	if err := opts.Validate(); err != nil {
+		return DomainResponse{}, err
+	}
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
 	payload.addValue("name", name)
-	payload.addValue("smtp_password", password)
+	if password != "" {
+		payload.addValue("smtp_password", password)
+	}
 
 	if opts != nil {
 		if opts.SpamAction != "" {
@@ -292,6 +358,9 @@ func (mg *MailgunImpl) CreateDomain(ctx context.Context, name string, password s
 		if len(opts.IPS) != 0 {
 			payload.addValue("ips", strings.Join(opts.IPS, ","))
 		}
+		if opts.WebScheme != "" {
+			payload.addValue("web_scheme", opts.WebScheme)
+		}
 	}
 	var resp DomainResponse
 	err := postResponseFromJSON(ctx, r, payload, &resp)
@@ -301,7 +370,7 @@ func (mg *MailgunImpl) CreateDomain(ctx context.Context, name string, password s
 // Returns delivery connection settings for the defined domain
 func (mg *MailgunImpl) GetDomainConnection(ctx context.Context, domain string) (DomainConnection, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain + "/connection")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var resp domainConnectionResponse
 	err := getResponseFromJSON(ctx, r, &resp)
@@ -311,7 +380,7 @@ func (mg *MailgunImpl) GetDomainConnection(ctx context.Context, domain string) (
 // Updates the specified delivery connection settings for the defined domain
 func (mg *MailgunImpl) UpdateDomainConnection(ctx context.Context, domain string, settings DomainConnection) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain + "/connection")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
@@ -324,7 +393,7 @@ func (mg *MailgunImpl) UpdateDomainConnection(ctx context.Context, domain string
 // DeleteDomain instructs Mailgun to dispose of the named domain name
 func (mg *MailgunImpl) DeleteDomain(ctx context.Context, name string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + name)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -333,13 +402,71 @@ func (mg *MailgunImpl) DeleteDomain(ctx context.Context, name string) error {
 // Returns tracking settings for a domain
 func (mg *MailgunImpl) GetDomainTracking(ctx context.Context, domain string) (DomainTracking, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain + "/tracking")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var resp domainTrackingResponse
 	err := getResponseFromJSON(ctx, r, &resp)
 	return resp.Tracking, err
 }
 
+// UpdateOpenTracking enables or disables open tracking for the messages of a domain.
+func (mg *MailgunImpl) UpdateOpenTracking(ctx context.Context, domain string, active bool) error {
+	payload := newUrlEncodedPayload()
+	payload.addValue("active", yesNo(active))
+	return mg.updateDomainTracking(ctx, domain, "open", payload)
+}
+
+// UpdateClickTracking sets the click tracking mode for the messages of a domain, one of
+// TrackingClicksEnabled, TrackingClicksDisabled or TrackingClicksHTMLOnly.
+func (mg *MailgunImpl) UpdateClickTracking(ctx context.Context, domain string, mode TrackingClicksMode) error {
+	switch mode {
+	case TrackingClicksEnabled, TrackingClicksDisabled, TrackingClicksHTMLOnly:
+	default:
+		return invalidOption("mode", fmt.Sprintf("unknown click tracking mode '%s'", mode))
+	}
+	payload := newUrlEncodedPayload()
+	payload.addValue("active", string(mode))
+	return mg.updateDomainTracking(ctx, domain, "click", payload)
+}
+
+// UpdateUnsubscribeTracking enables or disables the unsubscribe link Mailgun appends to the
+// messages of a domain, and sets the footers containing it. The footers reference the link as
+// %unsubscribe_url%, empty footers are left unchanged.
+func (mg *MailgunImpl) UpdateUnsubscribeTracking(ctx context.Context, domain string, active bool, htmlFooter, textFooter string) error {
+	payload := newUrlEncodedPayload()
+	payload.addValue("active", yesNo(active))
+	if htmlFooter != "" {
+		payload.addValue("html_footer", htmlFooter)
+	}
+	if textFooter != "" {
+		payload.addValue("text_footer", textFooter)
+	}
+	return mg.updateDomainTracking(ctx, domain, "unsubscribe", payload)
+}
+
+func (mg *MailgunImpl) updateDomainTracking(ctx context.Context, domain, kind string, payload *urlEncodedPayload) error {
+	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + domain + "/tracking/" + kind)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makePutRequest(ctx, r, payload)
+	return err
+}
+
+// GetSendingQueues returns the status of the sending queues of the domain configured for this client.
+// Changes in the size of the queues are published to the ClientEvents of the client, if any.
+func (mg *MailgunImpl) GetSendingQueues(ctx context.Context) (SendingQueues, error) {
+	r := newHTTPRequest(generateDomainApiUrl(mg, sendingQueuesEndpoint))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	var resp SendingQueues
+	if err := getResponseFromJSON(ctx, r, &resp); err != nil {
+		return resp, err
+	}
+	mg.events.publishQueueDepth(mg.domain, "regular", resp.Regular.Size)
+	mg.events.publishQueueDepth(mg.domain, "scheduled", resp.Scheduled.Size)
+	return resp, nil
+}
+
 func boolToString(b bool) string {
 	if b {
 		return "true"
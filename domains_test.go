@@ -83,6 +83,30 @@ func TestAddDeleteDomain(t *testing.T) {
 	ensure.Nil(t, mg.DeleteDomain(ctx, "mx.mailgun.test"))
 }
 
+func TestCreateDomainOptions(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	_, err := mg.CreateDomain(ctx, "brand.mailgun.test", "", &mailgun.CreateDomainOptions{
+		SpamAction:         mailgun.SpamActionDelete,
+		Wildcard:           true,
+		ForceDKIMAuthority: true,
+		DKIMKeySize:        2048,
+		WebScheme:          "https",
+	})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteDomain(ctx, "brand.mailgun.test"))
+	}()
+
+	resp, err := mg.GetDomain(ctx, "brand.mailgun.test")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, resp.Domain.SpamAction, mailgun.SpamActionDelete)
+	ensure.True(t, resp.Domain.Wildcard)
+	ensure.DeepEqual(t, resp.Domain.WebScheme, "https")
+}
+
 func TestDomainConnection(t *testing.T) {
 	mg := mailgun.NewMailgun(testDomain, testKey)
 	mg.SetAPIBase(server.URL())
@@ -127,3 +151,56 @@ func TestDomainVerify(t *testing.T) {
 	_, err := mg.VerifyDomain(ctx, testDomain)
 	ensure.Nil(t, err)
 }
+
+func TestGetSendingQueues(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+
+	queues, err := mg.GetSendingQueues(context.Background())
+	ensure.Nil(t, err)
+
+	ensure.DeepEqual(t, queues.Regular.Size, 12)
+	ensure.False(t, queues.Regular.IsDisabled)
+	ensure.DeepEqual(t, queues.Scheduled.Size, 3)
+	ensure.True(t, queues.Scheduled.IsDisabled)
+	ensure.DeepEqual(t, queues.Scheduled.Disabled.Reason, "sending limit exceeded")
+}
+
+func TestUpdateDomainTracking(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	const domain = "tracking.mailgun.test"
+	_, err := mg.CreateDomain(ctx, domain, "", nil)
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteDomain(ctx, domain))
+	}()
+
+	ensure.Nil(t, mg.UpdateOpenTracking(ctx, domain, true))
+	ensure.Nil(t, mg.UpdateClickTracking(ctx, domain, mailgun.TrackingClicksHTMLOnly))
+	ensure.Nil(t, mg.UpdateUnsubscribeTracking(ctx, domain, true,
+		`<p><a href="%unsubscribe_url%">Unsubscribe</a></p>`, "Unsubscribe: %unsubscribe_url%"))
+
+	tracking, err := mg.GetDomainTracking(ctx, domain)
+	ensure.Nil(t, err)
+	ensure.True(t, tracking.Open.Active)
+	ensure.True(t, tracking.Click.Active)
+	ensure.True(t, tracking.Unsubscribe.Active)
+	ensure.DeepEqual(t, tracking.Unsubscribe.HTMLFooter, `<p><a href="%unsubscribe_url%">Unsubscribe</a></p>`)
+	ensure.DeepEqual(t, tracking.Unsubscribe.TextFooter, "Unsubscribe: %unsubscribe_url%")
+
+	// Empty footers are left unchanged
+	ensure.Nil(t, mg.UpdateUnsubscribeTracking(ctx, domain, false, "", ""))
+	ensure.Nil(t, mg.UpdateOpenTracking(ctx, domain, false))
+	tracking, err = mg.GetDomainTracking(ctx, domain)
+	ensure.Nil(t, err)
+	ensure.False(t, tracking.Open.Active)
+	ensure.False(t, tracking.Unsubscribe.Active)
+	ensure.DeepEqual(t, tracking.Unsubscribe.TextFooter, "Unsubscribe: %unsubscribe_url%")
+
+	err = mg.UpdateClickTracking(ctx, domain, "sometimes")
+	_, ok := err.(*mailgun.OptionError)
+	ensure.True(t, ok)
+}
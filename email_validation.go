@@ -137,7 +137,7 @@ func (m *EmailValidatorImpl) getAddressURL(endpoint string) string {
 // It may also be used to break an email address into its sub-components.  (See example.)
 func (m *EmailValidatorImpl) ValidateEmail(ctx context.Context, email string, mailBoxVerify bool) (EmailVerification, error) {
 	r := newHTTPRequest(m.getAddressURL("validate"))
-	r.setClient(m.Client())
+	r.setClient(m)
 	r.addParameter("address", email)
 	if mailBoxVerify {
 		r.addParameter("mailbox_verification", "true")
@@ -157,7 +157,7 @@ func (m *EmailValidatorImpl) ValidateEmail(ctx context.Context, email string, ma
 // NOTE: Use of this function requires a proper public API key.  The private API key will not work.
 func (m *EmailValidatorImpl) ParseAddresses(ctx context.Context, addresses ...string) ([]string, []string, error) {
 	r := newHTTPRequest(m.getAddressURL("parse"))
-	r.setClient(m.Client())
+	r.setClient(m)
 	r.addParameter("addresses", strings.Join(addresses, ","))
 	r.setBasicAuth(basicAuthUser, m.APIKey())
 
@@ -169,3 +169,77 @@ func (m *EmailValidatorImpl) ParseAddresses(ctx context.Context, addresses ...st
 
 	return response.Parsed, response.Unparseable, nil
 }
+
+// ValidationResult is the verdict of a v4 email validation
+type ValidationResult string
+
+const (
+	ValidationDeliverable   ValidationResult = "deliverable"
+	ValidationUndeliverable ValidationResult = "undeliverable"
+	ValidationDoNotSend     ValidationResult = "do_not_send"
+	ValidationCatchAll      ValidationResult = "catch_all"
+	ValidationUnknown       ValidationResult = "unknown"
+)
+
+// ValidationRisk is the risk of sending to an address, as assessed by a v4 email validation
+type ValidationRisk string
+
+const (
+	RiskLow     ValidationRisk = "low"
+	RiskMedium  ValidationRisk = "medium"
+	RiskHigh    ValidationRisk = "high"
+	RiskUnknown ValidationRisk = "unknown"
+)
+
+// EmailValidation is the result of validating an address with the v4 validation API.
+// See ValidateEmail() for details.
+type EmailValidation struct {
+	// Echoes the address provided.
+	Address string `json:"address"`
+	// Provides a simple recommendation in case the address is invalid or
+	// Mailgun thinks you might have a typo. May be empty, in which case
+	// Mailgun has no recommendation to give.
+	DidYouMean string `json:"did_you_mean"`
+	// Indicates whether Mailgun thinks the address is from a known
+	// disposable mailbox provider.
+	IsDisposableAddress bool `json:"is_disposable_address"`
+	// Indicates whether Mailgun thinks the address is an email distribution list.
+	IsRoleAddress bool `json:"is_role_address"`
+	// The reasons the address is not reported as deliverable, if any.
+	Reason []string         `json:"reason"`
+	Result ValidationResult `json:"result"`
+	Risk   ValidationRisk   `json:"risk"`
+	// The address without any alias or tag, if it differs from Address.
+	RootAddress string `json:"root_address"`
+}
+
+// ValidateEmailOptions are the optional parameters of ValidateEmail()
+type ValidateEmailOptions struct {
+	// Skips the lookup of the mailbox provider of the address, which is faster but
+	// may return ValidationUnknown more often.
+	SkipProviderLookup bool
+}
+
+// ValidateEmail checks an address with the v4 email validation API, which reports whether the
+// address is deliverable and the risk of sending to it. Unlike EmailValidatorImpl, which uses the
+// deprecated v3 API, ValidateEmail uses the private API key of the client.
+//
+//	v, err := mg.ValidateEmail(ctx, "recipient@example.com", nil)
+//	if err == nil && v.Result == mailgun.ValidationDeliverable && v.Risk != mailgun.RiskHigh {
+//		...
+//	}
+func (mg *MailgunImpl) ValidateEmail(ctx context.Context, address string, opts *ValidateEmailOptions) (EmailValidation, error) {
+	r := newHTTPRequest(generateV4ApiUrl(mg, "address/validate"))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	r.addParameter("address", address)
+	if opts != nil && opts.SkipProviderLookup {
+		r.addParameter("provider_lookup", "false")
+	}
+
+	var response EmailValidation
+	if err := getResponseFromJSON(ctx, r, &response); err != nil {
+		return EmailValidation{}, err
+	}
+	return response, nil
+}
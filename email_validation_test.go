@@ -76,3 +76,50 @@ func TestUnmarshallResponse(t *testing.T) {
 	ensure.DeepEqual(t, ev.Parts.Domain, "aol.com")
 	ensure.True(t, ev.Reason == "")
 }
+
+func TestValidateEmailV4(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	v, err := mg.ValidateEmail(ctx, "foo@mailgun.com", nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Address, "foo@mailgun.com")
+	ensure.DeepEqual(t, v.Result, mailgun.ValidationDeliverable)
+	ensure.DeepEqual(t, v.Risk, mailgun.RiskLow)
+	ensure.False(t, v.IsRoleAddress)
+	ensure.DeepEqual(t, len(v.Reason), 0)
+
+	v, err = mg.ValidateEmail(ctx, "postmaster@mailgun.com", &mailgun.ValidateEmailOptions{SkipProviderLookup: true})
+	ensure.Nil(t, err)
+	ensure.True(t, v.IsRoleAddress)
+	ensure.DeepEqual(t, v.Risk, mailgun.RiskMedium)
+
+	v, err = mg.ValidateEmail(ctx, "mailgun.com", nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, v.Result, mailgun.ValidationUndeliverable)
+	ensure.DeepEqual(t, v.Reason, []string{"malformed address"})
+}
+
+func TestUnmarshallV4Response(t *testing.T) {
+	payload := []byte(`{
+		"address": "some_email@aol.com",
+		"did_you_mean": null,
+		"is_disposable_address": false,
+		"is_role_address": false,
+		"reason": ["no_mx"],
+		"result": "undeliverable",
+		"risk": "high",
+		"root_address": null
+	}`)
+	var v mailgun.EmailValidation
+	err := json.Unmarshal(payload, &v)
+	ensure.Nil(t, err)
+
+	ensure.DeepEqual(t, v.Address, "some_email@aol.com")
+	ensure.DeepEqual(t, v.Result, mailgun.ValidationUndeliverable)
+	ensure.DeepEqual(t, v.Risk, mailgun.RiskHigh)
+	ensure.DeepEqual(t, v.Reason, []string{"no_mx"})
+	ensure.True(t, v.DidYouMean == "")
+	ensure.True(t, v.RootAddress == "")
+}
@@ -0,0 +1,126 @@
+package mailgun
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mailgun/mailgun-go/events"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// EventCodec decodes the JSON of events into the struct registered for their name, such as
+// events.Delivered for "delivered".
+type EventCodec struct {
+	mu    sync.RWMutex
+	types map[string]func() Event
+}
+
+// DefaultEventCodec decodes the events returned by ListEvents(), PollEvents() and ParseEvent().
+// Register custom event types with it to decode them wherever events are read. It shares its
+// registrations with EventNames.
+var DefaultEventCodec = &EventCodec{types: EventNames}
+
+// NewEventCodec creates a codec which decodes the events of EventNames.
+func NewEventCodec() *EventCodec {
+	c := &EventCodec{types: make(map[string]func() Event)}
+	for name, newEvent := range EventNames {
+		c.types[name] = newEvent
+	}
+	return c
+}
+
+// Register decodes the events named name into the Event returned by newEvent, replacing the
+// struct registered for the name, if any. Register is meant to be called during initialization,
+// before events are decoded.
+func (c *EventCodec) Register(name string, newEvent func() Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types[name] = newEvent
+}
+
+// RegisterCustom decodes the events named name into a *CustomEvent, whose Data is the value
+// returned by newData decoded with encoding/json. Use it for event types, such as beta events
+// or payloads augmented by a proxy, for which no easyjson code was generated.
+//
+//	type Escalated struct {
+//		Recipient string `json:"recipient"`
+//		Team      string `json:"team"`
+//	}
+//
+//	mailgun.DefaultEventCodec.RegisterCustom("escalated", func() interface{} { return &Escalated{} })
+//	...
+//	if e, ok := event.(*mailgun.CustomEvent); ok {
+//		escalated := e.Data.(*Escalated)
+//	}
+func (c *EventCodec) RegisterCustom(name string, newData func() interface{}) {
+	c.Register(name, func() Event {
+		return &CustomEvent{Data: newData()}
+	})
+}
+
+// Decode decodes the JSON of an event. Can accept events.RawJSON as input.
+func (c *EventCodec) Decode(raw []byte) (Event, error) {
+	// Try to recognize the event first.
+	var e events.EventName
+	if err := easyjson.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("failed to recognize event: %v", err)
+	}
+
+	c.mu.RLock()
+	newEvent, ok := c.types[e.Name]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported event: '%s'", e.Name)
+	}
+	event := newEvent()
+
+	if err := easyjson.Unmarshal(raw, event); err != nil {
+		return nil, fmt.Errorf("failed to parse event '%s': %v", e.Name, err)
+	}
+	return event, nil
+}
+
+// DecodeAll decodes a slice of events.
+func (c *EventCodec) DecodeAll(raw []events.RawJSON) ([]Event, error) {
+	var result []Event
+	for _, value := range raw {
+		event, err := c.Decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing event: %s", err)
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+// CustomEvent is an event decoded into a user-defined struct, see EventCodec.RegisterCustom().
+type CustomEvent struct {
+	events.Generic
+	// The user-defined struct the event was decoded into
+	Data interface{}
+}
+
+// UnmarshalEasyJSON decodes the common fields of the event and its Data with encoding/json.
+func (e *CustomEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	raw := l.Raw()
+	if !l.Ok() {
+		return
+	}
+	if err := json.Unmarshal(raw, &e.Generic); err != nil {
+		l.AddError(err)
+		return
+	}
+	if e.Data != nil {
+		if err := json.Unmarshal(raw, e.Data); err != nil {
+			l.AddError(err)
+		}
+	}
+}
+
+// MarshalEasyJSON encodes the Data of the event with encoding/json.
+func (e *CustomEvent) MarshalEasyJSON(w *jwriter.Writer) {
+	w.Raw(json.Marshal(e.Data))
+}
@@ -0,0 +1,48 @@
+package mailgun_test
+
+import (
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+type escalated struct {
+	Recipient string `json:"recipient"`
+	Team      string `json:"team"`
+}
+
+func TestEventCodec(t *testing.T) {
+	raw := []byte(`{"event": "escalated", "id": "id-1", "timestamp": 1560000000.5, "recipient": "bob@example.com", "team": "support"}`)
+
+	codec := mailgun.NewEventCodec()
+	_, err := codec.Decode(raw)
+	ensure.StringContains(t, err.Error(), "unsupported event: 'escalated'")
+
+	codec.RegisterCustom("escalated", func() interface{} { return &escalated{} })
+	event, err := codec.Decode(raw)
+	ensure.Nil(t, err)
+	custom, ok := event.(*mailgun.CustomEvent)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, custom.GetName(), "escalated")
+	ensure.DeepEqual(t, custom.GetID(), "id-1")
+	ensure.DeepEqual(t, custom.GetTimestamp().Unix(), int64(1560000000))
+	ensure.DeepEqual(t, custom.Data, &escalated{Recipient: "bob@example.com", Team: "support"})
+
+	// Standard events are still decoded into their structs
+	event, err = codec.Decode([]byte(`{"event": "delivered", "recipient": "bob@example.com"}`))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, event.(*events.Delivered).Recipient, "bob@example.com")
+
+	// Other codecs are not affected
+	_, err = mailgun.ParseEvent(raw)
+	ensure.NotNil(t, err)
+
+	// Types registered with the default codec are decoded everywhere events are read
+	mailgun.DefaultEventCodec.RegisterCustom("escalated", func() interface{} { return &escalated{} })
+	defer delete(mailgun.EventNames, "escalated")
+	parsed, err := mailgun.ParseEvents([]events.RawJSON{raw})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, parsed[0].(*mailgun.CustomEvent).Data.(*escalated).Team, "support")
+}
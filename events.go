@@ -28,6 +28,34 @@ type ListEventOptions struct {
 	PollInterval time.Duration
 }
 
+// MaxEventsLimit is the largest page of events the events api returns
+const MaxEventsLimit = 300
+
+// Validate returns an *OptionError if the options are invalid. A nil *ListEventOptions is valid.
+func (o *ListEventOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Limit < 0 || o.Limit > MaxEventsLimit {
+		return invalidOption("Limit", fmt.Sprintf("must be between 0 and %d", MaxEventsLimit))
+	}
+	if o.ForceAscending && o.ForceDescending {
+		return invalidOption("ForceAscending", "ForceAscending and ForceDescending are mutually exclusive")
+	}
+	if !o.Begin.IsZero() && !o.End.IsZero() {
+		if o.ForceAscending && o.Begin.After(o.End) {
+			return invalidOption("Begin", "must be before End when ForceAscending is set")
+		}
+		if o.ForceDescending && o.Begin.Before(o.End) {
+			return invalidOption("Begin", "must be after End when ForceDescending is set")
+		}
+	}
+	if o.PollInterval < 0 {
+		return invalidOption("PollInterval", "must not be negative")
+	}
+	return nil
+}
+
 // EventIterator maintains the state necessary for paging though small parcels of a larger set of events.
 type EventIterator struct {
 	events.Response
@@ -39,6 +67,9 @@ type EventIterator struct {
 func (mg *MailgunImpl) ListEvents(opts *ListEventOptions) *EventIterator {
 	req := newHTTPRequest(generateApiUrl(mg, eventsEndpoint))
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &EventIterator{mg: mg, err: err}
+		}
 		if opts.Limit > 0 {
 			req.addParameter("limit", fmt.Sprintf("%d", opts.Limit))
 		}
@@ -86,7 +117,7 @@ func (ei *EventIterator) Next(ctx context.Context, events *[]Event) bool {
 	if ei.err != nil {
 		return false
 	}
-	*events, ei.err = ParseEvents(ei.Items)
+	*events, ei.err = ei.parseItems()
 	if len(ei.Items) == 0 {
 		return false
 	}
@@ -104,7 +135,7 @@ func (ei *EventIterator) First(ctx context.Context, events *[]Event) bool {
 	if ei.err != nil {
 		return false
 	}
-	*events, ei.err = ParseEvents(ei.Items)
+	*events, ei.err = ei.parseItems()
 	return true
 }
 
@@ -120,7 +151,7 @@ func (ei *EventIterator) Last(ctx context.Context, events *[]Event) bool {
 	if ei.err != nil {
 		return false
 	}
-	*events, ei.err = ParseEvents(ei.Items)
+	*events, ei.err = ei.parseItems()
 	return true
 }
 
@@ -138,16 +169,35 @@ func (ei *EventIterator) Previous(ctx context.Context, events *[]Event) bool {
 	if ei.err != nil {
 		return false
 	}
-	*events, ei.err = ParseEvents(ei.Items)
+	*events, ei.err = ei.parseItems()
 	if len(ei.Items) == 0 {
 		return false
 	}
 	return true
 }
 
+// parseItems parses the events of the current page, decrypting their user variables
+// if the client has a Cipher installed. Variables which fail to decrypt are left as they are,
+// rather than failing the page.
+func (ei *EventIterator) parseItems() ([]Event, error) {
+	evs, err := ParseEvents(ei.Items)
+	if err != nil {
+		return nil, err
+	}
+	if ei.mg == nil {
+		return evs, nil
+	}
+	if c := ei.mg.VariableCipher(); c != nil {
+		for _, e := range evs {
+			DecryptEventVariables(c, e)
+		}
+	}
+	return evs, nil
+}
+
 func (ei *EventIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(ei.mg.Client())
+	r.setClient(ei.mg)
 	r.setBasicAuth(basicAuthUser, ei.mg.APIKey())
 
 	resp, err := makeRequest(ctx, r, "GET", nil)
@@ -170,6 +220,7 @@ type EventPoller struct {
 	sleepUntil    time.Time
 	mg            Mailgun
 	err           error
+	detectors     []*AnomalyDetector
 }
 
 // Poll the events api and return new events as they occur
@@ -219,6 +270,12 @@ func (ep *EventPoller) Err() error {
 	return ep.err
 }
 
+// AddAnomalyDetector arranges for every event returned by `Poll()` to be observed by the detector.
+// The detector's OnAnomaly callback is invoked from within `Poll()`.
+func (ep *EventPoller) AddAnomalyDetector(ad *AnomalyDetector) {
+	ep.detectors = append(ep.detectors, ad)
+}
+
 func (ep *EventPoller) Poll(ctx context.Context, events *[]Event) bool {
 	var currentPage string
 	var results []Event
@@ -251,6 +308,9 @@ func (ep *EventPoller) Poll(ctx context.Context, events *[]Event) bool {
 
 		// If we have events to return
 		if len(results) != 0 {
+			for _, ad := range ep.detectors {
+				ad.Observe(results)
+			}
 			*events = results
 			results = nil
 			return true
@@ -1,5 +1,11 @@
 package events
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	EventAccepted              = "accepted"
 	EventRejected              = "rejected"
@@ -34,24 +40,182 @@ const (
 	ClientRobot         = "robot"
 	ClientOther         = "other"
 
-	ReasonUnknown             = "unknown"
-	ReasonGeneric             = "generic"
-	ReasonBounce              = "bounce"
-	ReasonESPBlock            = "espblock"
-	ReasonGreylisted          = "greylisted"
-	ReasonBlacklisted         = "blacklisted"
-	ReasonSuppressBounce      = "suppress-bounce"
-	ReasonSuppressComplaint   = "suppress-complaint"
-	ReasonSuppressUnsubscribe = "suppress-unsubscribe"
-	ReasonOld                 = "old"
-	ReasonHardFail            = "hardfail"
-
-	SeverityUnknown   = "unknown"
-	SeverityTemporary = "temporary"
-	SeverityPermanent = "permanent"
-	SeverityInternal  = "internal"
-
 	MethodUnknown = "unknown"
 	MethodSMTP    = "smtp"
 	MethodHTTP    = "http"
 )
+
+// Reason is the reason a message failed, as reported by Failed events
+type Reason string
+
+const (
+	ReasonUnknown             Reason = "unknown"
+	ReasonGeneric             Reason = "generic"
+	ReasonBounce              Reason = "bounce"
+	ReasonESPBlock            Reason = "espblock"
+	ReasonGreylisted          Reason = "greylisted"
+	ReasonBlacklisted         Reason = "blacklisted"
+	ReasonSuppressBounce      Reason = "suppress-bounce"
+	ReasonSuppressComplaint   Reason = "suppress-complaint"
+	ReasonSuppressUnsubscribe Reason = "suppress-unsubscribe"
+	ReasonOld                 Reason = "old"
+	ReasonHardFail            Reason = "hardfail"
+)
+
+var reasons = []Reason{
+	ReasonUnknown, ReasonGeneric, ReasonBounce, ReasonESPBlock, ReasonGreylisted, ReasonBlacklisted,
+	ReasonSuppressBounce, ReasonSuppressComplaint, ReasonSuppressUnsubscribe, ReasonOld, ReasonHardFail,
+}
+
+// IsValid returns true if r is one of the Reason constants
+func (r Reason) IsValid() bool {
+	for _, v := range reasons {
+		if r == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Reason) String() string {
+	return string(r)
+}
+
+// ParseReason returns the Reason named by s, ignoring case
+func ParseReason(s string) (Reason, error) {
+	r := Reason(strings.ToLower(s))
+	if !r.IsValid() {
+		return ReasonUnknown, fmt.Errorf("unknown reason '%s'", s)
+	}
+	return r, nil
+}
+
+// Severity tells whether Mailgun retries delivery of a message after a failure, as reported by Failed events
+type Severity string
+
+const (
+	SeverityUnknown   Severity = "unknown"
+	SeverityTemporary Severity = "temporary"
+	SeverityPermanent Severity = "permanent"
+	SeverityInternal  Severity = "internal"
+)
+
+var severities = []Severity{SeverityUnknown, SeverityTemporary, SeverityPermanent, SeverityInternal}
+
+// IsValid returns true if s is one of the Severity constants
+func (s Severity) IsValid() bool {
+	for _, v := range severities {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Severity) String() string {
+	return string(s)
+}
+
+// ParseSeverity returns the Severity named by s, ignoring case
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(s))
+	if !sev.IsValid() {
+		return SeverityUnknown, fmt.Errorf("unknown severity '%s'", s)
+	}
+	return sev, nil
+}
+
+// LogLevel is the log level Mailgun assigns to events, which can be used to filter events
+// with the "log-level" filter of the events API
+type LogLevel string
+
+const (
+	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+)
+
+var logLevels = []LogLevel{LogLevelInfo, LogLevelWarn, LogLevelError}
+
+// IsValid returns true if l is one of the LogLevel constants
+func (l LogLevel) IsValid() bool {
+	for _, v := range logLevels {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (l LogLevel) String() string {
+	return string(l)
+}
+
+// ParseLogLevel returns the LogLevel named by s, ignoring case
+func ParseLogLevel(s string) (LogLevel, error) {
+	l := LogLevel(strings.ToLower(s))
+	if !l.IsValid() {
+		return "", fmt.Errorf("unknown log level '%s'", s)
+	}
+	return l, nil
+}
+
+// DeliveryStatusCode is the SMTP reply code of a delivery attempt, such as 250 or 550
+type DeliveryStatusCode int
+
+// Common delivery status codes
+const (
+	CodeOK                     DeliveryStatusCode = 250
+	CodeMailboxUnavailable     DeliveryStatusCode = 450
+	CodeLocalError             DeliveryStatusCode = 451
+	CodeInsufficientStorage    DeliveryStatusCode = 452
+	CodeMailboxNotFound        DeliveryStatusCode = 550
+	CodeUserNotLocal           DeliveryStatusCode = 551
+	CodeExceededStorage        DeliveryStatusCode = 552
+	CodeMailboxNameNotAllowed  DeliveryStatusCode = 553
+	CodeTransactionFailed      DeliveryStatusCode = 554
+	CodeMailgunSuppressed      DeliveryStatusCode = 605
+	CodeMailgunNotDeliveredOld DeliveryStatusCode = 607
+)
+
+// IsValid returns true if c is a 2xx, 4xx or 5xx SMTP reply code, or one of the 6xx codes
+// Mailgun reports for messages it did not attempt to deliver
+func (c DeliveryStatusCode) IsValid() bool {
+	return (c >= 200 && c < 300) || (c >= 400 && c < 700)
+}
+
+// Temporary returns true if the code reports a failure which may succeed when retried
+func (c DeliveryStatusCode) Temporary() bool {
+	return c >= 400 && c < 500
+}
+
+// Permanent returns true if the code reports a failure which will not succeed when retried
+func (c DeliveryStatusCode) Permanent() bool {
+	return c >= 500 && c < 700
+}
+
+func (c DeliveryStatusCode) String() string {
+	return strconv.Itoa(int(c))
+}
+
+// ParseDeliveryStatusCode parses a delivery status code, which Mailgun reports as either
+// a number or a string
+func ParseDeliveryStatusCode(s string) (DeliveryStatusCode, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || !DeliveryStatusCode(n).IsValid() {
+		return 0, fmt.Errorf("invalid delivery status code '%s'", s)
+	}
+	return DeliveryStatusCode(n), nil
+}
+
+// UnmarshalJSON accepts the code as a number or a string. Mailgun sometimes reports other
+// values, such as an enhanced status code like "5.1.1", which decode to 0 rather than failing
+// the event.
+func (c *DeliveryStatusCode) UnmarshalJSON(data []byte) error {
+	n, err := strconv.Atoi(strings.TrimSpace(strings.Trim(string(data), `"`)))
+	if err != nil {
+		n = 0
+	}
+	*c = DeliveryStatusCode(n)
+	return nil
+}
@@ -52,6 +52,7 @@ type Accepted struct {
 
 	Envelope Envelope `json:"envelope"`
 	Message  Message  `json:"message"`
+	Storage  Storage  `json:"storage"`
 	Flags    Flags    `json:"flags"`
 
 	Recipient       string     `json:"recipient"`
@@ -83,6 +84,7 @@ type Delivered struct {
 
 	Envelope Envelope `json:"envelope"`
 	Message  Message  `json:"message"`
+	Storage  Storage  `json:"storage"`
 	Flags    Flags    `json:"flags"`
 
 	Recipient       string     `json:"recipient"`
@@ -99,6 +101,7 @@ type Failed struct {
 
 	Envelope Envelope `json:"envelope"`
 	Message  Message  `json:"message"`
+	Storage  Storage  `json:"storage"`
 	Flags    Flags    `json:"flags"`
 
 	Recipient       string     `json:"recipient"`
@@ -108,8 +111,8 @@ type Failed struct {
 	Campaigns       []Campaign `json:"campaigns"`
 
 	DeliveryStatus DeliveryStatus `json:"delivery-status"`
-	Severity       string         `json:"severity"`
-	Reason         string         `json:"reason"`
+	Severity       Severity       `json:"severity"`
+	Reason         Reason         `json:"reason"`
 }
 
 type Stored struct {
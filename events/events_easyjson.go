@@ -2187,6 +2187,8 @@ func easyjson692db02bDecodeGithubComMailgunMailgunGoEvents12(in *jlexer.Lexer, o
 			(out.Envelope).UnmarshalEasyJSON(in)
 		case "message":
 			(out.Message).UnmarshalEasyJSON(in)
+		case "storage":
+			(out.Storage).UnmarshalEasyJSON(in)
 		case "flags":
 			(out.Flags).UnmarshalEasyJSON(in)
 		case "recipient":
@@ -2244,9 +2246,9 @@ func easyjson692db02bDecodeGithubComMailgunMailgunGoEvents12(in *jlexer.Lexer, o
 		case "delivery-status":
 			(out.DeliveryStatus).UnmarshalEasyJSON(in)
 		case "severity":
-			out.Severity = string(in.String())
+			out.Severity = Severity(in.String())
 		case "reason":
-			out.Reason = string(in.String())
+			out.Reason = Reason(in.String())
 		case "timestamp":
 			out.Timestamp = float64(in.Float64())
 		case "id":
@@ -2287,6 +2289,16 @@ func easyjson692db02bEncodeGithubComMailgunMailgunGoEvents12(out *jwriter.Writer
 		}
 		(in.Message).MarshalEasyJSON(out)
 	}
+	{
+		const prefix string = ",\"storage\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		(in.Storage).MarshalEasyJSON(out)
+	}
 	{
 		const prefix string = ",\"flags\":"
 		if first {
@@ -2549,6 +2561,8 @@ func easyjson692db02bDecodeGithubComMailgunMailgunGoEvents14(in *jlexer.Lexer, o
 			(out.Envelope).UnmarshalEasyJSON(in)
 		case "message":
 			(out.Message).UnmarshalEasyJSON(in)
+		case "storage":
+			(out.Storage).UnmarshalEasyJSON(in)
 		case "flags":
 			(out.Flags).UnmarshalEasyJSON(in)
 		case "recipient":
@@ -2645,6 +2659,16 @@ func easyjson692db02bEncodeGithubComMailgunMailgunGoEvents14(out *jwriter.Writer
 		}
 		(in.Message).MarshalEasyJSON(out)
 	}
+	{
+		const prefix string = ",\"storage\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		(in.Storage).MarshalEasyJSON(out)
+	}
 	{
 		const prefix string = ",\"flags\":"
 		if first {
@@ -3348,6 +3372,8 @@ func easyjson692db02bDecodeGithubComMailgunMailgunGoEvents17(in *jlexer.Lexer, o
 			(out.Envelope).UnmarshalEasyJSON(in)
 		case "message":
 			(out.Message).UnmarshalEasyJSON(in)
+		case "storage":
+			(out.Storage).UnmarshalEasyJSON(in)
 		case "flags":
 			(out.Flags).UnmarshalEasyJSON(in)
 		case "recipient":
@@ -3444,6 +3470,16 @@ func easyjson692db02bEncodeGithubComMailgunMailgunGoEvents17(out *jwriter.Writer
 		}
 		(in.Message).MarshalEasyJSON(out)
 	}
+	{
+		const prefix string = ",\"storage\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		(in.Storage).MarshalEasyJSON(out)
+	}
 	{
 		const prefix string = ",\"flags\":"
 		if first {
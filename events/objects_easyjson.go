@@ -837,6 +837,10 @@ func easyjsonCce3d1beDecodeGithubComMailgunMailgunGoEvents7(in *jlexer.Lexer, ou
 			continue
 		}
 		switch key {
+		case "code":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Code).UnmarshalJSON(data))
+			}
 		case "message":
 			out.Message = string(in.String())
 		case "session-seconds":
@@ -855,6 +859,16 @@ func easyjsonCce3d1beEncodeGithubComMailgunMailgunGoEvents7(out *jwriter.Writer,
 	out.RawByte('{')
 	first := true
 	_ = first
+	{
+		const prefix string = ",\"code\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.Code))
+	}
 	{
 		const prefix string = ",\"message\":"
 		if first {
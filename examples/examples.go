@@ -115,7 +115,7 @@ func CreateWebhook(domain, apiKey string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
 
-	return mg.CreateWebhook(ctx, "clicked", []string{"https://your_domain.com/v1/clicked"})
+	return mg.CreateWebhook(ctx, mailgun.WebhookClicked, []string{"https://your_domain.com/v1/clicked"})
 }
 
 func ChangePassword(domain, apiKey string) error {
@@ -221,7 +221,7 @@ func DeleteWebhook(domain, apiKey string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
 
-	return mg.DeleteWebhook(ctx, "clicked")
+	return mg.DeleteWebhook(ctx, mailgun.WebhookClicked)
 }
 
 func PrintEventLog(domain, apiKey string) error {
@@ -624,16 +624,16 @@ func ValidateEmail(apiKey string) (mailgun.EmailVerification, error) {
 	return mv.ValidateEmail(ctx, "foo@mailgun.net", false)
 }
 
-func GetWebhook(domain, apiKey string) (string, error) {
+func GetWebhook(domain, apiKey string) (mailgun.Webhook, error) {
 	mg := mailgun.NewMailgun(domain, apiKey)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
 
-	return mg.GetWebhook(ctx, "clicked")
+	return mg.GetWebhook(ctx, mailgun.WebhookClicked)
 }
 
-func ListWebhooks(domain, apiKey string) (map[string]string, error) {
+func ListWebhooks(domain, apiKey string) (map[mailgun.WebhookKind]mailgun.Webhook, error) {
 	mg := mailgun.NewMailgun(domain, apiKey)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
@@ -882,7 +882,7 @@ func UpdateWebhook(domain, apiKey string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
 
-	return mg.UpdateWebhook(ctx, "clicked", []string{"https://your_domain.com/clicked"})
+	return mg.UpdateWebhook(ctx, mailgun.WebhookClicked, []string{"https://your_domain.com/clicked"})
 }
 
 func VerifyWebhookSignature(domain, apiKey, timestamp, token, signature string) (bool, error) {
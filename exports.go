@@ -20,7 +20,7 @@ type Export struct {
 // Create an export based on the URL given
 func (mg *MailgunImpl) CreateExport(ctx context.Context, url string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, exportsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
@@ -32,7 +32,7 @@ func (mg *MailgunImpl) CreateExport(ctx context.Context, url string) error {
 // List all exports created within the past 24 hours
 func (mg *MailgunImpl) ListExports(ctx context.Context, url string) ([]Export, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, exportsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	if url != "" {
 		r.addParameter("url", url)
 	}
@@ -53,7 +53,7 @@ func (mg *MailgunImpl) ListExports(ctx context.Context, url string) ([]Export, e
 // Get an export by id
 func (mg *MailgunImpl) GetExport(ctx context.Context, id string) (Export, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, exportsEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var resp Export
 	err := getResponseFromJSON(ctx, r, &resp)
@@ -71,7 +71,7 @@ func (mg *MailgunImpl) GetExportLink(ctx context.Context, id string) (string, er
 		return errors.New("redirect")
 	}
 
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	r.addHeader("User-Agent", MailgunGoUserAgent)
@@ -0,0 +1,274 @@
+// Package faulttransport injects failures in the HTTP requests of a Mailgun client, so
+// applications can test how they cope with timeouts, rate limiting, truncated responses
+// and slow responses without external chaos tooling.
+//
+//	ft := faulttransport.New(nil,
+//		faulttransport.Fault{Kind: faulttransport.TooManyRequests, Probability: 0.2},
+//		faulttransport.Fault{Kind: faulttransport.SlowResponse, Every: 10, Delay: 5 * time.Second},
+//	)
+//	mg.SetClient(&http.Client{Transport: ft})
+package faulttransport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is a kind of failure injected by the transport
+type Kind int
+
+const (
+	// Timeout fails the request with a timeout error after Fault.Delay, without sending it
+	Timeout Kind = iota
+	// TooManyRequests answers the request with a 429 response, without sending it. The
+	// response has a Retry-After header if Fault.RetryAfter is set.
+	TooManyRequests
+	// TruncatedBody sends the request, and cuts the body of the response after Fault.Bytes
+	// bytes. Reading past them returns io.ErrUnexpectedEOF.
+	TruncatedBody
+	// SlowResponse sends the request after Fault.Delay
+	SlowResponse
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Timeout:
+		return "timeout"
+	case TooManyRequests:
+		return "too many requests"
+	case TruncatedBody:
+		return "truncated body"
+	case SlowResponse:
+		return "slow response"
+	}
+	return "unknown"
+}
+
+// Fault describes a failure and when to inject it. A fault is injected at random with
+// Probability, or on every Nth request if Every is set.
+type Fault struct {
+	Kind Kind
+	// The probability of injecting the fault in a request, between 0 and 1
+	Probability float64
+	// If set, the fault is injected on every Nth request instead of at random
+	Every int
+	// If set, the fault is injected at most Limit times
+	Limit int
+	// If set, only requests whose URL path contains Path are affected
+	Path string
+	// If set, only requests with this HTTP method are affected
+	Method string
+
+	// How long Timeout and SlowResponse faults wait
+	Delay time.Duration
+	// The Retry-After delay of TooManyRequests responses
+	RetryAfter time.Duration
+	// The number of bytes of the body kept by TruncatedBody faults
+	Bytes int
+}
+
+// Transport is an http.RoundTripper which injects faults in the requests it forwards.
+// It is safe for concurrent use.
+type Transport struct {
+	// The transport requests are forwarded to, defaults to http.DefaultTransport
+	Base http.RoundTripper
+	// Called when a fault is injected
+	OnFault func(f Fault, req *http.Request)
+
+	mu       sync.Mutex
+	faults   []Fault
+	seen     []int
+	injected []int
+	rnd      *rand.Rand
+}
+
+// New creates a transport which forwards requests to base, injecting the faults provided.
+// Faults are considered in order, at most one fault is injected per request.
+func New(base http.RoundTripper, faults ...Fault) *Transport {
+	return &Transport{
+		Base:     base,
+		faults:   faults,
+		seen:     make([]int, len(faults)),
+		injected: make([]int, len(faults)),
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Seed seeds the random number generator deciding which requests fail, so a test can
+// replay the same sequence of failures.
+func (t *Transport) Seed(seed int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rnd = rand.New(rand.NewSource(seed))
+}
+
+// Injected returns the number of faults injected so far.
+func (t *Transport) Injected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total int
+	for _, n := range t.injected {
+		total += n
+	}
+	return total
+}
+
+// Reset clears the request and fault counters.
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen = make([]int, len(t.faults))
+	t.injected = make([]int, len(t.faults))
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, ok := t.pick(req)
+	if !ok {
+		return t.base().RoundTrip(req)
+	}
+	if t.OnFault != nil {
+		t.OnFault(f, req)
+	}
+
+	switch f.Kind {
+	case Timeout:
+		closeBody(req)
+		if err := sleep(req.Context(), f.Delay); err != nil {
+			return nil, err
+		}
+		return nil, &timeoutError{url: req.URL.String()}
+	case TooManyRequests:
+		closeBody(req)
+		body := `{"message": "Too many requests"}`
+		resp := &http.Response{
+			Status:        "429 Too Many Requests",
+			StatusCode:    http.StatusTooManyRequests,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}
+		if f.RetryAfter > 0 {
+			resp.Header.Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+		}
+		return resp, nil
+	case TruncatedBody:
+		resp, err := t.base().RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncatedBody{rc: resp.Body, remaining: f.Bytes}
+		return resp, nil
+	case SlowResponse:
+		if err := sleep(req.Context(), f.Delay); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	return t.base().RoundTrip(req)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// pick returns the fault to inject in the request, if any
+func (t *Transport) pick(req *http.Request) (Fault, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, f := range t.faults {
+		if f.Path != "" && !strings.Contains(req.URL.Path, f.Path) {
+			continue
+		}
+		if f.Method != "" && !strings.EqualFold(f.Method, req.Method) {
+			continue
+		}
+		t.seen[i]++
+		if f.Limit > 0 && t.injected[i] >= f.Limit {
+			continue
+		}
+
+		var inject bool
+		if f.Every > 0 {
+			inject = t.seen[i]%f.Every == 0
+		} else {
+			inject = t.rnd.Float64() < f.Probability
+		}
+		if inject {
+			t.injected[i]++
+			return f, true
+		}
+	}
+	return Fault{}, false
+}
+
+// sleep waits for d, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// closeBody closes the body of a request which is not sent, as the transport is expected to
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// timeoutError is returned for Timeout faults, it implements net.Error like the errors of
+// http.Client when the timeout of a request expires
+type timeoutError struct {
+	url string
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("faulttransport: injected timeout for %s", e.url)
+}
+
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+type truncatedBody struct {
+	rc        io.ReadCloser
+	remaining int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.rc.Read(p)
+	b.remaining -= n
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,135 @@
+package faulttransport
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func newServer() (*httptest.Server, *int) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		io.WriteString(w, `{"message": "Queued. Thank you."}`)
+	}))
+	return srv, &requests
+}
+
+func TestTooManyRequests(t *testing.T) {
+	srv, requests := newServer()
+	defer srv.Close()
+
+	ft := New(nil, Fault{Kind: TooManyRequests, Every: 2, Limit: 2, RetryAfter: 1500 * time.Millisecond})
+	client := &http.Client{Transport: ft}
+
+	var codes []int
+	for i := 0; i < 6; i++ {
+		resp, err := client.Get(srv.URL)
+		ensure.Nil(t, err)
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			ensure.DeepEqual(t, resp.Header.Get("Retry-After"), "2")
+		}
+	}
+	ensure.DeepEqual(t, codes, []int{200, 429, 200, 429, 200, 200})
+	ensure.DeepEqual(t, *requests, 4)
+	ensure.DeepEqual(t, ft.Injected(), 2)
+}
+
+func TestTimeout(t *testing.T) {
+	srv, requests := newServer()
+	defer srv.Close()
+
+	var injected []Kind
+	ft := New(nil, Fault{Kind: Timeout, Probability: 1, Method: http.MethodPost})
+	ft.OnFault = func(f Fault, req *http.Request) { injected = append(injected, f.Kind) }
+	client := &http.Client{Transport: ft}
+
+	resp, err := client.Get(srv.URL)
+	ensure.Nil(t, err)
+	resp.Body.Close()
+
+	_, err = client.Post(srv.URL, "text/plain", strings.NewReader("body"))
+	ensure.NotNil(t, err)
+	netErr, ok := err.(net.Error)
+	ensure.True(t, ok)
+	ensure.True(t, netErr.Timeout())
+	ensure.DeepEqual(t, *requests, 1)
+	ensure.DeepEqual(t, injected, []Kind{Timeout})
+
+	// A delayed timeout honors the deadline of the request
+	ft = New(nil, Fault{Kind: Timeout, Probability: 1, Delay: time.Minute})
+	client = &http.Client{Transport: ft}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	ensure.Nil(t, err)
+	_, err = client.Do(req.WithContext(ctx))
+	ensure.NotNil(t, err)
+}
+
+func TestTruncatedBody(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	ft := New(nil, Fault{Kind: TruncatedBody, Probability: 1, Path: "/messages", Bytes: 10})
+	client := &http.Client{Transport: ft}
+
+	resp, err := client.Get(srv.URL + "/messages")
+	ensure.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	ensure.DeepEqual(t, err, io.ErrUnexpectedEOF)
+	ensure.DeepEqual(t, string(body), `{"message"`)
+
+	resp, err = client.Get(srv.URL + "/events")
+	ensure.Nil(t, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(body), `{"message": "Queued. Thank you."}`)
+}
+
+func TestSlowResponse(t *testing.T) {
+	srv, requests := newServer()
+	defer srv.Close()
+
+	ft := New(nil, Fault{Kind: SlowResponse, Probability: 1, Delay: 50 * time.Millisecond})
+	client := &http.Client{Transport: ft}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	ensure.Nil(t, err)
+	resp.Body.Close()
+	ensure.True(t, time.Since(start) >= 50*time.Millisecond)
+	ensure.DeepEqual(t, *requests, 1)
+}
+
+func TestSeed(t *testing.T) {
+	srv, _ := newServer()
+	defer srv.Close()
+
+	run := func() []int {
+		ft := New(nil, Fault{Kind: TooManyRequests, Probability: 0.5})
+		ft.Seed(42)
+		client := &http.Client{Transport: ft}
+		var codes []int
+		for i := 0; i < 20; i++ {
+			resp, err := client.Get(srv.URL)
+			ensure.Nil(t, err)
+			resp.Body.Close()
+			codes = append(codes, resp.StatusCode)
+		}
+		return codes
+	}
+	ensure.DeepEqual(t, run(), run())
+}
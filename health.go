@@ -0,0 +1,131 @@
+package mailgun
+
+import (
+	"context"
+)
+
+// The thresholds above which DomainHealth.Healthy() reports a domain as unhealthy
+const (
+	// MaxHealthyFailureRate is the highest fraction of accepted messages which may permanently fail
+	MaxHealthyFailureRate = 0.05
+	// MaxHealthyComplaintRate is the highest fraction of accepted messages which may be reported as spam
+	MaxHealthyComplaintRate = 0.001
+)
+
+// HealthStatsDuration is the period of stats GetDomainHealth() computes rates over
+const HealthStatsDuration = "7d"
+
+// DomainHealth is a snapshot of the sending reputation of a domain as returned by GetDomainHealth()
+type DomainHealth struct {
+	Domain string
+	// The state of the domain, "active" once the DNS records are verified
+	State string
+	// The status of the sending queues of the domain
+	SendingQueues SendingQueues
+
+	// Message counts over the last HealthStatsDuration
+	Accepted        int
+	Delivered       int
+	FailedPermanent int
+	Complained      int
+
+	// Permanent failures and complaints as a fraction of accepted messages
+	FailureRate   float64
+	ComplaintRate float64
+
+	// The number of addresses on the suppression lists of the domain
+	Bounces      int
+	Unsubscribes int
+	Complaints   int
+}
+
+// Verified returns true if the DNS records of the domain are verified
+func (h DomainHealth) Verified() bool {
+	return h.State == "active"
+}
+
+// Healthy returns true if the domain is verified, none of its sending queues is disabled
+// and its failure and complaint rates are within MaxHealthyFailureRate and MaxHealthyComplaintRate.
+func (h DomainHealth) Healthy() bool {
+	return h.Verified() &&
+		!h.SendingQueues.Regular.IsDisabled &&
+		!h.SendingQueues.Scheduled.IsDisabled &&
+		h.FailureRate <= MaxHealthyFailureRate &&
+		h.ComplaintRate <= MaxHealthyComplaintRate
+}
+
+// GetDomainHealth gathers the verification state, sending queue status, recent failure and
+// complaint rates and suppression list sizes of a domain into a single report.
+//
+//	health, err := mg.GetDomainHealth(ctx, "example.com")
+//	if err != nil {
+//		return err
+//	}
+//	if !health.Healthy() {
+//		alert(health)
+//	}
+//
+// Counting the suppression lists pages through all of them, which may take a while for
+// domains with large lists.
+func (mg *MailgunImpl) GetDomainHealth(ctx context.Context, domain string) (DomainHealth, error) {
+	// Domain scoped calls use the domain of the client
+	dmg := *mg
+	dmg.domain = domain
+
+	health := DomainHealth{Domain: domain}
+
+	resp, err := mg.GetDomain(ctx, domain)
+	if err != nil {
+		return health, err
+	}
+	health.State = resp.Domain.State
+
+	if health.SendingQueues, err = dmg.GetSendingQueues(ctx); err != nil {
+		return health, err
+	}
+
+	stats, err := dmg.GetStats(ctx, []string{"accepted", "delivered", "failed", "complained"},
+		&GetStatOptions{Duration: HealthStatsDuration, Resolution: ResolutionDay})
+	if err != nil {
+		return health, err
+	}
+	sum := sumStats(stats)
+	health.Accepted = sum.Accepted.Total
+	health.Delivered = sum.Delivered.Total
+	health.FailedPermanent = sum.Failed.Permanent.Total
+	health.Complained = sum.Complained.Total
+	if health.Accepted != 0 {
+		health.FailureRate = float64(health.FailedPermanent) / float64(health.Accepted)
+		health.ComplaintRate = float64(health.Complained) / float64(health.Accepted)
+	}
+
+	opts := &ListOptions{Limit: 1000}
+	bounces := dmg.ListBounces(opts)
+	var bouncePage []Bounce
+	for bounces.Next(ctx, &bouncePage) {
+		health.Bounces += len(bouncePage)
+	}
+	if err := bounces.Err(); err != nil {
+		return health, err
+	}
+
+	unsubscribes := dmg.ListUnsubscribes(opts)
+	var unsubscribePage []Unsubscribe
+	for unsubscribes.Next(ctx, &unsubscribePage) {
+		health.Unsubscribes += len(unsubscribePage)
+	}
+	if err := unsubscribes.Err(); err != nil {
+		return health, err
+	}
+
+	complaints := dmg.ListComplaints(opts)
+	var complaintPage []Complaint
+	for complaints.Next(ctx, &complaintPage) {
+		health.Complaints += len(complaintPage)
+	}
+	if err := complaints.Err(); err != nil {
+		return health, err
+	}
+
+	return health, nil
+}
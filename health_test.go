@@ -0,0 +1,88 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestGetDomainHealth(t *testing.T) {
+	const domain = "health.example.com"
+	var srv *httptest.Server
+	addresses := func(n int) []map[string]string {
+		items := []map[string]string{}
+		for i := 0; i < n; i++ {
+			items = append(items, map[string]string{"address": "user@example.com"})
+		}
+		return items
+	}
+	// Suppression lists return their items on the first page, followed by an empty page
+	page := func(w http.ResponseWriter, req *http.Request, n int) {
+		if req.URL.Query().Get("page") != "" {
+			n = 0
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":  addresses(n),
+			"paging": map[string]string{"next": srv.URL + req.URL.Path + "?page=next"},
+		})
+	}
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/domains/" + domain:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"domain": map[string]string{"name": domain, "state": "active"},
+			})
+		case "/domains/" + domain + "/sending_queues":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"regular":   map[string]interface{}{"is_disabled": false},
+				"scheduled": map[string]interface{}{"is_disabled": false},
+			})
+		case "/" + domain + "/stats/total":
+			ensure.DeepEqual(t, req.URL.Query().Get("duration"), HealthStatsDuration)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"stats": []map[string]interface{}{
+					{"accepted": map[string]int{"total": 600}, "failed": map[string]interface{}{"permanent": map[string]int{"total": 6}}},
+					{"accepted": map[string]int{"total": 400}, "complained": map[string]int{"total": 2}},
+				},
+			})
+		case "/" + domain + "/bounces":
+			page(w, req, 3)
+		case "/" + domain + "/unsubscribes":
+			page(w, req, 2)
+		case "/" + domain + "/complaints":
+			page(w, req, 1)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	health, err := mg.GetDomainHealth(context.Background(), domain)
+	ensure.Nil(t, err)
+	ensure.True(t, health.Verified())
+	ensure.DeepEqual(t, health.Accepted, 1000)
+	ensure.DeepEqual(t, health.FailedPermanent, 6)
+	ensure.DeepEqual(t, health.FailureRate, 0.006)
+	ensure.DeepEqual(t, health.ComplaintRate, 0.002)
+	ensure.DeepEqual(t, health.Bounces, 3)
+	ensure.DeepEqual(t, health.Unsubscribes, 2)
+	ensure.DeepEqual(t, health.Complaints, 1)
+
+	// The complaint rate is above MaxHealthyComplaintRate
+	ensure.False(t, health.Healthy())
+	health.ComplaintRate = 0
+	ensure.True(t, health.Healthy())
+	health.SendingQueues.Regular.IsDisabled = true
+	ensure.False(t, health.Healthy())
+
+	// The client keeps its own domain
+	ensure.DeepEqual(t, mg.Domain(), exampleDomain)
+}
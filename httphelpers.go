@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path"
@@ -23,15 +24,19 @@ type httpRequest struct {
 	BasicAuthUser     string
 	BasicAuthPassword string
 	Client            *http.Client
+	// The number of bytes of the request and response UnexpectedResponseError retains
+	ErrorBodyCapture int
 }
 
 type httpResponse struct {
-	Code int
-	Data []byte
+	Code   int
+	Data   []byte
+	Header http.Header
 }
 
 type payload interface {
 	getPayloadBuffer() (*bytes.Buffer, error)
+	getPayloadReader() (io.Reader, error)
 	getContentType() string
 	getValues() []keyValuePair
 }
@@ -41,6 +46,12 @@ type keyValuePair struct {
 	value string
 }
 
+type keyFile struct {
+	key         string
+	path        string
+	contentType string
+}
+
 type keyNameRC struct {
 	key   string
 	name  string
@@ -55,8 +66,9 @@ type keyNameBuff struct {
 
 type formDataPayload struct {
 	contentType string
+	boundary    string
 	Values      []keyValuePair
-	Files       []keyValuePair
+	Files       []keyFile
 	ReadClosers []keyNameRC
 	Buffers     []keyNameBuff
 }
@@ -65,6 +77,10 @@ type urlEncodedPayload struct {
 	Values []keyValuePair
 }
 
+type jsonEncodedPayload struct {
+	payload interface{}
+}
+
 func newHTTPRequest(url string) *httpRequest {
 	return &httpRequest{URL: url, Client: http.DefaultClient}
 }
@@ -76,8 +92,18 @@ func (r *httpRequest) addParameter(name, value string) {
 	r.Parameters[name] = append(r.Parameters[name], value)
 }
 
-func (r *httpRequest) setClient(c *http.Client) {
-	r.Client = c
+// httpClient is implemented by the clients which make requests, such as MailgunImpl
+type httpClient interface {
+	Client() *http.Client
+}
+
+// setClient sets the HTTP client of the request, and applies the settings of the client
+// which affect how its requests fail.
+func (r *httpRequest) setClient(c httpClient) {
+	r.Client = c.Client()
+	if mg, ok := c.(*MailgunImpl); ok {
+		r.ErrorBodyCapture = mg.errorBodyCapture
+	}
 }
 
 func (r *httpRequest) setBasicAuth(user, password string) {
@@ -101,6 +127,10 @@ func (f *urlEncodedPayload) getPayloadBuffer() (*bytes.Buffer, error) {
 	return bytes.NewBufferString(data.Encode()), nil
 }
 
+func (f *urlEncodedPayload) getPayloadReader() (io.Reader, error) {
+	return f.getPayloadBuffer()
+}
+
 func (f *urlEncodedPayload) getContentType() string {
 	return "application/x-www-form-urlencoded"
 }
@@ -109,6 +139,30 @@ func (f *urlEncodedPayload) getValues() []keyValuePair {
 	return f.Values
 }
 
+func newJSONEncodedPayload(payload interface{}) *jsonEncodedPayload {
+	return &jsonEncodedPayload{payload: payload}
+}
+
+func (j *jsonEncodedPayload) getPayloadBuffer() (*bytes.Buffer, error) {
+	b, err := json.Marshal(j.payload)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(b), nil
+}
+
+func (j *jsonEncodedPayload) getPayloadReader() (io.Reader, error) {
+	return j.getPayloadBuffer()
+}
+
+func (j *jsonEncodedPayload) getContentType() string {
+	return "application/json"
+}
+
+func (j *jsonEncodedPayload) getValues() []keyValuePair {
+	return nil
+}
+
 func (r *httpResponse) parseFromJSON(v interface{}) error {
 	return json.Unmarshal(r.Data, v)
 }
@@ -126,7 +180,11 @@ func (f *formDataPayload) addValue(key, value string) {
 }
 
 func (f *formDataPayload) addFile(key, file string) {
-	f.Files = append(f.Files, keyValuePair{key: key, value: file})
+	f.addFileWithType(key, file, "")
+}
+
+func (f *formDataPayload) addFileWithType(key, file, contentType string) {
+	f.Files = append(f.Files, keyFile{key: key, path: file, contentType: contentType})
 }
 
 func (f *formDataPayload) addBuffer(key, file string, buff []byte) {
@@ -139,57 +197,226 @@ func (f *formDataPayload) addReadCloser(key, name string, rc io.ReadCloser) {
 
 func (f *formDataPayload) getPayloadBuffer() (*bytes.Buffer, error) {
 	data := &bytes.Buffer{}
-	writer := multipart.NewWriter(data)
-	defer writer.Close()
+	writer, err := f.newWriter(data)
+	if err != nil {
+		f.closeReadClosers()
+		return nil, err
+	}
 
-	for _, keyVal := range f.Values {
-		if tmp, err := writer.CreateFormField(keyVal.key); err == nil {
-			tmp.Write([]byte(keyVal.value))
-		} else {
+	files, err := f.openFiles()
+	if err != nil {
+		f.closeReadClosers()
+		return nil, err
+	}
+	if err := f.writeTo(writer, files); err != nil {
+		return nil, err
+	}
+
+	f.contentType = writer.FormDataContentType()
+
+	return data, nil
+}
+
+// getPayloadReader returns the multipart body as a stream. Files and
+// io.ReadClosers are copied into the request as it is sent instead of being
+// buffered in memory first, so large attachments don't inflate the heap.
+func (f *formDataPayload) getPayloadReader() (io.Reader, error) {
+	pr, pw := io.Pipe()
+	writer, err := f.newWriter(pw)
+	if err != nil {
+		f.closeReadClosers()
+		return nil, err
+	}
+	f.contentType = writer.FormDataContentType()
+
+	// Open files up front so a missing attachment is reported before the request is made
+	files, err := f.openFiles()
+	if err != nil {
+		f.closeReadClosers()
+		return nil, err
+	}
+
+	go func() {
+		pw.CloseWithError(f.writeTo(writer, files))
+	}()
+	return pr, nil
+}
+
+// newWriter returns a multipart writer which uses the boundary of the payload, if set
+func (f *formDataPayload) newWriter(w io.Writer) (*multipart.Writer, error) {
+	writer := multipart.NewWriter(w)
+	if f.boundary != "" {
+		if err := writer.SetBoundary(f.boundary); err != nil {
 			return nil, err
 		}
 	}
+	return writer, nil
+}
 
+func (f *formDataPayload) openFiles() ([]*os.File, error) {
+	var files []*os.File
 	for _, file := range f.Files {
-		if tmp, err := writer.CreateFormFile(file.key, path.Base(file.value)); err == nil {
-			if fp, err := os.Open(file.value); err == nil {
-				defer fp.Close()
-				io.Copy(tmp, fp)
-			} else {
-				return nil, err
+		fp, err := os.Open(file.path)
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
 			}
-		} else {
 			return nil, err
 		}
+		files = append(files, fp)
 	}
+	return files, nil
+}
 
+// closeReadClosers closes the io.ReadClosers of a payload which will not be written
+func (f *formDataPayload) closeReadClosers() {
 	for _, file := range f.ReadClosers {
-		if tmp, err := writer.CreateFormFile(file.key, file.name); err == nil {
-			defer file.value.Close()
-			io.Copy(tmp, file.value)
-		} else {
-			return nil, err
+		file.value.Close()
+	}
+}
+
+// writeTo writes every part of the payload to the multipart writer, closing
+// the files and io.ReadClosers once they have been consumed.
+func (f *formDataPayload) writeTo(writer *multipart.Writer, files []*os.File) error {
+	defer func() {
+		for _, fp := range files {
+			fp.Close()
+		}
+		f.closeReadClosers()
+	}()
+
+	for _, keyVal := range f.Values {
+		tmp, err := writer.CreateFormField(keyVal.key)
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Write([]byte(keyVal.value)); err != nil {
+			return err
+		}
+	}
+
+	for i, file := range f.Files {
+		tmp, err := createFormFile(writer, file.key, path.Base(file.path), file.contentType)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(tmp, files[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range f.ReadClosers {
+		tmp, err := writer.CreateFormFile(file.key, file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(tmp, file.value); err != nil {
+			return err
 		}
 	}
 
 	for _, buff := range f.Buffers {
-		if tmp, err := writer.CreateFormFile(buff.key, buff.name); err == nil {
-			r := bytes.NewReader(buff.value)
-			io.Copy(tmp, r)
-		} else {
-			return nil, err
+		tmp, err := writer.CreateFormFile(buff.key, buff.name)
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Write(buff.value); err != nil {
+			return err
 		}
 	}
 
-	f.contentType = writer.FormDataContentType()
+	return writer.Close()
+}
 
-	return data, nil
+// size computes the length of the multipart body without consuming any of the
+// readers. Readers which don't report their length through Len() or Stat() are not counted.
+func (f *formDataPayload) size() (int64, error) {
+	var counter countingWriter
+	writer, err := f.newWriter(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	var bodies int64
+	for _, keyVal := range f.Values {
+		tmp, err := writer.CreateFormField(keyVal.key)
+		if err != nil {
+			return 0, err
+		}
+		tmp.Write([]byte(keyVal.value))
+	}
+
+	for _, file := range f.Files {
+		info, err := os.Stat(file.path)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := createFormFile(writer, file.key, path.Base(file.path), file.contentType); err != nil {
+			return 0, err
+		}
+		bodies += info.Size()
+	}
+
+	for _, file := range f.ReadClosers {
+		if _, err := writer.CreateFormFile(file.key, file.name); err != nil {
+			return 0, err
+		}
+		bodies += readerSize(file.value)
+	}
+
+	for _, buff := range f.Buffers {
+		if _, err := writer.CreateFormFile(buff.key, buff.name); err != nil {
+			return 0, err
+		}
+		bodies += int64(len(buff.value))
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n + bodies, nil
 }
 
-func (f *formDataPayload) getContentType() string {
-	if f.contentType == "" {
-		f.getPayloadBuffer()
+// readerSize returns the number of bytes remaining in r, or zero if unknown
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := v.Stat(); err == nil {
+			return info.Size()
+		}
 	}
+	return 0
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// createFormFile works like multipart.Writer.CreateFormFile but allows the Content-Type
+// of the part to be chosen, falling back to application/octet-stream if contentType is empty.
+func createFormFile(writer *multipart.Writer, key, name, contentType string) (io.Writer, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(key), quoteEscaper.Replace(name)))
+	h.Set("Content-Type", contentType)
+	return writer.CreatePart(h)
+}
+
+// getContentType returns the content type set by getPayloadBuffer() or getPayloadReader().
+// It doesn't build the payload itself, as that would consume the io.ReadClosers.
+func (f *formDataPayload) getContentType() string {
 	return f.contentType
 }
 
@@ -224,7 +451,7 @@ func (r *httpRequest) NewRequest(ctx context.Context, method string, payload pay
 
 	var body io.Reader
 	if payload != nil {
-		if body, err = payload.getPayloadBuffer(); err != nil {
+		if body, err = payload.getPayloadReader(); err != nil {
 			return nil, err
 		}
 	} else {
@@ -232,6 +459,10 @@ func (r *httpRequest) NewRequest(ctx context.Context, method string, payload pay
 	}
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
+		// Stop any goroutine streaming the payload
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
 		return nil, err
 	}
 
@@ -252,6 +483,27 @@ func (r *httpRequest) NewRequest(ctx context.Context, method string, payload pay
 }
 
 func (r *httpRequest) makeRequest(ctx context.Context, method string, payload payload) (*httpResponse, error) {
+	resp, err := r.makeStreamingRequest(ctx, method, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading response body")
+	}
+
+	return &httpResponse{
+		Code:   resp.StatusCode,
+		Header: resp.Header,
+		Data:   responseBody,
+	}, nil
+}
+
+// makeStreamingRequest performs the request without reading the response body,
+// the caller must close the body of the returned response.
+func (r *httpRequest) makeStreamingRequest(ctx context.Context, method string, payload payload) (*http.Response, error) {
 	req, err := r.NewRequest(ctx, method, payload)
 	if err != nil {
 		return nil, err
@@ -261,12 +513,7 @@ func (r *httpRequest) makeRequest(ctx context.Context, method string, payload pa
 		fmt.Println(r.curlString(req, payload))
 	}
 
-	response := httpResponse{}
-
 	resp, err := r.Client.Do(req)
-	if resp != nil {
-		response.Code = resp.StatusCode
-	}
 	if err != nil {
 		if urlErr, ok := err.(*url.Error); ok {
 			if urlErr.Err == io.EOF {
@@ -275,15 +522,7 @@ func (r *httpRequest) makeRequest(ctx context.Context, method string, payload pa
 		}
 		return nil, errors.Wrap(err, "while making http request")
 	}
-
-	defer resp.Body.Close()
-	responseBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "while reading response body")
-	}
-
-	response.Data = responseBody
-	return &response, nil
+	return resp, nil
 }
 
 func (r *httpRequest) generateUrlWithParameters() (string, error) {
@@ -0,0 +1,307 @@
+package mailgun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxInboundMemory is the number of bytes of an inbound message ParseInboundMessage() keeps in
+// memory, larger attachments are stored in temporary files.
+const MaxInboundMemory = 32 << 20
+
+// InboundMessage is a message posted by Mailgun to the URL of a forward() route action.
+type InboundMessage struct {
+	// The SMTP recipient and sender of the message
+	Recipient string
+	Sender    string
+	// The From and Subject headers of the message
+	From    string
+	Subject string
+
+	// The text and HTML parts of the message
+	BodyPlain string
+	BodyHTML  string
+	// The text and HTML parts without quoted replies and, for the text, the signature
+	StrippedText string
+	StrippedHTML string
+	// The signature found in the text part
+	StrippedSignature string
+
+	// All the headers of the message
+	Headers mail.Header
+	// The attachments, in the order of the message
+	Attachments []InboundAttachment
+	// The signature of the request, verify it with VerifyWebhookSignature()
+	Signature Signature
+}
+
+// InboundAttachment is an attachment of an InboundMessage
+type InboundAttachment struct {
+	Filename    string
+	ContentType string
+	// The size in bytes, 0 for attachments read with an InboundStream
+	Size int64
+	// The Content-ID of inline attachments, which the HTML part references as cid:<id>
+	ContentID string
+
+	field string
+	file  *multipart.FileHeader
+}
+
+// Open opens the content of an attachment returned by ParseInboundMessage(). Attachments read
+// with an InboundStream can't be opened.
+func (a InboundAttachment) Open() (io.ReadCloser, error) {
+	if a.file == nil {
+		return nil, errors.New("attachment was streamed and can't be opened")
+	}
+	return a.file.Open()
+}
+
+// ParseInboundMessage decodes the request Mailgun sends to the URL of a forward() route action.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		msg, err := mailgun.ParseInboundMessage(r)
+//		if err != nil {
+//			http.Error(w, err.Error(), http.StatusBadRequest)
+//			return
+//		}
+//		if verified, _ := mg.VerifyWebhookSignature(msg.Signature); !verified {
+//			w.WriteHeader(http.StatusNotAcceptable)
+//			return
+//		}
+//		...
+//	}
+//
+// Attachments larger than MaxInboundMemory are stored in temporary files, call
+// r.MultipartForm.RemoveAll() once the message is handled to remove them.
+func ParseInboundMessage(r *http.Request) (*InboundMessage, error) {
+	// Messages without attachments are posted url encoded
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(MaxInboundMemory); err != nil {
+			return nil, fmt.Errorf("while parsing inbound message: %s", err)
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("while parsing inbound message: %s", err)
+	}
+
+	msg, contentIDs, err := parseInboundFields(r.FormValue)
+	if err != nil {
+		return nil, err
+	}
+	count, err := inboundAttachmentCount(r.FormValue)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || r.MultipartForm == nil {
+		return msg, nil
+	}
+	for i := 1; i <= count; i++ {
+		field := fmt.Sprintf("attachment-%d", i)
+		files := r.MultipartForm.File[field]
+		if len(files) == 0 {
+			return nil, fmt.Errorf("inbound message is missing '%s'", field)
+		}
+		msg.Attachments = append(msg.Attachments, InboundAttachment{
+			Filename:    files[0].Filename,
+			ContentType: files[0].Header.Get("Content-Type"),
+			Size:        files[0].Size,
+			ContentID:   contentIDs[field],
+			field:       field,
+			file:        files[0],
+		})
+	}
+	return msg, nil
+}
+
+// InboundStream reads an inbound message part by part, so large attachments can be copied
+// elsewhere, such as to object storage, without being buffered in memory or on disk.
+//
+//	stream, err := mailgun.NewInboundStream(r)
+//	...
+//	for {
+//		a, content, err := stream.NextAttachment()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		upload(a.Filename, content)
+//	}
+//	msg := stream.Message()
+type InboundStream struct {
+	mr          *multipart.Reader
+	values      url.Values
+	budget      int64
+	attachments []InboundAttachment
+	msg         *InboundMessage
+}
+
+// NewInboundStream starts reading the multipart request Mailgun sends to the URL of a forward()
+// route action. Use ParseInboundMessage() for messages without attachments, which Mailgun posts
+// url encoded.
+func NewInboundStream(r *http.Request) (*InboundStream, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("while reading inbound message: %s", err)
+	}
+	return &InboundStream{mr: mr, values: make(url.Values), budget: MaxInboundMemory}, nil
+}
+
+// NextAttachment returns the next attachment and a reader of its content, which is valid until
+// the next call. The fields of the message are read along the way, up to MaxInboundMemory bytes.
+// Returns io.EOF after the last attachment, or an error if the message has fewer attachments
+// than its attachment-count field announced.
+//
+// The ContentID of an attachment is only set if the content-id-map field precedes it, the
+// attachments listed by Message() always have it.
+func (s *InboundStream) NextAttachment() (InboundAttachment, io.Reader, error) {
+	for {
+		part, err := s.mr.NextPart()
+		if err == io.EOF {
+			return InboundAttachment{}, nil, s.finish()
+		}
+		if err != nil {
+			return InboundAttachment{}, nil, fmt.Errorf("while reading inbound message: %s", err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, s.budget+1))
+			if err != nil {
+				return InboundAttachment{}, nil, fmt.Errorf("while reading inbound message: %s", err)
+			}
+			s.budget -= int64(len(value))
+			if s.budget < 0 {
+				return InboundAttachment{}, nil, fmt.Errorf("inbound message fields exceed %d bytes", MaxInboundMemory)
+			}
+			s.values.Add(name, string(value))
+			continue
+		}
+		if !strings.HasPrefix(name, "attachment-") {
+			continue
+		}
+
+		a := InboundAttachment{
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			field:       name,
+		}
+		if contentIDs, err := parseContentIDMap(s.values.Get("content-id-map")); err == nil {
+			a.ContentID = contentIDs[name]
+		}
+		s.attachments = append(s.attachments, a)
+		return a, part, nil
+	}
+}
+
+// Message returns the message once NextAttachment() returned io.EOF, nil before. Its
+// attachments can't be opened, their content was read from NextAttachment().
+func (s *InboundStream) Message() *InboundMessage {
+	return s.msg
+}
+
+// finish parses the fields once the last part was read
+func (s *InboundStream) finish() error {
+	if s.msg != nil {
+		return io.EOF
+	}
+	msg, contentIDs, err := parseInboundFields(s.values.Get)
+	if err != nil {
+		return err
+	}
+	count, err := inboundAttachmentCount(s.values.Get)
+	if err != nil {
+		return err
+	}
+	if len(s.attachments) < count {
+		return fmt.Errorf("inbound message has %d attachments, expected %d", len(s.attachments), count)
+	}
+	for _, a := range s.attachments {
+		a.ContentID = contentIDs[a.field]
+		msg.Attachments = append(msg.Attachments, a)
+	}
+	s.msg = msg
+	return io.EOF
+}
+
+// parseInboundFields returns the message described by the form fields of an inbound message,
+// without its attachments, and the attachment field of each inline Content-ID
+func parseInboundFields(get func(string) string) (*InboundMessage, map[string]string, error) {
+	msg := InboundMessage{
+		Recipient:         get("recipient"),
+		Sender:            get("sender"),
+		From:              get("from"),
+		Subject:           get("subject"),
+		BodyPlain:         get("body-plain"),
+		BodyHTML:          get("body-html"),
+		StrippedText:      get("stripped-text"),
+		StrippedHTML:      get("stripped-html"),
+		StrippedSignature: get("stripped-signature"),
+		Headers:           make(mail.Header),
+		Signature: Signature{
+			TimeStamp: get("timestamp"),
+			Token:     get("token"),
+			Signature: get("signature"),
+		},
+	}
+
+	if v := get("message-headers"); v != "" {
+		var headers [][]string
+		if err := json.Unmarshal([]byte(v), &headers); err != nil {
+			return nil, nil, fmt.Errorf("while parsing 'message-headers': %s", err)
+		}
+		for _, h := range headers {
+			if len(h) != 2 {
+				return nil, nil, fmt.Errorf("while parsing 'message-headers': expected a name and a value, got %q", h)
+			}
+			key := textproto.CanonicalMIMEHeaderKey(h[0])
+			msg.Headers[key] = append(msg.Headers[key], h[1])
+		}
+	}
+
+	contentIDs, err := parseContentIDMap(get("content-id-map"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &msg, contentIDs, nil
+}
+
+// parseContentIDMap parses the content-id-map field, which maps the Content-ID of inline
+// attachments to their field, and returns the Content-ID of each field
+func parseContentIDMap(v string) (map[string]string, error) {
+	contentIDs := make(map[string]string)
+	if v == "" {
+		return contentIDs, nil
+	}
+	var ids map[string]string
+	if err := json.Unmarshal([]byte(v), &ids); err != nil {
+		return nil, fmt.Errorf("while parsing 'content-id-map': %s", err)
+	}
+	for id, field := range ids {
+		contentIDs[field] = id
+	}
+	return contentIDs, nil
+}
+
+func inboundAttachmentCount(get func(string) string) (int, error) {
+	v := get("attachment-count")
+	if v == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("while parsing 'attachment-count': %s", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,144 @@
+package mailgun_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+func TestParseInboundMessage(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"recipient":        "support@example.com",
+		"sender":           "bob@example.com",
+		"from":             "Bob <bob@example.com>",
+		"subject":          "Help",
+		"body-plain":       "Hi\n\n> quoted\n--\nBob",
+		"stripped-text":    "Hi",
+		"body-html":        `<p>Hi <img src="cid:logo"></p>`,
+		"stripped-html":    "<p>Hi</p>",
+		"message-headers":  `[["Received", "by mx1"], ["Received", "by mx2"], ["X-Mailgun-Variables", "{}"]]`,
+		"content-id-map":   `{"<logo>": "attachment-2"}`,
+		"attachment-count": "2",
+		"timestamp":        "1560000000",
+		"token":            "token",
+		"signature":        "signature",
+	}
+	for k, v := range fields {
+		ensure.Nil(t, w.WriteField(k, v))
+	}
+	fw, err := w.CreateFormFile("attachment-1", "report.txt")
+	ensure.Nil(t, err)
+	fw.Write([]byte("report"))
+	fw, err = w.CreateFormFile("attachment-2", "logo.png")
+	ensure.Nil(t, err)
+	fw.Write([]byte("png"))
+	ensure.Nil(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/inbound", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	msg, err := mailgun.ParseInboundMessage(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg.Recipient, "support@example.com")
+	ensure.DeepEqual(t, msg.Sender, "bob@example.com")
+	ensure.DeepEqual(t, msg.From, "Bob <bob@example.com>")
+	ensure.DeepEqual(t, msg.Subject, "Help")
+	ensure.DeepEqual(t, msg.StrippedText, "Hi")
+	ensure.DeepEqual(t, msg.StrippedHTML, "<p>Hi</p>")
+	ensure.DeepEqual(t, msg.Headers["Received"], []string{"by mx1", "by mx2"})
+	ensure.DeepEqual(t, msg.Headers.Get("x-mailgun-variables"), "{}")
+	ensure.DeepEqual(t, msg.Signature, mailgun.Signature{TimeStamp: "1560000000", Token: "token", Signature: "signature"})
+
+	ensure.DeepEqual(t, len(msg.Attachments), 2)
+	ensure.DeepEqual(t, msg.Attachments[0].Filename, "report.txt")
+	ensure.DeepEqual(t, msg.Attachments[0].Size, int64(6))
+	ensure.DeepEqual(t, msg.Attachments[0].ContentID, "")
+	ensure.DeepEqual(t, msg.Attachments[1].ContentID, "<logo>")
+	rc, err := msg.Attachments[1].Open()
+	ensure.Nil(t, err)
+	content, err := ioutil.ReadAll(rc)
+	rc.Close()
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(content), "png")
+}
+
+func TestParseInboundMessageURLEncoded(t *testing.T) {
+	form := url.Values{"recipient": {"support@example.com"}, "subject": {"Help"}}
+	req := httptest.NewRequest(http.MethodPost, "/inbound", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	msg, err := mailgun.ParseInboundMessage(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg.Recipient, "support@example.com")
+	ensure.DeepEqual(t, msg.Subject, "Help")
+	ensure.DeepEqual(t, len(msg.Attachments), 0)
+
+	form.Set("message-headers", `{"Received": "by mx1"}`)
+	req = httptest.NewRequest(http.MethodPost, "/inbound", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = mailgun.ParseInboundMessage(req)
+	ensure.NotNil(t, err)
+}
+
+func TestInboundStream(t *testing.T) {
+	newRequest := func(count string) *http.Request {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		w.WriteField("recipient", "support@example.com")
+		w.WriteField("attachment-count", count)
+		fw, _ := w.CreateFormFile("attachment-1", "report.txt")
+		fw.Write(bytes.Repeat([]byte("r"), 1<<20))
+		w.WriteField("content-id-map", `{"<logo>": "attachment-2"}`)
+		fw, _ = w.CreateFormFile("attachment-2", "logo.png")
+		fw.Write([]byte("png"))
+		w.WriteField("subject", "Help")
+		w.Close()
+		req := httptest.NewRequest(http.MethodPost, "/inbound", &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		return req
+	}
+
+	stream, err := mailgun.NewInboundStream(newRequest("2"))
+	ensure.Nil(t, err)
+	var names []string
+	var sizes []int
+	for {
+		a, content, err := stream.NextAttachment()
+		if err == io.EOF {
+			break
+		}
+		ensure.Nil(t, err)
+		ensure.True(t, stream.Message() == nil)
+		data, err := ioutil.ReadAll(content)
+		ensure.Nil(t, err)
+		names = append(names, a.Filename+a.ContentID)
+		sizes = append(sizes, len(data))
+	}
+	ensure.DeepEqual(t, names, []string{"report.txt", "logo.png<logo>"})
+	ensure.DeepEqual(t, sizes, []int{1 << 20, 3})
+
+	msg := stream.Message()
+	ensure.DeepEqual(t, msg.Recipient, "support@example.com")
+	ensure.DeepEqual(t, msg.Subject, "Help")
+	ensure.DeepEqual(t, len(msg.Attachments), 2)
+	ensure.DeepEqual(t, msg.Attachments[1].ContentID, "<logo>")
+	_, err = msg.Attachments[0].Open()
+	ensure.NotNil(t, err)
+
+	// Attachments are skipped when not read, missing attachments are reported
+	stream, err = mailgun.NewInboundStream(newRequest("3"))
+	ensure.Nil(t, err)
+	for err == nil {
+		_, _, err = stream.NextAttachment()
+	}
+	ensure.DeepEqual(t, err.Error(), "inbound message has 2 attachments, expected 3")
+}
@@ -21,7 +21,7 @@ type okResp struct {
 // Returns a list of IPs assigned to your account
 func (mg *MailgunImpl) ListIPS(ctx context.Context, dedicated bool) ([]IPAddress, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, ipsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	if dedicated {
 		r.addParameter("dedicated", "true")
 	}
@@ -41,7 +41,7 @@ func (mg *MailgunImpl) ListIPS(ctx context.Context, dedicated bool) ([]IPAddress
 // Returns information about the specified IP
 func (mg *MailgunImpl) GetIP(ctx context.Context, ip string) (IPAddress, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, ipsEndpoint) + "/" + ip)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var resp IPAddress
 	err := getResponseFromJSON(ctx, r, &resp)
@@ -51,7 +51,7 @@ func (mg *MailgunImpl) GetIP(ctx context.Context, ip string) (IPAddress, error)
 // Returns a list of IPs currently assigned to the specified domain.
 func (mg *MailgunImpl) ListDomainIPS(ctx context.Context) ([]IPAddress, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + mg.domain + "/ips")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var resp ipAddressListResponse
@@ -68,7 +68,7 @@ func (mg *MailgunImpl) ListDomainIPS(ctx context.Context) ([]IPAddress, error) {
 // Assign a dedicated IP to the domain specified.
 func (mg *MailgunImpl) AddDomainIP(ctx context.Context, ip string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + mg.domain + "/ips")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
@@ -80,7 +80,7 @@ func (mg *MailgunImpl) AddDomainIP(ctx context.Context, ip string) error {
 // Unassign an IP from the domain specified.
 func (mg *MailgunImpl) DeleteDomainIP(ctx context.Context, ip string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, domainsEndpoint) + "/" + mg.domain + "/ips/" + ip)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
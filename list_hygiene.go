@@ -0,0 +1,164 @@
+package mailgun
+
+import (
+	"context"
+	"strings"
+)
+
+// HygieneAction is what ListHygiene does with a member found on a suppression list
+type HygieneAction int
+
+const (
+	// HygieneUnsubscribe marks the member as unsubscribed, keeping it on the list
+	HygieneUnsubscribe HygieneAction = iota
+	// HygieneRemove deletes the member from the list
+	HygieneRemove
+)
+
+func (a HygieneAction) String() string {
+	switch a {
+	case HygieneUnsubscribe:
+		return "unsubscribe"
+	case HygieneRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// ListHygiene keeps mailing lists clean by cross-referencing their members with the bounces and
+// complaints of a domain. Members found on a suppression list are unsubscribed or removed from
+// the list, and the changes are reported.
+//
+//	hygiene := mailgun.NewListHygiene(mg, mailgun.DomainSuppressions(mg))
+//	hygiene.Action = mailgun.HygieneRemove
+//	report, err := hygiene.Run(ctx, "newsletter@example.com")
+type ListHygiene struct {
+	// What to do with suppressed members, defaults to HygieneUnsubscribe
+	Action HygieneAction
+	// The suppression lists members are checked against, defaults to SuppressionBounces and
+	// SuppressionComplaints. With SuppressionUnsubscribes, only addresses unsubscribed from
+	// all tags are considered.
+	Lists []string
+	// DryRun reports the changes without making them
+	DryRun bool
+
+	mg     Mailgun
+	source SuppressionSource
+}
+
+// HygieneReport reports the changes ListHygiene made to a mailing list, or would make in a dry run.
+type HygieneReport struct {
+	// The address of the mailing list
+	List string
+	// The number of members checked
+	Checked int
+	// The members unsubscribed or removed
+	Members []HygieneMember
+}
+
+// HygieneMember is a member of a mailing list which is on a suppression list
+type HygieneMember struct {
+	Address string
+	// The suppression list the address was found on
+	Suppression string
+	Action      HygieneAction
+}
+
+// NewListHygiene creates a ListHygiene which checks mailing list members against the
+// suppressions of source, using the credentials of mg to update the lists.
+func NewListHygiene(mg Mailgun, source SuppressionSource) *ListHygiene {
+	return &ListHygiene{mg: mg, source: source}
+}
+
+// Run checks the members of the mailing lists and returns a report for each list, in the order
+// given. If an error occurs, Run returns the reports of the lists processed so far, the report
+// of the list which failed includes the changes made before the error.
+func (h *ListHygiene) Run(ctx context.Context, lists ...string) ([]HygieneReport, error) {
+	set, err := h.source.Suppressions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	suppressed := h.suppressed(set)
+
+	var reports []HygieneReport
+	for _, list := range lists {
+		report, err := h.clean(ctx, list, suppressed)
+		reports = append(reports, report)
+		if err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+func (h *ListHygiene) clean(ctx context.Context, list string, suppressed map[string]string) (HygieneReport, error) {
+	report := HygieneReport{List: list}
+
+	// Collect the members first, so removing members doesn't change the pages being read
+	var members, page []Member
+	it := h.mg.ListMembers(list, nil)
+	for it.Next(ctx, &page) {
+		members = append(members, page...)
+	}
+	if it.Err() != nil {
+		return report, it.Err()
+	}
+
+	for _, m := range members {
+		report.Checked++
+		suppression, ok := suppressed[strings.ToLower(m.Address)]
+		if !ok {
+			continue
+		}
+		if h.Action == HygieneUnsubscribe && m.Subscribed != nil && !*m.Subscribed {
+			continue
+		}
+
+		if !h.DryRun {
+			var err error
+			if h.Action == HygieneRemove {
+				err = h.mg.DeleteMember(ctx, m.Address, list)
+			} else {
+				_, err = h.mg.UpdateMember(ctx, m.Address, list, Member{Subscribed: Unsubscribed})
+			}
+			if err != nil {
+				return report, err
+			}
+		}
+		report.Members = append(report.Members, HygieneMember{
+			Address:     m.Address,
+			Suppression: suppression,
+			Action:      h.Action,
+		})
+	}
+	return report, nil
+}
+
+// suppressed returns the suppression list of each suppressed address, by lower case address
+func (h *ListHygiene) suppressed(set SuppressionSet) map[string]string {
+	lists := h.Lists
+	if len(lists) == 0 {
+		lists = []string{SuppressionBounces, SuppressionComplaints}
+	}
+
+	suppressed := make(map[string]string)
+	for _, list := range lists {
+		switch list {
+		case SuppressionBounces:
+			for _, b := range set.Bounces {
+				suppressed[strings.ToLower(b.Address)] = list
+			}
+		case SuppressionComplaints:
+			for _, c := range set.Complaints {
+				suppressed[strings.ToLower(c.Address)] = list
+			}
+		case SuppressionUnsubscribes:
+			for _, u := range set.Unsubscribes {
+				if len(u.Tags) == 0 || containsString(u.Tags, "*") {
+					suppressed[strings.ToLower(u.Address)] = list
+				}
+			}
+		}
+	}
+	return suppressed
+}
@@ -0,0 +1,74 @@
+package mailgun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+func TestListHygiene(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+	ensure.Nil(t, mg.CreateMembers(ctx, false, address, []mailgun.Member{
+		{Address: "alice@example.com"},
+		{Address: "bounced@example.com"},
+		{Address: "complained@example.com"},
+		{Address: "unsubscribed@example.com"},
+	}))
+
+	suppressions := mailgun.SuppressionSet{
+		Bounces:      []mailgun.Bounce{{Address: "Bounced@example.com"}},
+		Complaints:   []mailgun.Complaint{{Address: "complained@example.com"}},
+		Unsubscribes: []mailgun.Unsubscribe{{Address: "unsubscribed@example.com", Tags: []string{"*"}}},
+	}
+	hygiene := mailgun.NewListHygiene(mg, suppressions)
+
+	hygiene.DryRun = true
+	reports, err := hygiene.Run(ctx, address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(reports), 1)
+	ensure.DeepEqual(t, reports[0].Checked, 4)
+	ensure.DeepEqual(t, reports[0].Members, []mailgun.HygieneMember{
+		{Address: "bounced@example.com", Suppression: mailgun.SuppressionBounces, Action: mailgun.HygieneUnsubscribe},
+		{Address: "complained@example.com", Suppression: mailgun.SuppressionComplaints, Action: mailgun.HygieneUnsubscribe},
+	})
+	member, err := mg.GetMember(ctx, "bounced@example.com", address)
+	ensure.Nil(t, err)
+	ensure.True(t, member.Subscribed == nil || *member.Subscribed)
+
+	hygiene.DryRun = false
+	reports, err = hygiene.Run(ctx, address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(reports[0].Members), 2)
+	member, err = mg.GetMember(ctx, "bounced@example.com", address)
+	ensure.Nil(t, err)
+	ensure.False(t, *member.Subscribed)
+
+	// Members already unsubscribed are not reported again
+	reports, err = hygiene.Run(ctx, address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(reports[0].Members), 0)
+
+	hygiene.Action = mailgun.HygieneRemove
+	hygiene.Lists = []string{mailgun.SuppressionUnsubscribes}
+	reports, err = hygiene.Run(ctx, address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, reports[0].Members, []mailgun.HygieneMember{
+		{Address: "unsubscribed@example.com", Suppression: mailgun.SuppressionUnsubscribes, Action: mailgun.HygieneRemove},
+	})
+	_, err = mg.GetMember(ctx, "unsubscribed@example.com", address)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+
+	_, err = hygiene.Run(ctx, "unknown@"+testDomain)
+	ensure.NotNil(t, err)
+}
@@ -0,0 +1,175 @@
+package mailgun
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Headers which are derived from the message by Mailgun and are not copied by NewMessageFromMail()
+var skipMailHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Subject":                   true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Content-Id":                true,
+	"Dkim-Signature":            true,
+	"Received":                  true,
+	"Return-Path":               true,
+}
+
+// NewMessageFromMail converts a message parsed by the net/mail package into a Mailgun
+// message, easing migration from senders which build messages for SMTP.
+//
+//	msg, err := mail.ReadMessage(r)
+//	...
+//	m, err := mg.NewMessageFromMail(msg)
+//	...
+//	mg.Send(ctx, m)
+//
+// The From, To, Cc, Bcc and Subject headers become the sender, recipients and subject of the
+// message. Text and HTML parts become the message bodies, parts with a Content-ID become
+// inline attachments and all other parts become attachments. Remaining headers are copied
+// as custom headers. The body of msg is consumed.
+func (mg *MailgunImpl) NewMessageFromMail(msg *mail.Message) (*Message, error) {
+	from, err := mailAddresses(msg.Header, "From")
+	if err != nil {
+		return nil, err
+	}
+	if len(from) != 1 {
+		return nil, fmt.Errorf("message must have exactly one From address, found %d", len(from))
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, err
+	}
+	to, err := mailAddresses(msg.Header, "To")
+	if err != nil {
+		return nil, err
+	}
+
+	m := mg.NewMessage(from[0], subject, "", to...)
+	for _, name := range []string{"Cc", "Bcc"} {
+		addresses, err := mailAddresses(msg.Header, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addresses {
+			if name == "Cc" {
+				m.AddCC(addr)
+			} else {
+				m.AddBCC(addr)
+			}
+		}
+	}
+	for name, values := range msg.Header {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if skipMailHeaders[name] {
+			continue
+		}
+		for _, v := range values {
+			m.AddHeader(name, v)
+		}
+	}
+
+	header := textproto.MIMEHeader(msg.Header)
+	if err := addMailPart(m, header, msg.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mailAddresses returns the addresses in the named header, or nil if the header is not present
+func mailAddresses(h mail.Header, name string) ([]string, error) {
+	if h.Get(name) == "" {
+		return nil, nil
+	}
+	list, err := h.AddressList(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %s", name, err)
+	}
+	var result []string
+	for _, a := range list {
+		if a.Name == "" {
+			result = append(result, a.Address)
+			continue
+		}
+		result = append(result, a.String())
+	}
+	return result, nil
+}
+
+// addMailPart adds the body of a MIME part to the message, descending into multipart parts
+func addMailPart(m *Message, header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 defaults parts without a content type to plain text
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := addMailPart(m, p.Header, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := ioutil.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	contentID := strings.Trim(header.Get("Content-Id"), "<>")
+
+	switch {
+	case contentID != "":
+		m.AddReaderInline(contentID, ioutil.NopCloser(bytes.NewReader(data)))
+	case disposition != "attachment" && mediaType == "text/plain" && m.specific.(*plainMessage).text == "":
+		m.specific.setText(string(data))
+	case disposition != "attachment" && mediaType == "text/html" && m.specific.getHtml() == "":
+		m.SetHtml(string(data))
+	default:
+		if filename == "" {
+			filename = "attachment"
+		}
+		m.AddBufferAttachment(filename, data)
+	}
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
@@ -0,0 +1,82 @@
+package mailgun
+
+import (
+	"io/ioutil"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+const exampleMail = "From: \"Sender\" <sender@example.com>\r\n" +
+	"To: one@example.com, \"Two\" <two@example.com>\r\n" +
+	"Cc: cc@example.com\r\n" +
+	"Subject: =?utf-8?q?Caf=C3=A9_menu?=\r\n" +
+	"X-Campaign: spring\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=C3=A9 menu\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<img src=\"cid:logo\">\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Id: <logo>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"bG9nbw==\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Disposition: attachment; filename=\"notes.txt\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"bm90ZXM=\r\n" +
+	"--outer--\r\n"
+
+func TestNewMessageFromMail(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	msg, err := mail.ReadMessage(strings.NewReader(exampleMail))
+	ensure.Nil(t, err)
+
+	m, err := mg.NewMessageFromMail(msg)
+	ensure.Nil(t, err)
+
+	pm := m.specific.(*plainMessage)
+	ensure.DeepEqual(t, pm.from, `"Sender" <sender@example.com>`)
+	ensure.DeepEqual(t, pm.subject, "Café menu")
+	ensure.DeepEqual(t, m.to, []string{"one@example.com", `"Two" <two@example.com>`})
+	ensure.DeepEqual(t, pm.cc, []string{"cc@example.com"})
+	ensure.DeepEqual(t, pm.text, "Café menu")
+	ensure.DeepEqual(t, pm.html, `<img src="cid:logo">`)
+	ensure.DeepEqual(t, m.GetHeaders(), map[string]string{"X-Campaign": "spring"})
+
+	ensure.DeepEqual(t, len(m.readerInlines), 1)
+	ensure.DeepEqual(t, m.readerInlines[0].Filename, "logo")
+	inline, err := ioutil.ReadAll(m.readerInlines[0].ReadCloser)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(inline), "logo")
+
+	ensure.DeepEqual(t, m.bufferAttachments, []BufferAttachment{{Filename: "notes.txt", Buffer: []byte("notes")}})
+}
+
+func TestNewMessageFromMailRequiresFrom(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	msg, err := mail.ReadMessage(strings.NewReader("To: one@example.com\r\nSubject: hi\r\n\r\nbody\r\n"))
+	ensure.Nil(t, err)
+
+	_, err = mg.NewMessageFromMail(msg)
+	ensure.NotNil(t, err)
+}
@@ -6,11 +6,11 @@
 // For further information please see the Mailgun documentation at
 // http://documentation.mailgun.com/
 //
-//  Original Author: Michael Banzon
-//  Contributions:   Samuel A. Falvo II <sam.falvo %at% rackspace.com>
-//                   Derrick J. Wippler <thrawn01 %at% gmail.com>
+//	Original Author: Michael Banzon
+//	Contributions:   Samuel A. Falvo II <sam.falvo %at% rackspace.com>
+//	                 Derrick J. Wippler <thrawn01 %at% gmail.com>
 //
-// Examples
+// # Examples
 //
 // All functions and method have a corresponding test, so if you don't find an
 // example for a function you'd like to know more about, please check for a
@@ -18,7 +18,7 @@
 // welcome as well. Feel free to submit a pull request or open a Github issue
 // if you cannot find an example to suit your needs.
 //
-// List iterators
+// # List iterators
 //
 // Most methods that begin with `List` return an iterator which simplfies
 // paging through large result sets returned by the mailgun API. Most `List`
@@ -28,23 +28,22 @@
 //
 // For example, the following iterates over all pages of events 100 items at a time
 //
-//  mg := mailgun.NewMailgun("your-domain.com", "your-api-key")
-//  it := mg.ListEvents(&mailgun.ListEventOptions{Limit: 100})
+//	mg := mailgun.NewMailgun("your-domain.com", "your-api-key")
+//	it := mg.ListEvents(&mailgun.ListEventOptions{Limit: 100})
 //
-//  // The entire operation should not take longer than 30 seconds
-//  ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-//  defer cancel()
+//	// The entire operation should not take longer than 30 seconds
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+//	defer cancel()
 //
-//  // For each page of 100 events
-//  var page []mailgun.Event
-//  for it.Next(ctx, &page) {
-//    for _, e := range page {
-//      // Do something with 'e'
-//    }
-//  }
+//	// For each page of 100 events
+//	var page []mailgun.Event
+//	for it.Next(ctx, &page) {
+//	  for _, e := range page {
+//	    // Do something with 'e'
+//	  }
+//	}
 //
-//
-// License
+// # License
 //
 // Copyright (c) 2013-2019, Michael Banzon.
 // All rights reserved.
@@ -81,7 +80,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -90,22 +91,27 @@ var Debug = false
 
 const (
 	// Base Url the library uses to contact mailgun. Use SetAPIBase() to override
-	APIBase              = "https://api.mailgun.net/v3"
-	messagesEndpoint     = "messages"
-	mimeMessagesEndpoint = "messages.mime"
-	bouncesEndpoint      = "bounces"
-	statsTotalEndpoint   = "stats/total"
-	domainsEndpoint      = "domains"
-	tagsEndpoint         = "tags"
-	eventsEndpoint       = "events"
-	unsubscribesEndpoint = "unsubscribes"
-	routesEndpoint       = "routes"
-	ipsEndpoint          = "ips"
-	exportsEndpoint      = "exports"
-	webhooksEndpoint     = "webhooks"
-	listsEndpoint        = "lists"
-	basicAuthUser        = "api"
-	templatesEndpoint    = "templates"
+	APIBase               = "https://api.mailgun.net/v3"
+	messagesEndpoint      = "messages"
+	mimeMessagesEndpoint  = "messages.mime"
+	bouncesEndpoint       = "bounces"
+	statsTotalEndpoint    = "stats/total"
+	domainsEndpoint       = "domains"
+	tagsEndpoint          = "tags"
+	eventsEndpoint        = "events"
+	unsubscribesEndpoint  = "unsubscribes"
+	routesEndpoint        = "routes"
+	ipsEndpoint           = "ips"
+	exportsEndpoint       = "exports"
+	webhooksEndpoint      = "webhooks"
+	listsEndpoint         = "lists"
+	basicAuthUser         = "api"
+	templatesEndpoint     = "templates"
+	envelopesEndpoint     = "envelopes"
+	sendingQueuesEndpoint = "sending_queues"
+	signingKeyEndpoint    = "accounts/http_signing_key"
+	bulkValidateEndpoint  = "address/validate/bulk"
+	bulkPreviewEndpoint   = "address/validate/preview"
 )
 
 // Mailgun defines the supported subset of the Mailgun API.
@@ -123,18 +129,33 @@ type Mailgun interface {
 	Client() *http.Client
 	SetClient(client *http.Client)
 	SetAPIBase(url string)
+	SetTagValidator(v TagValidator)
+	SetVariableCipher(c Cipher)
+	VariableCipher() Cipher
+	SetTestMode(enabled bool)
+	SetStrictMode(enabled bool)
+	SetErrorBodyCapture(n int)
+	SetDomainRouting(enabled bool)
+	SetDomainThrottle(t *DomainThrottle)
+	SetResponseCache(c *ResponseCache)
+	SetClientEvents(e *ClientEvents)
+	ClientEvents() *ClientEvents
+	ValidateTag(tag string) error
 
 	Send(ctx context.Context, m *Message) (string, string, error)
 	ReSend(ctx context.Context, id string, recipients ...string) (string, string, error)
 	NewMessage(from, subject, text string, to ...string) *Message
 	NewMIMEMessage(body io.ReadCloser, to ...string) *Message
+	NewMessageFromMail(msg *mail.Message) (*Message, error)
 
 	ListBounces(opts *ListOptions) *BouncesIterator
 	GetBounce(ctx context.Context, address string) (Bounce, error)
 	AddBounce(ctx context.Context, address, code, error string) error
 	DeleteBounce(ctx context.Context, address string) error
+	DeleteAllBounces(ctx context.Context) error
 
 	GetStats(ctx context.Context, events []string, opts *GetStatOptions) ([]Stats, error)
+	CompareStats(ctx context.Context, events []string, current, previous *GetStatOptions) (StatsComparison, error)
 	GetTag(ctx context.Context, tag string) (Tag, error)
 	DeleteTag(ctx context.Context, tag string) error
 	ListTags(*ListTagOptions) *TagIterator
@@ -147,12 +168,22 @@ type Mailgun interface {
 	UpdateDomainConnection(ctx context.Context, domain string, dc DomainConnection) error
 	GetDomainConnection(ctx context.Context, domain string) (DomainConnection, error)
 	GetDomainTracking(ctx context.Context, domain string) (DomainTracking, error)
+	UpdateOpenTracking(ctx context.Context, domain string, active bool) error
+	UpdateClickTracking(ctx context.Context, domain string, mode TrackingClicksMode) error
+	UpdateUnsubscribeTracking(ctx context.Context, domain string, active bool, htmlFooter, textFooter string) error
+	GetSendingQueues(ctx context.Context) (SendingQueues, error)
+	GetDomainHealth(ctx context.Context, domain string) (DomainHealth, error)
 
 	GetStoredMessage(ctx context.Context, id string) (StoredMessage, error)
+	GetStoredMessages(ctx context.Context, ids []string, concurrency int) []StoredMessageResult
 	GetStoredMessageRaw(ctx context.Context, id string) (StoredMessageRaw, error)
+	GetStoredMessageMIME(ctx context.Context, id string) (io.ReadCloser, error)
 	GetStoredMessageForURL(ctx context.Context, url string) (StoredMessage, error)
 	GetStoredMessageRawForURL(ctx context.Context, url string) (StoredMessageRaw, error)
+	GetStoredAttachment(ctx context.Context, url string) (io.ReadCloser, error)
+	SearchStoredMessages(ctx context.Context, q *StoredMessageQuery) ([]StoredMessageMatch, error)
 	DeleteStoredMessage(ctx context.Context, id string) error
+	DeleteScheduledMessages(ctx context.Context) error
 
 	ListCredentials(opts *ListOptions) *CredentialsIterator
 	CreateCredential(ctx context.Context, login, password string) error
@@ -169,19 +200,27 @@ type Mailgun interface {
 	GetComplaint(ctx context.Context, address string) (Complaint, error)
 	CreateComplaint(ctx context.Context, address string) error
 	DeleteComplaint(ctx context.Context, address string) error
+	MirrorSuppressions(ctx context.Context, opts *MirrorOptions) (MirrorReport, error)
 
 	ListRoutes(opts *ListOptions) *RoutesIterator
 	GetRoute(ctx context.Context, address string) (Route, error)
 	CreateRoute(ctx context.Context, address Route) (Route, error)
 	DeleteRoute(ctx context.Context, address string) error
 	UpdateRoute(ctx context.Context, address string, r Route) (Route, error)
-
-	ListWebhooks(ctx context.Context) (map[string]string, error)
-	CreateWebhook(ctx context.Context, kind string, url []string) error
-	DeleteWebhook(ctx context.Context, kind string) error
-	GetWebhook(ctx context.Context, kind string) (string, error)
-	UpdateWebhook(ctx context.Context, kind string, url []string) error
+	MatchRoute(ctx context.Context, address string) (Route, error)
+
+	ListWebhooks(ctx context.Context) (map[WebhookKind]Webhook, error)
+	CreateWebhook(ctx context.Context, kind WebhookKind, url []string) error
+	DeleteWebhook(ctx context.Context, kind WebhookKind) error
+	GetWebhook(ctx context.Context, kind WebhookKind) (Webhook, error)
+	UpdateWebhook(ctx context.Context, kind WebhookKind, url []string) error
+	TestWebhook(ctx context.Context, kind WebhookKind, url string) (WebhookTestResult, error)
+	GetWebhookSigningKey(ctx context.Context) (string, error)
+	SetWebhookSigningKey(key string)
+	VerifyWebhookSignature(sig Signature) (verified bool, err error)
 	VerifyWebhookRequest(req *http.Request) (verified bool, err error)
+	ParseWebhookPayload(raw []byte) (Event, error)
+	SetWebhookIPAllowlist(allowlist *WebhookIPAllowlist)
 
 	ListMailingLists(opts *ListOptions) *ListsIterator
 	CreateMailingList(ctx context.Context, address MailingList) (MailingList, error)
@@ -189,15 +228,20 @@ type Mailgun interface {
 	GetMailingList(ctx context.Context, address string) (MailingList, error)
 	UpdateMailingList(ctx context.Context, address string, ml MailingList) (MailingList, error)
 
-	ListMembers(address string, opts *ListOptions) *MemberListIterator
+	ListMembers(address string, opts *ListMembersOptions) *MemberListIterator
 	GetMember(ctx context.Context, MemberAddr, listAddr string) (Member, error)
-	CreateMember(ctx context.Context, merge bool, addr string, prototype Member) error
-	CreateMemberList(ctx context.Context, subscribed *bool, addr string, newMembers []interface{}) error
+	CreateMember(ctx context.Context, upsert bool, addr string, prototype Member) error
+	CreateMemberList(ctx context.Context, upsert *bool, addr string, newMembers []interface{}) error
+	CreateMembers(ctx context.Context, upsert bool, addr string, members []Member) error
+	CreateMembersFromCSV(ctx context.Context, upsert bool, addr string, data io.Reader) error
+	ReplaceMembers(ctx context.Context, addr string, members []Member) (MembershipChanges, error)
+	ExportMembers(ctx context.Context, list string, w io.Writer, opts *ExportMembersOptions) (int, error)
 	UpdateMember(ctx context.Context, Member, list string, prototype Member) (Member, error)
 	DeleteMember(ctx context.Context, Member, list string) error
 
 	ListEvents(*ListEventOptions) *EventIterator
 	PollEvents(*ListEventOptions) *EventPoller
+	CoordinateEvents(store Store, id string, opts *ListEventOptions) *EventCoordinator
 
 	ListIPS(ctx context.Context, dedicated bool) ([]IPAddress, error)
 	GetIP(ctx context.Context, ip string) (IPAddress, error)
@@ -205,6 +249,19 @@ type Mailgun interface {
 	AddDomainIP(ctx context.Context, ip string) error
 	DeleteDomainIP(ctx context.Context, ip string) error
 
+	ValidateEmail(ctx context.Context, address string, opts *ValidateEmailOptions) (EmailValidation, error)
+	CreateBulkValidation(ctx context.Context, listID, file string) error
+	CreateBulkValidationFromReader(ctx context.Context, listID string, data io.Reader) error
+	GetBulkValidation(ctx context.Context, listID string) (BulkValidation, error)
+	ListBulkValidations(opts *ListOptions) *BulkValidationsIterator
+	DeleteBulkValidation(ctx context.Context, listID string) error
+	DownloadBulkValidationResult(ctx context.Context, listID string, w io.Writer) error
+	CreateBulkPreview(ctx context.Context, listID, file string) error
+	GetBulkPreview(ctx context.Context, listID string) (BulkPreview, error)
+	ListBulkPreviews(ctx context.Context) ([]BulkPreview, error)
+	DeleteBulkPreview(ctx context.Context, listID string) error
+	PromoteBulkPreview(ctx context.Context, listID string) error
+
 	ListExports(ctx context.Context, url string) ([]Export, error)
 	GetExport(ctx context.Context, id string) (Export, error)
 	GetExportLink(ctx context.Context, id string) (string, error)
@@ -213,16 +270,17 @@ type Mailgun interface {
 	GetTagLimits(ctx context.Context, domain string) (TagLimits, error)
 
 	CreateTemplate(ctx context.Context, template *Template) error
-	GetTemplate(ctx context.Context, id string) (Template, error)
+	GetTemplate(ctx context.Context, name string) (Template, error)
 	UpdateTemplate(ctx context.Context, template *Template) error
-	DeleteTemplate(ctx context.Context, id string) error
+	DeleteTemplate(ctx context.Context, name string) error
 	ListTemplates(opts *ListOptions) *TemplatesIterator
 
-	AddTemplateVersion(ctx context.Context, templateId string, version *TemplateVersion) error
-	GetTemplateVersion(ctx context.Context, templateId, versionId string) (TemplateVersion, error)
-	UpdateTemplateVersion(ctx context.Context, templateId string, version *TemplateVersion) error
-	DeleteTemplateVersion(ctx context.Context, templateId, versionId string) error
-	ListTemplateVersions(templateId string, opts *ListOptions) *TemplateVersionsIterator
+	AddTemplateVersion(ctx context.Context, templateName string, version *TemplateVersion) error
+	GetTemplateVersion(ctx context.Context, templateName, tag string) (TemplateVersion, error)
+	UpdateTemplateVersion(ctx context.Context, templateName string, version *TemplateVersion) error
+	ActivateTemplateVersion(ctx context.Context, templateName, tag string) error
+	DeleteTemplateVersion(ctx context.Context, templateName, tag string) error
+	ListTemplateVersions(templateName string, opts *ListOptions) *TemplateVersionsIterator
 }
 
 // MailgunImpl bundles data needed by a large number of methods in order to interact with the Mailgun API.
@@ -233,6 +291,20 @@ type MailgunImpl struct {
 	apiKey  string
 	client  *http.Client
 	baseURL string
+
+	tagValidator     TagValidator
+	testMode         bool
+	strictMode       bool
+	errorBodyCapture int
+	webhookIPs       *WebhookIPAllowlist
+	cipher           Cipher
+	events           *ClientEvents
+	signingKey       *signingKeyCache
+
+	domainRouting bool
+	domainRoutes  *domainRoutingCache
+	throttle      *DomainThrottle
+	maintenance   *maintenancePause
 }
 
 // NewMailGun creates a new client instance.
@@ -242,11 +314,15 @@ func NewMailgun(domain, apiKey string) *MailgunImpl {
 		domain:  domain,
 		apiKey:  apiKey,
 		client:  http.DefaultClient,
+
+		signingKey:   &signingKeyCache{},
+		domainRoutes: &domainRoutingCache{},
+		maintenance:  &maintenancePause{},
 	}
 }
 
 // Return a new Mailgun client using the environment variables
-// MG_API_KEY, MG_DOMAIN, and MG_URL
+// MG_API_KEY, MG_DOMAIN, MG_URL and MG_WEBHOOK_SIGNING_KEY
 func NewMailgunFromEnv() (*MailgunImpl, error) {
 	apiKey := os.Getenv("MG_API_KEY")
 	if apiKey == "" {
@@ -264,6 +340,10 @@ func NewMailgunFromEnv() (*MailgunImpl, error) {
 		mg.SetAPIBase(url)
 	}
 
+	if key := os.Getenv("MG_WEBHOOK_SIGNING_KEY"); key != "" {
+		mg.SetWebhookSigningKey(key)
+	}
+
 	return mg, nil
 }
 
@@ -297,6 +377,59 @@ func (mg *MailgunImpl) SetAPIBase(address string) {
 	mg.apiBase = address
 }
 
+// SetTestMode forces test mode on every message sent through this client, regardless of
+// whether EnableTestMode() was called on the message. Messages sent in test mode are accepted
+// by Mailgun but never delivered, use this to ensure staging environments can't send real mail.
+func (mg *MailgunImpl) SetTestMode(enabled bool) {
+	mg.testMode = enabled
+}
+
+// SetErrorBodyCapture sets a number of bytes to have the UnexpectedResponseErrors of this client
+// retain a copy of the request parameters, with secrets redacted, and to limit the response body
+// they retain to that size. Useful for post-incident debugging of failed requests.
+// Pass zero, the default, to retain only the response body.
+func (mg *MailgunImpl) SetErrorBodyCapture(n int) {
+	mg.errorBodyCapture = n
+}
+
+// SetStrictMode makes Send() fail with a *MessageWarningsError for every message with
+// Warnings(), as if Message.SetStrictMode() was called on each of them.
+func (mg *MailgunImpl) SetStrictMode(enabled bool) {
+	mg.strictMode = enabled
+}
+
+// SetTagValidator installs a validator which is consulted by Message.AddTag() for
+// every tag added to messages created by this client. Pass nil to remove the validator.
+func (mg *MailgunImpl) SetTagValidator(v TagValidator) {
+	mg.tagValidator = v
+}
+
+// ValidateTag returns an error if the tag is rejected by the installed TagValidator.
+func (mg *MailgunImpl) ValidateTag(tag string) error {
+	if mg.tagValidator == nil {
+		return nil
+	}
+	return mg.tagValidator(tag)
+}
+
+// SetVariableCipher installs a Cipher which encrypts the values passed to Message.AddVariable()
+// for messages created by this client, and decrypts the user variables of events returned by
+// ListEvents(). Pass nil to remove the cipher.
+func (mg *MailgunImpl) SetVariableCipher(c Cipher) {
+	mg.cipher = c
+}
+
+// VariableCipher returns the Cipher installed with SetVariableCipher(), or nil.
+func (mg *MailgunImpl) VariableCipher() Cipher {
+	return mg.cipher
+}
+
+// SetWebhookIPAllowlist installs an allowlist which VerifyWebhookRequest() checks the
+// source address of webhook requests against. Pass nil to remove the allowlist.
+func (mg *MailgunImpl) SetWebhookIPAllowlist(allowlist *WebhookIPAllowlist) {
+	mg.webhookIPs = allowlist
+}
+
 // generateApiUrl renders a URL for an API endpoint using the domain and endpoint name.
 func generateApiUrl(m Mailgun, endpoint string) string {
 	return fmt.Sprintf("%s/%s/%s", m.APIBase(), m.Domain(), endpoint)
@@ -353,6 +486,19 @@ func generatePublicApiUrl(m Mailgun, endpoint string) string {
 	return fmt.Sprintf("%s/%s", m.APIBase(), endpoint)
 }
 
+// generateV4ApiUrl works as generatePublicApiUrl, but for endpoints which are only available
+// in version 4 of the API. The version suffix of the API base is replaced with /v4.
+func generateV4ApiUrl(m Mailgun, endpoint string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(m.APIBase(), "/v3"), "/v4")
+	return fmt.Sprintf("%s/v4/%s", base, endpoint)
+}
+
+// generateV5ApiUrl returns the url of an endpoint of version 5 of the API, such as account settings
+func generateV5ApiUrl(m Mailgun, endpoint string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(m.APIBase(), "/v3"), "/v4")
+	return fmt.Sprintf("%s/v5/%s", base, endpoint)
+}
+
 // generateParameterizedUrl works as generateApiUrl, but supports query parameters.
 func generateParameterizedUrl(m Mailgun, endpoint string, payload payload) (string, error) {
 	paramBuffer, err := payload.getPayloadBuffer()
@@ -1,10 +1,14 @@
 package mailgun
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/facebookgo/ensure"
+	"github.com/pkg/errors"
 )
 
 const domain = "valid-mailgun-domain"
@@ -21,3 +25,61 @@ func TestMailgun(t *testing.T) {
 	m.SetClient(client)
 	ensure.DeepEqual(t, client, m.Client())
 }
+
+func TestServiceUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	m := NewMailgun(domain, apiKey)
+	m.SetAPIBase(srv.URL)
+
+	_, err := m.GetDomain(context.Background(), domain)
+	ensure.True(t, IsServiceUnavailable(err))
+	ensure.True(t, err.(*UnexpectedResponseError).Is(ErrServiceUnavailable))
+	ensure.DeepEqual(t, GetStatusFromErr(err), http.StatusServiceUnavailable)
+	ensure.DeepEqual(t, RetryAfter(err, time.Second), 2*time.Minute)
+
+	// Wrapped errors are unwrapped
+	wrapped := errors.Wrap(err, "while fetching the domain")
+	ensure.True(t, IsServiceUnavailable(wrapped))
+	ensure.DeepEqual(t, RetryAfter(wrapped, time.Second), 2*time.Minute)
+
+	other := &UnexpectedResponseError{Actual: http.StatusBadRequest}
+	ensure.False(t, IsServiceUnavailable(other))
+	ensure.False(t, IsServiceUnavailable(errors.New("no response")))
+	ensure.DeepEqual(t, RetryAfter(other, time.Second), time.Second)
+}
+
+func TestSendMaintenanceBackoff(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	m := NewMailgun(domain, apiKey)
+	m.SetAPIBase(srv.URL)
+
+	_, _, err := m.Send(context.Background(), m.NewMessage(fromUser, exampleSubject, exampleText, "joe@example.com"))
+	ensure.True(t, IsServiceUnavailable(err))
+	ensure.DeepEqual(t, requests, 1)
+
+	// The next sends wait for the delay requested by Mailgun instead of hitting the API
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = m.Send(ctx, m.NewMessage(fromUser, exampleSubject, exampleText, "joe@example.com"))
+	ensure.DeepEqual(t, err, context.DeadlineExceeded)
+	ensure.DeepEqual(t, requests, 1)
+
+	// Other clients are not paused
+	other := NewMailgun(domain, apiKey)
+	other.SetAPIBase(srv.URL)
+	_, _, err = other.Send(context.Background(), other.NewMessage(fromUser, exampleSubject, exampleText, "joe@example.com"))
+	ensure.True(t, IsServiceUnavailable(err))
+	ensure.DeepEqual(t, requests, 2)
+}
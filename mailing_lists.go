@@ -40,7 +40,7 @@ type listsResponse struct {
 }
 
 type mailingListResponse struct {
-	MailingList MailingList `json:"member"`
+	MailingList MailingList `json:"list"`
 }
 
 type ListsIterator struct {
@@ -52,9 +52,12 @@ type ListsIterator struct {
 // ListMailingLists returns the specified set of mailing lists administered by your account.
 func (mg *MailgunImpl) ListMailingLists(opts *ListOptions) *ListsIterator {
 	r := newHTTPRequest(generatePublicApiUrl(mg, listsEndpoint) + "/pages")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &ListsIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -152,7 +155,7 @@ func (li *ListsIterator) Previous(ctx context.Context, items *[]MailingList) boo
 
 func (li *ListsIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(li.mg.Client())
+	r.setClient(li.mg)
 	r.setBasicAuth(basicAuthUser, li.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &li.listsResponse)
@@ -163,9 +166,10 @@ func (li *ListsIterator) fetch(ctx context.Context, url string) error {
 // Description, and AccessLevel are optional.
 // If unspecified, Description remains blank,
 // while AccessLevel defaults to Everyone.
+// The list is returned as created by Mailgun.
 func (mg *MailgunImpl) CreateMailingList(ctx context.Context, prototype MailingList) (MailingList, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, listsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	if prototype.Address != "" {
@@ -184,16 +188,16 @@ func (mg *MailgunImpl) CreateMailingList(ctx context.Context, prototype MailingL
 	if err != nil {
 		return MailingList{}, err
 	}
-	var l MailingList
-	err = response.parseFromJSON(&l)
-	return l, err
+	var resp mailingListResponse
+	err = response.parseFromJSON(&resp)
+	return resp.MailingList, err
 }
 
 // DeleteMailingList removes all current members of the list, then removes the list itself.
 // Attempts to send e-mail to the list will fail subsequent to this call.
 func (mg *MailgunImpl) DeleteMailingList(ctx context.Context, addr string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, listsEndpoint) + "/" + addr)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -203,7 +207,7 @@ func (mg *MailgunImpl) DeleteMailingList(ctx context.Context, addr string) error
 // representing a mailing list, so long as you have its e-mail address.
 func (mg *MailgunImpl) GetMailingList(ctx context.Context, addr string) (MailingList, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, listsEndpoint) + "/" + addr)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	response, err := makeGetRequest(ctx, r)
 	if err != nil {
@@ -215,16 +219,17 @@ func (mg *MailgunImpl) GetMailingList(ctx context.Context, addr string) (Mailing
 	return resp.MailingList, err
 }
 
-// UpdateList allows you to change various attributes of a list.
+// UpdateMailingList allows you to change various attributes of a list.
 // Address, Name, Description, and AccessLevel are all optional;
 // only those fields which are set in the prototype will change.
 //
 // Be careful!  If changing the address of a mailing list,
 // e-mail sent to the old address will not succeed.
 // Make sure you account for the change accordingly.
+// The list is returned with its changes applied.
 func (mg *MailgunImpl) UpdateMailingList(ctx context.Context, addr string, prototype MailingList) (MailingList, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, listsEndpoint) + "/" + addr)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	if prototype.Address != "" {
@@ -239,11 +244,11 @@ func (mg *MailgunImpl) UpdateMailingList(ctx context.Context, addr string, proto
 	if prototype.AccessLevel != "" {
 		p.addValue("access_level", string(prototype.AccessLevel))
 	}
-	var l MailingList
 	response, err := makePutRequest(ctx, r, p)
 	if err != nil {
-		return l, err
+		return MailingList{}, err
 	}
-	err = response.parseFromJSON(&l)
-	return l, err
+	var resp mailingListResponse
+	err = response.parseFromJSON(&resp)
+	return resp.MailingList, err
 }
@@ -1,7 +1,12 @@
 package mailgun_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/facebookgo/ensure"
@@ -90,6 +95,24 @@ func TestMailingListMembers(t *testing.T) {
 	ensure.DeepEqual(t, theMember.Name, "Joe's Cool Account")
 	ensure.NotNil(t, theMember.Subscribed)
 	ensure.True(t, *theMember.Subscribed)
+
+	var listAddresses = func(opts *mailgun.ListMembersOptions) []string {
+		var page []mailgun.Member
+		var addresses []string
+		it := mg.ListMembers(address, opts)
+		for it.Next(ctx, &page) {
+			for _, m := range page {
+				addresses = append(addresses, m.Address)
+			}
+		}
+		ensure.Nil(t, it.Err())
+		return addresses
+	}
+	ensure.DeepEqual(t, listAddresses(&mailgun.ListMembersOptions{Subscribed: mailgun.Unsubscribed}),
+		[]string{"joe.user1@example.com"})
+	ensure.DeepEqual(t, listAddresses(&mailgun.ListMembersOptions{Subscribed: mailgun.Subscribed, Limit: 1}),
+		[]string{"joe.user2@example.com", "joe.user3@example.com"})
+	ensure.DeepEqual(t, len(listAddresses(nil)), 3)
 }
 
 func TestMailingLists(t *testing.T) {
@@ -116,8 +139,10 @@ func TestMailingLists(t *testing.T) {
 		return count
 	}
 
-	_, err := mg.CreateMailingList(ctx, protoList)
+	created, err := mg.CreateMailingList(ctx, protoList)
 	ensure.Nil(t, err)
+	ensure.DeepEqual(t, created.Address, address)
+	ensure.DeepEqual(t, created.AccessLevel, mailgun.AccessLevel(mailgun.AccessLevelMembers))
 	defer func() {
 		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
 
@@ -134,10 +159,12 @@ func TestMailingLists(t *testing.T) {
 	protoList.CreatedAt = theList.CreatedAt // ignore this field when comparing.
 	ensure.DeepEqual(t, theList, protoList)
 
-	_, err = mg.UpdateMailingList(ctx, address, mailgun.MailingList{
+	updated, err := mg.UpdateMailingList(ctx, address, mailgun.MailingList{
 		Description: "A list whose description changed",
 	})
 	ensure.Nil(t, err)
+	ensure.DeepEqual(t, updated.Description, "A list whose description changed")
+	ensure.DeepEqual(t, updated.Name, protoList.Name)
 
 	theList, err = mg.GetMailingList(ctx, address)
 	ensure.Nil(t, err)
@@ -146,3 +173,240 @@ func TestMailingLists(t *testing.T) {
 	newList.Description = "A list whose description changed"
 	ensure.DeepEqual(t, theList, newList)
 }
+
+func TestCreateMembers(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+
+	// More members than fit in a single request
+	var members []mailgun.Member
+	for i := 0; i < 1500; i++ {
+		members = append(members, mailgun.Member{Address: fmt.Sprintf("user%d@example.com", i)})
+	}
+	ensure.Nil(t, mg.CreateMembers(ctx, false, address, members))
+
+	var page []mailgun.Member
+	var count int
+	it := mg.ListMembers(address, nil)
+	for it.Next(ctx, &page) {
+		count += len(page)
+	}
+	ensure.Nil(t, it.Err())
+	ensure.DeepEqual(t, count, 1500)
+
+	csv := "address,name,vars,subscribed\n" +
+		"user1@example.com,User One,\"{\"\"plan\"\": \"\"pro\"\"}\",no\n" +
+		"Someone New <new@example.com>,,,\n"
+	err = mg.CreateMembersFromCSV(ctx, false, address, strings.NewReader(csv))
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 409)
+
+	ensure.Nil(t, mg.CreateMembersFromCSV(ctx, true, address, strings.NewReader(csv)))
+	member, err := mg.GetMember(ctx, "user1@example.com", address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, member.Name, "User One")
+	ensure.DeepEqual(t, member.Vars, map[string]interface{}{"plan": "pro"})
+	ensure.False(t, *member.Subscribed)
+
+	_, err = mg.GetMember(ctx, "new@example.com", address)
+	ensure.Nil(t, err)
+}
+
+func TestCreateMemberUpsert(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+
+	ensure.Nil(t, mg.CreateMember(ctx, false, address, mailgun.Member{
+		Address: "joe@example.com",
+		Name:    "Joe",
+		Vars:    map[string]interface{}{"plan": "free"},
+	}))
+	member, err := mg.GetMember(ctx, "joe@example.com", address)
+	ensure.Nil(t, err)
+	ensure.True(t, *member.Subscribed)
+
+	// Without upsert, adding an existing member fails
+	err = mg.CreateMember(ctx, false, address, mailgun.Member{Address: "joe@example.com"})
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 409)
+
+	ensure.Nil(t, mg.CreateMember(ctx, true, address, mailgun.Member{
+		Address:    "joe@example.com",
+		Name:       "Joe",
+		Vars:       map[string]interface{}{"plan": "pro"},
+		Subscribed: mailgun.Unsubscribed,
+	}))
+	member, err = mg.GetMember(ctx, "joe@example.com", address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, member.Vars, map[string]interface{}{"plan": "pro"})
+	ensure.False(t, *member.Subscribed)
+
+	var page []mailgun.Member
+	it := mg.ListMembers(address, nil)
+	ensure.True(t, it.Next(ctx, &page))
+	ensure.DeepEqual(t, len(page), 1)
+}
+
+func TestReplaceMembers(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+	ensure.Nil(t, mg.CreateMembers(ctx, false, address, []mailgun.Member{
+		{Address: "alice@example.com", Name: "Alice", Subscribed: mailgun.Subscribed, Vars: map[string]interface{}{"visits": 1}},
+		{Address: "bob@example.com", Name: "Bob", Subscribed: mailgun.Unsubscribed},
+		{Address: "carol@example.com", Name: "Carol"},
+	}))
+
+	changes, err := mg.ReplaceMembers(ctx, address, []mailgun.Member{
+		{Address: "alice@example.com", Name: "Alice", Subscribed: mailgun.Subscribed, Vars: map[string]interface{}{"visits": 1}},
+		{Address: "bob@example.com", Name: "Robert"},
+		{Address: "dave@example.com", Name: "Dave"},
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, changes, mailgun.MembershipChanges{
+		Added:   []string{"dave@example.com"},
+		Updated: []string{"bob@example.com"},
+		Removed: []string{"carol@example.com"},
+	})
+
+	bob, err := mg.GetMember(ctx, "bob@example.com", address)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, bob.Name, "Robert")
+	// The subscribed state is kept when not provided
+	ensure.False(t, *bob.Subscribed)
+
+	_, err = mg.GetMember(ctx, "carol@example.com", address)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+
+	// Replacing with the same members changes nothing
+	changes, err = mg.ReplaceMembers(ctx, address, []mailgun.Member{
+		{Address: "alice@example.com", Name: "Alice", Subscribed: mailgun.Subscribed, Vars: map[string]interface{}{"visits": 1}},
+		{Address: "bob@example.com", Name: "Robert"},
+		{Address: "dave@example.com", Name: "Dave"},
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, changes, mailgun.MembershipChanges{})
+}
+
+func TestReplaceMembersPartialFailure(t *testing.T) {
+	var posts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+			return
+		}
+		posts++
+		if posts > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"message": "Mailing list has been updated"}`)
+	}))
+	defer srv.Close()
+
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(srv.URL)
+
+	var members []mailgun.Member
+	var added []string
+	for i := 0; i <= mailgun.MaxMembersPerRequest; i++ {
+		address := fmt.Sprintf("member%d@example.com", i)
+		members = append(members, mailgun.Member{Address: address})
+		if i < mailgun.MaxMembersPerRequest {
+			added = append(added, address)
+		}
+	}
+
+	// The members of the first batch are reported when the second batch fails
+	changes, err := mg.ReplaceMembers(context.Background(), "list@example.com", members)
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, posts, 2)
+	ensure.DeepEqual(t, changes, mailgun.MembershipChanges{Added: added})
+}
+
+func TestExportMembers(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	address := randomEmail("list", testDomain)
+	_, err := mg.CreateMailingList(ctx, mailgun.MailingList{Address: address, Name: address})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, address))
+	}()
+	ensure.Nil(t, mg.CreateMembers(ctx, false, address, []mailgun.Member{
+		{Address: "alice@example.com", Name: "Alice", Subscribed: mailgun.Subscribed, Vars: map[string]interface{}{
+			"plan": "pro", "address": map[string]interface{}{"country": "FR"},
+		}},
+		{Address: "bob@example.com", Name: "Bob, Jr.", Subscribed: mailgun.Unsubscribed},
+	}))
+
+	var buf bytes.Buffer
+	n, err := mg.ExportMembers(ctx, address, &buf, nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, n, 2)
+	ensure.DeepEqual(t, buf.String(), "address,name,subscribed,vars\n"+
+		`alice@example.com,Alice,yes,"{""address"":{""country"":""FR""},""plan"":""pro""}"`+"\n"+
+		`bob@example.com,"Bob, Jr.",no,`+"\n")
+
+	// The default columns can be imported back
+	copyAddress := randomEmail("list", testDomain)
+	_, err = mg.CreateMailingList(ctx, mailgun.MailingList{Address: copyAddress, Name: copyAddress})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteMailingList(ctx, copyAddress))
+	}()
+	ensure.Nil(t, mg.CreateMembersFromCSV(ctx, false, copyAddress, &buf))
+	bob, err := mg.GetMember(ctx, "bob@example.com", copyAddress)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, bob.Name, "Bob, Jr.")
+	ensure.DeepEqual(t, *bob.Subscribed, false)
+
+	buf.Reset()
+	n, err = mg.ExportMembers(ctx, address, &buf, &mailgun.ExportMembersOptions{
+		Columns:    []string{"address", "vars.address.country", "vars.missing"},
+		Subscribed: mailgun.Subscribed,
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, n, 1)
+	ensure.DeepEqual(t, buf.String(), "address,vars.address.country,vars.missing\nalice@example.com,FR,\n")
+
+	buf.Reset()
+	_, err = mg.ExportMembers(ctx, address, &buf, &mailgun.ExportMembersOptions{
+		Format:      mailgun.ExportNDJSON,
+		Columns:     []string{"address", "subscribed", "vars"},
+		FlattenVars: true,
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, buf.String(),
+		`{"address":"alice@example.com","subscribed":true,"vars.address.country":"FR","vars.plan":"pro"}`+"\n"+
+			`{"address":"bob@example.com","subscribed":false}`+"\n")
+
+	_, err = mg.ExportMembers(ctx, address, &buf, &mailgun.ExportMembersOptions{FlattenVars: true})
+	ensure.NotNil(t, err)
+	_, err = mg.ExportMembers(ctx, address, &buf, &mailgun.ExportMembersOptions{Columns: []string{"email"}})
+	ensure.NotNil(t, err)
+}
@@ -0,0 +1,46 @@
+package mailgun
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaintenanceBackoff is how long Send() holds back messages after Mailgun answered
+// 503 Service Unavailable without a Retry-After header.
+const DefaultMaintenanceBackoff = time.Minute
+
+// maintenancePause holds back the messages of a client while Mailgun is unavailable, instead of
+// sending each of them into the maintenance window
+type maintenancePause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// start pauses the sends for d, unless they are already paused for longer
+func (p *maintenancePause) start(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(d); until.After(p.until) {
+		p.until = until
+	}
+}
+
+// wait blocks until the pause is over, or ctx is done
+func (p *maintenancePause) wait(ctx context.Context) error {
+	p.mu.Lock()
+	d := time.Until(p.until)
+	p.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package mailgun
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AttachmentManifestVariable is the name of the custom variable which holds the
+// attachment manifest of messages sent with EnableAttachmentManifest().
+const AttachmentManifestVariable = "attachment-manifest"
+
+// AttachmentChecksum records the size and SHA-256 checksum of an attachment as it was sent
+type AttachmentChecksum struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// EnableAttachmentManifest arranges for Send() to compute the size and SHA-256 checksum of
+// every attachment of the message. The manifest is available from AttachmentManifest() once
+// the message is sent, and is included in the message as a JSON encoded custom variable named
+// AttachmentManifestVariable, so it is also reported on the events of the message.
+//
+// Reader attachments are read into memory to compute their checksum before the message is sent.
+func (m *Message) EnableAttachmentManifest() {
+	m.attachmentManifest = true
+}
+
+// AttachmentManifest returns the checksums of the attachments, in the order they were added,
+// computed when the message was last sent. Returns nil if EnableAttachmentManifest() was not
+// called or the message was not sent yet.
+func (m *Message) AttachmentManifest() []AttachmentChecksum {
+	return m.manifest
+}
+
+// attachmentKind identifies the list an attachment of a message is held in
+type attachmentKind int
+
+const (
+	attachFile attachmentKind = iota
+	attachReader
+	attachBuffer
+)
+
+// computeManifest computes the checksums of all attachments and adds the manifest variable
+func (m *Message) computeManifest() error {
+	var manifest []AttachmentChecksum
+	var files, readers, buffers int
+
+	for _, kind := range m.attachmentOrder {
+		var c AttachmentChecksum
+		var err error
+		switch kind {
+		case attachFile:
+			c, err = fileChecksum(m.attachments[files].path)
+			files++
+		case attachReader:
+			ra := &m.readerAttachments[readers]
+			readers++
+			if ra.ReadCloser == nil {
+				return fmt.Errorf("reader attachment '%s' has a nil io.ReadCloser", ra.Filename)
+			}
+			var rc io.ReadCloser
+			if rc, err = bufferReadCloser(&ra.ReadCloser); err == nil {
+				c, err = checksum(ra.Filename, rc)
+			}
+		case attachBuffer:
+			ba := m.bufferAttachments[buffers]
+			buffers++
+			sum := sha256.Sum256(ba.Buffer)
+			c = AttachmentChecksum{
+				Filename: ba.Filename,
+				Size:     int64(len(ba.Buffer)),
+				SHA256:   hex.EncodeToString(sum[:]),
+			}
+		}
+		if err != nil {
+			return err
+		}
+		manifest = append(manifest, c)
+	}
+
+	encoded, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if m.variables == nil {
+		m.variables = make(map[string]string)
+	}
+	m.variables[AttachmentManifestVariable] = string(encoded)
+	m.manifest = manifest
+	return nil
+}
+
+func fileChecksum(path string) (AttachmentChecksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return AttachmentChecksum{}, err
+	}
+	defer f.Close()
+	return checksum(filepath.Base(path), f)
+}
+
+func checksum(filename string, r io.Reader) (AttachmentChecksum, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return AttachmentChecksum{}, err
+	}
+	return AttachmentChecksum{
+		Filename: filename,
+		Size:     n,
+		SHA256:   hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,209 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// MemberExportFormat is the file format written by ExportMembers()
+type MemberExportFormat int
+
+const (
+	// ExportCSV writes a header row naming the columns, then a row per member. Files exported
+	// with the default columns can be imported with CreateMembersFromCSV().
+	ExportCSV MemberExportFormat = iota
+	// ExportNDJSON writes a JSON object per member and line, keyed by column
+	ExportNDJSON
+)
+
+// DefaultMemberExportColumns are the columns ExportMembers() writes by default
+var DefaultMemberExportColumns = []string{"address", "name", "subscribed", "vars"}
+
+// ExportMembersOptions modifies the behavior of ExportMembers()
+type ExportMembersOptions struct {
+	Format MemberExportFormat
+	// The columns written, among "address", "name", "subscribed", "vars" for all the vars as a
+	// JSON object, and "vars.<name>" for a single var, where <name> may be a dot separated path
+	// to a nested var. Defaults to DefaultMemberExportColumns.
+	Columns []string
+	// Use Subscribed or Unsubscribed to export only the members in that state
+	Subscribed *bool
+	// Replaces the "vars" column by a "vars.<path>" key per var, flattening nested vars.
+	// Only supported by ExportNDJSON, as the columns of a CSV file are fixed by its header.
+	FlattenVars bool
+}
+
+// Validate returns an *OptionError if the options are invalid. A nil *ExportMembersOptions is valid.
+func (o *ExportMembersOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Format != ExportCSV && o.Format != ExportNDJSON {
+		return invalidOption("Format", "must be ExportCSV or ExportNDJSON")
+	}
+	for _, c := range o.Columns {
+		switch {
+		case c == "address", c == "name", c == "subscribed", c == "vars":
+		case strings.HasPrefix(c, "vars.") && len(c) > len("vars."):
+		default:
+			return invalidOption("Columns", "unknown column '"+c+"'")
+		}
+	}
+	if o.FlattenVars && o.Format == ExportCSV {
+		return invalidOption("FlattenVars", "is not supported by ExportCSV, list the vars in Columns instead")
+	}
+	return nil
+}
+
+// ExportMembers writes all the members of a mailing list to w, reading them page by page so
+// lists of any size can be exported, e.g. for backups or analytics. Returns the number of
+// members written.
+//
+//	f, err := os.Create("members.csv")
+//	...
+//	n, err := mg.ExportMembers(ctx, "list@example.com", f, &mailgun.ExportMembersOptions{
+//		Columns: []string{"address", "vars.plan", "vars.address.country"},
+//	})
+func (mg *MailgunImpl) ExportMembers(ctx context.Context, list string, w io.Writer, opts *ExportMembersOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+	var o ExportMembersOptions
+	if opts != nil {
+		o = *opts
+	}
+	columns := o.Columns
+	if len(columns) == 0 {
+		columns = DefaultMemberExportColumns
+	}
+
+	var write func(m Member) error
+	var flush func() error
+	switch o.Format {
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return 0, err
+		}
+		record := make([]string, len(columns))
+		write = func(m Member) error {
+			for i, c := range columns {
+				v, err := csvMemberValue(m, c)
+				if err != nil {
+					return err
+				}
+				record[i] = v
+			}
+			return cw.Write(record)
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportNDJSON:
+		enc := json.NewEncoder(w)
+		write = func(m Member) error {
+			return enc.Encode(jsonMemberValues(m, columns, o.FlattenVars))
+		}
+		flush = func() error { return nil }
+	}
+
+	var count int
+	var page []Member
+	it := mg.ListMembers(list, &ListMembersOptions{Subscribed: o.Subscribed})
+	for it.Next(ctx, &page) {
+		for _, m := range page {
+			if err := write(m); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	if it.Err() != nil {
+		return count, it.Err()
+	}
+	return count, flush()
+}
+
+// csvMemberValue returns the value of a column of the member in a CSV file
+func csvMemberValue(m Member, column string) (string, error) {
+	switch column {
+	case "address":
+		return m.Address, nil
+	case "name":
+		return m.Name, nil
+	case "subscribed":
+		if m.Subscribed == nil {
+			return "", nil
+		}
+		return yesNo(*m.Subscribed), nil
+	case "vars":
+		if len(m.Vars) == 0 {
+			return "", nil
+		}
+		b, err := json.Marshal(m.Vars)
+		return string(b), err
+	}
+	v, ok := lookupVar(m.Vars, strings.TrimPrefix(column, "vars."))
+	if !ok || v == nil {
+		return "", nil
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// jsonMemberValues returns the values of the columns of the member, keyed by column
+func jsonMemberValues(m Member, columns []string, flatten bool) map[string]interface{} {
+	values := make(map[string]interface{}, len(columns))
+	for _, c := range columns {
+		switch c {
+		case "address":
+			values[c] = m.Address
+		case "name":
+			values[c] = m.Name
+		case "subscribed":
+			values[c] = m.Subscribed
+		case "vars":
+			if flatten {
+				flattenVars(values, "vars", m.Vars)
+			} else {
+				values[c] = m.Vars
+			}
+		default:
+			values[c], _ = lookupVar(m.Vars, strings.TrimPrefix(c, "vars."))
+		}
+	}
+	return values
+}
+
+// lookupVar returns the var at a dot separated path
+func lookupVar(vars map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = vars
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// flattenVars adds the vars to values, keyed by their dot separated path under prefix
+func flattenVars(values map[string]interface{}, prefix string, vars map[string]interface{}) {
+	for k, v := range vars {
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenVars(values, prefix+"."+k, nested)
+			continue
+		}
+		values[prefix+"."+k] = v
+	}
+}
@@ -3,9 +3,19 @@ package mailgun
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
 	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
+// MaxMembersPerRequest is the number of members Mailgun accepts in a single request to add
+// members to a mailing list
+const MaxMembersPerRequest = 1000
+
 // yes and no are variables which provide us the ability to take their addresses.
 // Subscribed and Unsubscribed are pointers to these booleans.
 //
@@ -18,7 +28,7 @@ var (
 )
 
 // Mailing list members have an attribute that determines if they've subscribed to the mailing list or not.
-// This attribute may be used to filter the results returned by GetSubscribers().
+// This attribute may be used to filter the results returned by ListMembers().
 // All, Subscribed, and Unsubscribed provides a convenient and readable syntax for specifying the scope of the search.
 var (
 	All          *bool = nil
@@ -41,7 +51,7 @@ type memberListResponse struct {
 }
 
 type memberResponse struct {
-	Member Member `json:"list"`
+	Member Member `json:"member"`
 }
 
 type MemberListIterator struct {
@@ -55,14 +65,60 @@ type ListOptions struct {
 	Limit int
 }
 
-func (mg *MailgunImpl) ListMembers(address string, opts *ListOptions) *MemberListIterator {
+// Validate returns an *OptionError if the options are invalid. A nil *ListOptions is valid.
+func (o *ListOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Limit < 0 {
+		return invalidOption("Limit", "must not be negative")
+	}
+	return nil
+}
+
+// ListMembersOptions modifies the behavior of ListMembers()
+type ListMembersOptions struct {
+	Limit int
+	// Use Subscribed or Unsubscribed to list only the members in that state, All or nil lists
+	// every member
+	Subscribed *bool
+}
+
+// Validate returns an *OptionError if the options are invalid. A nil *ListMembersOptions is valid.
+func (o *ListMembersOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Limit < 0 {
+		return invalidOption("Limit", "must not be negative")
+	}
+	return nil
+}
+
+// ListMembers returns an iterator over the pages of members of a mailing list.
+//
+//	it := mg.ListMembers("list@example.com", &mailgun.ListMembersOptions{Subscribed: mailgun.Subscribed})
+//	var page []mailgun.Member
+//	for it.Next(ctx, &page) {
+//		...
+//	}
+//	if it.Err() != nil {
+//		...
+//	}
+func (mg *MailgunImpl) ListMembers(address string, opts *ListMembersOptions) *MemberListIterator {
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, address) + "/pages")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &MemberListIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
+		if opts.Subscribed != nil {
+			r.addParameter("subscribed", yesNo(*opts.Subscribed))
+		}
 	}
 	url, err := r.generateUrlWithParameters()
 	return &MemberListIterator{
@@ -149,7 +205,7 @@ func (li *MemberListIterator) Previous(ctx context.Context, items *[]Member) boo
 
 func (li *MemberListIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(li.mg.Client())
+	r.setClient(li.mg)
 	r.setBasicAuth(basicAuthUser, li.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &li.memberListResponse)
@@ -159,7 +215,7 @@ func (li *MemberListIterator) fetch(ctx context.Context, url string) error {
 // given only their subscription e-mail address.
 func (mg *MailgunImpl) GetMember(ctx context.Context, s, l string) (Member, error) {
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, l) + "/" + s)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	response, err := makeGetRequest(ctx, r)
 	if err != nil {
@@ -171,19 +227,20 @@ func (mg *MailgunImpl) GetMember(ctx context.Context, s, l string) (Member, erro
 }
 
 // CreateMember registers a new member of the indicated mailing list.
-// If merge is set to true, then the registration may update an existing Member's settings.
+// If upsert is true and the address is already a member of the list, the member is updated
+// with the name, vars and subscribed state of the prototype instead.
 // Otherwise, an error will occur if you attempt to add a member with a duplicate e-mail address.
-func (mg *MailgunImpl) CreateMember(ctx context.Context, merge bool, addr string, prototype Member) error {
+func (mg *MailgunImpl) CreateMember(ctx context.Context, upsert bool, addr string, prototype Member) error {
 	vs, err := json.Marshal(prototype.Vars)
 	if err != nil {
 		return err
 	}
 
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, addr))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newFormDataPayload()
-	p.addValue("upsert", yesNo(merge))
+	p.addValue("upsert", yesNo(upsert))
 	p.addValue("address", prototype.Address)
 	p.addValue("name", prototype.Name)
 	p.addValue("vars", string(vs))
@@ -198,7 +255,7 @@ func (mg *MailgunImpl) CreateMember(ctx context.Context, merge bool, addr string
 // Address, Name, Vars, and Subscribed fields may be changed.
 func (mg *MailgunImpl) UpdateMember(ctx context.Context, s, l string, prototype Member) (Member, error) {
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, l) + "/" + s)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newFormDataPayload()
 	if prototype.Address != "" {
@@ -231,7 +288,7 @@ func (mg *MailgunImpl) UpdateMember(ctx context.Context, s, l string, prototype
 // DeleteMember removes the member from the list.
 func (mg *MailgunImpl) DeleteMember(ctx context.Context, member, addr string) error {
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, addr) + "/" + member)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -239,20 +296,20 @@ func (mg *MailgunImpl) DeleteMember(ctx context.Context, member, addr string) er
 
 // CreateMemberList registers multiple Members and non-Member members to a single mailing list
 // in a single round-trip.
-// u indicates if the existing members should be updated or duplicates should be updated.
-// Use All to elect not to provide a default.
+// upsert indicates if members already on the list are updated, pass nil to use the
+// default of Mailgun, which is not to update them.
 // The newMembers list can take one of two JSON-encodable forms: an slice of strings, or
 // a slice of Member structures.
 // If a simple slice of strings is passed, each string refers to the member's e-mail address.
 // Otherwise, each Member needs to have at least the Address field filled out.
 // Other fields are optional, but may be set according to your needs.
-func (mg *MailgunImpl) CreateMemberList(ctx context.Context, u *bool, addr string, newMembers []interface{}) error {
+func (mg *MailgunImpl) CreateMemberList(ctx context.Context, upsert *bool, addr string, newMembers []interface{}) error {
 	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, addr) + ".json")
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newFormDataPayload()
-	if u != nil {
-		p.addValue("upsert", yesNo(*u))
+	if upsert != nil {
+		p.addValue("upsert", yesNo(*upsert))
 	}
 	bs, err := json.Marshal(newMembers)
 	if err != nil {
@@ -262,3 +319,152 @@ func (mg *MailgunImpl) CreateMemberList(ctx context.Context, u *bool, addr strin
 	_, err = makePostRequest(ctx, r, p)
 	return err
 }
+
+// CreateMembers adds members to a mailing list in batches of MaxMembersPerRequest, so large
+// imports take a few requests instead of one per address. If upsert is true, members already
+// on the list are updated. If a batch fails, the error reports which members were not added,
+// the members of the previous batches are on the list.
+func (mg *MailgunImpl) CreateMembers(ctx context.Context, upsert bool, addr string, members []Member) error {
+	for start := 0; start < len(members); start += MaxMembersPerRequest {
+		end := minInt(start+MaxMembersPerRequest, len(members))
+		batch := make([]interface{}, 0, end-start)
+		for _, m := range members[start:end] {
+			batch = append(batch, m)
+		}
+		if err := mg.CreateMemberList(ctx, &upsert, addr, batch); err != nil {
+			return errors.Wrapf(err, "while adding members %d to %d of %d", start+1, end, len(members))
+		}
+	}
+	return nil
+}
+
+// CreateMembersFromCSV adds the members listed in CSV data to a mailing list in a single
+// request. The first row names the columns: "address" is required, "name", "vars" (a JSON
+// object) and "subscribed" ("yes" or "no") are optional. If upsert is true, members already
+// on the list are updated. The data is streamed into the request, the reader is not closed.
+func (mg *MailgunImpl) CreateMembersFromCSV(ctx context.Context, upsert bool, addr string, data io.Reader) error {
+	r := newHTTPRequest(generateMemberApiUrl(mg, listsEndpoint, addr) + ".csv")
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	p := newFormDataPayload()
+	p.addValue("upsert", yesNo(upsert))
+	p.addReadCloser("members", "members.csv", ioutil.NopCloser(data))
+	_, err := makePostRequest(ctx, r, p)
+	return err
+}
+
+// MembershipChanges reports the members ReplaceMembers() added, updated and removed
+type MembershipChanges struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
+
+// ReplaceMembers makes the members of a mailing list match the members provided, for instance
+// to synchronise a segment of a CRM with a mailing list. New members are added and members whose
+// name, vars or subscribed state differ are updated, in batches of MaxMembersPerRequest. Members
+// which are not provided are removed one at a time, as Mailgun has no bulk removal.
+// A member with a nil Subscribed keeps its current subscribed state.
+//
+// The replacement is not atomic: if an error occurs, the changes reports what was changed
+// before the error.
+func (mg *MailgunImpl) ReplaceMembers(ctx context.Context, addr string, members []Member) (MembershipChanges, error) {
+	var changes MembershipChanges
+	var current, page []Member
+	it := mg.ListMembers(addr, nil)
+	for it.Next(ctx, &page) {
+		current = append(current, page...)
+	}
+	if it.Err() != nil {
+		return changes, it.Err()
+	}
+	existing := make(map[string]Member, len(current))
+	for _, m := range current {
+		existing[strings.ToLower(m.Address)] = m
+	}
+
+	desired := make(map[string]bool, len(members))
+	var upserts []Member
+	var isNew []bool
+	for _, m := range members {
+		key := strings.ToLower(m.Address)
+		if desired[key] {
+			continue
+		}
+		desired[key] = true
+		c, ok := existing[key]
+		if !ok {
+			upserts = append(upserts, m)
+			isNew = append(isNew, true)
+			continue
+		}
+		same, err := sameMember(c, m)
+		if err != nil {
+			return changes, err
+		}
+		if !same {
+			if m.Subscribed == nil {
+				m.Subscribed = c.Subscribed
+			}
+			upserts = append(upserts, m)
+			isNew = append(isNew, false)
+		}
+	}
+
+	// Batches are sent here rather than by CreateMembers() to report the batches completed
+	// before an error
+	for start := 0; start < len(upserts); start += MaxMembersPerRequest {
+		end := minInt(start+MaxMembersPerRequest, len(upserts))
+		if err := mg.CreateMembers(ctx, true, addr, upserts[start:end]); err != nil {
+			return changes, errors.Wrapf(err, "while adding members %d to %d of %d", start+1, end, len(upserts))
+		}
+		for i, m := range upserts[start:end] {
+			if isNew[start+i] {
+				changes.Added = append(changes.Added, m.Address)
+			} else {
+				changes.Updated = append(changes.Updated, m.Address)
+			}
+		}
+	}
+
+	for _, m := range current {
+		if desired[strings.ToLower(m.Address)] {
+			continue
+		}
+		if err := mg.DeleteMember(ctx, m.Address, addr); err != nil {
+			return changes, err
+		}
+		changes.Removed = append(changes.Removed, m.Address)
+	}
+	return changes, nil
+}
+
+// sameMember returns true if the desired member doesn't change the current member
+func sameMember(current, desired Member) (bool, error) {
+	if current.Name != desired.Name {
+		return false, nil
+	}
+	if desired.Subscribed != nil && (current.Subscribed == nil || *current.Subscribed != *desired.Subscribed) {
+		return false, nil
+	}
+	if len(current.Vars) == 0 && len(desired.Vars) == 0 {
+		return true, nil
+	}
+	// Compare the vars as they are returned by Mailgun
+	cv, err := json.Marshal(current.Vars)
+	if err != nil {
+		return false, err
+	}
+	dv, err := json.Marshal(desired.Vars)
+	if err != nil {
+		return false, err
+	}
+	var c, d interface{}
+	if err := json.Unmarshal(cv, &c); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(dv, &d); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(c, d), nil
+}
@@ -0,0 +1,91 @@
+package mailgun
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageWarning reports an option of a message which is ignored, or conflicts with another
+// option, so Mailgun would not send the message as intended.
+type MessageWarning struct {
+	// The methods which set the options, e.g. "SetTracking, SetTrackingClicks"
+	Option string
+	Reason string
+}
+
+func (w MessageWarning) String() string {
+	return w.Option + ": " + w.Reason
+}
+
+// MessageWarningsError is returned by Validate() and Send() in strict mode, when a message
+// has Warnings().
+type MessageWarningsError struct {
+	Warnings []MessageWarning
+}
+
+func (e *MessageWarningsError) Error() string {
+	var s []string
+	for _, w := range e.Warnings {
+		s = append(s, w.String())
+	}
+	return fmt.Sprintf("message has conflicting options: %s", strings.Join(s, "; "))
+}
+
+// SetStrictMode makes Validate(), and so Send(), fail with a *MessageWarningsError if the
+// message has Warnings(), instead of letting Mailgun ignore or override the options.
+func (m *Message) SetStrictMode(strict bool) {
+	m.strict = strict
+}
+
+// Warnings returns the options of the message which are ignored or conflict with each other:
+//
+//   - SetHtml(), SetAmpHtml(), AddCC() and AddBCC() called on a MIME message, which already
+//     contains its bodies and headers
+//   - click or open tracking enabled while SetTracking(false) disables all tracking
+//   - SetDeliveryTime() together with SetSTOPeriod(), which picks its own delivery time
+func (m *Message) Warnings() []MessageWarning {
+	var warnings []MessageWarning
+	if mm, ok := m.specific.(*mimeMessage); ok {
+		for _, option := range mm.ignored {
+			warnings = append(warnings, MessageWarning{
+				Option: option,
+				Reason: "ignored for MIME messages, set it in the MIME body instead",
+			})
+		}
+	}
+	if m.trackingSet && !m.tracking {
+		if m.trackingClicks == TrackingClicksEnabled || m.trackingClicks == TrackingClicksHTMLOnly {
+			warnings = append(warnings, MessageWarning{
+				Option: "SetTracking, SetTrackingClicks",
+				Reason: "click tracking is enabled but tracking is disabled",
+			})
+		}
+		if m.trackingOpensSet && m.trackingOpens {
+			warnings = append(warnings, MessageWarning{
+				Option: "SetTracking, SetTrackingOpens",
+				Reason: "open tracking is enabled but tracking is disabled",
+			})
+		}
+	}
+	if !m.deliveryTime.IsZero() && m.stoPeriod != 0 {
+		warnings = append(warnings, MessageWarning{
+			Option: "SetDeliveryTime, SetSTOPeriod",
+			Reason: "send time optimization overrides the delivery time",
+		})
+	}
+	return warnings
+}
+
+func (m *Message) checkWarnings() error {
+	if warnings := m.Warnings(); len(warnings) != 0 {
+		return &MessageWarningsError{Warnings: warnings}
+	}
+	return nil
+}
+
+// ignore records an option which doesn't apply to MIME messages
+func (mm *mimeMessage) ignore(option string) {
+	if !containsString(mm.ignored, option) {
+		mm.ignored = append(mm.ignored, option)
+	}
+}
@@ -0,0 +1,74 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestMessageWarnings(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@example.com")
+	ensure.DeepEqual(t, len(m.Warnings()), 0)
+	m.SetTracking(false)
+	m.SetTrackingClicksMode(TrackingClicksHTMLOnly)
+	m.SetTrackingOpens(true)
+	m.SetDeliveryTime(time.Now().Add(time.Hour))
+	m.SetSTOPeriod(24 * time.Hour)
+	ensure.DeepEqual(t, m.Warnings(), []MessageWarning{
+		{Option: "SetTracking, SetTrackingClicks", Reason: "click tracking is enabled but tracking is disabled"},
+		{Option: "SetTracking, SetTrackingOpens", Reason: "open tracking is enabled but tracking is disabled"},
+		{Option: "SetDeliveryTime, SetSTOPeriod", Reason: "send time optimization overrides the delivery time"},
+	})
+	ensure.Nil(t, m.Validate())
+	m.SetStrictMode(true)
+	err := m.Validate()
+	warnings, ok := err.(*MessageWarningsError)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, len(warnings.Warnings), 3)
+
+	mime := mg.NewMIMEMessage(ioutil.NopCloser(strings.NewReader(exampleMime)), "test@example.com")
+	mime.SetHtml("<p>ignored</p>")
+	mime.AddCC("cc@example.com")
+	mime.SetHtml("<p>ignored</p>")
+	c, err := mime.Clone()
+	ensure.Nil(t, err)
+	for _, m := range []*Message{mime, c} {
+		ensure.DeepEqual(t, len(m.Warnings()), 2)
+		ensure.DeepEqual(t, m.Warnings()[0].Option, "SetHtml")
+		ensure.DeepEqual(t, m.Warnings()[1].Option, "AddCC")
+	}
+}
+
+func TestSendStrictMode(t *testing.T) {
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sent++
+		fmt.Fprint(w, `{"id": "<id@example.com>", "message": "Queued. Thank you."}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@example.com")
+	m.SetTracking(false)
+	m.SetTrackingClicks(true)
+	_, _, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sent, 1)
+
+	mg.SetStrictMode(true)
+	_, _, err = mg.Send(ctx, m)
+	ensure.StringContains(t, err.Error(), "click tracking is enabled but tracking is disabled")
+	ensure.DeepEqual(t, sent, 1)
+}
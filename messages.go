@@ -1,12 +1,25 @@
 package mailgun
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	htmltemplate "html/template"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
 	"time"
 )
 
@@ -24,28 +37,43 @@ type Message struct {
 	campaigns         []string
 	dkim              bool
 	deliveryTime      time.Time
-	attachments       []string
+	stoPeriod         time.Duration
+	timeZoneLocalize  string
+	secondaryDKIM     string
+	secondaryDKIMPub  string
+	attachments       []fileAttachment
 	readerAttachments []ReaderAttachment
 	inlines           []string
 	readerInlines     []ReaderAttachment
 	bufferAttachments []BufferAttachment
+	// The kind of each attachment, in the order they were added
+	attachmentOrder []attachmentKind
 
 	nativeSend         bool
 	testMode           bool
 	tracking           bool
-	trackingClicks     bool
+	trackingClicks     TrackingClicksMode
 	trackingOpens      bool
-	headers            map[string]string
+	headers            map[string][]string
 	variables          map[string]string
 	recipientVariables map[string]map[string]interface{}
 	domain             string
-
-	dkimSet           bool
-	trackingSet       bool
-	trackingClicksSet bool
-	trackingOpensSet  bool
-	requireTLS        bool
-	skipVerification  bool
+	attachmentManifest bool
+	manifest           []AttachmentChecksum
+	suppressionMode    SuppressionMode
+	skipped            []SuppressedRecipient
+	boundary           string
+	contacts           ContactSource
+	contactBatchSize   int
+
+	dkimSet          bool
+	trackingSet      bool
+	trackingOpensSet bool
+	requireTLS       bool
+	skipVerification bool
+	strict           bool
+	slaClass         string
+	slaDeadline      time.Duration
 
 	specific features
 	mg       Mailgun
@@ -56,6 +84,11 @@ type ReaderAttachment struct {
 	ReadCloser io.ReadCloser
 }
 
+type fileAttachment struct {
+	path        string
+	contentType string
+}
+
 type BufferAttachment struct {
 	Filename string
 	Buffer   []byte
@@ -116,11 +149,14 @@ type plainMessage struct {
 	subject string
 	text    string
 	html    string
+	ampHtml string
 }
 
 // mimeMessage contains fields relevant to pre-packaged MIME messages.
 type mimeMessage struct {
 	body io.ReadCloser
+	// The options set on the message which don't apply to MIME messages, see Warnings()
+	ignored []string
 }
 
 type sendMessageResponse struct {
@@ -137,11 +173,16 @@ type sendMessageResponse struct {
 type features interface {
 	addCC(string)
 	addBCC(string)
+	setText(string)
 	setHtml(string)
+	setAmpHtml(string)
+	getHtml() string
+	rewriteInlineCIDs(map[string]string)
 	addValues(*formDataPayload)
 	isValid() bool
 	endpoint() string
 	recipientCount() int
+	clone() (features, error)
 }
 
 // NewMessage returns a new e-mail message with the simplest envelop needed to send.
@@ -202,10 +243,12 @@ func (mg *MailgunImpl) NewMIMEMessage(body io.ReadCloser, to ...string) *Message
 // File contents are read from a io.ReadCloser.
 // The filename parameter is the resulting filename of the attachment.
 // The readCloser parameter is the io.ReadCloser which reads the actual bytes to be used
-// as the contents of the attached file.
+// as the contents of the attached file. The contents are streamed into the request body
+// as the message is sent, and readCloser is closed once Send() has consumed it.
 func (m *Message) AddReaderAttachment(filename string, readCloser io.ReadCloser) {
 	ra := ReaderAttachment{Filename: filename, ReadCloser: readCloser}
 	m.readerAttachments = append(m.readerAttachments, ra)
+	m.attachmentOrder = append(m.attachmentOrder, attachReader)
 }
 
 // AddBufferAttachment arranges to send a file along with the e-mail message.
@@ -216,20 +259,33 @@ func (m *Message) AddReaderAttachment(filename string, readCloser io.ReadCloser)
 func (m *Message) AddBufferAttachment(filename string, buffer []byte) {
 	ba := BufferAttachment{Filename: filename, Buffer: buffer}
 	m.bufferAttachments = append(m.bufferAttachments, ba)
+	m.attachmentOrder = append(m.attachmentOrder, attachBuffer)
 }
 
 // AddAttachment arranges to send a file from the filesystem along with the e-mail message.
 // The attachment parameter is a filename, which must refer to a file which actually resides
 // in the local filesystem.
 func (m *Message) AddAttachment(attachment string) {
-	m.attachments = append(m.attachments, attachment)
+	m.attachments = append(m.attachments, fileAttachment{path: attachment})
+	m.attachmentOrder = append(m.attachmentOrder, attachFile)
+}
+
+// AddAttachmentWithType works like AddAttachment, but sends the attachment with the
+// given MIME content type instead of the default application/octet-stream, for example
+//
+//     m.AddAttachmentWithType("/tmp/export.bin", "application/octet-stream; charset=utf-8")
+func (m *Message) AddAttachmentWithType(attachment, contentType string) {
+	m.attachments = append(m.attachments, fileAttachment{path: attachment, contentType: contentType})
+	m.attachmentOrder = append(m.attachmentOrder, attachFile)
 }
 
 // AddReaderInline arranges to send a file along with the e-mail message.
 // File contents are read from a io.ReadCloser.
 // The filename parameter is the resulting filename of the attachment.
 // The readCloser parameter is the io.ReadCloser which reads the actual bytes to be used
-// as the contents of the attached file.
+// as the contents of the attached file. Mailgun uses the filename as the Content-ID
+// of the inline part, so the HTML body can reference it as "cid:<filename>".
+// See RewriteInlineCIDs().
 func (m *Message) AddReaderInline(filename string, readCloser io.ReadCloser) {
 	ra := ReaderAttachment{Filename: filename, ReadCloser: readCloser}
 	m.readerInlines = append(m.readerInlines, ra)
@@ -244,6 +300,95 @@ func (m *Message) AddInline(inline string) {
 	m.inlines = append(m.inlines, inline)
 }
 
+var imgSrcRegex = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)("[^"]*"|'[^']*')`)
+
+// RewriteInlineCIDs rewrites <img src="..."> references in the HTML body which name an
+// inline attachment to the "cid:" URL of that attachment, so
+//
+//     m.SetHtml(`<img src="images/logo.png">`)
+//     m.AddInline("/path/to/images/logo.png")
+//     m.RewriteInlineCIDs()
+//
+// results in an HTML body of `<img src="cid:logo.png">`. A reference matches an inline
+// attachment when it is equal to the attachment path given to AddInline() or when its
+// base name is equal to the attachment filename. References with a scheme, such as
+// "https:" or "cid:", are left alone. Call RewriteInlineCIDs() after SetHtml() and after
+// adding the inline attachments. This method is ignored for MIME messages.
+func (m *Message) RewriteInlineCIDs() {
+	cids := make(map[string]string)
+	for _, inline := range m.inlines {
+		cids[inline] = path.Base(inline)
+		cids[path.Base(inline)] = path.Base(inline)
+	}
+	for _, inline := range m.readerInlines {
+		cids[inline.Filename] = inline.Filename
+	}
+	m.specific.rewriteInlineCIDs(cids)
+}
+
+func (pm *plainMessage) rewriteInlineCIDs(cids map[string]string) {
+	pm.html = imgSrcRegex.ReplaceAllStringFunc(pm.html, func(match string) string {
+		sub := imgSrcRegex.FindStringSubmatch(match)
+		quote, src := sub[2][:1], sub[2][1:len(sub[2])-1]
+		if strings.Contains(src, ":") {
+			return match
+		}
+		cid, ok := cids[src]
+		if !ok {
+			cid, ok = cids[path.Base(src)]
+		}
+		if !ok {
+			return match
+		}
+		return sub[1] + quote + "cid:" + cid + quote
+	})
+}
+
+func (mm *mimeMessage) rewriteInlineCIDs(_ map[string]string) {}
+
+// EmbedImages finds <img> tags in the HTML body which reference local files, attaches those
+// files as inline attachments and rewrites the references to their "cid:" URLs. Relative
+// references are resolved against dir, typically the directory holding the template the body
+// was rendered from. References are cleaned and must stay within dir, so a template cannot
+// attach files such as "../../etc/passwd". Returns an error if a reference escapes dir, if a
+// referenced file does not exist, or if two different files share the same file name, as
+// Mailgun uses the file name as the Content-ID, in which case no image is attached.
+// Call EmbedImages() after the HTML body has been set. This method is ignored for MIME messages.
+func (m *Message) EmbedImages(dir string) error {
+	// Validate every image before attaching any, so an error leaves the message unchanged
+	embedded := make(map[string]string)
+	var files []string
+	for _, match := range imgSrcRegex.FindAllStringSubmatch(m.specific.getHtml(), -1) {
+		src := match[2][1 : len(match[2])-1]
+		if src == "" || strings.Contains(src, ":") || strings.HasPrefix(src, "//") {
+			continue
+		}
+
+		if previous, ok := embedded[path.Base(src)]; ok {
+			if previous != src {
+				return fmt.Errorf("images '%s' and '%s' have the same file name", previous, src)
+			}
+			continue
+		}
+
+		file := filepath.Join(dir, filepath.FromSlash(path.Clean(strings.TrimPrefix(src, "/"))))
+		if rel, err := filepath.Rel(dir, file); err != nil || rel == ".." ||
+			strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("image '%s' is outside of '%s'", src, dir)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+		embedded[path.Base(src)] = src
+		files = append(files, file)
+	}
+	for _, file := range files {
+		m.AddInline(file)
+	}
+	m.RewriteInlineCIDs()
+	return nil
+}
+
 // AddRecipient appends a receiver to the To: header of a message.
 // It will return an error if the limit of recipients have been exceeded for this message
 func (m *Message) AddRecipient(recipient string) error {
@@ -290,12 +435,143 @@ func (mm *mimeMessage) recipientCount() int {
 	return 10
 }
 
+// Clone returns a copy of the message with everything except its recipients, so a message
+// can be built once and then sent to each recipient with
+//
+//     for _, r := range recipients {
+//         m, err := template.Clone()
+//         ...
+//         m.AddRecipient(r)
+//         mg.Send(ctx, m)
+//     }
+//
+// To:, Cc: and Bcc: recipients and recipient variables are not copied. Headers, variables,
+// options and attachments are. Reader attachments, reader inlines and MIME bodies can only
+// be read once, so Clone reads them into memory and replaces the readers of both messages
+// with in-memory copies.
+func (m *Message) Clone() (*Message, error) {
+	specific, err := m.specific.clone()
+	if err != nil {
+		return nil, err
+	}
+	readerAttachments, err := cloneReaderAttachments(m.readerAttachments)
+	if err != nil {
+		return nil, err
+	}
+	readerInlines, err := cloneReaderAttachments(m.readerInlines)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *m
+	c.to = nil
+	c.recipientVariables = nil
+	c.manifest = nil
+	c.skipped = nil
+	c.specific = specific
+	c.readerAttachments = readerAttachments
+	c.readerInlines = readerInlines
+	c.tags = append([]string(nil), m.tags...)
+	c.campaigns = append([]string(nil), m.campaigns...)
+	c.attachments = append([]fileAttachment(nil), m.attachments...)
+	c.attachmentOrder = append([]attachmentKind(nil), m.attachmentOrder...)
+	c.inlines = append([]string(nil), m.inlines...)
+	c.bufferAttachments = nil
+	for _, ba := range m.bufferAttachments {
+		c.bufferAttachments = append(c.bufferAttachments, BufferAttachment{
+			Filename: ba.Filename,
+			Buffer:   append([]byte(nil), ba.Buffer...),
+		})
+	}
+	if m.headers != nil {
+		c.headers = make(map[string][]string, len(m.headers))
+		for k, v := range m.headers {
+			c.headers[k] = append([]string(nil), v...)
+		}
+	}
+	if m.variables != nil {
+		c.variables = make(map[string]string, len(m.variables))
+		for k, v := range m.variables {
+			c.variables[k] = v
+		}
+	}
+	return &c, nil
+}
+
+func (pm *plainMessage) clone() (features, error) {
+	c := *pm
+	c.cc = nil
+	c.bcc = nil
+	return &c, nil
+}
+
+func (mm *mimeMessage) clone() (features, error) {
+	body, err := bufferReadCloser(&mm.body)
+	if err != nil {
+		return nil, err
+	}
+	return &mimeMessage{body: body, ignored: append([]string(nil), mm.ignored...)}, nil
+}
+
+// cloneReaderAttachments buffers each attachment into memory, replacing the original
+// readers, and returns copies of the attachments reading from the same buffers
+func cloneReaderAttachments(attachments []ReaderAttachment) ([]ReaderAttachment, error) {
+	var result []ReaderAttachment
+	for i := range attachments {
+		rc, err := bufferReadCloser(&attachments[i].ReadCloser)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ReaderAttachment{Filename: attachments[i].Filename, ReadCloser: rc})
+	}
+	return result, nil
+}
+
+// bufferReadCloser reads and closes *rc, replaces it with an in-memory reader of the
+// contents, and returns a second in-memory reader of the same contents
+func bufferReadCloser(rc *io.ReadCloser) (io.ReadCloser, error) {
+	if *rc == nil {
+		return nil, nil
+	}
+	buf, err := ioutil.ReadAll(*rc)
+	if err != nil {
+		return nil, err
+	}
+	if err := (*rc).Close(); err != nil {
+		return nil, err
+	}
+	*rc = ioutil.NopCloser(bytes.NewReader(buf))
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
 func (m *Message) send(ctx context.Context) (string, string, error) {
 	return m.mg.Send(ctx, m)
 }
 
-func (m *Message) SetReplyTo(recipient string) {
-	m.AddHeader("Reply-To", recipient)
+// SetReplyTo sets the Reply-To header of the message. Each address must be a valid
+// RFC 5322 address, such as "Bob <bob@example.com>", or a comma separated list of them.
+// Multiple addresses are joined into a single header. Returns an error, leaving the
+// header unchanged, if any address is invalid.
+func (m *Message) SetReplyTo(addresses ...string) error {
+	var replyTo []string
+	for _, a := range addresses {
+		list, err := mail.ParseAddressList(a)
+		if err != nil {
+			return fmt.Errorf("invalid Reply-To address '%s': %s", a, err)
+		}
+		for _, addr := range list {
+			if addr.Name == "" {
+				replyTo = append(replyTo, addr.Address)
+				continue
+			}
+			replyTo = append(replyTo, addr.String())
+		}
+	}
+	if len(replyTo) == 0 {
+		return errors.New("SetReplyTo() requires at least one address")
+	}
+	m.SetHeader("Reply-To", strings.Join(replyTo, ", "))
+	return nil
 }
 
 // AddCC appends a receiver to the carbon-copy header of a message.
@@ -307,7 +583,7 @@ func (pm *plainMessage) addCC(r string) {
 	pm.cc = append(pm.cc, r)
 }
 
-func (mm *mimeMessage) addCC(_ string) {}
+func (mm *mimeMessage) addCC(_ string) { mm.ignore("AddCC") }
 
 // AddBCC appends a receiver to the blind-carbon-copy header of a message.
 func (m *Message) AddBCC(recipient string) {
@@ -318,7 +594,7 @@ func (pm *plainMessage) addBCC(r string) {
 	pm.bcc = append(pm.bcc, r)
 }
 
-func (mm *mimeMessage) addBCC(_ string) {}
+func (mm *mimeMessage) addBCC(_ string) { mm.ignore("AddBCC") }
 
 // If you're sending a message that isn't already MIME encoded, SetHtml() will arrange to bundle
 // an HTML representation of your message in addition to your plain-text body.
@@ -330,15 +606,76 @@ func (pm *plainMessage) setHtml(h string) {
 	pm.html = h
 }
 
-func (mm *mimeMessage) setHtml(_ string) {}
+func (mm *mimeMessage) setHtml(_ string) { mm.ignore("SetHtml") }
+
+// SetAmpHtml arranges to bundle an AMP for Email representation of your message in addition to
+// your plain-text and HTML bodies. Mailgun requires a text or HTML body to be present as a fallback
+// for clients which do not support AMP. This setting is ignored for MIME messages.
+func (m *Message) SetAmpHtml(html string) {
+	m.specific.setAmpHtml(html)
+}
+
+func (pm *plainMessage) setAmpHtml(h string) {
+	pm.ampHtml = h
+}
+
+func (mm *mimeMessage) setAmpHtml(_ string) { mm.ignore("SetAmpHtml") }
+
+func (pm *plainMessage) getHtml() string {
+	return pm.html
+}
+
+func (mm *mimeMessage) getHtml() string {
+	return ""
+}
+
+// SetHtmlFromTemplate renders the template with the given data and uses the result as the
+// HTML body of the message. Returns any error from executing the template, in which case
+// the body is left unchanged. This setting is ignored for MIME messages.
+func (m *Message) SetHtmlFromTemplate(t *htmltemplate.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	m.specific.setHtml(buf.String())
+	return nil
+}
+
+// SetTextFromTemplate renders the template with the given data and uses the result as the
+// plain text body of the message, replacing the text given to NewMessage(). Returns any error
+// from executing the template, in which case the body is left unchanged.
+// This setting is ignored for MIME messages.
+func (m *Message) SetTextFromTemplate(t *texttemplate.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	m.specific.setText(buf.String())
+	return nil
+}
+
+func (pm *plainMessage) setText(t string) {
+	pm.text = t
+}
+
+func (mm *mimeMessage) setText(_ string) {}
 
 // AddTag attaches tags to the message.  Tags are useful for metrics gathering and event tracking purposes.
+// If a TagValidator is installed on the client no tags are added unless all of them are accepted.
 // Refer to the Mailgun documentation for further details.
 func (m *Message) AddTag(tag ...string) error {
 	if len(m.tags) >= MaxNumberOfTags {
 		return fmt.Errorf("cannot add any new tags. Message tag limit (%b) reached", MaxNumberOfTags)
 	}
 
+	if m.mg != nil {
+		for _, t := range tag {
+			if err := m.mg.ValidateTag(t); err != nil {
+				return err
+			}
+		}
+	}
+
 	m.tags = append(m.tags, tag...)
 	return nil
 }
@@ -355,6 +692,22 @@ func (m *Message) SetDKIM(dkim bool) {
 	m.dkimSet = true
 }
 
+// SetSecondaryDKIM arranges for the message to be signed with a second DKIM signature using
+// the key of the given domain and selector, e.g. while migrating to a new domain.
+// The domain must have a DKIM key configured with Mailgun.
+// Refer to the Mailgun documentation for more information.
+func (m *Message) SetSecondaryDKIM(domain, selector string) {
+	m.secondaryDKIM = domain + "/" + selector
+}
+
+// SetSecondaryDKIMPublic sets the domain and selector which are published in the secondary
+// DKIM signature in place of those passed to SetSecondaryDKIM(). Use this when the public
+// domain is a CNAME alias for the key of the signing domain.
+// Refer to the Mailgun documentation for more information.
+func (m *Message) SetSecondaryDKIMPublic(domain, selector string) {
+	m.secondaryDKIMPub = domain + "/" + selector
+}
+
 // EnableNativeSend allows the return path to match the address in the Message.Headers.From:
 // field when sending from Mailgun rather than the usual bounce+ address in the return path.
 func (m *Message) EnableNativeSend() {
@@ -374,6 +727,35 @@ func (m *Message) SetDeliveryTime(dt time.Time) {
 	m.deliveryTime = dt
 }
 
+// SetSTOPeriod enables Send Time Optimization (STO) for the message. Mailgun will deliver the
+// message within the given period, at the time each recipient is most likely to engage with it.
+// The period must be a whole number of hours between 24h and 72h. Pass zero to disable STO.
+// Refer to the Mailgun documentation for more information.
+func (m *Message) SetSTOPeriod(period time.Duration) error {
+	if period != 0 && (period%time.Hour != 0 || period < 24*time.Hour || period > 72*time.Hour) {
+		return fmt.Errorf("STO period must be a whole number of hours between 24h and 72h, got %s", period)
+	}
+	m.stoPeriod = period
+	return nil
+}
+
+// SetTimeZoneLocalize delivers the message at the given local time of day in each
+// recipient's time zone, as determined by Mailgun from the recipient's IP address.
+// The time must be in 24 hour ("09:00") or 12 hour ("9:00AM") format. Pass an empty
+// string to disable time zone localization.
+// Refer to the Mailgun documentation for more information.
+func (m *Message) SetTimeZoneLocalize(localTime string) error {
+	if localTime != "" {
+		if _, err := time.Parse("15:04", localTime); err != nil {
+			if _, err := time.Parse("3:04PM", strings.ToUpper(localTime)); err != nil {
+				return fmt.Errorf("'%s' is not a valid time of day, use HH:MM or H:MMAM format", localTime)
+			}
+		}
+	}
+	m.timeZoneLocalize = localTime
+	return nil
+}
+
 // SetTracking sets the o:tracking message parameter to adjust, on a message-by-message basis,
 // whether or not Mailgun will rewrite URLs to facilitate event tracking.
 // Events tracked includes opens, clicks, unsubscribes, etc.
@@ -386,10 +768,27 @@ func (m *Message) SetTracking(tracking bool) {
 	m.trackingSet = true
 }
 
+// TrackingClicksMode is the value of the o:tracking-clicks message parameter
+type TrackingClicksMode string
+
+// Click tracking modes accepted by SetTrackingClicksMode()
+const (
+	TrackingClicksEnabled  = TrackingClicksMode("yes")
+	TrackingClicksDisabled = TrackingClicksMode("no")
+	// Only rewrite links in the HTML body, leaving the plain text body untouched
+	TrackingClicksHTMLOnly = TrackingClicksMode("htmlonly")
+)
+
 // Refer to the Mailgun documentation for more information.
 func (m *Message) SetTrackingClicks(trackingClicks bool) {
-	m.trackingClicks = trackingClicks
-	m.trackingClicksSet = true
+	m.trackingClicks = TrackingClicksMode(yesNo(trackingClicks))
+}
+
+// SetTrackingClicksMode works as SetTrackingClicks(), but also supports
+// tracking clicks in the HTML body only with TrackingClicksHTMLOnly.
+// Refer to the Mailgun documentation for more information.
+func (m *Message) SetTrackingClicksMode(mode TrackingClicksMode) {
+	m.trackingClicks = mode
 }
 
 // Refer to the Mailgun documentation for more information.
@@ -409,16 +808,51 @@ func (m *Message) SetTrackingOpens(trackingOpens bool) {
 }
 
 // AddHeader allows you to send custom MIME headers with the message.
+// Adding a header which was already added sends the header once for each value,
+// use SetHeader() to replace the existing value instead.
 func (m *Message) AddHeader(header, value string) {
 	if m.headers == nil {
-		m.headers = make(map[string]string)
+		m.headers = make(map[string][]string)
+	}
+	m.headers[header] = append(m.headers[header], value)
+}
+
+// SetHeader works like AddHeader, but replaces any values already added for the header.
+func (m *Message) SetHeader(header, value string) {
+	if m.headers == nil {
+		m.headers = make(map[string][]string)
+	}
+	m.headers[header] = []string{value}
+}
+
+// AddListUnsubscribe adds the List-Unsubscribe and List-Unsubscribe-Post headers which let
+// mailbox providers such as Gmail and Yahoo offer a one-click unsubscribe button.
+// The mailto parameter is an optional address recipients can email to unsubscribe.
+// The url parameter is the unsubscribe link, if empty Mailgun's %unsubscribe_url% variable
+// is used so unsubscribes are handled by Mailgun.
+func (m *Message) AddListUnsubscribe(mailto, url string) {
+	if url == "" {
+		url = "%unsubscribe_url%"
+	}
+
+	value := "<" + url + ">"
+	if mailto != "" {
+		if !strings.HasPrefix(mailto, "mailto:") {
+			mailto = "mailto:" + mailto
+		}
+		value = "<" + mailto + ">, " + value
+	}
+	m.SetHeader("List-Unsubscribe", value)
+	// One-click unsubscribe (RFC 8058) requires an https link
+	if url == "%unsubscribe_url%" || strings.HasPrefix(url, "https://") {
+		m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
 	}
-	m.headers[header] = value
 }
 
 // AddVariable lets you associate a set of variables with messages you send,
 // which Mailgun can use to, in essence, complete form-mail.
 // Refer to the Mailgun documentation for more information.
+// If the client has a Cipher installed with SetVariableCipher() the value is encrypted.
 func (m *Message) AddVariable(variable string, value interface{}) error {
 	if m.variables == nil {
 		m.variables = make(map[string]string)
@@ -435,29 +869,104 @@ func (m *Message) AddVariable(variable string, value interface{}) error {
 		v = encoded
 	}
 
+	if m.mg != nil {
+		if c := m.mg.VariableCipher(); c != nil {
+			if v, err = c.Encrypt(v); err != nil {
+				return err
+			}
+		}
+	}
+
 	m.variables[variable] = v
 	return nil
 }
 
+// SetMultipartBoundary sets the boundary which separates the fields of the multipart request
+// Send() uploads, instead of a random one. Together with the stable order of the fields this
+// makes the request body deterministic, so golden-file tests of the exact request are stable.
+// Returns an error if the boundary is not valid as described in RFC 2046.
+func (m *Message) SetMultipartBoundary(boundary string) error {
+	if err := multipart.NewWriter(ioutil.Discard).SetBoundary(boundary); err != nil {
+		return err
+	}
+	m.boundary = boundary
+	return nil
+}
+
 // AddDomain allows you to use a separate domain for the type of messages you are sending.
 func (m *Message) AddDomain(domain string) {
 	m.domain = domain
 }
 
-// Retrieve the http headers associated with this message
+// Retrieve the http headers associated with this message. For headers
+// with multiple values only the last value is returned, see GetHeaderValues().
 func (m *Message) GetHeaders() map[string]string {
-	return m.headers
+	if m.headers == nil {
+		return nil
+	}
+	headers := make(map[string]string, len(m.headers))
+	for header, values := range m.headers {
+		headers[header] = values[len(values)-1]
+	}
+	return headers
+}
+
+// GetHeaderValues returns all the values added for the given header
+func (m *Message) GetHeaderValues(header string) []string {
+	return m.headers[header]
 }
 
 // Returned by `Send()` when the `mailgun.Message` struct is incomplete
 var ErrInvalidMessage = errors.New("message not valid")
 
+// Returned by `Send()` and `Validate()` when the message exceeds MaxMessageSize
+var ErrMessageTooLarge = errors.New("message exceeds the maximum message size")
+
+// MaxMessageSize is the largest message, including attachments, that Mailgun accepts
+const MaxMessageSize = 25 * 1024 * 1024
+
+// Size estimates the size in bytes of the multipart request which Send() would upload
+// for this message. Files are measured with os.Stat(). Reader attachments and MIME bodies
+// are only measured if they report their size through a Len() or Stat() method,
+// otherwise they are not counted.
+func (m *Message) Size() (int64, error) {
+	payload, err := m.payload()
+	if err != nil {
+		return 0, err
+	}
+	return payload.size()
+}
+
+// Validate returns ErrInvalidMessage if the message is incomplete, or ErrMessageTooLarge
+// if Size() exceeds MaxMessageSize. In strict mode, it returns a *MessageWarningsError if
+// the message has Warnings(). Send() calls Validate() before uploading the message.
+func (m *Message) Validate() error {
+	if !isValid(m) {
+		return ErrInvalidMessage
+	}
+	if m.strict {
+		if err := m.checkWarnings(); err != nil {
+			return err
+		}
+	}
+	size, err := m.Size()
+	if err != nil {
+		return err
+	}
+	if size > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	return nil
+}
+
 // Send attempts to queue a message (see Message, NewMessage, and its methods) for delivery.
 // It returns the Mailgun server response, which consists of two components:
 // a human-readable status message, and a message ID.  The status and message ID are set only
 // if no error occurred.
+// Once Mailgun answers 503 Service Unavailable, the following calls to Send() wait for the
+// delay requested by its Retry-After header, or DefaultMaintenanceBackoff, before sending.
 func (mg *MailgunImpl) Send(ctx context.Context, message *Message) (mes string, id string, err error) {
-	if mg.domain == "" {
+	if mg.domain == "" && !mg.domainRouting {
 		err = errors.New("you must provide a valid domain before calling Send()")
 		return
 	}
@@ -467,109 +976,210 @@ func (mg *MailgunImpl) Send(ctx context.Context, message *Message) (mes string,
 		return
 	}
 
-	if !isValid(message) {
-		err = ErrInvalidMessage
+	if err = message.Validate(); err != nil {
+		return
+	}
+	if mg.strictMode {
+		if err = message.checkWarnings(); err != nil {
+			return
+		}
+	}
+
+	start := time.Now()
+	ctx, cancel := message.slaContext(ctx)
+	defer cancel()
+	defer func() {
+		duration := time.Since(start)
+		domain := message.domain
+		if domain == "" {
+			domain = mg.Domain()
+		}
+		mg.events.publishSend(SendEvent{
+			Domain:   domain,
+			SLAClass: message.slaClass,
+			Deadline: message.slaDeadline,
+			Duration: duration,
+			Missed:   message.slaDeadline > 0 && (duration > message.slaDeadline || ctx.Err() == context.DeadlineExceeded),
+			Err:      err,
+		})
+	}()
+
+	if mg.domainRouting {
+		if err = mg.routeDomain(ctx, message); err != nil {
+			return
+		}
+		if message.domain == "" && mg.domain == "" {
+			err = errors.New("no domain of the account matches the From address of the message")
+			return
+		}
+	}
+	if message.suppressionMode != SuppressionIgnore {
+		// The suppressed recipients are removed from a copy, the message may be sent again
+		var filtered *Message
+		if filtered, err = mg.filterSuppressed(ctx, message); err != nil {
+			return
+		}
+		message = filtered
+	}
+	if message.contacts != nil {
+		if err = message.loadContacts(ctx); err != nil {
+			return
+		}
+		// The recipient variables count towards the size of the message
+		if err = message.Validate(); err != nil {
+			return
+		}
+	}
+	if mg.maintenance != nil {
+		if err = mg.maintenance.wait(ctx); err != nil {
+			return
+		}
+	}
+	if mg.throttle != nil {
+		if err = mg.throttle.Wait(ctx, message); err != nil {
+			return
+		}
+	}
+	if message.attachmentManifest {
+		if err = message.computeManifest(); err != nil {
+			return
+		}
+	}
+	payload, err := message.payload()
+	if err != nil {
 		return
 	}
+	if mg.testMode && !message.testMode {
+		payload.addValue("o:testmode", "yes")
+	}
+
+	if message.domain == "" {
+		message.domain = mg.Domain()
+	}
+
+	r := newHTTPRequest(generateApiUrlWithDomain(mg, message.specific.endpoint(), message.domain))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+
+	var response sendMessageResponse
+	err = postResponseFromJSON(ctx, r, payload, &response)
+	if err == nil {
+		mes = response.Message
+		id = response.Id
+	} else if IsServiceUnavailable(err) && mg.maintenance != nil {
+		mg.maintenance.start(RetryAfter(err, DefaultMaintenanceBackoff))
+	}
+
+	return
+}
+
+// payload builds the form data which Send() submits to Mailgun
+func (m *Message) payload() (*formDataPayload, error) {
 	payload := newFormDataPayload()
+	payload.boundary = m.boundary
 
-	message.specific.addValues(payload)
-	for _, to := range message.to {
+	m.specific.addValues(payload)
+	for _, to := range m.to {
 		payload.addValue("to", to)
 	}
-	for _, tag := range message.tags {
+	for _, tag := range m.tags {
 		payload.addValue("o:tag", tag)
 	}
-	for _, campaign := range message.campaigns {
+	for _, campaign := range m.campaigns {
 		payload.addValue("o:campaign", campaign)
 	}
-	if message.dkimSet {
-		payload.addValue("o:dkim", yesNo(message.dkim))
+	if m.dkimSet {
+		payload.addValue("o:dkim", yesNo(m.dkim))
+	}
+	if m.secondaryDKIM != "" {
+		payload.addValue("o:secondary-dkim", m.secondaryDKIM)
+	}
+	if m.secondaryDKIMPub != "" {
+		payload.addValue("o:secondary-dkim-public", m.secondaryDKIMPub)
+	}
+	if !m.deliveryTime.IsZero() {
+		payload.addValue("o:deliverytime", formatMailgunTime(m.deliveryTime))
 	}
-	if !message.deliveryTime.IsZero() {
-		payload.addValue("o:deliverytime", formatMailgunTime(message.deliveryTime))
+	if m.stoPeriod != 0 {
+		payload.addValue("o:deliverytime-optimize-period", fmt.Sprintf("%dh", m.stoPeriod/time.Hour))
 	}
-	if message.nativeSend {
+	if m.timeZoneLocalize != "" {
+		payload.addValue("o:time-zone-localize", m.timeZoneLocalize)
+	}
+	if m.nativeSend {
 		payload.addValue("o:native-send", "yes")
 	}
-	if message.testMode {
+	if m.testMode {
 		payload.addValue("o:testmode", "yes")
 	}
-	if message.trackingSet {
-		payload.addValue("o:tracking", yesNo(message.tracking))
+	if m.trackingSet {
+		payload.addValue("o:tracking", yesNo(m.tracking))
+	}
+	if m.trackingClicks != "" {
+		payload.addValue("o:tracking-clicks", string(m.trackingClicks))
 	}
-	if message.trackingClicksSet {
-		payload.addValue("o:tracking-clicks", yesNo(message.trackingClicks))
+	if m.trackingOpensSet {
+		payload.addValue("o:tracking-opens", yesNo(m.trackingOpens))
 	}
-	if message.trackingOpensSet {
-		payload.addValue("o:tracking-opens", yesNo(message.trackingOpens))
+	if m.requireTLS {
+		payload.addValue("o:require-tls", trueFalse(m.requireTLS))
 	}
-	if message.requireTLS {
-		payload.addValue("o:require-tls", trueFalse(message.requireTLS))
+	if m.skipVerification {
+		payload.addValue("o:skip-verification", trueFalse(m.skipVerification))
 	}
-	if message.skipVerification {
-		payload.addValue("o:skip-verification", trueFalse(message.skipVerification))
+	// Headers and variables are added in a stable order so the payload is deterministic
+	headers := make([]string, 0, len(m.headers))
+	for header := range m.headers {
+		headers = append(headers, header)
 	}
-	if message.headers != nil {
-		for header, value := range message.headers {
+	sort.Strings(headers)
+	for _, header := range headers {
+		for _, value := range m.headers[header] {
 			payload.addValue("h:"+header, value)
 		}
 	}
-	if message.variables != nil {
-		for variable, value := range message.variables {
-			payload.addValue("v:"+variable, value)
-		}
+	variables := make([]string, 0, len(m.variables))
+	for variable := range m.variables {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	for _, variable := range variables {
+		payload.addValue("v:"+variable, m.variables[variable])
 	}
-	if message.recipientVariables != nil {
-		j, err := json.Marshal(message.recipientVariables)
+	if m.recipientVariables != nil {
+		j, err := json.Marshal(m.recipientVariables)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
 		payload.addValue("recipient-variables", string(j))
 	}
-	if message.attachments != nil {
-		for _, attachment := range message.attachments {
-			payload.addFile("attachment", attachment)
+	if m.attachments != nil {
+		for _, attachment := range m.attachments {
+			payload.addFileWithType("attachment", attachment.path, attachment.contentType)
 		}
 	}
-	if message.readerAttachments != nil {
-		for _, readerAttachment := range message.readerAttachments {
+	if m.readerAttachments != nil {
+		for _, readerAttachment := range m.readerAttachments {
 			payload.addReadCloser("attachment", readerAttachment.Filename, readerAttachment.ReadCloser)
 		}
 	}
-	if message.bufferAttachments != nil {
-		for _, bufferAttachment := range message.bufferAttachments {
+	if m.bufferAttachments != nil {
+		for _, bufferAttachment := range m.bufferAttachments {
 			payload.addBuffer("attachment", bufferAttachment.Filename, bufferAttachment.Buffer)
 		}
 	}
-	if message.inlines != nil {
-		for _, inline := range message.inlines {
+	if m.inlines != nil {
+		for _, inline := range m.inlines {
 			payload.addFile("inline", inline)
 		}
 	}
 
-	if message.readerInlines != nil {
-		for _, readerAttachment := range message.readerInlines {
+	if m.readerInlines != nil {
+		for _, readerAttachment := range m.readerInlines {
 			payload.addReadCloser("inline", readerAttachment.Filename, readerAttachment.ReadCloser)
 		}
 	}
-
-	if message.domain == "" {
-		message.domain = mg.Domain()
-	}
-
-	r := newHTTPRequest(generateApiUrlWithDomain(mg, message.specific.endpoint(), message.domain))
-	r.setClient(mg.Client())
-	r.setBasicAuth(basicAuthUser, mg.APIKey())
-
-	var response sendMessageResponse
-	err = postResponseFromJSON(ctx, r, payload, &response)
-	if err == nil {
-		mes = response.Message
-		id = response.Id
-	}
-
-	return
+	return payload, nil
 }
 
 func (pm *plainMessage) addValues(p *formDataPayload) {
@@ -585,6 +1195,9 @@ func (pm *plainMessage) addValues(p *formDataPayload) {
 	if pm.html != "" {
 		p.addValue("html", pm.html)
 	}
+	if pm.ampHtml != "" {
+		p.addValue("amp-html", pm.ampHtml)
+	}
 }
 
 func (mm *mimeMessage) addValues(p *formDataPayload) {
@@ -691,7 +1304,7 @@ func validateStringList(list []string, requireOne bool) bool {
 func (mg *MailgunImpl) GetStoredMessage(ctx context.Context, id string) (StoredMessage, error) {
 	url := generateStoredMessageUrl(mg, messagesEndpoint, id)
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var response StoredMessage
@@ -699,11 +1312,50 @@ func (mg *MailgunImpl) GetStoredMessage(ctx context.Context, id string) (StoredM
 	return response, err
 }
 
+// StoredMessageResult is the outcome of retrieving one stored message with GetStoredMessages()
+type StoredMessageResult struct {
+	ID      string
+	Message StoredMessage
+	Err     error
+}
+
+// GetStoredMessages retrieves many stored messages, making at most concurrency requests at a time.
+// The results are returned in the same order as ids, a message which could not be retrieved has
+// its Err field set. Once ctx is cancelled the remaining messages fail with the context's error.
+func (mg *MailgunImpl) GetStoredMessages(ctx context.Context, ids []string, concurrency int) []StoredMessageResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]StoredMessageResult, len(ids))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, id := range ids {
+		results[i].ID = id
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *StoredMessageResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result.Message, result.Err = mg.GetStoredMessage(ctx, result.ID)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
 // Given a storage id resend the stored message to the specified recipients
 func (mg *MailgunImpl) ReSend(ctx context.Context, storageURL string, recipients ...string) (string, string, error) {
 	url := generateDomainApiUrl(mg, messagesEndpoint) + "/" + storageURL
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newFormDataPayload()
@@ -731,7 +1383,7 @@ func (mg *MailgunImpl) ReSend(ctx context.Context, storageURL string, recipients
 func (mg *MailgunImpl) GetStoredMessageRaw(ctx context.Context, id string) (StoredMessageRaw, error) {
 	url := generateStoredMessageUrl(mg, messagesEndpoint, id)
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	r.addHeader("Accept", "message/rfc2822")
 
@@ -740,11 +1392,33 @@ func (mg *MailgunImpl) GetStoredMessageRaw(ctx context.Context, id string) (Stor
 	return response, err
 }
 
+// GetStoredMessageMIME works like GetStoredMessageRaw, but returns only the original MIME body
+// of the message, ready to be archived or passed to NewMIMEMessage() for re-injection.
+// The body is streamed from Mailgun as it is read, the caller must close the returned
+// io.ReadCloser.
+func (mg *MailgunImpl) GetStoredMessageMIME(ctx context.Context, id string) (io.ReadCloser, error) {
+	url := generateStoredMessageUrl(mg, messagesEndpoint, id)
+	r := newHTTPRequest(url)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	r.addHeader("Accept", "message/rfc2822")
+
+	body, err := getResponseStream(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return storedMIMEReader(body)
+}
+
 // GetStoredMessageForURL retrieves information about a received e-mail message.
 // This provides visibility into, e.g., replies to a message sent to a mailing list.
+//
+// The url is used as is, so the storage URL of an event (such as events.Stored.Storage.URL)
+// can be passed directly, even if it refers to a storage host in a different region than
+// the API base of the client.
 func (mg *MailgunImpl) GetStoredMessageForURL(ctx context.Context, url string) (StoredMessage, error) {
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var response StoredMessage
@@ -755,9 +1429,10 @@ func (mg *MailgunImpl) GetStoredMessageForURL(ctx context.Context, url string) (
 // GetStoredMessageRawForURL retrieves the raw MIME body of a received e-mail message.
 // Compared to GetStoredMessage, it gives access to the unparsed MIME body, and
 // thus delegates to the caller the required parsing.
+// Like GetStoredMessageForURL, the url may be the storage URL of an event.
 func (mg *MailgunImpl) GetStoredMessageRawForURL(ctx context.Context, url string) (StoredMessageRaw, error) {
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	r.addHeader("Accept", "message/rfc2822")
 
@@ -767,13 +1442,34 @@ func (mg *MailgunImpl) GetStoredMessageRawForURL(ctx context.Context, url string
 
 }
 
+// GetStoredAttachment retrieves an attachment of a stored message, using the Url field of a
+// StoredAttachment. The attachment is streamed from Mailgun as it is read, the caller must
+// close the returned io.ReadCloser.
+func (mg *MailgunImpl) GetStoredAttachment(ctx context.Context, url string) (io.ReadCloser, error) {
+	r := newHTTPRequest(url)
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	return getResponseStream(ctx, r)
+}
+
 // DeleteStoredMessage removes a previously stored message.
 // Note that Mailgun institutes a policy of automatically deleting messages after a set time.
 // Consult the current Mailgun API documentation for more details.
 func (mg *MailgunImpl) DeleteStoredMessage(ctx context.Context, id string) error {
 	url := generateStoredMessageUrl(mg, messagesEndpoint, id)
 	r := newHTTPRequest(url)
-	r.setClient(mg.Client())
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makeDeleteRequest(ctx, r)
+	return err
+}
+
+// DeleteScheduledMessages removes all messages which are queued for delivery at a later
+// time on the domain, such as messages sent with SetDeliveryTime(). Use it to stop a
+// scheduled campaign which was sent by mistake.
+func (mg *MailgunImpl) DeleteScheduledMessages(ctx context.Context) error {
+	r := newHTTPRequest(generateApiUrl(mg, envelopesEndpoint))
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
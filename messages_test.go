@@ -2,13 +2,20 @@ package mailgun
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/facebookgo/ensure"
@@ -534,3 +541,905 @@ func TestSendTLSOptions(t *testing.T) {
 	ensure.DeepEqual(t, msg, exampleMessage)
 	ensure.DeepEqual(t, id, exampleID)
 }
+
+func TestSendAmpHtml(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+		exampleAmp     = `<!doctype html><html ⚡4email><head></head><body>Hello</body></html>`
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.Method, http.MethodPost)
+		ensure.DeepEqual(t, req.URL.Path, fmt.Sprintf("/%s/messages", exampleDomain))
+		ensure.DeepEqual(t, req.FormValue("text"), exampleText)
+		ensure.DeepEqual(t, req.FormValue("amp-html"), exampleAmp)
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.SetAmpHtml(exampleAmp)
+
+	msg, id, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+// closeRecorder records whether it was closed, which the transport may do from another goroutine
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func (c *closeRecorder) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+func TestSendReaderAttachment(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+		attachmentSize = 5 << 20
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.FormValue("text"), exampleText)
+
+		file, header, err := req.FormFile("attachment")
+		ensure.Nil(t, err)
+		defer file.Close()
+		ensure.DeepEqual(t, header.Filename, "report.pdf")
+
+		n, err := io.Copy(ioutil.Discard, file)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, n, int64(attachmentSize))
+
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	attachment := &closeRecorder{Reader: io.LimitReader(zeroReader{}, attachmentSize)}
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.AddReaderAttachment("report.pdf", attachment)
+
+	msg, id, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+	ensure.True(t, attachment.isClosed())
+}
+
+func TestPayloadClosesReadersOnError(t *testing.T) {
+	for _, build := range []func(p *formDataPayload) error{
+		func(p *formDataPayload) error { _, err := p.getPayloadReader(); return err },
+		func(p *formDataPayload) error { _, err := p.getPayloadBuffer(); return err },
+	} {
+		attachment := &closeRecorder{Reader: strings.NewReader("attachment data")}
+		p := newFormDataPayload()
+		p.addReadCloser("attachment", "report.pdf", attachment)
+		p.addFile("attachment", "/does/not/exist.pdf")
+		ensure.NotNil(t, build(p))
+		ensure.True(t, attachment.isClosed())
+	}
+
+	// The content type is not computed by consuming the payload
+	attachment := &closeRecorder{Reader: strings.NewReader("attachment data")}
+	p := newFormDataPayload()
+	p.addReadCloser("attachment", "report.pdf", attachment)
+	ensure.DeepEqual(t, p.getContentType(), "")
+	ensure.False(t, attachment.isClosed())
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestSendMissingAttachmentFile(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase("http://127.0.0.1:0")
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddAttachment("/this/file/does/not/exist.pdf")
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.True(t, os.IsNotExist(err))
+}
+
+func TestRewriteInlineCIDs(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.SetHtml(`<p><img src="images/logo.png" alt="logo"><IMG class="x" SRC='banner.gif'>` +
+		`<img src="https://example.com/logo.png"><img src="unknown.png"></p>`)
+	m.AddInline("/var/assets/images/logo.png")
+	m.AddReaderInline("banner.gif", ioutil.NopCloser(strings.NewReader("GIF89a")))
+	m.RewriteInlineCIDs()
+
+	ensure.DeepEqual(t, m.specific.(*plainMessage).html,
+		`<p><img src="cid:logo.png" alt="logo"><IMG class="x" SRC='cid:banner.gif'>`+
+			`<img src="https://example.com/logo.png"><img src="unknown.png"></p>`)
+}
+
+func TestSendAttachmentWithType(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+		contentType    = "application/octet-stream; charset=utf-8"
+	)
+	file, err := ioutil.TempFile("", "export-*.bin")
+	ensure.Nil(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("exported data")
+	ensure.Nil(t, err)
+	ensure.Nil(t, file.Close())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		attachments := req.MultipartForm.File["attachment"]
+		ensure.DeepEqual(t, len(attachments), 2)
+		ensure.DeepEqual(t, attachments[0].Header.Get("Content-Type"), contentType)
+		ensure.DeepEqual(t, attachments[1].Header.Get("Content-Type"), "application/octet-stream")
+
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.AddAttachmentWithType(file.Name(), contentType)
+	m.AddAttachment(file.Name())
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestSendAttachmentManifest(t *testing.T) {
+	const (
+		toUser    = "test@test.com"
+		exampleID = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	file, err := ioutil.TempFile("", "export-*.bin")
+	ensure.Nil(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("exported data")
+	ensure.Nil(t, err)
+	ensure.Nil(t, file.Close())
+
+	var variable string
+	var readerContent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		variable = req.FormValue("v:" + AttachmentManifestVariable)
+		attachments := req.MultipartForm.File["attachment"]
+		ensure.DeepEqual(t, len(attachments), 3)
+		f, err := attachments[1].Open()
+		ensure.Nil(t, err)
+		b, err := ioutil.ReadAll(f)
+		ensure.Nil(t, err)
+		readerContent = string(b)
+		fmt.Fprintf(w, `{"message":"Queued", "id":"%s"}`, exampleID)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.EnableAttachmentManifest()
+	m.AddAttachment(file.Name())
+	m.AddReaderAttachment("reader.txt", ioutil.NopCloser(strings.NewReader("reader data")))
+	m.AddBufferAttachment("buffer.txt", []byte("buffer data"))
+
+	_, _, err = mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	// The reader attachment is still sent after being read for the checksum
+	ensure.DeepEqual(t, readerContent, "reader data")
+
+	manifest := m.AttachmentManifest()
+	ensure.DeepEqual(t, manifest, []AttachmentChecksum{
+		{
+			Filename: filepath.Base(file.Name()),
+			Size:     13,
+			SHA256:   "2bd42a82c1c449f178b7c8e3b80724e28862f1ff1eb5afb49f26f29bee97a237",
+		},
+		{
+			Filename: "reader.txt",
+			Size:     11,
+			SHA256:   "3accf8675eccde79247faa145a239fade64916ba6f7a83fb3b960eb351da4e81",
+		},
+		{
+			Filename: "buffer.txt",
+			Size:     11,
+			SHA256:   "4a112caa239bc09c8aa724de9422ecfbffbc84804be374b4b869da32835db607",
+		},
+	})
+
+	var sent []AttachmentChecksum
+	ensure.Nil(t, json.Unmarshal([]byte(variable), &sent))
+	ensure.DeepEqual(t, sent, manifest)
+}
+
+func TestAttachmentManifestOrder(t *testing.T) {
+	file, err := ioutil.TempFile("", "export-*.bin")
+	ensure.Nil(t, err)
+	defer os.Remove(file.Name())
+	ensure.Nil(t, file.Close())
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddBufferAttachment("buffer.txt", []byte("buffer data"))
+	m.AddReaderAttachment("reader.txt", ioutil.NopCloser(strings.NewReader("reader data")))
+	m.AddAttachment(file.Name())
+	m.AddBufferAttachment("second.txt", nil)
+	ensure.Nil(t, m.computeManifest())
+
+	var names []string
+	for _, c := range m.AttachmentManifest() {
+		names = append(names, c.Filename)
+	}
+	ensure.DeepEqual(t, names, []string{"buffer.txt", "reader.txt", filepath.Base(file.Name()), "second.txt"})
+
+	// A nil reader is reported instead of panicking
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddReaderAttachment("nil.txt", nil)
+	err = m.computeManifest()
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "nil.txt")
+}
+
+func TestErrorBodyCapture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message": "'to' parameter is not a valid address. please check documentation"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetErrorBodyCapture(30)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "not-an-address")
+	m.AddVariable("api_key", "super secret")
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.NotNil(t, err)
+
+	e, ok := err.(*UnexpectedResponseError)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, e.Actual, http.StatusBadRequest)
+	ensure.DeepEqual(t, string(e.Data), `{"message": "'to' parameter is`)
+	ensure.True(t, len(e.Request) <= 30)
+
+	mg.SetErrorBodyCapture(1 << 10)
+	_, _, err = mg.Send(context.Background(), m)
+	e = err.(*UnexpectedResponseError)
+	ensure.StringContains(t, e.Request, "to=not-an-address")
+	ensure.StringContains(t, e.Request, "v%3Aapi_key=REDACTED")
+	ensure.StringDoesNotContain(t, e.Request, "super")
+
+	// The capture is a setting of the client
+	other := NewMailgun(exampleDomain, exampleAPIKey)
+	other.SetAPIBase(srv.URL)
+	_, _, err = other.Send(context.Background(), m)
+	e = err.(*UnexpectedResponseError)
+	ensure.DeepEqual(t, e.Request, "")
+}
+
+type sizedReader struct {
+	zeroReader
+	size int
+}
+
+func (s sizedReader) Len() int     { return s.size }
+func (s sizedReader) Close() error { return nil }
+
+func TestMessageSize(t *testing.T) {
+	var received int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n, err := io.Copy(ioutil.Discard, req.Body)
+		ensure.Nil(t, err)
+		received = n
+		fmt.Fprint(w, `{"message":"Queued. Thank you.", "id":"<20111114174239.25659.5817@samples.mailgun.org>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.SetHtml("<html><body>Hello</body></html>")
+	m.AddBufferAttachment("report.csv", []byte("a,b,c\n1,2,3\n"))
+	m.AddReaderAttachment("notes.txt", ioutil.NopCloser(strings.NewReader("some notes")))
+
+	size, err := m.Size()
+	ensure.Nil(t, err)
+	ensure.Nil(t, m.Validate())
+
+	_, _, err = mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	// The reader attachment is hidden behind NopCloser, so its length can't be counted
+	ensure.DeepEqual(t, size, received-int64(len("some notes")))
+}
+
+func TestSendMessageTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Fatal("message should not have been uploaded")
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddReaderAttachment("huge.bin", sizedReader{size: MaxMessageSize})
+
+	ensure.DeepEqual(t, m.Validate(), ErrMessageTooLarge)
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.DeepEqual(t, err, ErrMessageTooLarge)
+}
+
+func TestSendSTOPeriod(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.FormValue("o:deliverytime-optimize-period"), "48h")
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	ensure.NotNil(t, m.SetSTOPeriod(12*time.Hour))
+	ensure.NotNil(t, m.SetSTOPeriod(73*time.Hour))
+	ensure.NotNil(t, m.SetSTOPeriod(36*time.Hour+time.Minute))
+	ensure.Nil(t, m.SetSTOPeriod(48*time.Hour))
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestSendTimeZoneLocalize(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.FormValue("o:time-zone-localize"), "09:00")
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	ensure.Nil(t, m.SetTimeZoneLocalize("9:00am"))
+	ensure.Nil(t, m.SetTimeZoneLocalize("9:00PM"))
+	ensure.NotNil(t, m.SetTimeZoneLocalize("25:00"))
+	ensure.NotNil(t, m.SetTimeZoneLocalize("tomorrow"))
+	ensure.Nil(t, m.SetTimeZoneLocalize("09:00"))
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestSendSecondaryDKIM(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.FormValue("o:secondary-dkim"), "new.example.com/s1")
+		ensure.DeepEqual(t, req.FormValue("o:secondary-dkim-public"), "public.example.com/s2")
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.SetSecondaryDKIM("new.example.com", "s1")
+	m.SetSecondaryDKIMPublic("public.example.com", "s2")
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestSetBodyFromTemplate(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	data := struct{ Name string }{Name: "<Bob>"}
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	html := htmltemplate.Must(htmltemplate.New("html").Parse(`<p>Hello {{.Name}}</p>`))
+	ensure.Nil(t, m.SetHtmlFromTemplate(html, data))
+	text := texttemplate.Must(texttemplate.New("text").Parse(`Hello {{.Name}}`))
+	ensure.Nil(t, m.SetTextFromTemplate(text, data))
+
+	pm := m.specific.(*plainMessage)
+	ensure.DeepEqual(t, pm.html, "<p>Hello &lt;Bob&gt;</p>")
+	ensure.DeepEqual(t, pm.text, "Hello <Bob>")
+
+	broken := texttemplate.Must(texttemplate.New("broken").Parse(`Hello {{.Missing}}`))
+	ensure.NotNil(t, m.SetTextFromTemplate(broken, data))
+	ensure.DeepEqual(t, pm.text, "Hello <Bob>")
+}
+
+func TestSendTrackingClicksHTMLOnly(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.FormValue("o:tracking-clicks"), "htmlonly")
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.SetTrackingClicks(false)
+	m.SetTrackingClicksMode(TrackingClicksHTMLOnly)
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestEmbedImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailgun-templates")
+	ensure.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ensure.Nil(t, os.Mkdir(dir+"/images", 0755))
+	ensure.Nil(t, ioutil.WriteFile(dir+"/images/logo.png", []byte("PNG"), 0644))
+	ensure.Nil(t, ioutil.WriteFile(dir+"/footer.gif", []byte("GIF"), 0644))
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.SetHtml(`<img src="images/logo.png"><img src="https://example.com/x.png"><img src="images/logo.png"><img src='footer.gif'>`)
+
+	ensure.Nil(t, m.EmbedImages(dir))
+	ensure.DeepEqual(t, m.specific.(*plainMessage).html,
+		`<img src="cid:logo.png"><img src="https://example.com/x.png"><img src="cid:logo.png"><img src='cid:footer.gif'>`)
+	ensure.DeepEqual(t, len(m.inlines), 2)
+
+	// An invalid image leaves the message unchanged, even after valid ones
+	m.SetHtml(`<img src="footer.gif"><img src="missing.png">`)
+	ensure.NotNil(t, m.EmbedImages(dir))
+	ensure.DeepEqual(t, len(m.inlines), 2)
+	ensure.DeepEqual(t, m.specific.(*plainMessage).html, `<img src="footer.gif"><img src="missing.png">`)
+
+	ensure.Nil(t, ioutil.WriteFile(dir+"/logo.png", []byte("PNG"), 0644))
+	m.SetHtml(`<img src="images/logo.png"><img src="logo.png">`)
+	ensure.NotNil(t, m.EmbedImages(dir))
+	ensure.DeepEqual(t, len(m.inlines), 2)
+
+	// References are resolved within dir
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.SetHtml(`<img src="/images/../footer.gif">`)
+	ensure.Nil(t, m.EmbedImages(dir))
+	ensure.DeepEqual(t, m.specific.(*plainMessage).html, `<img src="cid:footer.gif">`)
+	ensure.DeepEqual(t, m.inlines, []string{filepath.Join(dir, "footer.gif")})
+
+	ensure.Nil(t, ioutil.WriteFile(filepath.Join(filepath.Dir(dir), "secret.png"), []byte("PNG"), 0644))
+	defer os.Remove(filepath.Join(filepath.Dir(dir), "secret.png"))
+	for _, src := range []string{"../secret.png", "images/../../secret.png", "/../secret.png"} {
+		m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+		m.SetHtml(`<img src="` + src + `">`)
+		err := m.EmbedImages(dir)
+		ensure.NotNil(t, err)
+		ensure.StringContains(t, err.Error(), "outside")
+		ensure.DeepEqual(t, len(m.inlines), 0)
+	}
+}
+
+func TestAddListUnsubscribe(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddListUnsubscribe("unsubscribe@example.com", "")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe"], "<mailto:unsubscribe@example.com>, <%unsubscribe_url%>")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe-Post"], "List-Unsubscribe=One-Click")
+
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddListUnsubscribe("mailto:unsubscribe@example.com", "https://example.com/unsubscribe?id=1")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe"], "<mailto:unsubscribe@example.com>, <https://example.com/unsubscribe?id=1>")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe-Post"], "List-Unsubscribe=One-Click")
+
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	m.AddListUnsubscribe("", "http://example.com/unsubscribe")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe"], "<http://example.com/unsubscribe>")
+	_, ok := m.GetHeaders()["List-Unsubscribe-Post"]
+	ensure.False(t, ok)
+}
+
+func TestSetReplyTo(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+
+	ensure.Nil(t, m.SetReplyTo("support@example.com"))
+	ensure.DeepEqual(t, m.GetHeaders()["Reply-To"], "support@example.com")
+
+	ensure.Nil(t, m.SetReplyTo("Support Team <support@example.com>", "billing@example.com, Sales <sales@example.com>"))
+	ensure.DeepEqual(t, m.GetHeaders()["Reply-To"],
+		`"Support Team" <support@example.com>, billing@example.com, "Sales" <sales@example.com>`)
+
+	ensure.NotNil(t, m.SetReplyTo("not an address"))
+	ensure.NotNil(t, m.SetReplyTo("valid@example.com", "invalid@"))
+	ensure.NotNil(t, m.SetReplyTo())
+	ensure.DeepEqual(t, m.GetHeaders()["Reply-To"],
+		`"Support Team" <support@example.com>, billing@example.com, "Sales" <sales@example.com>`)
+}
+
+func TestSendMultiValueHeaders(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		ensure.DeepEqual(t, req.MultipartForm.Value["h:X-Team"], []string{"billing", "platform"})
+		ensure.DeepEqual(t, req.MultipartForm.Value["h:X-Priority"], []string{"1"})
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.AddHeader("X-Team", "billing")
+	m.AddHeader("X-Team", "platform")
+	m.AddHeader("X-Priority", "3")
+	m.SetHeader("X-Priority", "1")
+
+	ensure.DeepEqual(t, m.GetHeaderValues("X-Team"), []string{"billing", "platform"})
+	ensure.DeepEqual(t, m.GetHeaders()["X-Team"], "platform")
+
+	msg, id, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg, exampleMessage)
+	ensure.DeepEqual(t, id, exampleID)
+}
+
+func TestSendClientTestMode(t *testing.T) {
+	const (
+		exampleDomain  = "testDomain"
+		exampleAPIKey  = "testAPIKey"
+		toUser         = "test@test.com"
+		exampleMessage = "Queue. Thank you"
+		exampleID      = "<20111114174239.25659.5817@samples.mailgun.org>"
+	)
+	var testMode []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.Nil(t, req.ParseMultipartForm(1<<20))
+		testMode = req.MultipartForm.Value["o:testmode"]
+		rsp := fmt.Sprintf(`{"message":"%s", "id":"%s"}`, exampleMessage, exampleID)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetTestMode(true)
+	ctx := context.Background()
+
+	_, _, err := mg.Send(ctx, mg.NewMessage(fromUser, exampleSubject, exampleText, toUser))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, testMode, []string{"yes"})
+
+	// Not sent twice when also enabled on the message
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, toUser)
+	m.EnableTestMode()
+	_, _, err = mg.Send(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, testMode, []string{"yes"})
+
+	mg.SetTestMode(false)
+	_, _, err = mg.Send(ctx, mg.NewMessage(fromUser, exampleSubject, exampleText, toUser))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(testMode), 0)
+}
+
+func TestMessageClone(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	attachment := &closeRecorder{Reader: strings.NewReader("attachment data")}
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "original@test.com")
+	m.AddCC("cc@test.com")
+	m.AddBCC("bcc@test.com")
+	m.AddReaderAttachment("report.txt", attachment)
+	m.AddHeader("X-Campaign", "spring")
+	ensure.Nil(t, m.AddTag("newsletter"))
+	ensure.Nil(t, m.AddVariable("plan", "gold"))
+
+	c, err := m.Clone()
+	ensure.Nil(t, err)
+	ensure.True(t, attachment.isClosed())
+	ensure.DeepEqual(t, c.RecipientCount(), 0)
+	ensure.DeepEqual(t, m.RecipientCount(), 3)
+	ensure.DeepEqual(t, c.GetHeaders()["X-Campaign"], "spring")
+	ensure.DeepEqual(t, c.tags, []string{"newsletter"})
+	ensure.DeepEqual(t, c.variables, m.variables)
+	ensure.DeepEqual(t, c.specific.(*plainMessage).text, exampleText)
+
+	// Changes to the clone don't affect the original
+	ensure.Nil(t, c.AddRecipient("clone@test.com"))
+	c.SetHeader("X-Campaign", "summer")
+	ensure.Nil(t, c.AddTag("clone"))
+	ensure.DeepEqual(t, m.to, []string{"original@test.com"})
+	ensure.DeepEqual(t, m.GetHeaders()["X-Campaign"], "spring")
+	ensure.DeepEqual(t, m.tags, []string{"newsletter"})
+
+	// Both messages can read the attachment
+	for _, msg := range []*Message{m, c} {
+		data, err := ioutil.ReadAll(msg.readerAttachments[0].ReadCloser)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, string(data), "attachment data")
+	}
+}
+
+func TestGetStoredMessages(t *testing.T) {
+	var active, maxActive int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			max := atomic.LoadInt32(&maxActive)
+			if n <= max || atomic.CompareAndSwapInt32(&maxActive, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		id := path.Base(req.URL.Path)
+		if id == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"subject": "message %s"}`, id)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	ids := []string{"1", "2", "missing", "4", "5", "6"}
+	results := mg.GetStoredMessages(context.Background(), ids, 2)
+	ensure.DeepEqual(t, len(results), len(ids))
+	for i, r := range results {
+		ensure.DeepEqual(t, r.ID, ids[i])
+		if r.ID == "missing" {
+			ensure.DeepEqual(t, GetStatusFromErr(r.Err), http.StatusNotFound)
+			continue
+		}
+		ensure.Nil(t, r.Err)
+		ensure.DeepEqual(t, r.Message.Subject, "message "+r.ID)
+	}
+	ensure.True(t, atomic.LoadInt32(&maxActive) <= 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = mg.GetStoredMessages(ctx, ids, 2)
+	for _, r := range results {
+		ensure.NotNil(t, r.Err)
+	}
+}
+
+func TestDeleteScheduledMessages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.Method, http.MethodDelete)
+		ensure.DeepEqual(t, req.URL.Path, "/"+exampleDomain+"/envelopes")
+		fmt.Fprint(w, `{"message": "done"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	ensure.Nil(t, mg.DeleteScheduledMessages(context.Background()))
+}
+
+func TestGetStoredMessageMIME(t *testing.T) {
+	const mime = "From: sender@example.com\r\nSubject: Hello\r\n\r\nHello world\r\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.URL.Path, "/domains/"+exampleDomain+"/messages/abc")
+		ensure.DeepEqual(t, req.Header.Get("Accept"), "message/rfc2822")
+		fmt.Fprintf(w, `{"body-mime": %q}`, mime)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	rc, err := mg.GetStoredMessageMIME(context.Background(), "abc")
+	ensure.Nil(t, err)
+	defer rc.Close()
+	body, err := ioutil.ReadAll(rc)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(body), mime)
+}
+
+func TestGetStoredMessageMIMEStream(t *testing.T) {
+	const mime = `Subject: Caf\u00e9 \ud83d\ude00\r\n\r\n\"Hello\"\\world\r\n`
+	var raw bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if raw {
+			w.Header().Set("Content-Type", "message/rfc2822")
+			fmt.Fprint(w, "Subject: Raw\r\n\r\nbody\r\n")
+			return
+		}
+		// body-mime is not the first key, and is escaped as Mailgun escapes it
+		fmt.Fprintf(w, `{"recipients": "a@example.com", "headers": {"x": [1, "}"]}, "body-mime": "%s", "sender": "b"}`, mime)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	rc, err := mg.GetStoredMessageMIME(ctx, "abc")
+	ensure.Nil(t, err)
+	body, err := ioutil.ReadAll(rc)
+	ensure.Nil(t, err)
+	ensure.Nil(t, rc.Close())
+	ensure.DeepEqual(t, string(body), "Subject: Café 😀\r\n\r\n\"Hello\"\\world\r\n")
+
+	raw = true
+	rc, err = mg.GetStoredMessageMIME(ctx, "abc")
+	ensure.Nil(t, err)
+	body, err = ioutil.ReadAll(rc)
+	ensure.Nil(t, err)
+	ensure.Nil(t, rc.Close())
+	ensure.DeepEqual(t, string(body), "Subject: Raw\r\n\r\nbody\r\n")
+}
+
+func TestGetStoredAttachment(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, apiKey, _ := req.BasicAuth()
+		ensure.DeepEqual(t, apiKey, exampleAPIKey)
+		if req.URL.Path != "/messages/abc/attachments/0" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, "attachment data")
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	ctx := context.Background()
+
+	rc, err := mg.GetStoredAttachment(ctx, srv.URL+"/messages/abc/attachments/0")
+	ensure.Nil(t, err)
+	data, err := ioutil.ReadAll(rc)
+	ensure.Nil(t, err)
+	ensure.Nil(t, rc.Close())
+	ensure.DeepEqual(t, string(data), "attachment data")
+
+	_, err = mg.GetStoredAttachment(ctx, srv.URL+"/messages/abc/attachments/1")
+	ensure.DeepEqual(t, GetStatusFromErr(err), http.StatusNotFound)
+}
+
+func TestGetStoredMessageForURL(t *testing.T) {
+	// The storage host of the message is not the API base of the client
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, apiKey, _ := req.BasicAuth()
+		ensure.DeepEqual(t, apiKey, exampleAPIKey)
+		ensure.DeepEqual(t, req.URL.Path, "/v3/domains/"+exampleDomain+"/messages/abc")
+		fmt.Fprint(w, `{"subject": "Hello", "sender": "sender@example.com"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase("https://api.eu.mailgun.net/v3")
+
+	msg, err := mg.GetStoredMessageForURL(context.Background(), srv.URL+"/v3/domains/"+exampleDomain+"/messages/abc")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg.Subject, "Hello")
+	ensure.DeepEqual(t, msg.Sender, "sender@example.com")
+}
+
+func TestSendDeterministicPayload(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.Header.Get("Content-Type"), "multipart/form-data; boundary=golden-boundary")
+		body, err := ioutil.ReadAll(req.Body)
+		ensure.Nil(t, err)
+		bodies = append(bodies, string(body))
+		fmt.Fprint(w, `{"message": "Queued", "id": "<id@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	for i := 0; i < 5; i++ {
+		m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+		ensure.Nil(t, m.SetMultipartBoundary("golden-boundary"))
+		for _, name := range []string{"X-One", "X-Two", "X-Three", "X-Four"} {
+			m.AddHeader(name, "value")
+			ensure.Nil(t, m.AddVariable(name, "value"))
+		}
+		m.AddBufferAttachment("data.txt", []byte("data"))
+
+		_, _, err := mg.Send(context.Background(), m)
+		ensure.Nil(t, err)
+	}
+	for _, body := range bodies {
+		ensure.DeepEqual(t, body, bodies[0])
+	}
+	ensure.StringContains(t, bodies[0], "--golden-boundary\r\n")
+	ensure.True(t, strings.Index(bodies[0], `name="h:X-Four"`) < strings.Index(bodies[0], `name="h:X-One"`))
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	ensure.NotNil(t, m.SetMultipartBoundary("not a valid boundary "))
+}
@@ -0,0 +1,316 @@
+// Package mime assembles RFC 2822 MIME messages locally, so they can be sent with
+// NewMIMEMessage() without a third party MIME library.
+//
+//	b := mime.NewBuilder("Sender <sender@example.com>", "Your invoice")
+//	b.AddTo("customer@example.com")
+//	b.SetText("Your invoice is attached")
+//	b.SetHTML("<p>Your invoice is attached</p>")
+//	b.AddAttachment("invoice.pdf", "application/pdf", data)
+//
+//	body, err := b.Reader()
+//	...
+//	m := mg.NewMIMEMessage(body, "customer@example.com")
+package mime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The maximum length of a base64 encoded line, as required by RFC 2045
+const base64LineLength = 76
+
+type part struct {
+	filename    string
+	contentType string
+	contentID   string
+	data        []byte
+}
+
+// Builder assembles a MIME message. The zero value is not usable, create builders with NewBuilder().
+type Builder struct {
+	from        string
+	subject     string
+	to          []string
+	cc          []string
+	text        string
+	html        string
+	headers     textproto.MIMEHeader
+	attachments []part
+	inlines     []part
+	date        time.Time
+}
+
+// NewBuilder creates a builder for a message from the given sender with the given subject.
+func NewBuilder(from, subject string) *Builder {
+	return &Builder{
+		from:    from,
+		subject: subject,
+		headers: make(textproto.MIMEHeader),
+	}
+}
+
+// AddTo adds a recipient to the To: header.
+func (b *Builder) AddTo(address string) {
+	b.to = append(b.to, address)
+}
+
+// AddCC adds a recipient to the Cc: header.
+func (b *Builder) AddCC(address string) {
+	b.cc = append(b.cc, address)
+}
+
+// SetText sets the plain text body of the message.
+func (b *Builder) SetText(text string) {
+	b.text = text
+}
+
+// SetHTML sets the HTML body of the message. When both a text and an HTML body are set
+// the message is sent as multipart/alternative.
+func (b *Builder) SetHTML(html string) {
+	b.html = html
+}
+
+// SetDate sets the Date: header, which defaults to the time the message is built.
+func (b *Builder) SetDate(date time.Time) {
+	b.date = date
+}
+
+// AddHeader adds a custom header to the message.
+func (b *Builder) AddHeader(name, value string) {
+	b.headers.Add(name, value)
+}
+
+// AddAttachment attaches data to the message as a file with the given name. If contentType
+// is empty it is guessed from the filename extension.
+func (b *Builder) AddAttachment(filename, contentType string, data []byte) {
+	b.attachments = append(b.attachments, part{filename: filename, contentType: contentType, data: data})
+}
+
+// AddInline adds data which the HTML body references as "cid:<contentID>", such as images.
+// If contentType is empty it is guessed from the contentID extension.
+func (b *Builder) AddInline(contentID, contentType string, data []byte) {
+	b.inlines = append(b.inlines, part{filename: contentID, contentType: contentType, contentID: contentID, data: data})
+}
+
+// Reader builds the message and returns it in a form which can be passed to NewMIMEMessage().
+func (b *Builder) Reader() (io.ReadCloser, error) {
+	msg, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(msg)), nil
+}
+
+// Build assembles the message. Returns an error if the sender or a recipient is not a valid address.
+func (b *Builder) Build() ([]byte, error) {
+	header := make(textproto.MIMEHeader)
+	for k, v := range b.headers {
+		header[k] = append([]string(nil), v...)
+	}
+
+	from, err := formatAddresses([]string{b.from})
+	if err != nil {
+		return nil, err
+	}
+	header.Set("From", from)
+	if len(b.to) != 0 {
+		to, err := formatAddresses(b.to)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("To", to)
+	}
+	if len(b.cc) != 0 {
+		cc, err := formatAddresses(b.cc)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Cc", cc)
+	}
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", b.subject))
+	date := b.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	header.Set("Date", date.Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	var body bytes.Buffer
+	if err := b.writeMixed(header, &body); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	writeHeader(&msg, header)
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// writeMixed writes the attachments and the related content in a multipart/mixed body
+func (b *Builder) writeMixed(header textproto.MIMEHeader, w io.Writer) error {
+	if len(b.attachments) == 0 {
+		return b.writeRelated(header, w)
+	}
+	mw := newMultipart(header, "multipart/mixed", w)
+	if err := writePart(mw, func(h textproto.MIMEHeader, w io.Writer) error { return b.writeRelated(h, w) }); err != nil {
+		return err
+	}
+	for _, a := range b.attachments {
+		if err := writeBinary(mw, a, "attachment"); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeRelated writes the inline parts and the bodies in a multipart/related body
+func (b *Builder) writeRelated(header textproto.MIMEHeader, w io.Writer) error {
+	if len(b.inlines) == 0 {
+		return b.writeAlternative(header, w)
+	}
+	mw := newMultipart(header, "multipart/related", w)
+	if err := writePart(mw, func(h textproto.MIMEHeader, w io.Writer) error { return b.writeAlternative(h, w) }); err != nil {
+		return err
+	}
+	for _, i := range b.inlines {
+		if err := writeBinary(mw, i, "inline"); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeAlternative writes the text and HTML bodies, as multipart/alternative if both are set
+func (b *Builder) writeAlternative(header textproto.MIMEHeader, w io.Writer) error {
+	switch {
+	case b.text != "" && b.html != "":
+		mw := newMultipart(header, "multipart/alternative", w)
+		for _, t := range []struct{ contentType, body string }{
+			{"text/plain", b.text},
+			{"text/html", b.html},
+		} {
+			t := t
+			err := writePart(mw, func(h textproto.MIMEHeader, w io.Writer) error {
+				return writeText(h, w, t.contentType, t.body)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return mw.Close()
+	case b.html != "":
+		return writeText(header, w, "text/html", b.html)
+	default:
+		return writeText(header, w, "text/plain", b.text)
+	}
+}
+
+// writePart writes a part whose headers are set by the content function
+func writePart(mw *multipart.Writer, content func(textproto.MIMEHeader, io.Writer) error) error {
+	h := make(textproto.MIMEHeader)
+	var body bytes.Buffer
+	if err := content(h, &body); err != nil {
+		return err
+	}
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// newMultipart returns a writer for a multipart body, setting the Content-Type of the header
+func newMultipart(header textproto.MIMEHeader, contentType string, w io.Writer) *multipart.Writer {
+	mw := multipart.NewWriter(w)
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"boundary": mw.Boundary()}))
+	return mw
+}
+
+func writeText(header textproto.MIMEHeader, w io.Writer, contentType, text string) error {
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeBinary(mw *multipart.Writer, p part, disposition string) error {
+	contentType := p.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(extension(p.filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": p.filename}))
+	if p.contentID != "" {
+		h.Set("Content-ID", "<"+p.contentID+">")
+	}
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(p.data)
+	for len(encoded) > base64LineLength {
+		if _, err := io.WriteString(w, encoded[:base64LineLength]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+// writeHeader writes the header in a stable order followed by the blank line which ends it
+func writeHeader(w io.Writer, header textproto.MIMEHeader) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", k, v)
+		}
+	}
+	io.WriteString(w, "\r\n")
+}
+
+func formatAddresses(addresses []string) (string, error) {
+	var formatted []string
+	for _, a := range addresses {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return "", fmt.Errorf("invalid address '%s': %s", a, err)
+		}
+		formatted = append(formatted, addr.String())
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func extension(filename string) string {
+	if i := strings.LastIndex(filename, "."); i != -1 {
+		return filename[i:]
+	}
+	return ""
+}
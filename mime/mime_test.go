@@ -0,0 +1,115 @@
+package mime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder("Café <sender@example.com>", "Your invoice")
+	b.AddTo("customer@example.com")
+	b.AddCC("Accounts <accounts@example.com>")
+	b.SetText("Your invoice is attached")
+	b.SetHTML(`<p>Your invoice is attached <img src="cid:logo.png"></p>`)
+	b.AddHeader("X-Campaign", "invoices")
+	b.AddInline("logo.png", "", []byte("png data"))
+	b.AddAttachment("invoice.pdf", "application/pdf", []byte(strings.Repeat("pdf data ", 20)))
+	b.SetDate(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	data, err := b.Build()
+	ensure.Nil(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg.Header.Get("From"), "=?utf-8?q?Caf=C3=A9?= <sender@example.com>")
+	ensure.DeepEqual(t, msg.Header.Get("To"), "<customer@example.com>")
+	ensure.DeepEqual(t, msg.Header.Get("Cc"), `"Accounts" <accounts@example.com>`)
+	ensure.DeepEqual(t, msg.Header.Get("Subject"), "Your invoice")
+	ensure.DeepEqual(t, msg.Header.Get("Date"), "Wed, 02 Jan 2019 03:04:05 +0000")
+	ensure.DeepEqual(t, msg.Header.Get("X-Campaign"), "invoices")
+
+	// multipart/mixed containing multipart/related and the attachment
+	mixed := readParts(t, msg.Header.Get("Content-Type"), msg.Body, "multipart/mixed")
+	ensure.DeepEqual(t, len(mixed), 2)
+	ensure.DeepEqual(t, mixed[1].contentType, "application/pdf")
+	ensure.DeepEqual(t, mixed[1].disposition, "attachment")
+	ensure.DeepEqual(t, mixed[1].body, strings.Repeat("pdf data ", 20))
+
+	// multipart/related containing multipart/alternative and the inline
+	related := readParts(t, mixed[0].header, strings.NewReader(mixed[0].body), "multipart/related")
+	ensure.DeepEqual(t, len(related), 2)
+	ensure.DeepEqual(t, related[1].contentType, "image/png")
+	ensure.DeepEqual(t, related[1].contentID, "<logo.png>")
+	ensure.DeepEqual(t, related[1].body, "png data")
+
+	alternative := readParts(t, related[0].header, strings.NewReader(related[0].body), "multipart/alternative")
+	ensure.DeepEqual(t, len(alternative), 2)
+	ensure.DeepEqual(t, alternative[0].contentType, "text/plain")
+	ensure.DeepEqual(t, alternative[0].body, "Your invoice is attached")
+	ensure.DeepEqual(t, alternative[1].contentType, "text/html")
+	ensure.DeepEqual(t, alternative[1].body, `<p>Your invoice is attached <img src="cid:logo.png"></p>`)
+}
+
+func TestBuilderTextOnly(t *testing.T) {
+	b := NewBuilder("sender@example.com", "Hello")
+	b.SetText("Hello world")
+
+	rc, err := b.Reader()
+	ensure.Nil(t, err)
+	msg, err := mail.ReadMessage(rc)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, msg.Header.Get("Content-Type"), "text/plain; charset=utf-8")
+	ensure.DeepEqual(t, msg.Header.Get("To"), "")
+
+	_, err = NewBuilder("not an address", "Hello").Build()
+	ensure.NotNil(t, err)
+}
+
+type testPart struct {
+	header      string
+	contentType string
+	disposition string
+	contentID   string
+	body        string
+}
+
+func readParts(t *testing.T, contentType string, body io.Reader, expected string) []testPart {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, mediaType, expected)
+
+	var parts []testPart
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(p)
+		ensure.Nil(t, err)
+		partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			data, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data)))
+			ensure.Nil(t, err)
+		}
+		parts = append(parts, testPart{
+			header:      p.Header.Get("Content-Type"),
+			contentType: partType,
+			disposition: disposition,
+			contentID:   p.Header.Get("Content-Id"),
+			body:        string(data),
+		})
+	}
+	return parts
+}
@@ -24,6 +24,13 @@ type MockServer struct {
 	mailingList []mailingListContainer
 	routeList   []Route
 	events      []Event
+	templates   []Template
+
+	bulkValidations []BulkValidation
+	bulkPreviews    []BulkValidation
+	bulkResults     map[string][]BulkValidationResult
+
+	templateVersions map[string][]TemplateVersion
 }
 
 // Create a new instance of the mailgun API mock server
@@ -42,6 +49,10 @@ func NewMockServer() MockServer {
 		ms.addMessagesRoutes(r)
 		ms.addValidationRoutes(r)
 		ms.addRoutes(r)
+		ms.addTemplateRoutes(r)
+	})
+	r.Route("/v4", func(r chi.Router) {
+		ms.addValidationV4Routes(r)
 	})
 
 	// Start the server
@@ -14,6 +14,7 @@ type domainContainer struct {
 	Connection          *DomainConnection `json:"connection,omitempty"`
 	Tracking            *DomainTracking   `json:"tracking,omitempty"`
 	TagLimits           *TagLimits        `json:"limits,omitempty"`
+	SendingQueues       *SendingQueues    `json:"sending_queues,omitempty"`
 }
 
 func (ms *MockServer) addDomainRoutes(r chi.Router) {
@@ -36,6 +37,17 @@ func (ms *MockServer) addDomainRoutes(r chi.Router) {
 			Limit: 50000,
 			Count: 5000,
 		},
+		SendingQueues: &SendingQueues{
+			Regular: SendingQueue{Size: 12},
+			Scheduled: SendingQueue{
+				Size:       3,
+				IsDisabled: true,
+				Disabled: QueueDisabledBy{
+					Until:  "Fri, 04 Jan 2019 10:00:00 UTC",
+					Reason: "sending limit exceeded",
+				},
+			},
+		},
 		Tracking: &DomainTracking{
 			Click: TrackingStatus{Active: true},
 			Open:  TrackingStatus{Active: true},
@@ -93,8 +105,9 @@ func (ms *MockServer) addDomainRoutes(r chi.Router) {
 	r.Get("/domains/{domain}/connection", ms.getConnection)
 	r.Put("/domains/{domain}/connection", ms.updateConnection)
 	r.Get("/domains/{domain}/tracking", ms.getTracking)
-	//r.Put("/domains/{domain}/tracking/{type}", ms.updateTracking)
+	r.Put("/domains/{domain}/tracking/{type}", ms.updateTracking)
 	r.Get("/domains/{domain}/limits/tag", ms.getTagLimits)
+	r.Get("/domains/{domain}/sending_queues", ms.getSendingQueues)
 }
 
 func (ms *MockServer) listDomains(w http.ResponseWriter, r *http.Request) {
@@ -146,6 +159,10 @@ func (ms *MockServer) getDomain(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ms *MockServer) createDomain(w http.ResponseWriter, r *http.Request) {
+	webScheme := r.FormValue("web_scheme")
+	if webScheme == "" {
+		webScheme = "http"
+	}
 	ms.domainList = append(ms.domainList, domainContainer{
 		Domain: Domain{
 			CreatedAt:    RFC2822Time(time.Now()),
@@ -155,6 +172,7 @@ func (ms *MockServer) createDomain(w http.ResponseWriter, r *http.Request) {
 			Wildcard:     stringToBool(r.FormValue("wildcard")),
 			SpamAction:   SpamAction(r.FormValue("spam_action")),
 			State:        "active",
+			WebScheme:    webScheme,
 		},
 	})
 	toJSON(w, okResp{Message: "Domain has been created"})
@@ -222,6 +240,43 @@ func (ms *MockServer) getTracking(w http.ResponseWriter, r *http.Request) {
 	toJSON(w, okResp{Message: "domain not found"})
 }
 
+func (ms *MockServer) updateTracking(w http.ResponseWriter, r *http.Request) {
+	for i := range ms.domainList {
+		d := &ms.domainList[i]
+		if d.Domain.Name != chi.URLParam(r, "domain") {
+			continue
+		}
+		if d.Tracking == nil {
+			d.Tracking = &DomainTracking{}
+		}
+		var status *TrackingStatus
+		switch chi.URLParam(r, "type") {
+		case "open":
+			status = &d.Tracking.Open
+		case "click":
+			status = &d.Tracking.Click
+		case "unsubscribe":
+			status = &d.Tracking.Unsubscribe
+			if footer := r.FormValue("html_footer"); footer != "" {
+				status.HTMLFooter = footer
+			}
+			if footer := r.FormValue("text_footer"); footer != "" {
+				status.TextFooter = footer
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			toJSON(w, okResp{Message: "unknown tracking type"})
+			return
+		}
+		// The htmlonly click tracking mode is reported as active
+		status.Active = r.FormValue("active") != "no"
+		toJSON(w, okResp{Message: "Domain tracking settings have been updated"})
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "domain not found"})
+}
+
 func (ms *MockServer) getTagLimits(w http.ResponseWriter, r *http.Request) {
 	for _, d := range ms.domainList {
 		if d.Domain.Name == chi.URLParam(r, "domain") {
@@ -237,3 +292,14 @@ func (ms *MockServer) getTagLimits(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 	toJSON(w, okResp{Message: "domain not found"})
 }
+
+func (ms *MockServer) getSendingQueues(w http.ResponseWriter, r *http.Request) {
+	for _, d := range ms.domainList {
+		if d.Domain.Name == chi.URLParam(r, "domain") {
+			toJSON(w, d.SendingQueues)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "domain not found"})
+}
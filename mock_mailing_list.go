@@ -1,9 +1,11 @@
 package mailgun
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -27,6 +29,7 @@ func (ms *MockServer) addMailingListRoutes(r chi.Router) {
 	r.Put("/lists/{address}/members/{member}", ms.updateMember)
 	r.Delete("/lists/{address}/members/{member}", ms.deleteMember)
 	r.Post("/lists/{address}/members.json", ms.bulkCreate)
+	r.Post("/lists/{address}/members.csv", ms.bulkCreateCSV)
 
 	ms.mailingList = append(ms.mailingList, mailingListContainer{
 		MailingList: MailingList{
@@ -134,7 +137,10 @@ func (ms *MockServer) updateMailingList(w http.ResponseWriter, r *http.Request)
 			if r.FormValue("access_level") != "" {
 				ms.mailingList[i].MailingList.AccessLevel = AccessLevel(r.FormValue("access_level"))
 			}
-			toJSON(w, okResp{Message: "Mailing list member has been updated"})
+			toJSON(w, map[string]interface{}{
+				"message": "Mailing list has been updated",
+				"list":    ms.mailingList[i].MailingList,
+			})
 			return
 		}
 	}
@@ -143,16 +149,21 @@ func (ms *MockServer) updateMailingList(w http.ResponseWriter, r *http.Request)
 }
 
 func (ms *MockServer) createMailingList(w http.ResponseWriter, r *http.Request) {
-	ms.mailingList = append(ms.mailingList, mailingListContainer{
-		MailingList: MailingList{
-			CreatedAt:   RFC2822Time(time.Now().UTC()),
-			Name:        r.FormValue("name"),
-			Address:     r.FormValue("address"),
-			Description: r.FormValue("description"),
-			AccessLevel: AccessLevel(r.FormValue("access_level")),
-		},
+	list := MailingList{
+		CreatedAt:   RFC2822Time(time.Now().UTC()),
+		Name:        r.FormValue("name"),
+		Address:     r.FormValue("address"),
+		Description: r.FormValue("description"),
+		AccessLevel: AccessLevel(r.FormValue("access_level")),
+	}
+	if list.AccessLevel == "" {
+		list.AccessLevel = AccessLevelEveryone
+	}
+	ms.mailingList = append(ms.mailingList, mailingListContainer{MailingList: list})
+	toJSON(w, map[string]interface{}{
+		"message": "Mailing list has been created",
+		"list":    list,
 	})
-	toJSON(w, okResp{Message: "Mailing list has been created"})
 }
 
 func (ms *MockServer) listMembers(w http.ResponseWriter, r *http.Request) {
@@ -164,6 +175,11 @@ func (ms *MockServer) listMembers(w http.ResponseWriter, r *http.Request) {
 		if ml.MailingList.Address == chi.URLParam(r, "address") {
 			found = true
 			for _, member := range ml.Members {
+				// Members are subscribed unless specified otherwise
+				subscribed := member.Subscribed == nil || *member.Subscribed
+				if r.FormValue("subscribed") != "" && r.FormValue("subscribed") != yesNo(subscribed) {
+					continue
+				}
 				list = append(list, member)
 				idx = append(idx, member.Address)
 			}
@@ -188,19 +204,25 @@ func (ms *MockServer) listMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pageURL := func(params url.Values) string {
+		if r.FormValue("subscribed") != "" {
+			params.Add("subscribed", r.FormValue("subscribed"))
+		}
+		return getPageURL(r, params)
+	}
 	resp := memberListResponse{
 		Paging: Paging{
-			First: getPageURL(r, url.Values{
+			First: pageURL(url.Values{
 				"page": []string{"first"},
 			}),
-			Last: getPageURL(r, url.Values{
+			Last: pageURL(url.Values{
 				"page": []string{"last"},
 			}),
-			Next: getPageURL(r, url.Values{
+			Next: pageURL(url.Values{
 				"page":    []string{"next"},
 				"address": []string{results[len(results)-1].Address},
 			}),
-			Previous: getPageURL(r, url.Values{
+			Previous: pageURL(url.Values{
 				"page":    []string{"prev"},
 				"address": []string{results[0].Address},
 			}),
@@ -317,23 +339,24 @@ func (ms *MockServer) createMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sub := stringToBool(r.FormValue("subscribed"))
+	// Members are subscribed unless specified otherwise
+	sub := r.FormValue("subscribed") == "" || stringToBool(r.FormValue("subscribed"))
 
-	if len(ms.mailingList[idx].Members) != 0 {
-		for i, m := range ms.mailingList[idx].Members {
-			if m.Address == r.FormValue("address") {
-				if !stringToBool(r.FormValue("upsert")) {
-					w.WriteHeader(http.StatusConflict)
-					toJSON(w, okResp{Message: "member already exists"})
-					return
-				}
+	for i, m := range ms.mailingList[idx].Members {
+		if m.Address == parseAddress(r.FormValue("address")) {
+			if !stringToBool(r.FormValue("upsert")) {
+				w.WriteHeader(http.StatusConflict)
+				toJSON(w, okResp{Message: "member already exists"})
+				return
+			}
 
-				ms.mailingList[idx].Members[i].Address = parseAddress(r.FormValue("address"))
-				ms.mailingList[idx].Members[i].Name = r.FormValue("name")
-				ms.mailingList[idx].Members[i].Vars = stringToMap(r.FormValue("vars"))
+			ms.mailingList[idx].Members[i].Name = r.FormValue("name")
+			ms.mailingList[idx].Members[i].Vars = stringToMap(r.FormValue("vars"))
+			if r.FormValue("subscribed") != "" {
 				ms.mailingList[idx].Members[i].Subscribed = &sub
-				break
 			}
+			toJSON(w, okResp{Message: "Mailing list member has been updated"})
+			return
 		}
 	}
 
@@ -347,13 +370,7 @@ func (ms *MockServer) createMember(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ms *MockServer) bulkCreate(w http.ResponseWriter, r *http.Request) {
-	idx := -1
-	for i, ml := range ms.mailingList {
-		if ml.MailingList.Address == chi.URLParam(r, "address") {
-			idx = i
-		}
-	}
-
+	idx := ms.mailingListIndex(chi.URLParam(r, "address"))
 	if idx == -1 {
 		w.WriteHeader(http.StatusNotFound)
 		toJSON(w, okResp{Message: "mailing list not found"})
@@ -366,24 +383,96 @@ func (ms *MockServer) bulkCreate(w http.ResponseWriter, r *http.Request) {
 		toJSON(w, okResp{Message: "while un-marshalling 'members' param - " + err.Error()})
 		return
 	}
+	if len(bulkList) > MaxMembersPerRequest {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "too many members, the limit is 1000"})
+		return
+	}
+	ms.addMembers(w, r, idx, bulkList)
+}
+
+func (ms *MockServer) bulkCreateCSV(w http.ResponseWriter, r *http.Request) {
+	idx := ms.mailingListIndex(chi.URLParam(r, "address"))
+	if idx == -1 {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "mailing list not found"})
+		return
+	}
+
+	file, _, err := r.FormFile("members")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "'members' parameter is required"})
+		return
+	}
+	defer file.Close()
 
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(rows) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "invalid csv file"})
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "csv file has no 'address' column"})
+		return
+	}
+	value := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var bulkList []Member
+	for _, row := range rows[1:] {
+		member := Member{
+			Address: value(row, "address"),
+			Name:    value(row, "name"),
+			Vars:    stringToMap(value(row, "vars")),
+		}
+		if sub := value(row, "subscribed"); sub != "" {
+			subscribed := stringToBool(sub)
+			member.Subscribed = &subscribed
+		}
+		bulkList = append(bulkList, member)
+	}
+	ms.addMembers(w, r, idx, bulkList)
+}
+
+// addMembers adds members to the mailing list at idx, updating existing members if the
+// upsert parameter of the request is set
+func (ms *MockServer) addMembers(w http.ResponseWriter, r *http.Request, idx int, members []Member) {
 BULK:
-	for _, member := range bulkList {
+	for _, member := range members {
 		member.Address = parseAddress(member.Address)
-		if len(ms.mailingList[idx].Members) != 0 {
-			for i, m := range ms.mailingList[idx].Members {
-				if m.Address == member.Address {
-					if !stringToBool(r.FormValue("upsert")) {
-						w.WriteHeader(http.StatusConflict)
-						toJSON(w, okResp{Message: "member already exists"})
-						return
-					}
-					ms.mailingList[idx].Members[i] = member
-					continue BULK
+		for i, m := range ms.mailingList[idx].Members {
+			if m.Address == member.Address {
+				if !stringToBool(r.FormValue("upsert")) {
+					w.WriteHeader(http.StatusConflict)
+					toJSON(w, okResp{Message: "member already exists"})
+					return
 				}
+				ms.mailingList[idx].Members[i] = member
+				continue BULK
 			}
 		}
 		ms.mailingList[idx].Members = append(ms.mailingList[idx].Members, member)
 	}
 	toJSON(w, okResp{Message: "Mailing list has been updated"})
 }
+
+// mailingListIndex returns the index of a mailing list, or -1 if it does not exist
+func (ms *MockServer) mailingListIndex(address string) int {
+	for i, ml := range ms.mailingList {
+		if ml.MailingList.Address == address {
+			return i
+		}
+	}
+	return -1
+}
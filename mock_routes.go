@@ -14,6 +14,7 @@ type routeResponse struct {
 func (ms *MockServer) addRoutes(r chi.Router) {
 	r.Post("/routes", ms.createRoute)
 	r.Get("/routes", ms.listRoutes)
+	r.Get("/routes/match", ms.matchRoute)
 	r.Get("/routes/{id}", ms.getRoute)
 	r.Put("/routes/{id}", ms.updateRoute)
 	r.Delete("/routes/{id}", ms.deleteRoute)
@@ -74,6 +75,22 @@ func (ms *MockServer) getRoute(w http.ResponseWriter, r *http.Request) {
 	toJSON(w, okResp{Message: "route not found"})
 }
 
+func (ms *MockServer) matchRoute(w http.ResponseWriter, r *http.Request) {
+	sim, err := NewRouteSimulator(ms.routeList)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		toJSON(w, okResp{Message: err.Error()})
+		return
+	}
+	result := sim.Simulate(SampleMessage{Recipient: r.FormValue("address")})
+	if len(result.Matched) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "Route not found"})
+		return
+	}
+	toJSON(w, routeResponse{Route: result.Matched[0]})
+}
+
 func (ms *MockServer) createRoute(w http.ResponseWriter, r *http.Request) {
 	if r.FormValue("action") == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -120,6 +137,7 @@ func (ms *MockServer) updateRoute(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ms *MockServer) deleteRoute(w http.ResponseWriter, r *http.Request) {
+	count := len(ms.routeList)
 	result := ms.routeList[:0]
 	for _, item := range ms.routeList {
 		if item.Id == chi.URLParam(r, "id") {
@@ -128,7 +146,7 @@ func (ms *MockServer) deleteRoute(w http.ResponseWriter, r *http.Request) {
 		result = append(result, item)
 	}
 
-	if len(result) != len(ms.domainList) {
+	if len(result) != count {
 		toJSON(w, okResp{Message: "success"})
 		ms.routeList = result
 		return
@@ -0,0 +1,332 @@
+package mailgun
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+func (ms *MockServer) addTemplateRoutes(r chi.Router) {
+	r.Get("/{domain}/templates", ms.listTemplates)
+	r.Get("/{domain}/templates/{name}", ms.getTemplate)
+	r.Post("/{domain}/templates", ms.createTemplate)
+	r.Put("/{domain}/templates/{name}", ms.updateTemplate)
+	r.Delete("/{domain}/templates/{name}", ms.deleteTemplate)
+
+	r.Get("/{domain}/templates/{name}/versions", ms.listTemplateVersions)
+	r.Get("/{domain}/templates/{name}/versions/{tag}", ms.getTemplateVersion)
+	r.Post("/{domain}/templates/{name}/versions", ms.createTemplateVersion)
+	r.Put("/{domain}/templates/{name}/versions/{tag}", ms.updateTemplateVersion)
+	r.Delete("/{domain}/templates/{name}/versions/{tag}", ms.deleteTemplateVersion)
+
+	ms.templateVersions = make(map[string][]TemplateVersion)
+
+	ms.templates = append(ms.templates, Template{
+		Name:        "template1",
+		Description: "Sample template",
+		CreatedAt:   RFC2822Time(time.Now().UTC()),
+	})
+}
+
+func (ms *MockServer) listTemplates(w http.ResponseWriter, r *http.Request) {
+	var idx []string
+	for _, t := range ms.templates {
+		idx = append(idx, t.Name)
+	}
+
+	limit := stringToInt(r.FormValue("limit"))
+	if limit == 0 {
+		limit = 100
+	}
+	start, end := pageOffsets(idx, r.FormValue("page"), r.FormValue("p"), limit)
+	results := ms.templates[start:end]
+
+	if len(results) == 0 {
+		toJSON(w, templateListResp{})
+		return
+	}
+
+	toJSON(w, templateListResp{
+		Paging: Paging{
+			First: getPageURL(r, url.Values{
+				"page": []string{"first"},
+			}),
+			Last: getPageURL(r, url.Values{
+				"page": []string{"last"},
+			}),
+			Next: getPageURL(r, url.Values{
+				"page": []string{"next"},
+				"p":    []string{results[len(results)-1].Name},
+			}),
+			Previous: getPageURL(r, url.Values{
+				"page": []string{"prev"},
+				"p":    []string{results[0].Name},
+			}),
+		},
+		Items: results,
+	})
+}
+
+func (ms *MockServer) getTemplate(w http.ResponseWriter, r *http.Request) {
+	for _, t := range ms.templates {
+		if t.Name == chi.URLParam(r, "name") {
+			for _, v := range ms.templateVersions[t.Name] {
+				if v.Active {
+					t.Version = v
+				}
+			}
+			toJSON(w, templateResp{Item: t})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "template not found"})
+}
+
+func (ms *MockServer) createTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("name") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "'name' parameter is required"})
+		return
+	}
+
+	for _, t := range ms.templates {
+		if t.Name == r.FormValue("name") {
+			w.WriteHeader(http.StatusConflict)
+			toJSON(w, okResp{Message: "template already exists"})
+			return
+		}
+	}
+
+	template := Template{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		CreatedAt:   RFC2822Time(time.Now().UTC()),
+	}
+	ms.templates = append(ms.templates, template)
+
+	if r.FormValue("template") != "" {
+		template.Version = TemplateVersion{
+			Tag:       r.FormValue("tag"),
+			Template:  r.FormValue("template"),
+			Engine:    TemplateEngine(r.FormValue("engine")),
+			Comment:   r.FormValue("comment"),
+			CreatedAt: RFC2822Time(time.Now().UTC()),
+			Active:    true,
+		}
+		if template.Version.Tag == "" {
+			template.Version.Tag = "initial"
+		}
+		ms.templateVersions[template.Name] = []TemplateVersion{template.Version}
+	}
+	toJSON(w, templateResp{Message: "template has been stored", Item: template})
+}
+
+func (ms *MockServer) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	for i, t := range ms.templates {
+		if t.Name == chi.URLParam(r, "name") {
+			if r.FormValue("description") != "" {
+				ms.templates[i].Description = r.FormValue("description")
+			}
+			toJSON(w, templateResp{Message: "template has been updated", Item: ms.templates[i]})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "template not found"})
+}
+
+func (ms *MockServer) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	result := ms.templates[:0]
+	for _, t := range ms.templates {
+		if t.Name == chi.URLParam(r, "name") {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	if len(result) != len(ms.templates) {
+		ms.templates = result
+		delete(ms.templateVersions, chi.URLParam(r, "name"))
+		toJSON(w, okResp{Message: "template has been deleted"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "template not found"})
+}
+
+func (ms *MockServer) findTemplate(name string) (Template, bool) {
+	for _, t := range ms.templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+func (ms *MockServer) listTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	template, ok := ms.findTemplate(chi.URLParam(r, "name"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "template not found"})
+		return
+	}
+
+	var idx []string
+	versions := ms.templateVersions[template.Name]
+	for _, v := range versions {
+		idx = append(idx, v.Tag)
+	}
+
+	limit := stringToInt(r.FormValue("limit"))
+	if limit == 0 {
+		limit = 100
+	}
+	start, end := pageOffsets(idx, r.FormValue("page"), r.FormValue("p"), limit)
+	results := versions[start:end]
+
+	var resp templateVersionListResp
+	resp.Template.Template = template
+	if len(results) == 0 {
+		toJSON(w, resp)
+		return
+	}
+
+	resp.Template.Versions = results
+	resp.Paging = Paging{
+		First: getPageURL(r, url.Values{
+			"page": []string{"first"},
+		}),
+		Last: getPageURL(r, url.Values{
+			"page": []string{"last"},
+		}),
+		Next: getPageURL(r, url.Values{
+			"page": []string{"next"},
+			"p":    []string{results[len(results)-1].Tag},
+		}),
+		Previous: getPageURL(r, url.Values{
+			"page": []string{"prev"},
+			"p":    []string{results[0].Tag},
+		}),
+	}
+	toJSON(w, resp)
+}
+
+func (ms *MockServer) getTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	template, ok := ms.findTemplate(chi.URLParam(r, "name"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "template not found"})
+		return
+	}
+
+	for _, v := range ms.templateVersions[template.Name] {
+		if v.Tag == chi.URLParam(r, "tag") {
+			template.Version = v
+			toJSON(w, templateResp{Item: template})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "version not found"})
+}
+
+func (ms *MockServer) createTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	template, ok := ms.findTemplate(chi.URLParam(r, "name"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "template not found"})
+		return
+	}
+
+	if r.FormValue("tag") == "" || r.FormValue("template") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "'tag' and 'template' parameters are required"})
+		return
+	}
+
+	versions := ms.templateVersions[template.Name]
+	for _, v := range versions {
+		if v.Tag == r.FormValue("tag") {
+			w.WriteHeader(http.StatusConflict)
+			toJSON(w, okResp{Message: "version already exists"})
+			return
+		}
+	}
+
+	// The first version of a template is always active
+	active := stringToBool(r.FormValue("active")) || len(versions) == 0
+	if active {
+		for i := range versions {
+			versions[i].Active = false
+		}
+	}
+
+	template.Version = TemplateVersion{
+		Tag:       r.FormValue("tag"),
+		Template:  r.FormValue("template"),
+		Engine:    TemplateEngine(r.FormValue("engine")),
+		Comment:   r.FormValue("comment"),
+		CreatedAt: RFC2822Time(time.Now().UTC()),
+		Active:    active,
+	}
+	ms.templateVersions[template.Name] = append(versions, template.Version)
+	toJSON(w, templateResp{Message: "new version of the template has been stored", Item: template})
+}
+
+func (ms *MockServer) updateTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	template, ok := ms.findTemplate(chi.URLParam(r, "name"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		toJSON(w, okResp{Message: "template not found"})
+		return
+	}
+
+	versions := ms.templateVersions[template.Name]
+	for i, v := range versions {
+		if v.Tag == chi.URLParam(r, "tag") {
+			if r.FormValue("template") != "" {
+				versions[i].Template = r.FormValue("template")
+			}
+			if r.FormValue("comment") != "" {
+				versions[i].Comment = r.FormValue("comment")
+			}
+			if stringToBool(r.FormValue("active")) {
+				for j := range versions {
+					versions[j].Active = false
+				}
+				versions[i].Active = true
+			}
+			template.Version = versions[i]
+			toJSON(w, templateResp{Message: "version has been updated", Item: template})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "version not found"})
+}
+
+func (ms *MockServer) deleteTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	versions := ms.templateVersions[name]
+
+	result := versions[:0]
+	for _, v := range versions {
+		if v.Tag == chi.URLParam(r, "tag") {
+			continue
+		}
+		result = append(result, v)
+	}
+
+	if len(result) != len(versions) {
+		ms.templateVersions[name] = result
+		toJSON(w, okResp{Message: "version has been deleted"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "version not found"})
+}
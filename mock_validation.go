@@ -1,9 +1,14 @@
 package mailgun
 
 import (
+	"compress/gzip"
+	"encoding/csv"
+	"io"
 	"net/http"
 	"net/mail"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 )
@@ -15,6 +20,21 @@ func (ms *MockServer) addValidationRoutes(r chi.Router) {
 	r.Get("/address/private/parse", ms.parseEmail)
 }
 
+func (ms *MockServer) addValidationV4Routes(r chi.Router) {
+	ms.bulkResults = make(map[string][]BulkValidationResult)
+	r.Get("/address/validate", ms.validateEmailV4)
+	r.Get("/address/validate/bulk", ms.listBulkValidations)
+	r.Post("/address/validate/bulk/{id}", ms.createBulkValidation)
+	r.Get("/address/validate/bulk/{id}", ms.getBulkValidation)
+	r.Delete("/address/validate/bulk/{id}", ms.deleteBulkValidation)
+	r.Get("/address/validate/bulk/{id}/result.csv.gz", ms.downloadBulkValidation)
+	r.Get("/address/validate/preview", ms.listBulkPreviews)
+	r.Post("/address/validate/preview/{id}", ms.createBulkPreview)
+	r.Get("/address/validate/preview/{id}", ms.getBulkPreview)
+	r.Put("/address/validate/preview/{id}", ms.promoteBulkPreview)
+	r.Delete("/address/validate/preview/{id}", ms.deleteBulkPreview)
+}
+
 func (ms *MockServer) validateEmail(w http.ResponseWriter, r *http.Request) {
 	if r.FormValue("address") == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -33,6 +53,31 @@ func (ms *MockServer) validateEmail(w http.ResponseWriter, r *http.Request) {
 	toJSON(w, results)
 }
 
+func (ms *MockServer) validateEmailV4(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("address") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "'address' parameter is required"})
+		return
+	}
+
+	results := EmailValidation{
+		Address: r.FormValue("address"),
+		Reason:  []string{},
+		Result:  ValidationDeliverable,
+		Risk:    RiskLow,
+	}
+	parts, err := mail.ParseAddress(r.FormValue("address"))
+	if err != nil {
+		results.Reason = append(results.Reason, "malformed address")
+		results.Result = ValidationUndeliverable
+		results.Risk = RiskHigh
+	} else if strings.HasPrefix(parts.Address, "postmaster@") {
+		results.IsRoleAddress = true
+		results.Risk = RiskMedium
+	}
+	toJSON(w, results)
+}
+
 func (ms *MockServer) parseEmail(w http.ResponseWriter, r *http.Request) {
 	if r.FormValue("addresses") == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -53,3 +98,254 @@ func (ms *MockServer) parseEmail(w http.ResponseWriter, r *http.Request) {
 	}
 	toJSON(w, results)
 }
+
+func (ms *MockServer) createBulkValidation(w http.ResponseWriter, r *http.Request) {
+	job, results, ok := validateBulkFile(w, r)
+	if !ok {
+		return
+	}
+	job.DownloadURL.CSV = "http://" + r.Host + r.URL.EscapedPath() + "/result.csv.gz"
+	ms.bulkValidations = replaceBulkValidation(ms.bulkValidations, job)
+	ms.bulkResults[job.Id] = results
+	toJSON(w, map[string]string{
+		"id":      job.Id,
+		"message": "The validation job was submitted.",
+	})
+}
+
+// validateBulkFile validates the addresses of the uploaded file of a bulk validation or preview
+// request, and returns the job with the result of each address. Writes an error response and
+// returns false if the request is invalid.
+func validateBulkFile(w http.ResponseWriter, r *http.Request) (BulkValidation, []BulkValidationResult, bool) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		toJSON(w, okResp{Message: "'file' parameter is required"})
+		return BulkValidation{}, nil, false
+	}
+	defer file.Close()
+
+	job := BulkValidation{
+		Id:        chi.URLParam(r, "id"),
+		CreatedAt: time.Now().Unix(),
+		Status:    "uploaded",
+	}
+	rows := csv.NewReader(file)
+	rows.FieldsPerRecord = -1
+	column := -1
+	var results []BulkValidationResult
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			toJSON(w, okResp{Message: "invalid csv file: " + err.Error()})
+			return BulkValidation{}, nil, false
+		}
+		if column == -1 {
+			for i, name := range row {
+				if strings.EqualFold(strings.TrimSpace(name), "email") {
+					column = i
+				}
+			}
+			if column == -1 {
+				w.WriteHeader(http.StatusBadRequest)
+				toJSON(w, okResp{Message: "csv file has no 'email' column"})
+				return BulkValidation{}, nil, false
+			}
+			continue
+		}
+
+		job.Quantity++
+		job.RecordsProcessed++
+		result := BulkValidationResult{Result: "undeliverable", Risk: "high", Reason: "malformed address"}
+		if column < len(row) {
+			result.Address = row[column]
+			if _, err := mail.ParseAddress(row[column]); err == nil {
+				result = BulkValidationResult{Address: row[column], Result: "deliverable", Risk: "low"}
+			}
+		}
+		results = append(results, result)
+		if result.Result == "deliverable" {
+			job.Summary.Result.Deliverable++
+			job.Summary.Risk.Low++
+		} else {
+			job.Summary.Result.Undeliverable++
+			job.Summary.Risk.High++
+		}
+	}
+	return job, results, true
+}
+
+// replaceBulkValidation adds job to jobs, replacing a job with the same ID
+func replaceBulkValidation(jobs []BulkValidation, job BulkValidation) []BulkValidation {
+	for i, j := range jobs {
+		if j.Id == job.Id {
+			jobs = append(jobs[:i], jobs[i+1:]...)
+			break
+		}
+	}
+	return append(jobs, job)
+}
+
+func (ms *MockServer) getBulkValidation(w http.ResponseWriter, r *http.Request) {
+	for _, job := range ms.bulkValidations {
+		if job.Id == chi.URLParam(r, "id") {
+			toJSON(w, job)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "list not found"})
+}
+
+func (ms *MockServer) deleteBulkValidation(w http.ResponseWriter, r *http.Request) {
+	for i, job := range ms.bulkValidations {
+		if job.Id == chi.URLParam(r, "id") {
+			ms.bulkValidations = append(ms.bulkValidations[:i], ms.bulkValidations[i+1:]...)
+			delete(ms.bulkResults, job.Id)
+			toJSON(w, okResp{Message: "Validation job canceled."})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "list not found"})
+}
+
+// downloadBulkValidation serves the result file of a bulk validation job gzip compressed,
+// like the signed download urls of the real API
+func (ms *MockServer) downloadBulkValidation(w http.ResponseWriter, r *http.Request) {
+	results, ok := ms.bulkResults[chi.URLParam(r, "id")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	gz := gzip.NewWriter(w)
+	rows := csv.NewWriter(gz)
+	rows.Write([]string{"address", "is_role_address", "is_disposable_address", "did_you_mean", "result", "reason", "risk"})
+	for _, result := range results {
+		rows.Write([]string{result.Address, "false", "false", "", result.Result, result.Reason, result.Risk})
+	}
+	rows.Flush()
+	gz.Close()
+}
+
+func (ms *MockServer) listBulkValidations(w http.ResponseWriter, r *http.Request) {
+	var idx []string
+	for _, job := range ms.bulkValidations {
+		idx = append(idx, job.Id)
+	}
+
+	limit := stringToInt(r.FormValue("limit"))
+	if limit == 0 {
+		limit = 100
+	}
+	start, end := pageOffsets(idx, r.FormValue("page"), r.FormValue("p"), limit)
+	results := ms.bulkValidations[start:end]
+
+	if len(results) == 0 {
+		toJSON(w, bulkValidationsListResponse{Jobs: []BulkValidation{}, Total: len(idx)})
+		return
+	}
+
+	toJSON(w, bulkValidationsListResponse{
+		Paging: Paging{
+			First: getPageURL(r, url.Values{
+				"page": []string{"first"},
+			}),
+			Last: getPageURL(r, url.Values{
+				"page": []string{"last"},
+			}),
+			Next: getPageURL(r, url.Values{
+				"page": []string{"next"},
+				"p":    []string{results[len(results)-1].Id},
+			}),
+			Previous: getPageURL(r, url.Values{
+				"page": []string{"prev"},
+				"p":    []string{results[0].Id},
+			}),
+		},
+		Jobs:  results,
+		Total: len(idx),
+	})
+}
+
+func (ms *MockServer) createBulkPreview(w http.ResponseWriter, r *http.Request) {
+	job, _, ok := validateBulkFile(w, r)
+	if !ok {
+		return
+	}
+	ms.bulkPreviews = replaceBulkValidation(ms.bulkPreviews, job)
+	toJSON(w, map[string]string{
+		"id":      job.Id,
+		"message": "The bulk preview was submitted.",
+	})
+}
+
+// bulkPreview estimates the results of a validation job as percentages
+func bulkPreview(job BulkValidation) BulkPreview {
+	preview := BulkPreview{
+		Id:        job.Id,
+		CreatedAt: job.CreatedAt,
+		Quantity:  job.Quantity,
+		Status:    "preview_complete",
+		Valid:     true,
+	}
+	if job.Quantity == 0 {
+		return preview
+	}
+	percent := func(n int) float64 {
+		return float64(n) * 100 / float64(job.Quantity)
+	}
+	preview.Summary.Result.Deliverable = percent(job.Summary.Result.Deliverable)
+	preview.Summary.Result.Undeliverable = percent(job.Summary.Result.Undeliverable)
+	preview.Summary.Risk.Low = percent(job.Summary.Risk.Low)
+	preview.Summary.Risk.High = percent(job.Summary.Risk.High)
+	return preview
+}
+
+func (ms *MockServer) getBulkPreview(w http.ResponseWriter, r *http.Request) {
+	for _, job := range ms.bulkPreviews {
+		if job.Id == chi.URLParam(r, "id") {
+			toJSON(w, map[string]BulkPreview{"preview": bulkPreview(job)})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "preview not found"})
+}
+
+func (ms *MockServer) listBulkPreviews(w http.ResponseWriter, r *http.Request) {
+	previews := []BulkPreview{}
+	for _, job := range ms.bulkPreviews {
+		previews = append(previews, bulkPreview(job))
+	}
+	toJSON(w, map[string][]BulkPreview{"previews": previews})
+}
+
+func (ms *MockServer) promoteBulkPreview(w http.ResponseWriter, r *http.Request) {
+	for _, job := range ms.bulkPreviews {
+		if job.Id == chi.URLParam(r, "id") {
+			ms.bulkValidations = replaceBulkValidation(ms.bulkValidations, job)
+			toJSON(w, okResp{Message: "The validation job was submitted."})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "preview not found"})
+}
+
+func (ms *MockServer) deleteBulkPreview(w http.ResponseWriter, r *http.Request) {
+	for i, job := range ms.bulkPreviews {
+		if job.Id == chi.URLParam(r, "id") {
+			ms.bulkPreviews = append(ms.bulkPreviews[:i], ms.bulkPreviews[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+	toJSON(w, okResp{Message: "preview not found"})
+}
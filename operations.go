@@ -0,0 +1,159 @@
+package mailgun
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Operation describes a Mailgun API endpoint implemented by the client.
+type Operation struct {
+	// The method of the Mailgun interface which calls the endpoint
+	Name string `json:"name"`
+	// The HTTP method of the request
+	Method string `json:"method"`
+	// The path of the endpoint relative to the API version, with its parameters in braces,
+	// e.g. "/{domain}/bounces/{address}"
+	Path string `json:"path"`
+	// The version of the API, e.g. "v3"
+	Version string `json:"version"`
+}
+
+// operations lists the endpoints called by the client. It must be kept up to date as methods
+// are added to the Mailgun interface, TestOperations checks every method of the interface which
+// calls an endpoint is listed.
+var operations = []Operation{
+	{"Send", http.MethodPost, "/{domain}/messages", "v3"},
+	{"Send", http.MethodPost, "/{domain}/messages.mime", "v3"},
+	{"ReSend", http.MethodPost, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredMessage", http.MethodGet, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredMessageRaw", http.MethodGet, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredMessageMIME", http.MethodGet, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredMessageForURL", http.MethodGet, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredMessageRawForURL", http.MethodGet, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"GetStoredAttachment", http.MethodGet, "/domains/{domain}/messages/{storage_key}/attachments/{attachment}", "v3"},
+	{"DeleteStoredMessage", http.MethodDelete, "/domains/{domain}/messages/{storage_key}", "v3"},
+	{"DeleteScheduledMessages", http.MethodDelete, "/{domain}/envelopes", "v3"},
+
+	{"ListBounces", http.MethodGet, "/{domain}/bounces", "v3"},
+	{"GetBounce", http.MethodGet, "/{domain}/bounces/{address}", "v3"},
+	{"AddBounce", http.MethodPost, "/{domain}/bounces", "v3"},
+	{"DeleteBounce", http.MethodDelete, "/{domain}/bounces/{address}", "v3"},
+	{"DeleteAllBounces", http.MethodDelete, "/{domain}/bounces", "v3"},
+
+	{"ListUnsubscribes", http.MethodGet, "/{domain}/unsubscribes", "v3"},
+	{"GetUnsubscribe", http.MethodGet, "/{domain}/unsubscribes/{address}", "v3"},
+	{"CreateUnsubscribe", http.MethodPost, "/{domain}/unsubscribes", "v3"},
+	{"DeleteUnsubscribe", http.MethodDelete, "/{domain}/unsubscribes/{address}", "v3"},
+	{"DeleteUnsubscribeWithTag", http.MethodDelete, "/{domain}/unsubscribes/{address}", "v3"},
+
+	{"ListComplaints", http.MethodGet, "/{domain}/complaints", "v3"},
+	{"GetComplaint", http.MethodGet, "/{domain}/complaints/{address}", "v3"},
+	{"CreateComplaint", http.MethodPost, "/{domain}/complaints", "v3"},
+	{"DeleteComplaint", http.MethodDelete, "/{domain}/complaints/{address}", "v3"},
+
+	{"GetStats", http.MethodGet, "/{domain}/stats/total", "v3"},
+	{"ListTags", http.MethodGet, "/{domain}/tags", "v3"},
+	{"GetTag", http.MethodGet, "/{domain}/tags/{tag}", "v3"},
+	{"DeleteTag", http.MethodDelete, "/{domain}/tags/{tag}", "v3"},
+	{"ListEvents", http.MethodGet, "/{domain}/events", "v3"},
+
+	{"ListDomains", http.MethodGet, "/domains", "v3"},
+	{"GetDomain", http.MethodGet, "/domains/{domain}", "v3"},
+	{"CreateDomain", http.MethodPost, "/domains", "v3"},
+	{"DeleteDomain", http.MethodDelete, "/domains/{domain}", "v3"},
+	{"VerifyDomain", http.MethodPut, "/domains/{domain}/verify", "v3"},
+	{"GetDomainConnection", http.MethodGet, "/domains/{domain}/connection", "v3"},
+	{"UpdateDomainConnection", http.MethodPut, "/domains/{domain}/connection", "v3"},
+	{"GetDomainTracking", http.MethodGet, "/domains/{domain}/tracking", "v3"},
+	{"UpdateOpenTracking", http.MethodPut, "/domains/{domain}/tracking/open", "v3"},
+	{"UpdateClickTracking", http.MethodPut, "/domains/{domain}/tracking/click", "v3"},
+	{"UpdateUnsubscribeTracking", http.MethodPut, "/domains/{domain}/tracking/unsubscribe", "v3"},
+	{"GetTagLimits", http.MethodGet, "/domains/{domain}/limits/tag", "v3"},
+	{"GetSendingQueues", http.MethodGet, "/domains/{domain}/sending_queues", "v3"},
+
+	{"ListCredentials", http.MethodGet, "/domains/{domain}/credentials", "v3"},
+	{"CreateCredential", http.MethodPost, "/domains/{domain}/credentials", "v3"},
+	{"ChangeCredentialPassword", http.MethodPut, "/domains/{domain}/credentials/{login}", "v3"},
+	{"DeleteCredential", http.MethodDelete, "/domains/{domain}/credentials/{login}", "v3"},
+
+	{"ListWebhooks", http.MethodGet, "/domains/{domain}/webhooks", "v3"},
+	{"CreateWebhook", http.MethodPost, "/domains/{domain}/webhooks", "v3"},
+	{"GetWebhook", http.MethodGet, "/domains/{domain}/webhooks/{kind}", "v3"},
+	{"UpdateWebhook", http.MethodPut, "/domains/{domain}/webhooks/{kind}", "v3"},
+	{"DeleteWebhook", http.MethodDelete, "/domains/{domain}/webhooks/{kind}", "v3"},
+	{"TestWebhook", http.MethodPut, "/domains/{domain}/webhooks/{kind}/test", "v3"},
+	{"GetWebhookSigningKey", http.MethodGet, "/accounts/http_signing_key", "v5"},
+
+	{"ListIPS", http.MethodGet, "/ips", "v3"},
+	{"GetIP", http.MethodGet, "/ips/{ip}", "v3"},
+	{"ListDomainIPS", http.MethodGet, "/domains/{domain}/ips", "v3"},
+	{"AddDomainIP", http.MethodPost, "/domains/{domain}/ips", "v3"},
+	{"DeleteDomainIP", http.MethodDelete, "/domains/{domain}/ips/{ip}", "v3"},
+
+	{"ListRoutes", http.MethodGet, "/routes", "v3"},
+	{"GetRoute", http.MethodGet, "/routes/{id}", "v3"},
+	{"CreateRoute", http.MethodPost, "/routes", "v3"},
+	{"UpdateRoute", http.MethodPut, "/routes/{id}", "v3"},
+	{"DeleteRoute", http.MethodDelete, "/routes/{id}", "v3"},
+	{"MatchRoute", http.MethodGet, "/routes/match", "v3"},
+
+	{"ListMailingLists", http.MethodGet, "/lists/pages", "v3"},
+	{"GetMailingList", http.MethodGet, "/lists/{list}", "v3"},
+	{"CreateMailingList", http.MethodPost, "/lists", "v3"},
+	{"UpdateMailingList", http.MethodPut, "/lists/{list}", "v3"},
+	{"DeleteMailingList", http.MethodDelete, "/lists/{list}", "v3"},
+	{"ListMembers", http.MethodGet, "/lists/{list}/members/pages", "v3"},
+	{"GetMember", http.MethodGet, "/lists/{list}/members/{member}", "v3"},
+	{"CreateMember", http.MethodPost, "/lists/{list}/members", "v3"},
+	{"CreateMemberList", http.MethodPost, "/lists/{list}/members.json", "v3"},
+	{"CreateMembers", http.MethodPost, "/lists/{list}/members.json", "v3"},
+	{"CreateMembersFromCSV", http.MethodPost, "/lists/{list}/members.csv", "v3"},
+	{"UpdateMember", http.MethodPut, "/lists/{list}/members/{member}", "v3"},
+	{"DeleteMember", http.MethodDelete, "/lists/{list}/members/{member}", "v3"},
+
+	{"ListTemplates", http.MethodGet, "/{domain}/templates", "v3"},
+	{"GetTemplate", http.MethodGet, "/{domain}/templates/{name}", "v3"},
+	{"CreateTemplate", http.MethodPost, "/{domain}/templates", "v3"},
+	{"UpdateTemplate", http.MethodPut, "/{domain}/templates/{name}", "v3"},
+	{"DeleteTemplate", http.MethodDelete, "/{domain}/templates/{name}", "v3"},
+	{"ListTemplateVersions", http.MethodGet, "/{domain}/templates/{name}/versions", "v3"},
+	{"GetTemplateVersion", http.MethodGet, "/{domain}/templates/{name}/versions/{tag}", "v3"},
+	{"AddTemplateVersion", http.MethodPost, "/{domain}/templates/{name}/versions", "v3"},
+	{"UpdateTemplateVersion", http.MethodPut, "/{domain}/templates/{name}/versions/{tag}", "v3"},
+	{"DeleteTemplateVersion", http.MethodDelete, "/{domain}/templates/{name}/versions/{tag}", "v3"},
+
+	{"ListExports", http.MethodGet, "/exports", "v3"},
+	{"GetExport", http.MethodGet, "/exports/{id}", "v3"},
+	{"GetExportLink", http.MethodGet, "/exports/{id}/download_url", "v3"},
+	{"CreateExport", http.MethodPost, "/exports", "v3"},
+
+	{"ValidateEmail", http.MethodGet, "/address/validate", "v4"},
+	{"ListBulkValidations", http.MethodGet, "/address/validate/bulk", "v4"},
+	{"GetBulkValidation", http.MethodGet, "/address/validate/bulk/{list_id}", "v4"},
+	{"CreateBulkValidation", http.MethodPost, "/address/validate/bulk/{list_id}", "v4"},
+	{"CreateBulkValidationFromReader", http.MethodPost, "/address/validate/bulk/{list_id}", "v4"},
+	{"DeleteBulkValidation", http.MethodDelete, "/address/validate/bulk/{list_id}", "v4"},
+	{"ListBulkPreviews", http.MethodGet, "/address/validate/preview", "v4"},
+	{"GetBulkPreview", http.MethodGet, "/address/validate/preview/{list_id}", "v4"},
+	{"CreateBulkPreview", http.MethodPost, "/address/validate/preview/{list_id}", "v4"},
+	{"PromoteBulkPreview", http.MethodPut, "/address/validate/preview/{list_id}", "v4"},
+	{"DeleteBulkPreview", http.MethodDelete, "/address/validate/preview/{list_id}", "v4"},
+}
+
+// Operations returns the catalog of Mailgun API endpoints implemented by the client, sorted
+// by version, path and method. Use it to track the coverage of the API, e.g. by comparing it
+// with the OpenAPI specification published by Mailgun.
+func Operations() []Operation {
+	ops := make([]Operation, len(operations))
+	copy(ops, operations)
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].Version != ops[j].Version {
+			return ops[i].Version < ops[j].Version
+		}
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
@@ -0,0 +1,162 @@
+package mailgun
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+// The paths and methods of the Mailgun API in OpenAPI format. The file was written by hand from
+// the endpoints listed in the Mailgun API reference (https://documentation.mailgun.com), which
+// is the source to check when Mailgun announces new endpoints. To refresh it from a copy of the
+// OpenAPI specification Mailgun publishes with the reference, keep only the paths and methods:
+//
+//	jq '{openapi, info, paths: (.paths | map_values(with_entries(
+//		select(.key | test("^(get|post|put|patch|delete)$")) | .value = {})))}' \
+//		mailgun.json > testdata/openapi.json
+//
+// Endpoints of the specification the client doesn't implement are logged by
+// TestOperationsOpenAPI, operations missing from the specification fail it.
+const openAPISpecFile = "testdata/openapi.json"
+
+var (
+	pathParam     = regexp.MustCompile(`\{[^}]*\}`)
+	versionPrefix = regexp.MustCompile(`^/v[0-9]+/`)
+)
+
+// operationKey identifies an endpoint regardless of the names of its path parameters
+func operationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + pathParam.ReplaceAllString(path, "{}")
+}
+
+// diffOperations compares the operations with the paths of an OpenAPI specification, and
+// returns the endpoints of the specification not implemented by the client, and the
+// operations which are not in the specification. Paths of the specification without a
+// version prefix are assumed to be v3 endpoints.
+func diffOperations(spec []byte, ops []Operation) (missing, unknown []string, err error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	specified := make(map[string]bool)
+	for path, methods := range doc.Paths {
+		if !versionPrefix.MatchString(path) {
+			path = "/v3" + path
+		}
+		for method := range methods {
+			switch strings.ToUpper(method) {
+			case "GET", "POST", "PUT", "PATCH", "DELETE":
+				specified[operationKey(method, path)] = true
+			}
+		}
+	}
+
+	implemented := make(map[string]bool)
+	for _, op := range ops {
+		key := operationKey(op.Method, "/"+op.Version+op.Path)
+		if !specified[key] && !implemented[key] {
+			unknown = append(unknown, key)
+		}
+		implemented[key] = true
+	}
+	for key := range specified {
+		if !implemented[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unknown)
+	return missing, unknown, nil
+}
+
+// The methods of the Mailgun interface which don't call an endpoint themselves, they are local
+// or only call other methods of the interface
+var nonEndpointMethods = map[string]bool{
+	"APIBase": true, "APIKey": true, "Client": true, "ClientEvents": true, "Domain": true,
+	"NewMessage": true, "NewMIMEMessage": true, "NewMessageFromMail": true,
+	"SetAPIBase": true, "SetClient": true, "SetClientEvents": true, "SetDomainRouting": true,
+	"SetDomainThrottle": true, "SetErrorBodyCapture": true, "SetResponseCache": true,
+	"SetStrictMode": true, "SetTagValidator": true, "SetTestMode": true,
+	"SetVariableCipher": true, "SetWebhookIPAllowlist": true, "SetWebhookSigningKey": true,
+	"ValidateTag": true, "VariableCipher": true,
+	"ParseWebhookPayload": true, "VerifyWebhookRequest": true, "VerifyWebhookSignature": true,
+
+	"ActivateTemplateVersion": true, "CompareStats": true, "CoordinateEvents": true,
+	"DownloadBulkValidationResult": true, "ExportMembers": true, "GetDomainHealth": true,
+	"GetStoredMessages": true, "MirrorSuppressions": true, "PollEvents": true,
+	"ReplaceMembers": true, "SearchStoredMessages": true,
+}
+
+func TestOperations(t *testing.T) {
+	mailgun := reflect.TypeOf((*Mailgun)(nil)).Elem()
+	listed := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, op := range Operations() {
+		_, ok := mailgun.MethodByName(op.Name)
+		ensure.True(t, ok, op.Name)
+		ensure.False(t, nonEndpointMethods[op.Name], op.Name)
+		listed[op.Name] = true
+		ensure.True(t, strings.HasPrefix(op.Path, "/"), op.Name)
+		ensure.True(t, op.Version == "v3" || op.Version == "v4" || op.Version == "v5", op.Name)
+
+		key := op.Name + " " + operationKey(op.Method, op.Path)
+		ensure.False(t, seen[key], key)
+		seen[key] = true
+	}
+
+	for i := 0; i < mailgun.NumMethod(); i++ {
+		name := mailgun.Method(i).Name
+		if !nonEndpointMethods[name] && !listed[name] {
+			t.Errorf("%s is not in the operations catalog", name)
+		}
+	}
+}
+
+func TestDiffOperations(t *testing.T) {
+	spec := []byte(`{
+		"openapi": "3.0.0",
+		"paths": {
+			"/v3/{domain_name}/bounces": {"get": {}, "post": {}, "parameters": []},
+			"/v3/{domain_name}/bounces/{address}": {"get": {}, "delete": {}},
+			"/v4/address/validate": {"get": {}, "post": {}},
+			"/routes/{id}": {"get": {}}
+		}
+	}`)
+	missing, unknown, err := diffOperations(spec, []Operation{
+		{"ListBounces", "GET", "/{domain}/bounces", "v3"},
+		{"AddBounce", "POST", "/{domain}/bounces", "v3"},
+		{"GetBounce", "GET", "/{domain}/bounces/{address}", "v3"},
+		{"DeleteAllBounces", "DELETE", "/{domain}/bounces", "v3"},
+		{"ValidateEmail", "GET", "/address/validate", "v4"},
+		{"GetRoute", "GET", "/routes/{route_id}", "v3"},
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, missing, []string{"DELETE /v3/{}/bounces/{}", "POST /v4/address/validate"})
+	ensure.DeepEqual(t, unknown, []string{"DELETE /v3/{}/bounces"})
+}
+
+// TestOperationsOpenAPI reports the endpoints of the Mailgun API the client does not implement
+// yet. Operations of the catalog which are not in the specification fail the test, as they are
+// either mistakes in the catalog or endpoints Mailgun has removed.
+func TestOperationsOpenAPI(t *testing.T) {
+	spec, err := ioutil.ReadFile(openAPISpecFile)
+	ensure.Nil(t, err)
+
+	missing, unknown, err := diffOperations(spec, Operations())
+	ensure.Nil(t, err)
+	for _, key := range missing {
+		t.Logf("not implemented: %s", key)
+	}
+	for _, key := range unknown {
+		t.Errorf("not in the OpenAPI specification: %s", key)
+	}
+}
@@ -0,0 +1,21 @@
+package mailgun
+
+import "fmt"
+
+// OptionError is returned when an options struct such as ListOptions or ListEventOptions holds
+// an invalid value or combination of values. Iterators created with invalid options return
+// the OptionError from `Err()` without making any requests.
+type OptionError struct {
+	// The name of the invalid field
+	Field string
+	// Why the value is invalid
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid option %s: %s", e.Field, e.Reason)
+}
+
+func invalidOption(field, reason string) error {
+	return &OptionError{Field: field, Reason: reason}
+}
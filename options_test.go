@@ -0,0 +1,73 @@
+package mailgun
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		opts  interface{ Validate() error }
+		field string
+	}{
+		{opts: (*ListOptions)(nil)},
+		{opts: &ListOptions{Limit: 10}},
+		{opts: &ListOptions{Limit: -1}, field: "Limit"},
+		{opts: &ListTagOptions{Limit: -1}, field: "Limit"},
+		{opts: &ListEventOptions{Limit: 300}},
+		{opts: &ListEventOptions{Limit: 301}, field: "Limit"},
+		{opts: &ListEventOptions{ForceAscending: true, ForceDescending: true}, field: "ForceAscending"},
+		{opts: &ListEventOptions{ForceAscending: true, Begin: now, End: now.Add(-time.Hour)}, field: "Begin"},
+		{opts: &ListEventOptions{ForceDescending: true, Begin: now, End: now.Add(-time.Hour)}},
+		{opts: &ListEventOptions{PollInterval: -time.Second}, field: "PollInterval"},
+		{opts: &GetStatOptions{Resolution: ResolutionDay, Duration: "1m"}},
+		{opts: &GetStatOptions{Resolution: "week"}, field: "Resolution"},
+		{opts: &GetStatOptions{Duration: "1m", Start: now}, field: "Duration"},
+		{opts: &GetStatOptions{Start: now, End: now.Add(-time.Hour)}, field: "Start"},
+		{opts: &CreateDomainOptions{SpamAction: SpamActionTag, DKIMKeySize: 2048, IPS: []string{"192.0.2.1"}}},
+		{opts: &CreateDomainOptions{SpamAction: "drop"}, field: "SpamAction"},
+		{opts: &CreateDomainOptions{DKIMKeySize: 512}, field: "DKIMKeySize"},
+		{opts: &CreateDomainOptions{IPS: []string{"192.0.2"}}, field: "IPS"},
+		{opts: &CreateDomainOptions{WebScheme: "https"}},
+		{opts: &CreateDomainOptions{WebScheme: "ftp"}, field: "WebScheme"},
+	} {
+		err := tc.opts.Validate()
+		if tc.field == "" {
+			ensure.Nil(t, err)
+			continue
+		}
+		optErr, ok := err.(*OptionError)
+		ensure.True(t, ok)
+		ensure.DeepEqual(t, optErr.Field, tc.field)
+	}
+}
+
+func TestInvalidOptionsMakeNoRequests(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase("http://127.0.0.1:0")
+	ctx := context.Background()
+
+	var events []Event
+	it := mg.ListEvents(&ListEventOptions{ForceAscending: true, ForceDescending: true})
+	ensure.False(t, it.Next(ctx, &events))
+	_, ok := it.Err().(*OptionError)
+	ensure.True(t, ok)
+
+	var routes []Route
+	routesIt := mg.ListRoutes(&ListOptions{Limit: -1})
+	ensure.False(t, routesIt.Next(ctx, &routes))
+	_, ok = routesIt.Err().(*OptionError)
+	ensure.True(t, ok)
+
+	_, err := mg.GetStats(ctx, []string{"accepted"}, &GetStatOptions{Resolution: "week"})
+	_, ok = err.(*OptionError)
+	ensure.True(t, ok)
+
+	_, err = mg.CreateDomain(ctx, "example.com", "password", &CreateDomainOptions{DKIMKeySize: 512})
+	_, ok = err.(*OptionError)
+	ensure.True(t, ok)
+}
@@ -62,38 +62,14 @@ func parseResponse(raw []byte) ([]Event, error) {
 	return result, nil
 }
 
-// Given a slice of events.RawJSON events return a slice of Event for each parsed event
+// Given a slice of events.RawJSON events return a slice of Event for each parsed event.
+// Events are decoded with DefaultEventCodec.
 func ParseEvents(raw []events.RawJSON) ([]Event, error) {
-	var result []Event
-	for _, value := range raw {
-		event, err := ParseEvent(value)
-		if err != nil {
-			return nil, fmt.Errorf("while parsing event: %s", err)
-		}
-		result = append(result, event)
-	}
-	return result, nil
+	return DefaultEventCodec.DecodeAll(raw)
 }
 
-// Parse converts raw bytes data into an event struct. Can accept events.RawJSON as input
+// Parse converts raw bytes data into an event struct. Can accept events.RawJSON as input.
+// Events are decoded with DefaultEventCodec.
 func ParseEvent(raw []byte) (Event, error) {
-	// Try to recognize the event first.
-	var e events.EventName
-	if err := easyjson.Unmarshal(raw, &e); err != nil {
-		return nil, fmt.Errorf("failed to recognize event: %v", err)
-	}
-
-	// Get the event "constructor" from the map.
-	newEvent, ok := EventNames[e.Name]
-	if !ok {
-		return nil, fmt.Errorf("unsupported event: '%s'", e.Name)
-	}
-	event := newEvent()
-
-	// Parse the known event.
-	if err := easyjson.Unmarshal(raw, event); err != nil {
-		return nil, fmt.Errorf("failed to parse event '%s': %v", e.Name, err)
-	}
-
-	return event, nil
+	return DefaultEventCodec.Decode(raw)
 }
@@ -171,3 +171,60 @@ func TestStored(t *testing.T) {
 	ensure.DeepEqual(t, event.(*events.Stored).Storage.Key, key)
 	ensure.DeepEqual(t, event.(*events.Stored).Storage.URL, url)
 }
+
+func TestParseTypedFields(t *testing.T) {
+	event, err := ParseEvent([]byte(`{
+		"event": "failed",
+		"timestamp": 1420255392.850187,
+		"severity": "permanent",
+		"reason": "suppress-bounce"
+	}`))
+	ensure.Nil(t, err)
+
+	failed := event.(*events.Failed)
+	switch failed.Severity {
+	case events.SeverityPermanent:
+	default:
+		t.Fatalf("unexpected severity '%s'", failed.Severity)
+	}
+	ensure.DeepEqual(t, failed.Reason, events.ReasonSuppressBounce)
+	ensure.True(t, failed.Reason.IsValid())
+
+	severity, err := events.ParseSeverity("Temporary")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, severity, events.SeverityTemporary)
+	_, err = events.ParseSeverity("fatal")
+	ensure.NotNil(t, err)
+	ensure.False(t, events.Reason("because").IsValid())
+
+	level, err := events.ParseLogLevel("WARN")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, level, events.LogLevelWarn)
+
+	code, err := events.ParseDeliveryStatusCode("550")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, code, events.CodeMailboxNotFound)
+	ensure.True(t, code.Permanent())
+	ensure.False(t, code.Temporary())
+	_, err = events.ParseDeliveryStatusCode("999")
+	ensure.NotNil(t, err)
+}
+
+func TestParseDeliveryStatusCode(t *testing.T) {
+	for _, code := range []string{`550`, `"550"`} {
+		e, err := ParseEvent([]byte(`{"event": "failed", "delivery-status": {"code": ` + code + `, "message": "no such user"}}`))
+		ensure.Nil(t, err)
+		status := e.(*events.Failed).DeliveryStatus
+		ensure.DeepEqual(t, status.Code, events.CodeMailboxNotFound)
+		ensure.DeepEqual(t, status.Message, "no such user")
+	}
+
+	// Codes which aren't numbers decode to 0 instead of failing the event
+	for _, code := range []string{`""`, `"5.1.1"`, `null`} {
+		e, err := ParseEvent([]byte(`{"event": "failed", "delivery-status": {"code": ` + code + `, "message": "no such user"}}`))
+		ensure.Nil(t, err)
+		status := e.(*events.Failed).DeliveryStatus
+		ensure.DeepEqual(t, status.Code, events.DeliveryStatusCode(0))
+		ensure.DeepEqual(t, status.Message, "no such user")
+	}
+}
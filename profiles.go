@@ -0,0 +1,47 @@
+package mailgun
+
+// A Profile applies a bundle of defaults to a message, so the same policy is
+// applied consistently wherever messages of that kind are built. Organizations
+// can define their own profiles in addition to the ones provided here.
+//
+//	m := mg.NewMessage(from, subject, text, to)
+//	if err := m.ApplyProfile(mailgun.MarketingProfile, mailgun.TestModeProfile); err != nil {
+//		return err
+//	}
+type Profile func(m *Message) error
+
+// TransactionalProfile disables click tracking, so links such as password resets are
+// not rewritten, keeps open tracking enabled and tags the message as "transactional".
+func TransactionalProfile(m *Message) error {
+	m.SetTrackingClicks(false)
+	m.SetTrackingOpens(true)
+	return m.AddTag("transactional")
+}
+
+// MarketingProfile enables click and open tracking, adds List-Unsubscribe headers
+// pointing at the Mailgun unsubscribe link and tags the message as "marketing".
+func MarketingProfile(m *Message) error {
+	m.SetTracking(true)
+	m.SetTrackingClicks(true)
+	m.SetTrackingOpens(true)
+	m.AddListUnsubscribe("", "")
+	return m.AddTag("marketing")
+}
+
+// TestModeProfile enables test mode, use it in non-production environments to ensure
+// messages are accepted by Mailgun but never delivered.
+func TestModeProfile(m *Message) error {
+	m.EnableTestMode()
+	return nil
+}
+
+// ApplyProfile applies the given profiles to the message in order, settings applied by
+// later profiles override those of earlier profiles.
+func (m *Message) ApplyProfile(profiles ...Profile) error {
+	for _, profile := range profiles {
+		if err := profile(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
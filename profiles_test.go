@@ -0,0 +1,40 @@
+package mailgun
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestApplyProfile(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	ensure.Nil(t, m.ApplyProfile(TransactionalProfile, TestModeProfile))
+	ensure.DeepEqual(t, m.tags, []string{"transactional"})
+	ensure.DeepEqual(t, m.trackingClicks, TrackingClicksDisabled)
+	ensure.True(t, m.trackingOpens)
+	ensure.True(t, m.testMode)
+
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	ensure.Nil(t, m.ApplyProfile(MarketingProfile))
+	ensure.DeepEqual(t, m.tags, []string{"marketing"})
+	ensure.True(t, m.tracking)
+	ensure.DeepEqual(t, m.trackingClicks, TrackingClicksEnabled)
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe"], "<%unsubscribe_url%>")
+	ensure.DeepEqual(t, m.GetHeaders()["List-Unsubscribe-Post"], "List-Unsubscribe=One-Click")
+	ensure.False(t, m.testMode)
+}
+
+func TestApplyProfileError(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	policy := func(m *Message) error {
+		return errors.New("policy violation")
+	}
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "test@test.com")
+	err := m.ApplyProfile(policy, TransactionalProfile)
+	ensure.DeepEqual(t, err.Error(), "policy violation")
+	ensure.DeepEqual(t, len(m.tags), 0)
+}
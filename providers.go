@@ -0,0 +1,94 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// MailboxProvider identifies the company hosting a recipient's mailbox
+type MailboxProvider string
+
+// Mailbox providers recognized by ProviderClassifier
+const (
+	ProviderGmail     = MailboxProvider("gmail")
+	ProviderMicrosoft = MailboxProvider("microsoft")
+	ProviderYahoo     = MailboxProvider("yahoo")
+	ProviderOther     = MailboxProvider("other")
+)
+
+// MX host suffixes used to recognize each provider, this also catches
+// custom domains hosted by G Suite and Office 365.
+var providerMXSuffixes = []struct {
+	suffix   string
+	provider MailboxProvider
+}{
+	{"google.com", ProviderGmail},
+	{"googlemail.com", ProviderGmail},
+	{"outlook.com", ProviderMicrosoft},
+	{"hotmail.com", ProviderMicrosoft},
+	{"yahoodns.net", ProviderYahoo},
+	{"yahoo.com", ProviderYahoo},
+}
+
+// ProviderClassifier classifies recipient addresses by mailbox provider using the MX
+// records of the recipient domain. Results are cached per domain, so a single classifier
+// should be reused when classifying many addresses. It is safe for concurrent use.
+type ProviderClassifier struct {
+	mutex    sync.Mutex
+	cache    map[string]MailboxProvider
+	lookupMX func(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// NewProviderClassifier returns a ProviderClassifier which uses the default DNS resolver.
+func NewProviderClassifier() *ProviderClassifier {
+	return &ProviderClassifier{
+		cache:    make(map[string]MailboxProvider),
+		lookupMX: net.DefaultResolver.LookupMX,
+	}
+}
+
+// Classify returns the mailbox provider of the given address. Domains which have
+// no MX records, or whose MX records are not recognized, are classified as ProviderOther.
+func (pc *ProviderClassifier) Classify(ctx context.Context, address string) (MailboxProvider, error) {
+	at := strings.LastIndex(address, "@")
+	if at == -1 {
+		return "", fmt.Errorf("'%s' is not a valid email address", address)
+	}
+	domain := strings.ToLower(strings.TrimRight(address[at+1:], "> "))
+
+	pc.mutex.Lock()
+	provider, ok := pc.cache[domain]
+	pc.mutex.Unlock()
+	if ok {
+		return provider, nil
+	}
+
+	records, err := pc.lookupMX(ctx, domain)
+	if err != nil {
+		// A domain without MX records can't be attributed to any provider
+		if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+			return "", err
+		}
+	}
+
+	provider = classifyMX(records)
+	pc.mutex.Lock()
+	pc.cache[domain] = provider
+	pc.mutex.Unlock()
+	return provider, nil
+}
+
+func classifyMX(records []*net.MX) MailboxProvider {
+	for _, mx := range records {
+		host := strings.ToLower(strings.TrimSuffix(mx.Host, "."))
+		for _, s := range providerMXSuffixes {
+			if host == s.suffix || strings.HasSuffix(host, "."+s.suffix) {
+				return s.provider
+			}
+		}
+	}
+	return ProviderOther
+}
@@ -0,0 +1,53 @@
+package mailgun
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestProviderClassifier(t *testing.T) {
+	records := map[string][]*net.MX{
+		"gmail.com":   {{Host: "gmail-smtp-in.l.google.com.", Pref: 5}},
+		"example.com": {{Host: "ASPMX.L.GOOGLE.COM.", Pref: 1}},
+		"hotmail.com": {{Host: "hotmail-com.olc.protection.outlook.com.", Pref: 2}},
+		"yahoo.com":   {{Host: "mta5.am0.yahoodns.net.", Pref: 1}},
+		"mailgun.org": {{Host: "mxa.mailgun.org.", Pref: 10}},
+	}
+	lookups := 0
+
+	pc := NewProviderClassifier()
+	pc.lookupMX = func(ctx context.Context, name string) ([]*net.MX, error) {
+		lookups++
+		if mx, ok := records[name]; ok {
+			return mx, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	ctx := context.Background()
+
+	for address, expected := range map[string]MailboxProvider{
+		"user@gmail.com":             ProviderGmail,
+		"User <user@Example.com>":    ProviderGmail,
+		"user@hotmail.com":           ProviderMicrosoft,
+		"user@yahoo.com":             ProviderYahoo,
+		"user@mailgun.org":           ProviderOther,
+		"user@does-not-exist.mg.com": ProviderOther,
+	} {
+		provider, err := pc.Classify(ctx, address)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, provider, expected)
+	}
+	ensure.DeepEqual(t, lookups, 6)
+
+	// Results are cached per domain
+	provider, err := pc.Classify(ctx, "someone.else@gmail.com")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, provider, ProviderGmail)
+	ensure.DeepEqual(t, lookups, 6)
+
+	_, err = pc.Classify(ctx, "not-an-address")
+	ensure.NotNil(t, err)
+}
@@ -0,0 +1,192 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"time"
+)
+
+// TimeZoneResolver returns the time zone of a recipient, or nil if it is not known
+type TimeZoneResolver func(ctx context.Context, address string) (*time.Location, error)
+
+// QuietHours defers marketing messages which would reach recipients during the night in their
+// local time, until the quiet hours are over. The time zone of a recipient is read from a
+// recipient variable or looked up with a TimeZoneResolver.
+//
+//	quiet, err := mailgun.NewQuietHours("21:00", "08:00")
+//	quiet.TimeZoneVariable = "timezone"
+//	messages, err := quiet.Schedule(ctx, m)
+//	for _, m := range messages {
+//		_, _, err := mg.Send(ctx, m)
+//		...
+//	}
+//
+// Deferred messages are scheduled with SetDeliveryTime(), so Mailgun releases them once the
+// quiet hours of their recipients are over.
+type QuietHours struct {
+	// The tags of the messages the quiet hours apply to, defaults to "marketing"
+	Tags []string
+	// The recipient variable holding the IANA time zone of the recipient, e.g. "Europe/Paris"
+	TimeZoneVariable string
+	// Looks up the time zone of recipients without the recipient variable
+	Resolver TimeZoneResolver
+	// The time zone of recipients whose time zone is not known. If nil, messages to
+	// these recipients are not deferred.
+	DefaultZone *time.Location
+
+	start, end time.Duration
+	now        func() time.Time
+}
+
+// NewQuietHours creates quiet hours from start to end, as times of day in HH:MM format.
+// Quiet hours may span midnight, e.g. from "21:00" to "08:00".
+func NewQuietHours(start, end string) (*QuietHours, error) {
+	s, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid time of day, use HH:MM format", start)
+	}
+	e, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid time of day, use HH:MM format", end)
+	}
+	if s.Equal(e) {
+		return nil, fmt.Errorf("quiet hours must not start and end at the same time")
+	}
+	return &QuietHours{
+		start: time.Duration(s.Hour())*time.Hour + time.Duration(s.Minute())*time.Minute,
+		end:   time.Duration(e.Hour())*time.Hour + time.Duration(e.Minute())*time.Minute,
+		now:   time.Now,
+	}, nil
+}
+
+// Release returns the time at which a message sent at t may be delivered to a recipient in the
+// time zone loc: t itself if it is outside of the quiet hours, the end of the quiet hours otherwise.
+func (q *QuietHours) Release(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	timeOfDay := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+
+	day := local.Day()
+	if q.start < q.end {
+		if timeOfDay < q.start || timeOfDay >= q.end {
+			return t
+		}
+	} else {
+		if timeOfDay < q.start && timeOfDay >= q.end {
+			return t
+		}
+		// Quiet hours which started before midnight end on the next day
+		if timeOfDay >= q.start {
+			day++
+		}
+	}
+	// The end is a wall clock time, midnight plus q.end is off by an hour across a DST change
+	hour, minute := int(q.end/time.Hour), int(q.end%time.Hour/time.Minute)
+	return time.Date(local.Year(), local.Month(), day, hour, minute, 0, 0, loc)
+}
+
+// Schedule applies the quiet hours to a message. Messages without any of the Tags are returned
+// unchanged. Otherwise the To: recipients are grouped by the time the message may be delivered
+// to them, and a message is returned for each group, with its delivery time set if it is
+// deferred. The first message returned is m itself, which keeps the Cc: and Bcc: recipients,
+// the others are created with Clone().
+func (q *QuietHours) Schedule(ctx context.Context, m *Message) ([]*Message, error) {
+	if !q.applies(m) {
+		return []*Message{m}, nil
+	}
+	base := q.now()
+	if m.deliveryTime.After(base) {
+		base = m.deliveryTime
+	}
+
+	groups := make(map[int64][]string)
+	zones := make(map[string]*time.Location)
+	for _, to := range m.to {
+		loc, err := q.zone(ctx, m, to, zones)
+		if err != nil {
+			return nil, err
+		}
+		release := base
+		if loc != nil {
+			release = q.Release(base, loc)
+		}
+		groups[release.Unix()] = append(groups[release.Unix()], to)
+	}
+	if len(groups) <= 1 {
+		for release := range groups {
+			if release != base.Unix() {
+				m.SetDeliveryTime(time.Unix(release, 0))
+			}
+		}
+		return []*Message{m}, nil
+	}
+
+	releases := make([]int64, 0, len(groups))
+	for release := range groups {
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i] < releases[j] })
+
+	variables := m.recipientVariables
+	messages := []*Message{m}
+	for range releases[1:] {
+		c, err := m.Clone()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, c)
+	}
+	m.to, m.recipientVariables = nil, nil
+	for i, release := range releases {
+		if release != base.Unix() {
+			messages[i].SetDeliveryTime(time.Unix(release, 0))
+		}
+		for _, to := range groups[release] {
+			if err := messages[i].AddRecipientAndVariables(to, variables[to]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return messages, nil
+}
+
+func (q *QuietHours) applies(m *Message) bool {
+	tags := q.Tags
+	if len(tags) == 0 {
+		tags = []string{"marketing"}
+	}
+	for _, tag := range m.tags {
+		if containsString(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// zone returns the time zone of a recipient, caching the locations loaded by name in zones
+func (q *QuietHours) zone(ctx context.Context, m *Message, to string, zones map[string]*time.Location) (*time.Location, error) {
+	if name, ok := m.recipientVariables[to][q.TimeZoneVariable].(string); ok && q.TimeZoneVariable != "" {
+		loc, ok := zones[name]
+		if !ok {
+			// An unknown zone is treated as a missing one
+			loc, _ = time.LoadLocation(name)
+			zones[name] = loc
+		}
+		if loc != nil {
+			return loc, nil
+		}
+	}
+	if q.Resolver != nil {
+		address := to
+		if parsed, err := mail.ParseAddress(to); err == nil {
+			address = parsed.Address
+		}
+		loc, err := q.Resolver(ctx, address)
+		if err != nil || loc != nil {
+			return loc, err
+		}
+	}
+	return q.DefaultZone, nil
+}
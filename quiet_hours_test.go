@@ -0,0 +1,133 @@
+package mailgun
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestQuietHoursRelease(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	ensure.Nil(t, err)
+
+	overnight, err := NewQuietHours("21:00", "08:00")
+	ensure.Nil(t, err)
+	daytime, err := NewQuietHours("12:00", "14:00")
+	ensure.Nil(t, err)
+
+	at := func(s string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", s, paris)
+		ensure.Nil(t, err)
+		return parsed
+	}
+	for _, tt := range []struct {
+		quiet    *QuietHours
+		at       string
+		expected string
+	}{
+		{overnight, "2019-06-01 20:59", "2019-06-01 20:59"},
+		{overnight, "2019-06-01 21:00", "2019-06-02 08:00"},
+		{overnight, "2019-06-01 23:30", "2019-06-02 08:00"},
+		{overnight, "2019-06-02 03:00", "2019-06-02 08:00"},
+		{overnight, "2019-06-02 08:00", "2019-06-02 08:00"},
+		{overnight, "2019-06-30 22:00", "2019-07-01 08:00"},
+		{daytime, "2019-06-01 11:00", "2019-06-01 11:00"},
+		{daytime, "2019-06-01 12:30", "2019-06-01 14:00"},
+		{daytime, "2019-06-01 14:00", "2019-06-01 14:00"},
+	} {
+		ensure.True(t, tt.quiet.Release(at(tt.at), paris).Equal(at(tt.expected)), tt.at)
+	}
+
+	// The quiet hours end at 08:00 local time on the nights the clocks change
+	newYork, err := time.LoadLocation("America/New_York")
+	ensure.Nil(t, err)
+	for _, tt := range []struct {
+		at       string
+		expected string
+	}{
+		{"2026-03-07 22:00", "2026-03-08 08:00"},
+		{"2026-10-31 22:00", "2026-11-01 08:00"},
+	} {
+		at, err := time.ParseInLocation("2006-01-02 15:04", tt.at, newYork)
+		ensure.Nil(t, err)
+		release := overnight.Release(at, newYork)
+		ensure.DeepEqual(t, release.Format("2006-01-02 15:04"), tt.expected)
+	}
+
+	_, err = NewQuietHours("21:00", "21:00")
+	ensure.NotNil(t, err)
+	_, err = NewQuietHours("9pm", "08:00")
+	ensure.NotNil(t, err)
+}
+
+func TestQuietHoursSchedule(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	ensure.Nil(t, err)
+	ctx := context.Background()
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	quiet, err := NewQuietHours("21:00", "08:00")
+	ensure.Nil(t, err)
+	quiet.now = func() time.Time { return time.Date(2019, 6, 1, 20, 30, 0, 0, time.UTC) }
+	quiet.TimeZoneVariable = "tz"
+	quiet.Resolver = func(ctx context.Context, address string) (*time.Location, error) {
+		if address == "carol@example.com" {
+			return tokyo, nil
+		}
+		return nil, nil
+	}
+
+	// Transactional messages are not deferred
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText)
+	ensure.Nil(t, m.AddRecipientAndVariables("alice@example.com", map[string]interface{}{"tz": "Europe/Paris"}))
+	ensure.Nil(t, m.AddTag("transactional"))
+	messages, err := quiet.Schedule(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(messages), 1)
+	ensure.True(t, messages[0].deliveryTime.IsZero())
+
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText)
+	ensure.Nil(t, m.AddTag("marketing"))
+	// 22:30 in Paris, released at 08:00 Paris time
+	ensure.Nil(t, m.AddRecipientAndVariables("alice@example.com", map[string]interface{}{"tz": "Europe/Paris"}))
+	// 16:30 in New York
+	ensure.Nil(t, m.AddRecipientAndVariables("bob@example.com", map[string]interface{}{"tz": "America/New_York"}))
+	// 05:30 in Tokyo, released at 08:00 Tokyo time
+	ensure.Nil(t, m.AddRecipient("Carol <carol@example.com>"))
+	// Unknown time zone
+	ensure.Nil(t, m.AddRecipient("dave@example.com"))
+	m.AddCC("manager@example.com")
+
+	messages, err = quiet.Schedule(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(messages), 3)
+
+	ensure.True(t, messages[0] == m)
+	ensure.True(t, messages[0].deliveryTime.IsZero())
+	ensure.DeepEqual(t, messages[0].to, []string{"bob@example.com", "dave@example.com"})
+	ensure.DeepEqual(t, messages[0].recipientVariables, map[string]map[string]interface{}{
+		"bob@example.com": {"tz": "America/New_York"},
+	})
+	ensure.DeepEqual(t, messages[0].specific.(*plainMessage).cc, []string{"manager@example.com"})
+
+	ensure.True(t, messages[1].deliveryTime.Equal(time.Date(2019, 6, 1, 23, 0, 0, 0, time.UTC)))
+	ensure.DeepEqual(t, messages[1].to, []string{"Carol <carol@example.com>"})
+	ensure.DeepEqual(t, len(messages[1].specific.(*plainMessage).cc), 0)
+
+	ensure.True(t, messages[2].deliveryTime.Equal(time.Date(2019, 6, 2, 6, 0, 0, 0, time.UTC)))
+	ensure.DeepEqual(t, messages[2].to, []string{"alice@example.com"})
+	ensure.DeepEqual(t, messages[2].recipientVariables, map[string]map[string]interface{}{
+		"alice@example.com": {"tz": "Europe/Paris"},
+	})
+	ensure.DeepEqual(t, messages[2].tags, []string{"marketing"})
+
+	// A single recipient in quiet hours defers the message itself
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "carol@example.com")
+	ensure.Nil(t, m.AddTag("marketing"))
+	messages, err = quiet.Schedule(ctx, m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(messages), 1)
+	ensure.True(t, messages[0].deliveryTime.Equal(time.Date(2019, 6, 1, 23, 0, 0, 0, time.UTC)))
+}
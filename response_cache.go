@@ -0,0 +1,282 @@
+package mailgun
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a ResponseCache serves a response before revalidating it
+const DefaultCacheTTL = time.Minute
+
+// DefaultCachedOperations are the operations whose responses a ResponseCache keeps by default.
+// They read configuration which rarely changes, and which services may read on every request.
+var DefaultCachedOperations = []string{
+	"GetDomain",
+	"GetDomainConnection",
+	"GetDomainTracking",
+	"GetTagLimits",
+	"ListWebhooks",
+	"GetWebhook",
+	"ListTemplates",
+	"GetTemplate",
+	"ListTemplateVersions",
+	"GetTemplateVersion",
+	"GetRoute",
+}
+
+// ResponseCache caches the responses of read-heavy GET requests, to cut the number of API calls
+// made by services which read their configuration from Mailgun on every request.
+//
+// A cached response is served for TTL, then revalidated with If-None-Match or If-Modified-Since
+// if Mailgun sent an ETag or a Last-Modified header, or fetched again otherwise. A successful
+// POST, PUT or DELETE request made through the same client invalidates the responses for the
+// resource, its sub resources and the resources containing it, e.g. UpdateWebhook() invalidates
+// both GetWebhook() and ListWebhooks(), and ActivateTemplateVersion() invalidates GetTemplate().
+//
+//	mg.SetResponseCache(mailgun.NewResponseCache(5 * time.Minute))
+type ResponseCache struct {
+	// How long a response is served before it is revalidated, defaults to DefaultCacheTTL
+	TTL time.Duration
+	// The names of the operations to cache, see Operations(). Defaults to DefaultCachedOperations,
+	// must be set before the cache is used.
+	Operations []string
+
+	mu       sync.Mutex
+	entries  map[string]*cacheEntry
+	patterns []cachePattern
+	now      func() time.Time
+}
+
+// cachePattern matches the paths of a GET operation
+type cachePattern struct {
+	expr   *regexp.Regexp
+	params int
+	cached bool
+}
+
+// quotedPathParam matches the parameters of an Operation.Path escaped by regexp.QuoteMeta()
+var quotedPathParam = regexp.MustCompile(`\\\{[^}]*\\\}`)
+
+type cacheEntry struct {
+	path         string
+	status       int
+	header       http.Header
+	body         []byte
+	expires      time.Time
+	etag         string
+	lastModified string
+}
+
+// NewResponseCache creates a cache which serves responses for ttl before revalidating them.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		TTL:     ttl,
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// SetResponseCache caches the responses of some GET requests of the client, see ResponseCache.
+// The cache wraps the transport of the HTTP client, call SetClient() first when using both.
+// Pass nil to stop caching.
+func (mg *MailgunImpl) SetResponseCache(c *ResponseCache) {
+	client := *mg.Client()
+	if t, ok := client.Transport.(*cachingTransport); ok {
+		client.Transport = t.base
+	}
+	if c != nil {
+		client.Transport = &cachingTransport{cache: c, base: client.Transport}
+	}
+	mg.client = &client
+}
+
+// Purge removes all responses from the cache.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+}
+
+// cacheable returns true if the responses of GET requests to the path may be cached. A path
+// matching several operations, such as /v3/routes/match which also matches GetRoute(), belongs
+// to the operation with the fewest parameters.
+func (c *ResponseCache) cacheable(p string) bool {
+	c.mu.Lock()
+	if c.patterns == nil {
+		names := c.Operations
+		if len(names) == 0 {
+			names = DefaultCachedOperations
+		}
+		for _, op := range operations {
+			if op.Method != http.MethodGet {
+				continue
+			}
+			quoted := regexp.QuoteMeta(op.Path)
+			expr := quotedPathParam.ReplaceAllString(quoted, "[^/]+")
+			c.patterns = append(c.patterns, cachePattern{
+				expr:   regexp.MustCompile("/" + op.Version + expr + "$"),
+				params: len(quotedPathParam.FindAllString(quoted, -1)),
+				cached: containsString(names, op.Name),
+			})
+		}
+	}
+	patterns := c.patterns
+	c.mu.Unlock()
+
+	var match *cachePattern
+	for i, pattern := range patterns {
+		if !pattern.expr.MatchString(p) {
+			continue
+		}
+		// Operations sharing a path, such as GetStoredMessage() and GetStoredMessageRaw(),
+		// are cached if any of them is
+		if match == nil || pattern.params < match.params || (pattern.params == match.params && pattern.cached) {
+			match = &patterns[i]
+		}
+	}
+	return match != nil && match.cached
+}
+
+func (c *ResponseCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key]
+}
+
+func (c *ResponseCache) put(key string, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = e
+}
+
+func (c *ResponseCache) expiry() time.Time {
+	ttl := c.TTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	return c.now().Add(ttl)
+}
+
+// invalidate removes the responses for the resource at p, its sub resources and the resources
+// containing it
+func (c *ResponseCache) invalidate(p string) {
+	p = strings.TrimSuffix(p, "/")
+	ancestors := make(map[string]bool)
+	for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		ancestors[dir] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if e.path == p || strings.HasPrefix(e.path, p+"/") || ancestors[e.path] {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cachingTransport serves GET requests from a ResponseCache
+type cachingTransport struct {
+	cache *ResponseCache
+	base  http.RoundTripper
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp, err := base.RoundTrip(req)
+		if err == nil && resp.StatusCode < 300 {
+			t.cache.invalidate(req.URL.Path)
+		}
+		return resp, err
+	}
+	if req.Method != http.MethodGet || !t.cache.cacheable(req.URL.Path) {
+		return base.RoundTrip(req)
+	}
+
+	// Responses depend on the credentials of the request
+	key := req.URL.String() + "\x00" + req.Header.Get("Authorization")
+	entry := t.cache.get(key)
+	if entry != nil && t.cache.now().Before(entry.expires) {
+		return entry.response(req), nil
+	}
+
+	if entry != nil && (entry.etag != "" || entry.lastModified != "") {
+		// Round trippers must not modify the request, send a copy with its own headers
+		conditional := *req
+		conditional.Header = copyHeader(req.Header)
+		req = &conditional
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		revalidated := *entry
+		revalidated.expires = t.cache.expiry()
+		t.cache.put(key, &revalidated)
+		return revalidated.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry = &cacheEntry{
+		path:         req.URL.Path,
+		status:       resp.StatusCode,
+		header:       resp.Header,
+		body:         body,
+		expires:      t.cache.expiry(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	t.cache.put(key, entry)
+	return entry.response(req), nil
+}
+
+// response returns a copy of the cached response for the request
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        copyHeader(e.header),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// copyHeader returns a deep copy of h, as http.Header.Clone() requires Go 1.13
+func copyHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for name, values := range h {
+		c[name] = append([]string(nil), values...)
+	}
+	return c
+}
@@ -0,0 +1,149 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestResponseCache(t *testing.T) {
+	var gets, revalidations int
+	webhookURL := "https://example.com/clicked"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/v3/domains/"+exampleDomain+"/webhooks/clicked":
+			gets++
+			etag := `"` + webhookURL + `"`
+			if req.Header.Get("If-None-Match") == etag {
+				revalidations++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			fmt.Fprintf(w, `{"webhook": {"url": %q}}`, webhookURL)
+		case req.Method == http.MethodGet && req.URL.Path == "/v3/"+exampleDomain+"/events":
+			gets++
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+		case req.Method == http.MethodPut:
+			webhookURL = req.FormValue("url")
+			fmt.Fprint(w, `{"message": "Webhook has been updated"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL + "/v3")
+	cache := NewResponseCache(time.Minute)
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	mg.SetResponseCache(cache)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		hook, err := mg.GetWebhook(ctx, "clicked")
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, hook.URLs, []string{"https://example.com/clicked"})
+	}
+	ensure.DeepEqual(t, gets, 1)
+
+	// Expired responses are revalidated
+	now = now.Add(2 * time.Minute)
+	hook, err := mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hook.URLs, []string{"https://example.com/clicked"})
+	ensure.DeepEqual(t, gets, 2)
+	ensure.DeepEqual(t, revalidations, 1)
+	_, err = mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, gets, 2)
+
+	// Updates invalidate the cached responses
+	ensure.Nil(t, mg.UpdateWebhook(ctx, "clicked", []string{"https://example.com/new"}))
+	hook, err = mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hook.URLs, []string{"https://example.com/new"})
+	ensure.DeepEqual(t, gets, 3)
+
+	// Other requests are not cached
+	var page []Event
+	for i := 0; i < 2; i++ {
+		it := mg.ListEvents(nil)
+		it.Next(ctx, &page)
+		ensure.Nil(t, it.Err())
+	}
+	ensure.DeepEqual(t, gets, 5)
+
+	cache.Purge()
+	_, err = mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, gets, 6)
+
+	mg.SetResponseCache(nil)
+	_, err = mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, gets, 7)
+}
+
+func TestResponseCacheScope(t *testing.T) {
+	gets := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			fmt.Fprint(w, `{"message": "ok", "route": {"id": "123"}}`)
+			return
+		}
+		gets[req.URL.Path]++
+		switch req.URL.Path {
+		case "/v3/routes/123", "/v3/routes/match":
+			fmt.Fprint(w, `{"route": {"id": "123"}}`)
+		case "/v3/domains/" + exampleDomain + "/tracking":
+			fmt.Fprint(w, `{"tracking": {}}`)
+		case "/v3/" + exampleDomain + "/templates/welcome":
+			fmt.Fprint(w, `{"template": {"name": "welcome"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL + "/v3")
+	mg.SetResponseCache(NewResponseCache(time.Minute))
+	ctx := context.Background()
+
+	// The match endpoint is not the GetRoute() of a route named "match"
+	for i := 0; i < 2; i++ {
+		_, err := mg.GetRoute(ctx, "123")
+		ensure.Nil(t, err)
+		_, err = mg.MatchRoute(ctx, "joe@example.com")
+		ensure.Nil(t, err)
+	}
+	ensure.DeepEqual(t, gets["/v3/routes/123"], 1)
+	ensure.DeepEqual(t, gets["/v3/routes/match"], 2)
+
+	// Creating a route invalidates the routes, not the other resources of the API
+	_, err := mg.GetDomainTracking(ctx, exampleDomain)
+	ensure.Nil(t, err)
+	_, err = mg.CreateRoute(ctx, Route{Expression: "match_recipient('.*')", Actions: []string{"stop()"}})
+	ensure.Nil(t, err)
+	_, err = mg.GetRoute(ctx, "123")
+	ensure.Nil(t, err)
+	_, err = mg.GetDomainTracking(ctx, exampleDomain)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, gets["/v3/routes/123"], 2)
+	ensure.DeepEqual(t, gets["/v3/domains/"+exampleDomain+"/tracking"], 1)
+
+	// Activating a version invalidates the template which reports the active version
+	_, err = mg.GetTemplate(ctx, "welcome")
+	ensure.Nil(t, err)
+	ensure.Nil(t, mg.ActivateTemplateVersion(ctx, "welcome", "v2"))
+	_, err = mg.GetTemplate(ctx, "welcome")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, gets["/v3/"+exampleDomain+"/templates/welcome"], 2)
+}
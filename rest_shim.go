@@ -2,7 +2,15 @@ package mailgun
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // The MailgunGoUserAgent identifies the client to the server, for logging purposes.
@@ -13,16 +21,77 @@ const MailgunGoUserAgent = "mailgun-go/" + Version
 // This error will be returned whenever a Mailgun API returns an error response.
 // Your application can check the Actual field to see the actual HTTP response code returned.
 // URL contains the base URL accessed, sans any query parameters.
+// Request contains the request parameters, with secrets redacted, if
+// SetErrorBodyCapture() was called on the client.
+// RetryAfter contains the delay requested by the server through the Retry-After header, if any.
 type UnexpectedResponseError struct {
-	Expected []int
-	Actual   int
-	URL      string
-	Data     []byte
+	Expected   []int
+	Actual     int
+	URL        string
+	Data       []byte
+	Request    string
+	RetryAfter time.Duration
+}
+
+// ErrServiceUnavailable matches the UnexpectedResponseError returned when Mailgun responds with
+// 503 Service Unavailable, typically during a maintenance window. Test for it with
+// IsServiceUnavailable(), or errors.Is() on Go 1.13 and later. Send() waits out the delay
+// requested by the server on its own, see DefaultMaintenanceBackoff. Applications should pause
+// their other requests for UnexpectedResponseError.RetryAfter, or use a longer backoff than
+// usual if it is not set, instead of retrying immediately.
+//
+//	if mailgun.IsServiceUnavailable(err) {
+//		pauseProducers(mailgun.RetryAfter(err, time.Minute))
+//	}
+var ErrServiceUnavailable = errors.New("mailgun service unavailable")
+
+// Is reports whether the error matches target, see ErrServiceUnavailable.
+func (e *UnexpectedResponseError) Is(target error) bool {
+	return target == ErrServiceUnavailable && e.Actual == http.StatusServiceUnavailable
+}
+
+// IsServiceUnavailable returns true if err is or wraps the UnexpectedResponseError of a
+// 503 Service Unavailable response, see ErrServiceUnavailable.
+func IsServiceUnavailable(err error) bool {
+	obj := responseError(err)
+	return obj != nil && obj.Is(ErrServiceUnavailable)
+}
+
+// RetryAfter returns the delay requested by the server before the failed request may be
+// retried, or fallback if the error does not wrap an UnexpectedResponseError or no delay was
+// requested.
+func RetryAfter(err error, fallback time.Duration) time.Duration {
+	obj := responseError(err)
+	if obj == nil || obj.RetryAfter <= 0 {
+		return fallback
+	}
+	return obj.RetryAfter
+}
+
+// responseError returns the UnexpectedResponseError wrapped by err, if any. Errors are unwrapped
+// with their Cause() method, as github.com/pkg/errors does, or their Unwrap() method.
+func responseError(err error) *UnexpectedResponseError {
+	for err != nil {
+		switch e := err.(type) {
+		case *UnexpectedResponseError:
+			return e
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
 }
 
 // String() converts the error into a human-readable, logfmt-compliant string.
 // See http://godoc.org/github.com/kr/logfmt for details on logfmt formatting.
 func (e *UnexpectedResponseError) String() string {
+	if e.Request != "" {
+		return fmt.Sprintf("UnexpectedResponseError URL=%s ExpectedOneOf=%#v Got=%d Request=%q Error: %s", e.URL, e.Expected, e.Actual, e.Request, string(e.Data))
+	}
 	return fmt.Sprintf("UnexpectedResponseError URL=%s ExpectedOneOf=%#v Got=%d Error: %s", e.URL, e.Expected, e.Actual, string(e.Data))
 }
 
@@ -32,13 +101,72 @@ func (e *UnexpectedResponseError) Error() string {
 }
 
 // newError creates a new error condition to be returned.
-func newError(url string, expected []int, got *httpResponse) error {
-	return &UnexpectedResponseError{
-		URL:      url,
-		Expected: expected,
-		Actual:   got.Code,
-		Data:     got.Data,
+func newError(r *httpRequest, p payload, expected []int, got *httpResponse) error {
+	e := &UnexpectedResponseError{
+		URL:        r.URL,
+		Expected:   expected,
+		Actual:     got.Code,
+		Data:       got.Data,
+		RetryAfter: parseRetryAfter(got.Header.Get("Retry-After")),
+	}
+	if r.ErrorBodyCapture > 0 {
+		e.Request = truncate(redactedParameters(r, p), r.ErrorBodyCapture)
+		if len(e.Data) > r.ErrorBodyCapture {
+			e.Data = append([]byte(nil), e.Data[:r.ErrorBodyCapture]...)
+		}
+	}
+	return e
+}
+
+// redactedParameters returns the query and payload parameters of a request in
+// url encoded form, with the values of any secrets replaced by "REDACTED".
+func redactedParameters(r *httpRequest, p payload) string {
+	params := url.Values{}
+	for key, values := range r.Parameters {
+		for _, value := range values {
+			params.Add(key, redact(key, value))
+		}
+	}
+	if p != nil {
+		for _, kv := range p.getValues() {
+			params.Add(kv.key, redact(kv.key, kv.value))
+		}
+	}
+	return params.Encode()
+}
+
+func redact(key, value string) string {
+	key = strings.ToLower(key)
+	for _, secret := range []string{"password", "secret", "key", "token"} {
+		if strings.Contains(key, secret) {
+			return "REDACTED"
+		}
+	}
+	return value
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number
+// of seconds or an HTTP date. Returns zero if the header is absent or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
 	}
+	return 0
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
 }
 
 // notGood searches a list of response codes (the haystack) for a matching entry (the needle).
@@ -62,7 +190,7 @@ func makeRequest(ctx context.Context, r *httpRequest, method string, p payload)
 	r.addHeader("User-Agent", MailgunGoUserAgent)
 	rsp, err := r.makeRequest(ctx, method, p)
 	if (err == nil) && notGood(rsp.Code, expected) {
-		return rsp, newError(r.URL, expected, rsp)
+		return rsp, newError(r, p, expected, rsp)
 	}
 	return rsp, err
 }
@@ -76,7 +204,7 @@ func getResponseFromJSON(ctx context.Context, r *httpRequest, v interface{}) err
 		return err
 	}
 	if notGood(response.Code, expected) {
-		return newError(r.URL, expected, response)
+		return newError(r, nil, expected, response)
 	}
 	return response.parseFromJSON(v)
 }
@@ -90,7 +218,7 @@ func postResponseFromJSON(ctx context.Context, r *httpRequest, p payload, v inte
 		return err
 	}
 	if notGood(response.Code, expected) {
-		return newError(r.URL, expected, response)
+		return newError(r, p, expected, response)
 	}
 	return response.parseFromJSON(v)
 }
@@ -104,18 +232,34 @@ func putResponseFromJSON(ctx context.Context, r *httpRequest, p payload, v inter
 		return err
 	}
 	if notGood(response.Code, expected) {
-		return newError(r.URL, expected, response)
+		return newError(r, p, expected, response)
 	}
 	return response.parseFromJSON(v)
 }
 
+// getResponseStream shim performs a GET request, checking for a positive outcome.
+// The response body is returned unread, the caller must close it.
+func getResponseStream(ctx context.Context, r *httpRequest) (io.ReadCloser, error) {
+	r.addHeader("User-Agent", MailgunGoUserAgent)
+	rsp, err := r.makeStreamingRequest(ctx, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	if notGood(rsp.StatusCode, expected) {
+		defer rsp.Body.Close()
+		data, _ := ioutil.ReadAll(rsp.Body)
+		return nil, newError(r, nil, expected, &httpResponse{Code: rsp.StatusCode, Header: rsp.Header, Data: data})
+	}
+	return rsp.Body, nil
+}
+
 // makeGetRequest shim performs a GET request, checking for a positive outcome.
 // See simplehttp.MakeGetRequest for more details.
 func makeGetRequest(ctx context.Context, r *httpRequest) (*httpResponse, error) {
 	r.addHeader("User-Agent", MailgunGoUserAgent)
 	rsp, err := r.makeGetRequest(ctx)
 	if (err == nil) && notGood(rsp.Code, expected) {
-		return rsp, newError(r.URL, expected, rsp)
+		return rsp, newError(r, nil, expected, rsp)
 	}
 	return rsp, err
 }
@@ -126,7 +270,7 @@ func makePostRequest(ctx context.Context, r *httpRequest, p payload) (*httpRespo
 	r.addHeader("User-Agent", MailgunGoUserAgent)
 	rsp, err := r.makePostRequest(ctx, p)
 	if (err == nil) && notGood(rsp.Code, expected) {
-		return rsp, newError(r.URL, expected, rsp)
+		return rsp, newError(r, p, expected, rsp)
 	}
 	return rsp, err
 }
@@ -137,7 +281,7 @@ func makePutRequest(ctx context.Context, r *httpRequest, p payload) (*httpRespon
 	r.addHeader("User-Agent", MailgunGoUserAgent)
 	rsp, err := r.makePutRequest(ctx, p)
 	if (err == nil) && notGood(rsp.Code, expected) {
-		return rsp, newError(r.URL, expected, rsp)
+		return rsp, newError(r, p, expected, rsp)
 	}
 	return rsp, err
 }
@@ -148,7 +292,7 @@ func makeDeleteRequest(ctx context.Context, r *httpRequest) (*httpResponse, erro
 	r.addHeader("User-Agent", MailgunGoUserAgent)
 	rsp, err := r.makeDeleteRequest(ctx)
 	if (err == nil) && notGood(rsp.Code, expected) {
-		return rsp, newError(r.URL, expected, rsp)
+		return rsp, newError(r, nil, expected, rsp)
 	}
 	return rsp, err
 }
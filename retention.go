@@ -0,0 +1,159 @@
+package mailgun
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StoredMessageRetention is how long Mailgun keeps stored messages before deleting them
+const StoredMessageRetention = 3 * 24 * time.Hour
+
+// DefaultJanitorInterval is how often a RetentionJanitor deletes expired messages
+const DefaultJanitorInterval = time.Hour
+
+// RetentionJanitor deletes stored messages sooner than Mailgun does, for applications with
+// stricter retention requirements. It walks the events of the domain which refer to a stored
+// message, the stored events of inbound messages and the accepted, delivered and failed events
+// of sent messages, and deletes the messages stored longer than MaxAge, optionally only those
+// with one of Tags.
+//
+//	janitor := mailgun.NewRetentionJanitor(mg, 6*time.Hour)
+//	janitor.Tags = []string{"support"}
+//	err := janitor.Run(ctx)
+type RetentionJanitor struct {
+	// Messages stored longer than MaxAge are deleted, zero deletes them on the next sweep
+	MaxAge time.Duration
+	// If set, only messages with one of these tags are deleted
+	Tags []string
+	// How often Run() sweeps, defaults to DefaultJanitorInterval
+	Interval time.Duration
+	// Called for each message deleted, with the event it was found by: an *events.Stored,
+	// *events.Accepted, *events.Delivered or *events.Failed
+	OnDelete func(Event)
+	// Called by Run() when a sweep fails
+	OnError func(error)
+
+	mg  Mailgun
+	now func() time.Time
+
+	mu sync.Mutex
+	// The keys of the messages already deleted, with their storage time
+	deleted map[string]time.Time
+}
+
+// NewRetentionJanitor creates a janitor which deletes messages of the domain of mg stored
+// longer than maxAge.
+func NewRetentionJanitor(mg Mailgun, maxAge time.Duration) *RetentionJanitor {
+	return &RetentionJanitor{
+		MaxAge:  maxAge,
+		mg:      mg,
+		now:     time.Now,
+		deleted: make(map[string]time.Time),
+	}
+}
+
+// Run sweeps every Interval until the context is cancelled.
+func (j *RetentionJanitor) Run(ctx context.Context) error {
+	interval := j.Interval
+	if interval == 0 {
+		interval = DefaultJanitorInterval
+	}
+	for {
+		if _, err := j.Sweep(ctx); err != nil && j.OnError != nil && ctx.Err() == nil {
+			j.OnError(err)
+		}
+
+		tick := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			tick.Stop()
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// Sweep deletes the messages which have expired since the previous sweep, and returns the
+// number of messages deleted. Messages which Mailgun already deleted are skipped.
+func (j *RetentionJanitor) Sweep(ctx context.Context) (int, error) {
+	now := j.now()
+	end := now.Add(-j.MaxAge)
+	begin := now.Add(-StoredMessageRetention)
+	if !end.After(begin) {
+		return 0, nil
+	}
+	j.forget(begin)
+
+	it := j.mg.ListEvents(&ListEventOptions{
+		Begin:          begin,
+		End:            end,
+		ForceAscending: true,
+		Limit:          MaxEventsLimit,
+		Filter:         map[string]string{"event": storedMessageEvents},
+	})
+
+	var count int
+	var page []Event
+	for it.Next(ctx, &page) {
+		for _, e := range page {
+			// Each event of a sent message carries the same storage key
+			stored, ok := storedMessageOf(e)
+			if !ok || !j.matches(stored) {
+				continue
+			}
+			if j.isDeleted(stored.storage.Key) {
+				continue
+			}
+			err := j.mg.DeleteStoredMessage(ctx, stored.storage.Key)
+			if err != nil && GetStatusFromErr(err) != http.StatusNotFound {
+				return count, err
+			}
+			j.markDeleted(stored.storage.Key, e.GetTimestamp())
+			if err == nil {
+				count++
+				if j.OnDelete != nil {
+					j.OnDelete(e)
+				}
+			}
+		}
+	}
+	return count, it.Err()
+}
+
+func (j *RetentionJanitor) matches(stored storedMessage) bool {
+	if len(j.Tags) == 0 {
+		return true
+	}
+	for _, tag := range stored.tags {
+		if containsString(j.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *RetentionJanitor) isDeleted(key string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.deleted[key]
+	return ok
+}
+
+func (j *RetentionJanitor) markDeleted(key string, stored time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.deleted[key] = stored
+}
+
+// forget drops the deleted keys of messages Mailgun no longer keeps
+func (j *RetentionJanitor) forget(before time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for key, stored := range j.deleted {
+		if stored.Before(before) {
+			delete(j.deleted, key)
+		}
+	}
+}
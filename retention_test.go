@@ -0,0 +1,94 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+func TestRetentionJanitor(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	messageEvent := func(event, key, tag string, age time.Duration) string {
+		return fmt.Sprintf(`{"event": %q, "timestamp": %d, "tags": [%q], "storage": {"key": %q}}`,
+			event, now.Add(-age).Unix(), tag, key)
+	}
+	storedEvent := func(key, tag string, age time.Duration) string {
+		return messageEvent("stored", key, tag, age)
+	}
+
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/"+exampleDomain+"/events" && req.FormValue("page") == "":
+			ensure.DeepEqual(t, req.FormValue("event"), "stored OR accepted OR delivered OR failed")
+			ensure.DeepEqual(t, req.FormValue("begin"), formatMailgunTime(now.Add(-StoredMessageRetention)))
+			ensure.DeepEqual(t, req.FormValue("end"), formatMailgunTime(now.Add(-6*time.Hour)))
+			fmt.Fprintf(w, `{"items": [%s, %s, %s, %s, %s], "paging": {"next": "http://%s/%s/events?page=2"}}`,
+				storedEvent("key-1", "support", 48*time.Hour),
+				storedEvent("key-2", "billing", 24*time.Hour),
+				// A sent message, deleted once
+				messageEvent("accepted", "key-4", "support", 20*time.Hour),
+				messageEvent("delivered", "key-4", "support", 19*time.Hour),
+				storedEvent("key-3", "support", 12*time.Hour),
+				req.Host, exampleDomain)
+		case req.URL.Path == "/"+exampleDomain+"/events":
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+		case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/domains/"+exampleDomain+"/messages/"):
+			key := strings.TrimPrefix(req.URL.Path, "/domains/"+exampleDomain+"/messages/")
+			if key == "key-3" {
+				// Already deleted
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message": "Message not found"}`)
+				return
+			}
+			deleted = append(deleted, key)
+			fmt.Fprint(w, `{"message": "Message has been deleted"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	janitor := NewRetentionJanitor(mg, 6*time.Hour)
+	janitor.now = func() time.Time { return now }
+	janitor.Tags = []string{"support"}
+	var notified []string
+	janitor.OnDelete = func(e Event) {
+		switch e := e.(type) {
+		case *events.Stored:
+			notified = append(notified, e.Storage.Key)
+		case *events.Accepted:
+			notified = append(notified, e.Storage.Key)
+		}
+	}
+
+	count, err := janitor.Sweep(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, count, 2)
+	ensure.DeepEqual(t, deleted, []string{"key-1", "key-4"})
+	ensure.DeepEqual(t, notified, []string{"key-1", "key-4"})
+
+	// Messages deleted by a previous sweep are not deleted again
+	janitor.Tags = nil
+	count, err = janitor.Sweep(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, count, 1)
+	ensure.DeepEqual(t, deleted, []string{"key-1", "key-4", "key-2"})
+
+	// A maximum age longer than Mailgun keeps messages never deletes anything
+	janitor.MaxAge = 4 * 24 * time.Hour
+	count, err = janitor.Sweep(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, count, 0)
+}
@@ -0,0 +1,87 @@
+package mailgun
+
+import (
+	"strings"
+)
+
+// RouteExpression is the filter of a route, built with MatchRecipient(), MatchHeader() and
+// CatchAll() instead of writing Mailgun's expression syntax by hand.
+//
+//	route := mailgun.NewRoute(1, "Support tickets",
+//		mailgun.MatchRecipient("support@.*").And(mailgun.MatchHeader("subject", ".*urgent.*")),
+//		mailgun.Forward("https://example.com/tickets"),
+//		mailgun.Stop(),
+//	)
+//	route, err := mg.CreateRoute(ctx, route)
+type RouteExpression string
+
+// RouteAction is an action performed on the messages matched by a route, built with Forward(),
+// StoreMessage() and Stop().
+type RouteAction string
+
+// MatchRecipient matches messages whose SMTP recipient matches the regular expression.
+func MatchRecipient(pattern string) RouteExpression {
+	return RouteExpression("match_recipient(" + routeString(pattern) + ")")
+}
+
+// MatchHeader matches messages with a header whose value matches the regular expression.
+func MatchHeader(header, pattern string) RouteExpression {
+	return RouteExpression("match_header(" + routeString(header) + ", " + routeString(pattern) + ")")
+}
+
+// CatchAll matches messages which did not match any higher priority route.
+func CatchAll() RouteExpression {
+	return RouteExpression("catch_all()")
+}
+
+// And matches messages matched by both expressions.
+func (e RouteExpression) And(other RouteExpression) RouteExpression {
+	return e + " and " + other
+}
+
+func (e RouteExpression) String() string {
+	return string(e)
+}
+
+// Forward forwards messages to an email address or posts them to a URL.
+func Forward(destination string) RouteAction {
+	return RouteAction("forward(" + routeString(destination) + ")")
+}
+
+// StoreMessage stores messages for later retrieval with GetStoredMessage(). If notifyURL is not
+// empty, Mailgun posts a notification to it when a message is stored. It builds Mailgun's store()
+// action, but is named StoreMessage because Store is the key/value store used by EventCoordinator.
+func StoreMessage(notifyURL string) RouteAction {
+	if notifyURL == "" {
+		return RouteAction("store()")
+	}
+	return RouteAction("store(notify=" + routeString(notifyURL) + ")")
+}
+
+// Stop stops the evaluation of lower priority routes for the messages matched by the route.
+func Stop() RouteAction {
+	return RouteAction("stop()")
+}
+
+func (a RouteAction) String() string {
+	return string(a)
+}
+
+// NewRoute returns a route to pass to CreateRoute(), see RouteExpression.
+func NewRoute(priority int, description string, expr RouteExpression, actions ...RouteAction) Route {
+	route := Route{
+		Priority:    priority,
+		Description: description,
+		Expression:  string(expr),
+	}
+	for _, action := range actions {
+		route.Actions = append(route.Actions, string(action))
+	}
+	return route
+}
+
+// routeString quotes a string argument of a route filter or action. Mailgun keeps backslashes,
+// except before the quote character, so regular expressions are passed unchanged.
+func routeString(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
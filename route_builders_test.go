@@ -0,0 +1,47 @@
+package mailgun_test
+
+import (
+	"net/mail"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+func TestRouteBuilders(t *testing.T) {
+	route := mailgun.NewRoute(1, "Urgent support",
+		mailgun.MatchRecipient(`support@example\.com`).And(mailgun.MatchHeader("subject", `.*"urgent"`)),
+		mailgun.Forward("oncall@example.com"),
+		mailgun.StoreMessage("http://example.com/support"),
+		mailgun.Stop(),
+	)
+	ensure.DeepEqual(t, route, mailgun.Route{
+		Priority:    1,
+		Description: "Urgent support",
+		Expression:  `match_recipient("support@example\.com") and match_header("subject", ".*\"urgent\"")`,
+		Actions: []string{
+			`forward("oncall@example.com")`,
+			`store(notify="http://example.com/support")`,
+			`stop()`,
+		},
+	})
+	ensure.DeepEqual(t, mailgun.StoreMessage("").String(), "store()")
+	ensure.DeepEqual(t, mailgun.CatchAll().String(), "catch_all()")
+
+	// The expressions are understood by the route simulator
+	sim, err := mailgun.NewRouteSimulator([]mailgun.Route{
+		route,
+		mailgun.NewRoute(10, "Unrouted", mailgun.CatchAll(), mailgun.Forward("http://example.com/unrouted")),
+	})
+	ensure.Nil(t, err)
+	result := sim.Simulate(mailgun.SampleMessage{
+		Recipient: "support@example.com",
+		Header:    mail.Header{"Subject": {`Re: "urgent"`}},
+	})
+	ensure.DeepEqual(t, len(result.Matched), 1)
+	ensure.DeepEqual(t, result.Matched[0].Description, "Urgent support")
+	ensure.True(t, result.Stopped)
+
+	result = sim.Simulate(mailgun.SampleMessage{Recipient: "sales@example.com"})
+	ensure.DeepEqual(t, result.Actions, []string{`forward("http://example.com/unrouted")`})
+}
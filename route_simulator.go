@@ -0,0 +1,294 @@
+package mailgun
+
+import (
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SampleMessage is an inbound message evaluated by a RouteSimulator
+type SampleMessage struct {
+	// The SMTP recipient of the message, as matched by match_recipient()
+	Recipient string
+	// The headers of the message, as matched by match_header()
+	Header mail.Header
+}
+
+// RouteSimulation reports how Mailgun would route a sample message
+type RouteSimulation struct {
+	// The routes which match the message, in the order Mailgun evaluates them
+	Matched []Route
+	// The actions of the matched routes, in the order Mailgun performs them
+	Actions []string
+	// True if a matched route stopped the evaluation of lower priority routes with stop()
+	Stopped bool
+}
+
+// RouteSyntaxError is returned by NewRouteSimulator() when the expression of a route
+// cannot be parsed
+type RouteSyntaxError struct {
+	Route Route
+	// The offset in the expression where the error was found
+	Offset int
+	Reason string
+}
+
+func (e *RouteSyntaxError) Error() string {
+	return fmt.Sprintf("route '%s': invalid expression '%s' at offset %d: %s",
+		e.Route.Description, e.Route.Expression, e.Offset, e.Reason)
+}
+
+// RouteSimulator evaluates routes against sample messages locally, the way Mailgun evaluates
+// them against inbound messages. Use it to test route changes before they are deployed.
+//
+//	sim, err := mailgun.NewRouteSimulator(routes)
+//	if err != nil {
+//		return err
+//	}
+//	result := sim.Simulate(mailgun.SampleMessage{
+//		Recipient: "support@example.com",
+//		Header:    mail.Header{"Subject": {"Urgent: help"}},
+//	})
+//
+// The filters match_recipient(), match_header() and catch_all() are supported and may be combined
+// with 'and'. Patterns are matched from the start of the value, as Mailgun does.
+type RouteSimulator struct {
+	routes []compiledRoute
+}
+
+type compiledRoute struct {
+	route   Route
+	filters []routeFilter
+	stop    bool
+}
+
+// routeFilter returns true if the message matches, matched is true if a route was already matched
+type routeFilter func(msg *SampleMessage, matched bool) bool
+
+// NewRouteSimulator compiles the expressions of the routes. Routes are evaluated in order of
+// priority, routes of equal priority in the order given.
+func NewRouteSimulator(routes []Route) (*RouteSimulator, error) {
+	var sim RouteSimulator
+	for _, route := range routes {
+		filters, err := parseRouteExpression(route)
+		if err != nil {
+			return nil, err
+		}
+		cr := compiledRoute{route: route, filters: filters}
+		for _, action := range route.Actions {
+			if strings.TrimSpace(action) == "stop()" {
+				cr.stop = true
+			}
+		}
+		sim.routes = append(sim.routes, cr)
+	}
+	sort.SliceStable(sim.routes, func(i, j int) bool {
+		return sim.routes[i].route.Priority < sim.routes[j].route.Priority
+	})
+	return &sim, nil
+}
+
+// Simulate returns the routes which match the message and the actions Mailgun would perform.
+func (s *RouteSimulator) Simulate(msg SampleMessage) RouteSimulation {
+	var result RouteSimulation
+	for _, cr := range s.routes {
+		if !cr.matches(&msg, len(result.Matched) != 0) {
+			continue
+		}
+		result.Matched = append(result.Matched, cr.route)
+		result.Actions = append(result.Actions, cr.route.Actions...)
+		if cr.stop {
+			result.Stopped = true
+			break
+		}
+	}
+	return result
+}
+
+func (cr *compiledRoute) matches(msg *SampleMessage, matched bool) bool {
+	for _, f := range cr.filters {
+		if !f(msg, matched) {
+			return false
+		}
+	}
+	return true
+}
+
+// routeParser parses expressions of the form `filter(args) and filter(args) ...`
+type routeParser struct {
+	route Route
+	expr  string
+	pos   int
+}
+
+func parseRouteExpression(route Route) ([]routeFilter, error) {
+	p := routeParser{route: route, expr: route.Expression}
+	var filters []routeFilter
+	for {
+		p.skipSpace()
+		start := p.pos
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf("expected a filter")
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		f, err := p.filter(start, name, args)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+
+		p.skipSpace()
+		if p.pos == len(p.expr) {
+			return filters, nil
+		}
+		if start := p.pos; p.ident() != "and" {
+			return nil, p.errorAt(start, "expected 'and'")
+		}
+	}
+}
+
+func (p *routeParser) filter(start int, name string, args []string) (routeFilter, error) {
+	switch name {
+	case "match_recipient":
+		if len(args) != 1 {
+			return nil, p.errorAt(start, "match_recipient() takes a pattern")
+		}
+		re, err := compileRoutePattern(args[0])
+		if err != nil {
+			return nil, p.errorAt(start, err.Error())
+		}
+		return func(msg *SampleMessage, _ bool) bool {
+			return re.MatchString(msg.Recipient)
+		}, nil
+	case "match_header":
+		if len(args) != 2 {
+			return nil, p.errorAt(start, "match_header() takes a header name and a pattern")
+		}
+		re, err := compileRoutePattern(args[1])
+		if err != nil {
+			return nil, p.errorAt(start, err.Error())
+		}
+		key := textproto.CanonicalMIMEHeaderKey(args[0])
+		return func(msg *SampleMessage, _ bool) bool {
+			for _, v := range msg.Header[key] {
+				if re.MatchString(v) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "catch_all":
+		if len(args) != 0 {
+			return nil, p.errorAt(start, "catch_all() takes no arguments")
+		}
+		return func(_ *SampleMessage, matched bool) bool {
+			return !matched
+		}, nil
+	}
+	return nil, p.errorAt(start, fmt.Sprintf("unknown filter '%s'", name))
+}
+
+func (p *routeParser) skipSpace() {
+	for p.pos < len(p.expr) && strings.ContainsRune(" \t\r\n", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *routeParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// args parses a parenthesized list of quoted strings
+func (p *routeParser) args() ([]string, error) {
+	p.skipSpace()
+	if p.pos == len(p.expr) || p.expr[p.pos] != '(' {
+		return nil, p.errorf("expected '('")
+	}
+	p.pos++
+
+	var args []string
+	for {
+		p.skipSpace()
+		if p.pos == len(p.expr) {
+			return nil, p.errorf("expected ')'")
+		}
+		if p.expr[p.pos] == ')' && len(args) == 0 {
+			p.pos++
+			return args, nil
+		}
+		arg, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		p.skipSpace()
+		if p.pos == len(p.expr) {
+			return nil, p.errorf("expected ')'")
+		}
+		switch p.expr[p.pos] {
+		case ')':
+			p.pos++
+			return args, nil
+		case ',':
+			p.pos++
+		default:
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+// quoted parses a single or double quoted string. Backslashes are kept, except before
+// the quote character, so regular expression escapes pass through unchanged.
+func (p *routeParser) quoted() (string, error) {
+	if p.pos == len(p.expr) || (p.expr[p.pos] != '"' && p.expr[p.pos] != '\'') {
+		return "", p.errorf("expected a quoted string")
+	}
+	start, quote := p.pos, p.expr[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.expr) && p.expr[p.pos+1] == quote:
+			b.WriteByte(quote)
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorAt(start, "unterminated string")
+}
+
+func (p *routeParser) errorf(reason string) error {
+	return p.errorAt(p.pos, reason)
+}
+
+func (p *routeParser) errorAt(offset int, reason string) error {
+	return &RouteSyntaxError{Route: p.route, Offset: offset, Reason: reason}
+}
+
+// compileRoutePattern compiles a pattern which matches from the start of the value
+func compileRoutePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")")
+}
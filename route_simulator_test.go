@@ -0,0 +1,89 @@
+package mailgun_test
+
+import (
+	"net/mail"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go"
+)
+
+func TestRouteSimulator(t *testing.T) {
+	routes := []mailgun.Route{
+		{
+			Priority:    10,
+			Description: "Catch all",
+			Expression:  "catch_all()",
+			Actions:     []string{`forward("http://example.com/unrouted")`},
+		},
+		{
+			Priority:    1,
+			Description: "Urgent support",
+			Expression:  `match_recipient('support@example\.com') and match_header("subject", ".*(urgent|asap)")`,
+			Actions:     []string{`forward("oncall@example.com")`, `stop()`},
+		},
+		{
+			Priority:    1,
+			Description: "Support",
+			Expression:  `match_recipient("support@example\.com")`,
+			Actions:     []string{`store(notify="http://example.com/support")`},
+		},
+		{
+			Priority:    5,
+			Description: "Archive",
+			Expression:  `match_recipient(".*@example\.com")`,
+			Actions:     []string{`forward("archive@example.com")`},
+		},
+	}
+	sim, err := mailgun.NewRouteSimulator(routes)
+	ensure.Nil(t, err)
+
+	// The urgent route stops evaluation
+	result := sim.Simulate(mailgun.SampleMessage{
+		Recipient: "support@example.com",
+		Header:    mail.Header{"Subject": {"Printer on fire, urgent"}},
+	})
+	ensure.DeepEqual(t, len(result.Matched), 1)
+	ensure.DeepEqual(t, result.Matched[0].Description, "Urgent support")
+	ensure.DeepEqual(t, result.Actions, []string{`forward("oncall@example.com")`, `stop()`})
+	ensure.True(t, result.Stopped)
+
+	// Without the header every matching route runs, in order of priority
+	result = sim.Simulate(mailgun.SampleMessage{
+		Recipient: "support@example.com",
+		Header:    mail.Header{"Subject": {"Question"}},
+	})
+	ensure.DeepEqual(t, len(result.Matched), 2)
+	ensure.DeepEqual(t, result.Matched[0].Description, "Support")
+	ensure.DeepEqual(t, result.Matched[1].Description, "Archive")
+	ensure.False(t, result.Stopped)
+
+	// Patterns are anchored at the start of the value
+	result = sim.Simulate(mailgun.SampleMessage{Recipient: "customer-support@example.com"})
+	ensure.DeepEqual(t, len(result.Matched), 1)
+	ensure.DeepEqual(t, result.Matched[0].Description, "Archive")
+
+	// catch_all() only matches when no other route did
+	result = sim.Simulate(mailgun.SampleMessage{Recipient: "someone@other.com"})
+	ensure.DeepEqual(t, len(result.Matched), 1)
+	ensure.DeepEqual(t, result.Matched[0].Description, "Catch all")
+}
+
+func TestRouteSimulatorSyntaxError(t *testing.T) {
+	for _, tt := range []struct {
+		expression string
+		offset     int
+	}{
+		{`match_sender(".*")`, 0},
+		{`match_recipient(".*") or catch_all()`, 22},
+		{`match_recipient(".*`, 16},
+		{`match_header("subject")`, 0},
+		{`match_recipient("(")`, 0},
+	} {
+		_, err := mailgun.NewRouteSimulator([]mailgun.Route{{Expression: tt.expression}})
+		ensure.NotNil(t, err)
+		serr, ok := err.(*mailgun.RouteSyntaxError)
+		ensure.True(t, ok, tt.expression)
+		ensure.DeepEqual(t, serr.Offset, tt.offset)
+	}
+}
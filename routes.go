@@ -2,6 +2,7 @@ package mailgun
 
 import (
 	"context"
+	"errors"
 	"strconv"
 )
 
@@ -43,6 +44,9 @@ type createRouteResp struct {
 func (mg *MailgunImpl) ListRoutes(opts *ListOptions) *RoutesIterator {
 	var limit int
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &RoutesIterator{mg: mg, err: err}
+		}
 		limit = opts.Limit
 	}
 
@@ -66,13 +70,51 @@ type RoutesIterator struct {
 	offset int
 	url    string
 	err    error
+
+	seen       map[string]bool
+	firstTotal int
+	modified   bool
 }
 
+// ErrListModified is returned by `Warning()` when the list being iterated was modified
+// between pages. Lists paged by offset, such as routes, may then have skipped or repeated
+// items and should be iterated again if a consistent view is needed. Lists paged by address,
+// such as mailing list members and bounces, resume after the last address returned and are
+// not affected.
+var ErrListModified = errors.New("list was modified during iteration, items may have been skipped or repeated")
+
 // If an error occurred during iteration `Err()` will return non nil
 func (ri *RoutesIterator) Err() error {
 	return ri.err
 }
 
+// Returns ErrListModified if routes were added or removed while iterating with `First()`
+// and `Next()`, otherwise nil. Calling `First()` starts a new iteration and resets the warning.
+func (ri *RoutesIterator) Warning() error {
+	if ri.modified {
+		return ErrListModified
+	}
+	return nil
+}
+
+// Records the routes of the current page, flagging the iteration as modified
+// if the total count changed or a route was returned by a previous page.
+func (ri *RoutesIterator) track() {
+	if ri.seen == nil {
+		ri.seen = make(map[string]bool)
+		ri.firstTotal = ri.TotalCount
+	}
+	if ri.TotalCount != ri.firstTotal {
+		ri.modified = true
+	}
+	for _, route := range ri.Items {
+		if ri.seen[route.Id] {
+			ri.modified = true
+		}
+		ri.seen[route.Id] = true
+	}
+}
+
 // Returns the current offset of the iterator
 func (ri *RoutesIterator) Offset() int {
 	return ri.offset
@@ -90,6 +132,7 @@ func (ri *RoutesIterator) Next(ctx context.Context, items *[]Route) bool {
 	if ri.err != nil {
 		return false
 	}
+	ri.track()
 
 	cpy := make([]Route, len(ri.Items))
 	copy(cpy, ri.Items)
@@ -112,6 +155,8 @@ func (ri *RoutesIterator) First(ctx context.Context, items *[]Route) bool {
 	if ri.err != nil {
 		return false
 	}
+	ri.seen, ri.modified = nil, false
+	ri.track()
 	cpy := make([]Route, len(ri.Items))
 	copy(cpy, ri.Items)
 	*items = cpy
@@ -136,6 +181,8 @@ func (ri *RoutesIterator) Last(ctx context.Context, items *[]Route) bool {
 	if ri.offset < 0 {
 		ri.offset = 0
 	}
+	// Pages are no longer consecutive, only track modifications from here on
+	ri.seen = nil
 
 	ri.err = ri.fetch(ctx, ri.offset, ri.limit)
 	if ri.err != nil {
@@ -163,6 +210,8 @@ func (ri *RoutesIterator) Previous(ctx context.Context, items *[]Route) bool {
 	if ri.offset < 0 {
 		ri.offset = 0
 	}
+	// Pages are no longer consecutive, only track modifications from here on
+	ri.seen = nil
 
 	ri.err = ri.fetch(ctx, ri.offset, ri.limit)
 	if ri.err != nil {
@@ -180,7 +229,7 @@ func (ri *RoutesIterator) Previous(ctx context.Context, items *[]Route) bool {
 func (ri *RoutesIterator) fetch(ctx context.Context, skip, limit int) error {
 	r := newHTTPRequest(ri.url)
 	r.setBasicAuth(basicAuthUser, ri.mg.APIKey())
-	r.setClient(ri.mg.Client())
+	r.setClient(ri.mg)
 
 	if skip != 0 {
 		r.addParameter("skip", strconv.Itoa(skip))
@@ -198,7 +247,7 @@ func (ri *RoutesIterator) fetch(ctx context.Context, skip, limit int) error {
 // See the Route structure definition for more details.
 func (mg *MailgunImpl) CreateRoute(ctx context.Context, prototype Route) (_ignored Route, err error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, routesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	p.addValue("priority", strconv.Itoa(prototype.Priority))
@@ -219,7 +268,7 @@ func (mg *MailgunImpl) CreateRoute(ctx context.Context, prototype Route) (_ignor
 // See the Route structure definition and the Mailgun API documentation for more details.
 func (mg *MailgunImpl) DeleteRoute(ctx context.Context, id string) error {
 	r := newHTTPRequest(generatePublicApiUrl(mg, routesEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -228,18 +277,30 @@ func (mg *MailgunImpl) DeleteRoute(ctx context.Context, id string) error {
 // GetRoute retrieves the complete route definition associated with the unique route ID.
 func (mg *MailgunImpl) GetRoute(ctx context.Context, id string) (Route, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, routesEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var envelope struct {
 		Message string `json:"message"`
-		*Route  `json:"route"`
+		Route   `json:"route"`
 	}
 	err := getResponseFromJSON(ctx, r, &envelope)
-	if err != nil {
-		return Route{}, err
-	}
-	return *envelope.Route, err
+	return envelope.Route, err
+}
 
+// MatchRoute returns the route Mailgun would use for inbound messages to the address, so
+// routing rules can be verified before going live. If no route matches, the error has a
+// status of 404, see GetStatusFromErr().
+func (mg *MailgunImpl) MatchRoute(ctx context.Context, address string) (Route, error) {
+	r := newHTTPRequest(generatePublicApiUrl(mg, routesEndpoint) + "/match")
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	r.addParameter("address", address)
+	var envelope struct {
+		Message string `json:"message"`
+		Route   `json:"route"`
+	}
+	err := getResponseFromJSON(ctx, r, &envelope)
+	return envelope.Route, err
 }
 
 // UpdateRoute provides an "in-place" update of the specified route.
@@ -247,7 +308,7 @@ func (mg *MailgunImpl) GetRoute(ctx context.Context, id string) (Route, error) {
 // All other fields remain as-is.
 func (mg *MailgunImpl) UpdateRoute(ctx context.Context, id string, route Route) (Route, error) {
 	r := newHTTPRequest(generatePublicApiUrl(mg, routesEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	if route.Priority != 0 {
@@ -56,6 +56,43 @@ func TestRouteCRUD(t *testing.T) {
 	ensure.DeepEqual(t, len(changedRoute.Actions), 2)
 }
 
+func TestMatchRoute(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	route, err := mg.MatchRoute(ctx, "support@samples.mailgun.org")
+	ensure.Nil(t, err)
+	ensure.True(t, route.Id != "")
+	ensure.DeepEqual(t, route.Expression, `match_recipient(".*@samples.mailgun.org")`)
+
+	_, err = mg.MatchRoute(ctx, "support@example.com")
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+}
+
+func TestDeleteUnknownRoute(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	routeCount := len(mustListRoutes(t, mg))
+	err := mg.DeleteRoute(ctx, "ID-unknown")
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
+	ensure.DeepEqual(t, len(mustListRoutes(t, mg)), routeCount)
+}
+
+func mustListRoutes(t *testing.T, mg mailgun.Mailgun) []mailgun.Route {
+	var routes, page []mailgun.Route
+	it := mg.ListRoutes(nil)
+	for it.Next(context.Background(), &page) {
+		routes = append(routes, page...)
+	}
+	ensure.Nil(t, it.Err())
+	return routes
+}
+
 func TestRoutesIterator(t *testing.T) {
 	mg := mailgun.NewMailgun(testDomain, testKey)
 	mg.SetAPIBase(server.URL())
@@ -105,3 +142,40 @@ func TestRoutesIterator(t *testing.T) {
 	ensure.True(t, len(firstPage) != 0)
 
 }
+
+func TestRoutesIteratorWarning(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+	ctx := context.Background()
+
+	// Unmodified list iterates without a warning
+	it := mg.ListRoutes(&mailgun.ListOptions{Limit: 3})
+	var page []mailgun.Route
+	for it.Next(ctx, &page) {
+	}
+	ensure.Nil(t, it.Err())
+	ensure.Nil(t, it.Warning())
+
+	// Add a route between pages
+	it = mg.ListRoutes(&mailgun.ListOptions{Limit: 3})
+	ensure.True(t, it.Next(ctx, &page))
+	newRoute, err := mg.CreateRoute(ctx, mailgun.Route{
+		Priority:    1,
+		Description: "Sample Route",
+		Expression:  "match_recipient(\".*@samples.mailgun.org\")",
+		Actions:     []string{"stop()"},
+	})
+	ensure.Nil(t, err)
+	defer func() {
+		ensure.Nil(t, mg.DeleteRoute(ctx, newRoute.Id))
+	}()
+
+	for it.Next(ctx, &page) {
+	}
+	ensure.Nil(t, it.Err())
+	ensure.DeepEqual(t, it.Warning(), mailgun.ErrListModified)
+
+	// First() starts a new iteration
+	ensure.True(t, it.First(ctx, &page))
+	ensure.Nil(t, it.Warning())
+}
@@ -0,0 +1,202 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// ScheduledMessage is a message sent with a delivery time which Mailgun has not delivered yet
+type ScheduledMessage struct {
+	// The id returned by Send(), which identifies the message to Cancel()
+	ID           string    `json:"id"`
+	DeliveryTime time.Time `json:"delivery-time"`
+	Recipients   []string  `json:"recipients"`
+	Subject      string    `json:"subject"`
+	Tags         []string  `json:"tags"`
+}
+
+// ScheduleTracker records the messages sent with a delivery time in a Store, as Mailgun has no
+// endpoint listing the messages it holds until their delivery time. Messages sent through the
+// tracker are listed by ScheduledMessages() until their delivery time, and may be cancelled
+// with Cancel().
+//
+//	tracker := mailgun.NewScheduleTracker(mg, store)
+//	m.SetDeliveryTime(time.Now().Add(24 * time.Hour))
+//	_, id, err := tracker.Send(ctx, m)
+//	...
+//	pending, err := tracker.ScheduledMessages(ctx, "newsletter")
+//	err = tracker.Cancel(ctx, id)
+//
+// The messages are recorded under a single key of the store, a ScheduleTracker is safe for
+// concurrent use but processes sharing the store must not send through trackers of their own.
+type ScheduleTracker struct {
+	mg    Mailgun
+	store Store
+	now   func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewScheduleTracker creates a tracker which records the messages scheduled through mg in store.
+func NewScheduleTracker(mg Mailgun, store Store) *ScheduleTracker {
+	return &ScheduleTracker{mg: mg, store: store, now: time.Now}
+}
+
+// Send sends the message with mg.Send(), and records it if its delivery time is in the future.
+func (t *ScheduleTracker) Send(ctx context.Context, m *Message) (string, string, error) {
+	mes, id, err := t.mg.Send(ctx, m)
+	if err != nil || !m.deliveryTime.After(t.now()) {
+		return mes, id, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scheduled, err := t.load(ctx)
+	if err != nil {
+		return mes, id, err
+	}
+	sm := ScheduledMessage{
+		ID:           id,
+		DeliveryTime: m.deliveryTime,
+		Recipients:   m.recipients(),
+		Tags:         m.tags,
+	}
+	if pm, ok := m.specific.(*plainMessage); ok {
+		sm.Subject = pm.subject
+	}
+	scheduled = append(scheduled, sm)
+	return mes, id, t.save(ctx, scheduled)
+}
+
+// ScheduledMessages returns the messages whose delivery time has not passed, optionally only
+// those with one of tags, soonest first.
+func (t *ScheduleTracker) ScheduledMessages(ctx context.Context, tags ...string) ([]ScheduledMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scheduled, err := t.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forget the messages Mailgun has delivered
+	now := t.now()
+	pending := scheduled[:0]
+	for _, s := range scheduled {
+		if s.DeliveryTime.After(now) {
+			pending = append(pending, s)
+		}
+	}
+	if len(pending) != len(scheduled) {
+		if err := t.save(ctx, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []ScheduledMessage
+	for _, s := range pending {
+		if len(tags) == 0 || containsAny(tags, s.Tags) {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DeliveryTime.Before(result[j].DeliveryTime) })
+	return result, nil
+}
+
+// Cancel deletes a scheduled message from the storage of Mailgun, which cancels its delivery.
+// The message is found by the storage of its accepted event, which Mailgun may take a few
+// seconds to publish after the message was sent. If Mailgun no longer stores the message, it
+// is forgotten and the 404 error is returned, as it may have been delivered already.
+func (t *ScheduleTracker) Cancel(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scheduled, err := t.load(ctx)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for i < len(scheduled) && scheduled[i].ID != id {
+		i++
+	}
+	if i == len(scheduled) {
+		return fmt.Errorf("no scheduled message with id '%s'", id)
+	}
+
+	key, err := t.storageKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	deleteErr := t.mg.DeleteStoredMessage(ctx, key)
+	if deleteErr != nil && GetStatusFromErr(deleteErr) != http.StatusNotFound {
+		return deleteErr
+	}
+	scheduled = append(scheduled[:i], scheduled[i+1:]...)
+	if err := t.save(ctx, scheduled); err != nil {
+		return err
+	}
+	return deleteErr
+}
+
+// storageKey returns the storage key of a message from its accepted events
+func (t *ScheduleTracker) storageKey(ctx context.Context, id string) (string, error) {
+	it := t.mg.ListEvents(&ListEventOptions{
+		Limit: MaxEventsLimit,
+		Filter: map[string]string{
+			"event":      events.EventAccepted,
+			"message-id": strings.Trim(id, "<>"),
+		},
+	})
+	var page []Event
+	for it.Next(ctx, &page) {
+		for _, e := range page {
+			if accepted, ok := e.(*events.Accepted); ok && accepted.Storage.Key != "" {
+				return accepted.Storage.Key, nil
+			}
+		}
+	}
+	if it.Err() != nil {
+		return "", it.Err()
+	}
+	return "", fmt.Errorf("message '%s' is not stored yet, try again later", id)
+}
+
+func (t *ScheduleTracker) load(ctx context.Context) ([]ScheduledMessage, error) {
+	value, err := t.store.Get(ctx, t.key())
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var scheduled []ScheduledMessage
+	if err := json.Unmarshal([]byte(value), &scheduled); err != nil {
+		return nil, fmt.Errorf("while reading the scheduled messages: %s", err)
+	}
+	return scheduled, nil
+}
+
+func (t *ScheduleTracker) save(ctx context.Context, scheduled []ScheduledMessage) error {
+	value, err := json.Marshal(scheduled)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, t.key(), string(value))
+}
+
+func (t *ScheduleTracker) key() string {
+	return "mailgun-scheduled/" + t.mg.Domain()
+}
+
+// containsAny returns true if any of values is in list
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		if containsString(list, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestScheduleTracker(t *testing.T) {
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	var sent int
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/"+exampleDomain+"/messages":
+			sent++
+			fmt.Fprintf(w, `{"message": "Queued. Thank you.", "id": "<message-%d@%s>"}`, sent, exampleDomain)
+		case req.URL.Path == "/"+exampleDomain+"/events":
+			ensure.DeepEqual(t, req.FormValue("event"), "accepted")
+			id := req.FormValue("message-id")
+			fmt.Fprintf(w, `{"items": [{"event": "accepted", "timestamp": %d, "storage": {"key": "key-%s"},
+				"message": {"headers": {"message-id": %q}}}], "paging": {}}`, now.Unix(), id, id)
+		case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/domains/"+exampleDomain+"/messages/"):
+			key := strings.TrimPrefix(req.URL.Path, "/domains/"+exampleDomain+"/messages/")
+			if key == "key-message-3@"+exampleDomain {
+				// Delivered already
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message": "Message not found"}`)
+				return
+			}
+			deleted = append(deleted, key)
+			fmt.Fprint(w, `{"message": "Message has been deleted"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	tracker := NewScheduleTracker(mg, NewMemoryStore())
+	tracker.now = func() time.Time { return now }
+	send := func(subject, tag string, delay time.Duration) string {
+		m := mg.NewMessage(fromUser, subject, exampleText, "joe@example.com")
+		ensure.Nil(t, m.AddTag(tag))
+		if delay != 0 {
+			m.SetDeliveryTime(now.Add(delay))
+		}
+		_, id, err := tracker.Send(ctx, m)
+		ensure.Nil(t, err)
+		return id
+	}
+	send("Now", "newsletter", 0)
+	tomorrow := send("Tomorrow", "newsletter", 24*time.Hour)
+	soon := send("Soon", "billing", time.Hour)
+	send("Later", "newsletter", 2*time.Hour)
+
+	// Messages sent without a delivery time are not recorded
+	scheduled, err := tracker.ScheduledMessages(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(scheduled), 3)
+	ensure.DeepEqual(t, scheduled[0], ScheduledMessage{
+		ID:           soon,
+		DeliveryTime: now.Add(time.Hour),
+		Recipients:   []string{"joe@example.com"},
+		Subject:      "Soon",
+		Tags:         []string{"billing"},
+	})
+	ensure.DeepEqual(t, scheduled[1].Subject, "Later")
+	ensure.DeepEqual(t, scheduled[2].Subject, "Tomorrow")
+
+	scheduled, err = tracker.ScheduledMessages(ctx, "newsletter")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(scheduled), 2)
+
+	// Cancelling deletes the stored message
+	ensure.Nil(t, tracker.Cancel(ctx, tomorrow))
+	ensure.DeepEqual(t, deleted, []string{"key-message-2@" + exampleDomain})
+	ensure.NotNil(t, tracker.Cancel(ctx, tomorrow))
+
+	// A message Mailgun no longer stores is forgotten
+	ensure.DeepEqual(t, GetStatusFromErr(tracker.Cancel(ctx, soon)), http.StatusNotFound)
+
+	// Messages are forgotten once their delivery time has passed
+	now = now.Add(3 * time.Hour)
+	scheduled, err = tracker.ScheduledMessages(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(scheduled), 0)
+}
@@ -0,0 +1,115 @@
+package mailgun
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// SenderIdentity maps a logical sender such as "support" or "billing" to a From
+// address and the defaults used for messages sent on its behalf.
+type SenderIdentity struct {
+	// The logical name application code refers to the identity by
+	Name string
+	// The From address, e.g. "Example Support <support@example.com>"
+	From string
+	// Optional Reply-To address
+	ReplyTo string
+	// Optional footer appended to the plain text body, separated by a blank line
+	Footer string
+	// Optional sending domain, see Message.AddDomain()
+	Domain string
+}
+
+// SenderRegistry holds the sender identities of an application, so application code
+// references identities instead of hard-coded From addresses. It is safe for concurrent use.
+//
+//	senders := mailgun.NewSenderRegistry("example.com")
+//	err := senders.Register(mailgun.SenderIdentity{
+//		Name:    "support",
+//		From:    "Example Support <support@example.com>",
+//		ReplyTo: "help@example.com",
+//	})
+//	...
+//	m, err := senders.NewMessage(mg, "support", "Your ticket", "We are on it!", "user@example.org")
+type SenderRegistry struct {
+	mutex           sync.RWMutex
+	verifiedDomains map[string]bool
+	identities      map[string]SenderIdentity
+}
+
+// NewSenderRegistry creates an empty registry. If verified domains are given, only
+// identities with a From address in one of those domains can be registered.
+func NewSenderRegistry(verifiedDomains ...string) *SenderRegistry {
+	sr := SenderRegistry{
+		verifiedDomains: make(map[string]bool),
+		identities:      make(map[string]SenderIdentity),
+	}
+	for _, d := range verifiedDomains {
+		sr.verifiedDomains[strings.ToLower(d)] = true
+	}
+	return &sr
+}
+
+// Register adds an identity to the registry, replacing any identity with the same name.
+// Returns an error if the From or Reply-To address is not valid, or if the From address
+// is not in one of the verified domains.
+func (sr *SenderRegistry) Register(identity SenderIdentity) error {
+	if identity.Name == "" {
+		return fmt.Errorf("sender identity requires a name")
+	}
+	from, err := mail.ParseAddress(identity.From)
+	if err != nil {
+		return fmt.Errorf("sender identity '%s' has an invalid From address: %s", identity.Name, err)
+	}
+	if identity.ReplyTo != "" {
+		if _, err := mail.ParseAddress(identity.ReplyTo); err != nil {
+			return fmt.Errorf("sender identity '%s' has an invalid Reply-To address: %s", identity.Name, err)
+		}
+	}
+
+	if len(sr.verifiedDomains) != 0 {
+		domain := strings.ToLower(from.Address[strings.LastIndex(from.Address, "@")+1:])
+		if !sr.verifiedDomains[domain] {
+			return fmt.Errorf("sender identity '%s' uses unverified domain '%s'", identity.Name, domain)
+		}
+	}
+
+	sr.mutex.Lock()
+	sr.identities[identity.Name] = identity
+	sr.mutex.Unlock()
+	return nil
+}
+
+// Get returns the identity registered under the given name.
+func (sr *SenderRegistry) Get(name string) (SenderIdentity, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+	identity, ok := sr.identities[name]
+	return identity, ok
+}
+
+// NewMessage creates a message sent by the named identity, applying its Reply-To,
+// footer and sending domain.
+func (sr *SenderRegistry) NewMessage(mg Mailgun, name, subject, text string, to ...string) (*Message, error) {
+	identity, ok := sr.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown sender identity '%s'", name)
+	}
+
+	if identity.Footer != "" {
+		text = text + "\n\n" + identity.Footer
+	}
+
+	m := mg.NewMessage(identity.From, subject, text, to...)
+	if identity.ReplyTo != "" {
+		if err := m.SetReplyTo(identity.ReplyTo); err != nil {
+			return nil, err
+		}
+	}
+	if identity.Domain != "" {
+		m.AddDomain(identity.Domain)
+	}
+	return m, nil
+}
@@ -0,0 +1,39 @@
+package mailgun
+
+import (
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSenderRegistry(t *testing.T) {
+	senders := NewSenderRegistry("example.com")
+
+	ensure.Nil(t, senders.Register(SenderIdentity{
+		Name:    "support",
+		From:    "Example Support <support@Example.com>",
+		ReplyTo: "help@example.com",
+		Footer:  "-- The Example Support Team",
+		Domain:  "mg.example.com",
+	}))
+	ensure.NotNil(t, senders.Register(SenderIdentity{Name: "billing", From: "billing@unverified.com"}))
+	ensure.NotNil(t, senders.Register(SenderIdentity{Name: "billing", From: "not an address"}))
+	ensure.NotNil(t, senders.Register(SenderIdentity{From: "billing@example.com"}))
+
+	_, ok := senders.Get("billing")
+	ensure.False(t, ok)
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	m, err := senders.NewMessage(mg, "support", "Your ticket", "We are on it!", "user@example.org")
+	ensure.Nil(t, err)
+
+	pm := m.specific.(*plainMessage)
+	ensure.DeepEqual(t, pm.from, "Example Support <support@Example.com>")
+	ensure.DeepEqual(t, pm.text, "We are on it!\n\n-- The Example Support Team")
+	ensure.DeepEqual(t, m.GetHeaders()["Reply-To"], "help@example.com")
+	ensure.DeepEqual(t, m.domain, "mg.example.com")
+	ensure.DeepEqual(t, m.to, []string{"user@example.org"})
+
+	_, err = senders.NewMessage(mg, "billing", "Invoice", "Pay up")
+	ensure.NotNil(t, err)
+}
@@ -0,0 +1,150 @@
+package mailgun
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrShareExpired is returned by StoredMessageSharer.Verify() when the signed URL has expired
+	ErrShareExpired = errors.New("shared url has expired")
+	// ErrShareInvalid is returned by StoredMessageSharer.Verify() when the signed URL was not signed by the sharer
+	ErrShareInvalid = errors.New("shared url signature is invalid")
+)
+
+const (
+	shareKindMessage    = "message"
+	shareKindAttachment = "attachment"
+)
+
+// StoredMessageSharer creates short lived signed URLs for stored messages and their
+// attachments, and serves them through your application with the credentials of the client,
+// so support tools can share a message without access to the Mailgun API key.
+//
+//	sharer := mailgun.NewStoredMessageSharer(mg, []byte(secret), "https://support.example.com/shared")
+//	http.Handle("/shared", sharer)
+//
+//	link, err := sharer.SignMessageURL(event.Storage.URL, time.Hour)
+//
+// Anyone holding the link can view the message until it expires, so keep the expiry short.
+type StoredMessageSharer struct {
+	mg      Mailgun
+	key     []byte
+	baseURL string
+	now     func() time.Time
+}
+
+// NewStoredMessageSharer creates a sharer which signs URLs with key. The baseURL is the
+// address at which the sharer is served by your application.
+func NewStoredMessageSharer(mg Mailgun, key []byte, baseURL string) *StoredMessageSharer {
+	return &StoredMessageSharer{
+		mg:      mg,
+		key:     key,
+		baseURL: baseURL,
+		now:     time.Now,
+	}
+}
+
+// SignMessageURL returns a URL which serves the stored message at storageURL as JSON until ttl has passed.
+func (s *StoredMessageSharer) SignMessageURL(storageURL string, ttl time.Duration) (string, error) {
+	return s.sign(shareKindMessage, storageURL, ttl)
+}
+
+// SignAttachmentURL returns a URL which serves the stored attachment at attachmentURL until ttl has passed.
+// The attachment URL is the Url field of a StoredAttachment.
+func (s *StoredMessageSharer) SignAttachmentURL(attachmentURL string, ttl time.Duration) (string, error) {
+	return s.sign(shareKindAttachment, attachmentURL, ttl)
+}
+
+func (s *StoredMessageSharer) sign(kind, target string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(s.baseURL)
+	if err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+
+	q := u.Query()
+	q.Set("kind", kind)
+	q.Set("url", target)
+	q.Set("expires", expires)
+	q.Set("signature", hex.EncodeToString(s.signature(kind, target, expires)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (s *StoredMessageSharer) signature(kind, target, expires string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	io.WriteString(h, kind+"\n"+target+"\n"+expires)
+	return h.Sum(nil)
+}
+
+// Verify checks the signature and expiry of a request for a signed URL, returning the kind
+// ("message" or "attachment") and the Mailgun URL it grants access to.
+func (s *StoredMessageSharer) Verify(req *http.Request) (kind string, target string, err error) {
+	q := req.URL.Query()
+	kind, target, expires := q.Get("kind"), q.Get("url"), q.Get("expires")
+
+	signature, err := hex.DecodeString(q.Get("signature"))
+	if err != nil {
+		return "", "", ErrShareInvalid
+	}
+	if subtle.ConstantTimeCompare(signature, s.signature(kind, target, expires)) != 1 {
+		return "", "", ErrShareInvalid
+	}
+	if kind != shareKindMessage && kind != shareKindAttachment {
+		return "", "", ErrShareInvalid
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", "", ErrShareInvalid
+	}
+	if s.now().Unix() > exp {
+		return "", "", ErrShareExpired
+	}
+	return kind, target, nil
+}
+
+// ServeHTTP serves the stored message or attachment of a signed URL. Requests with an invalid
+// or expired signature are answered with 403 Forbidden, failures to retrieve the message from
+// Mailgun with 502 Bad Gateway.
+func (s *StoredMessageSharer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	kind, target, err := s.Verify(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if kind == shareKindMessage {
+		msg, err := s.mg.GetStoredMessageForURL(req.Context(), target)
+		if err != nil {
+			http.Error(w, "unable to retrieve stored message", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(msg)
+		return
+	}
+
+	r := newHTTPRequest(target)
+	r.setClient(s.mg)
+	r.setBasicAuth(basicAuthUser, s.mg.APIKey())
+	resp, err := makeGetRequest(req.Context(), r)
+	if err != nil {
+		http.Error(w, "unable to retrieve stored attachment", http.StatusBadGateway)
+		return
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Write(resp.Data)
+}
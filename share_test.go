@@ -0,0 +1,70 @@
+package mailgun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestStoredMessageSharer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, apiKey, _ := req.BasicAuth()
+		ensure.DeepEqual(t, apiKey, exampleAPIKey)
+
+		switch req.URL.Path {
+		case "/messages/abc":
+			fmt.Fprint(w, `{"subject": "Hello", "from": "sender@example.com"}`)
+		case "/messages/abc/attachments/0":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "attachment data")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	sharer := NewStoredMessageSharer(mg, []byte("secret"), "https://support.example.com/shared")
+
+	get := func(link string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		sharer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link, nil))
+		return w
+	}
+
+	// Signed message url
+	link, err := sharer.SignMessageURL(srv.URL+"/messages/abc", time.Hour)
+	ensure.Nil(t, err)
+	ensure.True(t, strings.HasPrefix(link, "https://support.example.com/shared?"))
+	ensure.False(t, strings.Contains(link, exampleAPIKey))
+
+	w := get(link)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	var msg StoredMessage
+	ensure.Nil(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	ensure.DeepEqual(t, msg.Subject, "Hello")
+
+	// Signed attachment url
+	link, err = sharer.SignAttachmentURL(srv.URL+"/messages/abc/attachments/0", time.Hour)
+	ensure.Nil(t, err)
+	w = get(link)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.DeepEqual(t, w.Header().Get("Content-Type"), "text/plain")
+	ensure.DeepEqual(t, w.Body.String(), "attachment data")
+
+	// Tampered url
+	w = get(strings.Replace(link, "attachments%2F0", "attachments%2F1", 1))
+	ensure.DeepEqual(t, w.Code, http.StatusForbidden)
+
+	// Expired url
+	sharer.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, _, err = sharer.Verify(httptest.NewRequest(http.MethodGet, link, nil))
+	ensure.DeepEqual(t, err, ErrShareExpired)
+	ensure.DeepEqual(t, get(link).Code, http.StatusForbidden)
+}
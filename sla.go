@@ -0,0 +1,136 @@
+package mailgun
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSLAWindow is the number of sends per SLA class an SLATracker keeps the latency of
+const DefaultSLAWindow = 1000
+
+// SetSLAClass annotates the message with an SLA class, such as "otp-60s", which Send() reports
+// with the SendEvent of the message. If deadline is not 0, Send() gives up once deadline has
+// passed, and the send counts as a missed deadline. The deadline bounds the call to Send(),
+// until Mailgun accepted the message, not its delivery to the recipients.
+func (m *Message) SetSLAClass(class string, deadline time.Duration) {
+	m.slaClass = class
+	m.slaDeadline = deadline
+}
+
+// SLAStats are the API send latencies of an SLA class tracked by an SLATracker: how long the
+// calls to Send() took until Mailgun accepted the messages. They don't include the time Mailgun
+// takes to deliver the messages, which is reported by the delivered events. The percentiles are
+// computed over the successful sends of the window.
+type SLAStats struct {
+	// The number of sends observed, including failed ones
+	Count int
+	// The sends which returned an error
+	Failed int
+	// The sends which took longer than the deadline of their message
+	Missed  int
+	SendP50 time.Duration
+	SendP90 time.Duration
+	SendP99 time.Duration
+	SendMax time.Duration
+}
+
+// SLATracker computes per SLA class API send latency percentiles from the SendEvent published by
+// a client, so teams can show Mailgun accepts their transactional messages within their SLA.
+//
+//	tracker := mailgun.NewSLATracker(0)
+//	events := mailgun.NewClientEvents()
+//	events.OnSend(tracker.Observe)
+//	mg.SetClientEvents(events)
+//	...
+//	otp := tracker.Stats()["otp-60s"]
+//	log.Printf("otp: send p99 %s, %d/%d missed", otp.SendP99, otp.Missed, otp.Count)
+type SLATracker struct {
+	mu      sync.Mutex
+	window  int
+	classes map[string]*slaClass
+}
+
+type slaClass struct {
+	stats     SLAStats
+	latencies []time.Duration
+	next      int
+}
+
+// NewSLATracker creates a tracker which computes the percentiles over the last window sends of
+// each class, or DefaultSLAWindow sends if window is 0.
+func NewSLATracker(window int) *SLATracker {
+	if window <= 0 {
+		window = DefaultSLAWindow
+	}
+	return &SLATracker{window: window, classes: make(map[string]*slaClass)}
+}
+
+// Observe records a send, sends of messages without an SLA class are ignored.
+func (t *SLATracker) Observe(e SendEvent) {
+	if e.SLAClass == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.classes[e.SLAClass]
+	if !ok {
+		c = &slaClass{}
+		t.classes[e.SLAClass] = c
+	}
+	c.stats.Count++
+	if e.Missed {
+		c.stats.Missed++
+	}
+	if e.Err != nil {
+		c.stats.Failed++
+		return
+	}
+	// The latencies form a ring buffer once the window is full
+	if len(c.latencies) < t.window {
+		c.latencies = append(c.latencies, e.Duration)
+		return
+	}
+	c.latencies[c.next] = e.Duration
+	c.next = (c.next + 1) % t.window
+}
+
+// Stats returns the stats of each SLA class observed so far.
+func (t *SLATracker) Stats() map[string]SLAStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[string]SLAStats, len(t.classes))
+	for name, c := range t.classes {
+		stats := c.stats
+		if len(c.latencies) != 0 {
+			sorted := append([]time.Duration(nil), c.latencies...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			stats.SendP50 = percentile(sorted, 50)
+			stats.SendP90 = percentile(sorted, 90)
+			stats.SendP99 = percentile(sorted, 99)
+			stats.SendMax = sorted[len(sorted)-1]
+		}
+		result[name] = stats
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// slaContext bounds ctx by the deadline of the SLA class of the message, if any
+func (m *Message) slaContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.slaDeadline <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, m.slaDeadline)
+}
@@ -0,0 +1,75 @@
+package mailgun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSLATracker(t *testing.T) {
+	tracker := NewSLATracker(100)
+	for i := 1; i <= 200; i++ {
+		tracker.Observe(SendEvent{SLAClass: "otp-60s", Duration: time.Duration(i) * time.Millisecond})
+	}
+	tracker.Observe(SendEvent{SLAClass: "otp-60s", Missed: true, Err: context.DeadlineExceeded})
+	tracker.Observe(SendEvent{SLAClass: "newsletter", Err: errors.New("boom")})
+	tracker.Observe(SendEvent{Duration: time.Second})
+
+	stats := tracker.Stats()
+	ensure.DeepEqual(t, len(stats), 2)
+	// Only the last 100 successful sends count towards the percentiles
+	ensure.DeepEqual(t, stats["otp-60s"], SLAStats{
+		Count:   201,
+		Failed:  1,
+		Missed:  1,
+		SendP50: 150 * time.Millisecond,
+		SendP90: 190 * time.Millisecond,
+		SendP99: 199 * time.Millisecond,
+		SendMax: 200 * time.Millisecond,
+	})
+	ensure.DeepEqual(t, stats["newsletter"], SLAStats{Count: 1, Failed: 1})
+}
+
+func TestSendSLAClass(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.FormValue("subject") == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"message":"Queued. Thank you.", "id":"<1@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	var sent []SendEvent
+	events := NewClientEvents()
+	events.OnSend(func(e SendEvent) { sent = append(sent, e) })
+	mg.SetClientEvents(events)
+	ctx := context.Background()
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "to@example.com")
+	m.SetSLAClass("otp-60s", time.Minute)
+	_, _, err := mg.Send(ctx, m)
+	ensure.Nil(t, err)
+
+	m = mg.NewMessage(fromUser, "slow", exampleText, "to@example.com")
+	m.SetSLAClass("otp-50ms", 50*time.Millisecond)
+	_, _, err = mg.Send(ctx, m)
+	ensure.NotNil(t, err)
+
+	ensure.DeepEqual(t, len(sent), 2)
+	ensure.DeepEqual(t, sent[0].Domain, exampleDomain)
+	ensure.DeepEqual(t, sent[0].SLAClass, "otp-60s")
+	ensure.DeepEqual(t, sent[0].Deadline, time.Minute)
+	ensure.False(t, sent[0].Missed)
+	ensure.Nil(t, sent[0].Err)
+	ensure.DeepEqual(t, sent[1].SLAClass, "otp-50ms")
+	ensure.True(t, sent[1].Missed)
+	ensure.NotNil(t, sent[1].Err)
+}
@@ -27,9 +27,12 @@ type complaintsResponse struct {
 // indicating that the message they received is, to them, spam.
 func (mg *MailgunImpl) ListComplaints(opts *ListOptions) *ComplaintsIterator {
 	r := newHTTPRequest(generateApiUrl(mg, complaintsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &ComplaintsIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -133,7 +136,7 @@ func (ci *ComplaintsIterator) Previous(ctx context.Context, items *[]Complaint)
 
 func (ci *ComplaintsIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(ci.mg.Client())
+	r.setClient(ci.mg)
 	r.setBasicAuth(basicAuthUser, ci.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &ci.complaintsResponse)
@@ -143,7 +146,7 @@ func (ci *ComplaintsIterator) fetch(ctx context.Context, url string) error {
 // If no complaint exists, the Complaint instance returned will be empty.
 func (mg *MailgunImpl) GetComplaint(ctx context.Context, address string) (Complaint, error) {
 	r := newHTTPRequest(generateApiUrl(mg, complaintsEndpoint) + "/" + address)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var c Complaint
@@ -155,7 +158,7 @@ func (mg *MailgunImpl) GetComplaint(ctx context.Context, address string) (Compla
 // from your domain.
 func (mg *MailgunImpl) CreateComplaint(ctx context.Context, address string) error {
 	r := newHTTPRequest(generateApiUrl(mg, complaintsEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	p.addValue("address", address)
@@ -167,7 +170,7 @@ func (mg *MailgunImpl) CreateComplaint(ctx context.Context, address string) erro
 // of receiving spam from your domain.
 func (mg *MailgunImpl) DeleteComplaint(ctx context.Context, address string) error {
 	r := newHTTPRequest(generateApiUrl(mg, complaintsEndpoint) + "/" + address)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -2,7 +2,9 @@ package mailgun
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -77,3 +79,51 @@ func TestCreateDeleteComplaint(t *testing.T) {
 	ensure.Nil(t, mg.DeleteComplaint(ctx, randomMail))
 	ensure.False(t, hasComplaint(randomMail))
 }
+
+func TestComplaintsRequests(t *testing.T) {
+	complaints := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		prefix := "/" + exampleDomain + "/complaints"
+		address := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		switch {
+		case req.Method == http.MethodPost && address == "":
+			complaints[req.FormValue("address")] = true
+			json.NewEncoder(w).Encode(map[string]string{"message": "Address has been added to the complaints table"})
+		case req.Method == http.MethodGet && address == "":
+			var items []Complaint
+			for a := range complaints {
+				items = append(items, Complaint{Address: a, Count: 1})
+			}
+			json.NewEncoder(w).Encode(complaintsResponse{Items: items})
+		case req.Method == http.MethodGet && complaints[address]:
+			json.NewEncoder(w).Encode(Complaint{Address: address, Count: 1})
+		case req.Method == http.MethodDelete && complaints[address]:
+			delete(complaints, address)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Spam complaint has been removed"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No spam complaints found for this address"})
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	ensure.Nil(t, mg.CreateComplaint(ctx, "spam@example.com"))
+
+	complaint, err := mg.GetComplaint(ctx, "spam@example.com")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, complaint.Count, 1)
+
+	var page []Complaint
+	it := mg.ListComplaints(nil)
+	ensure.True(t, it.First(ctx, &page))
+	ensure.DeepEqual(t, len(page), 1)
+	ensure.DeepEqual(t, page[0].Address, "spam@example.com")
+
+	ensure.Nil(t, mg.DeleteComplaint(ctx, "spam@example.com"))
+	_, err = mg.GetComplaint(ctx, "spam@example.com")
+	ensure.DeepEqual(t, GetStatusFromErr(err), http.StatusNotFound)
+}
@@ -2,6 +2,7 @@ package mailgun
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -24,6 +25,7 @@ type Delivered struct {
 // Stats on temporary failures
 type Temporary struct {
 	Espblock int `json:"espblock"`
+	Total    int `json:"total"`
 }
 
 // Stats on permanent failures
@@ -85,8 +87,30 @@ type GetStatOptions struct {
 	End        time.Time
 }
 
+// Validate returns an *OptionError if the options are invalid. A nil *GetStatOptions is valid.
+func (o *GetStatOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Resolution {
+	case "", ResolutionHour, ResolutionDay, ResolutionMonth:
+	default:
+		return invalidOption("Resolution", fmt.Sprintf("unknown resolution '%s'", o.Resolution))
+	}
+	if o.Duration != "" && !o.Start.IsZero() {
+		return invalidOption("Duration", "Duration and Start are mutually exclusive")
+	}
+	if !o.Start.IsZero() && !o.End.IsZero() && o.Start.After(o.End) {
+		return invalidOption("Start", "must be before End")
+	}
+	return nil
+}
+
 // Returns total stats for a given domain for the specified time period
 func (mg *MailgunImpl) GetStats(ctx context.Context, events []string, opts *GetStatOptions) ([]Stats, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
 	r := newHTTPRequest(generateApiUrl(mg, statsTotalEndpoint))
 
 	if opts != nil {
@@ -108,7 +132,7 @@ func (mg *MailgunImpl) GetStats(ctx context.Context, events []string, opts *GetS
 		r.addParameter("event", e)
 	}
 
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var res statsTotalResponse
@@ -119,3 +143,88 @@ func (mg *MailgunImpl) GetStats(ctx context.Context, events []string, opts *GetS
 		return res.Stats, nil
 	}
 }
+
+// StatsDelta describes how a single metric changed between two time periods
+type StatsDelta struct {
+	Previous int
+	Current  int
+	// Delta is the absolute change, Current - Previous
+	Delta int
+	// Percent is the change relative to Previous, e.g. 25.0 for a 25% increase.
+	// Percent is zero if Previous is zero.
+	Percent float64
+}
+
+// StatsComparison as returned by `CompareStats()`
+type StatsComparison struct {
+	Accepted        StatsDelta
+	Delivered       StatsDelta
+	FailedTemporary StatsDelta
+	FailedPermanent StatsDelta
+	Stored          StatsDelta
+	Opened          StatsDelta
+	Clicked         StatsDelta
+	Unsubscribed    StatsDelta
+	Complained      StatsDelta
+}
+
+func newStatsDelta(previous, current int) StatsDelta {
+	d := StatsDelta{
+		Previous: previous,
+		Current:  current,
+		Delta:    current - previous,
+	}
+	if previous != 0 {
+		d.Percent = float64(d.Delta) / float64(previous) * 100
+	}
+	return d
+}
+
+// Sums each metric across all the resolution buckets of a stats response
+func sumStats(stats []Stats) Stats {
+	var sum Stats
+	for _, s := range stats {
+		sum.Accepted.Total += s.Accepted.Total
+		sum.Delivered.Total += s.Delivered.Total
+		sum.Failed.Temporary.Espblock += s.Failed.Temporary.Espblock
+		sum.Failed.Temporary.Total += s.Failed.Temporary.Total
+		sum.Failed.Permanent.Total += s.Failed.Permanent.Total
+		sum.Stored.Total += s.Stored.Total
+		sum.Opened.Total += s.Opened.Total
+		sum.Clicked.Total += s.Clicked.Total
+		sum.Unsubscribed.Total += s.Unsubscribed.Total
+		sum.Complained.Total += s.Complained.Total
+	}
+	return sum
+}
+
+// Fetches stats for two time periods and returns the change of each metric
+// between the previous and the current period. Useful for "week over week" reporting.
+//
+//	now := time.Now()
+//	cmp, err := mg.CompareStats(ctx, []string{"accepted", "delivered"},
+//		&mailgun.GetStatOptions{Start: now.AddDate(0, 0, -7), End: now},
+//		&mailgun.GetStatOptions{Start: now.AddDate(0, 0, -14), End: now.AddDate(0, 0, -7)})
+func (mg *MailgunImpl) CompareStats(ctx context.Context, events []string, current, previous *GetStatOptions) (StatsComparison, error) {
+	cur, err := mg.GetStats(ctx, events, current)
+	if err != nil {
+		return StatsComparison{}, err
+	}
+	prev, err := mg.GetStats(ctx, events, previous)
+	if err != nil {
+		return StatsComparison{}, err
+	}
+
+	c, p := sumStats(cur), sumStats(prev)
+	return StatsComparison{
+		Accepted:        newStatsDelta(p.Accepted.Total, c.Accepted.Total),
+		Delivered:       newStatsDelta(p.Delivered.Total, c.Delivered.Total),
+		FailedTemporary: newStatsDelta(p.Failed.Temporary.Total, c.Failed.Temporary.Total),
+		FailedPermanent: newStatsDelta(p.Failed.Permanent.Total, c.Failed.Permanent.Total),
+		Stored:          newStatsDelta(p.Stored.Total, c.Stored.Total),
+		Opened:          newStatsDelta(p.Opened.Total, c.Opened.Total),
+		Clicked:         newStatsDelta(p.Clicked.Total, c.Clicked.Total),
+		Unsubscribed:    newStatsDelta(p.Unsubscribed.Total, c.Unsubscribed.Total),
+		Complained:      newStatsDelta(p.Complained.Total, c.Complained.Total),
+	}, nil
+}
@@ -2,7 +2,11 @@ package mailgun
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/facebookgo/ensure"
 )
@@ -27,6 +31,35 @@ func TestListStats(t *testing.T) {
 	}
 }
 
+func TestCompareStats(t *testing.T) {
+	current := time.Date(2019, 2, 11, 0, 0, 0, 0, time.UTC)
+	previous := current.AddDate(0, 0, -7)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.URL.Path, "/"+exampleDomain+"/stats/total")
+		accepted := []int{10, 15}
+		if req.FormValue("start") == previous.Format(iso8601date) {
+			accepted = []int{5, 15}
+		}
+		fmt.Fprintf(w, `{"stats": [{"accepted": {"total": %d}, "opened": {"total": 4}},`+
+			`{"accepted": {"total": %d}, "failed": {"temporary": {"espblock": 1, "total": 3}}}]}`,
+			accepted[0], accepted[1])
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	cmp, err := mg.CompareStats(context.Background(), []string{"accepted", "opened"},
+		&GetStatOptions{Start: current, End: current.AddDate(0, 0, 7)},
+		&GetStatOptions{Start: previous, End: current})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cmp.Accepted, StatsDelta{Previous: 20, Current: 25, Delta: 5, Percent: 25})
+	ensure.DeepEqual(t, cmp.Opened, StatsDelta{Previous: 4, Current: 4})
+	ensure.DeepEqual(t, cmp.FailedTemporary, StatsDelta{Previous: 3, Current: 3})
+	ensure.DeepEqual(t, cmp.Clicked, StatsDelta{})
+}
+
 func TestDeleteTag(t *testing.T) {
 	if reason := SkipNetworkTest(); reason != "" {
 		t.Skip(reason)
@@ -0,0 +1,195 @@
+package mailgun
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// storedMIMEReader returns a reader of the MIME body of a stored message response, which Mailgun
+// sends either as is or as the "body-mime" string of a JSON object. The JSON string is decoded
+// as it is read, so the message is never held in memory.
+func storedMIMEReader(body io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	first, err := br.Peek(1)
+	if err != nil && err != io.EOF {
+		body.Close()
+		return nil, err
+	}
+	// A MIME message starts with a header, never with a JSON object
+	if len(first) == 0 || first[0] != '{' {
+		return readCloser{Reader: br, Closer: body}, nil
+	}
+
+	dec := json.NewDecoder(br)
+	if _, err := dec.Token(); err != nil {
+		body.Close()
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		if key == "body-mime" {
+			value := bufio.NewReader(io.MultiReader(dec.Buffered(), br))
+			if err := openJSONString(value); err != nil {
+				body.Close()
+				return nil, err
+			}
+			return readCloser{Reader: &jsonStringReader{r: value}, Closer: body}, nil
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			body.Close()
+			return nil, err
+		}
+	}
+	body.Close()
+	return nil, errors.New("stored message response has no body-mime")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openJSONString consumes the colon following an object key and the opening quote of its value
+func openJSONString(r *bufio.Reader) error {
+	for _, want := range []byte{':', '"'} {
+		b, err := skipSpace(r)
+		if err != nil {
+			return err
+		}
+		if b != want {
+			return fmt.Errorf("body-mime is not a string, found '%c'", b)
+		}
+	}
+	return nil
+}
+
+func skipSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, nil
+	}
+}
+
+// jsonStringReader decodes a JSON string from r, whose opening quote was consumed, up to its
+// closing quote
+type jsonStringReader struct {
+	r       *bufio.Reader
+	buf     [2 * utf8.UTFMax]byte
+	pending []byte
+	err     error
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.pending) != 0 {
+			c := copy(p[n:], s.pending)
+			s.pending = s.pending[c:]
+			n += c
+			continue
+		}
+		if s.err != nil {
+			break
+		}
+		s.err = s.decode()
+	}
+	if n != 0 {
+		return n, nil
+	}
+	return 0, s.err
+}
+
+// decode decodes the next character of the string into pending, and returns io.EOF at the
+// closing quote
+func (s *jsonStringReader) decode() error {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	switch b {
+	case '"':
+		return io.EOF
+	case '\\':
+	default:
+		s.pending = append(s.buf[:0], b)
+		return nil
+	}
+
+	if b, err = s.r.ReadByte(); err != nil {
+		return unexpectedEOF(err)
+	}
+	switch b {
+	case '"', '\\', '/':
+	case 'b':
+		b = '\b'
+	case 'f':
+		b = '\f'
+	case 'n':
+		b = '\n'
+	case 'r':
+		b = '\r'
+	case 't':
+		b = '\t'
+	case 'u':
+		r, err := s.readHex()
+		if err != nil {
+			return err
+		}
+		if utf16.IsSurrogate(r) {
+			// The low surrogate must follow as another \u escape
+			if next, _ := s.r.Peek(2); string(next) == `\u` {
+				s.r.Discard(2)
+				low, err := s.readHex()
+				if err != nil {
+					return err
+				}
+				r = utf16.DecodeRune(r, low)
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		s.pending = s.buf[:utf8.EncodeRune(s.buf[:], r)]
+		return nil
+	default:
+		return fmt.Errorf("invalid escape '\\%c' in body-mime", b)
+	}
+	s.pending = append(s.buf[:0], b)
+	return nil
+}
+
+func (s *jsonStringReader) readHex() (rune, error) {
+	var hex [4]byte
+	if _, err := io.ReadFull(s.r, hex[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	n, err := strconv.ParseUint(string(hex[:]), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape '\\u%s' in body-mime", hex[:])
+	}
+	return rune(n), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
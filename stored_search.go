@@ -0,0 +1,185 @@
+package mailgun
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// StoredMessageQuery selects the stored messages returned by SearchStoredMessages().
+// All filters are case insensitive substring matches, empty filters match every message.
+type StoredMessageQuery struct {
+	// The time range to search, Begin is required and End defaults to now
+	Begin time.Time
+	End   time.Time
+
+	Subject string
+	From    string
+	To      string
+	// Header values the message must contain. Matching headers retrieves each stored message
+	// which passes the other filters, so combine them with other filters where possible.
+	Headers map[string]string
+
+	// The maximum number of matches to return, 0 returns all of them
+	Limit int
+}
+
+// Validate returns an *OptionError if the query is invalid.
+func (q *StoredMessageQuery) Validate() error {
+	if q == nil || q.Begin.IsZero() {
+		return invalidOption("Begin", "the start of the time range is required")
+	}
+	if !q.End.IsZero() && q.End.Before(q.Begin) {
+		return invalidOption("End", "must not be before Begin")
+	}
+	if q.Limit < 0 {
+		return invalidOption("Limit", "must not be negative")
+	}
+	return nil
+}
+
+// StoredMessageMatch is a stored message found by SearchStoredMessages()
+type StoredMessageMatch struct {
+	// The storage key, as passed to GetStoredMessage()
+	Key string
+	// The storage URL, as passed to GetStoredMessageForURL()
+	URL       string
+	Timestamp time.Time
+	From      string
+	To        string
+	Subject   string
+	MessageID string
+}
+
+// SearchStoredMessages walks the events of the domain within a time range which refer to a
+// stored message and returns the stored messages matching the query, oldest first. Mailgun
+// stores both the inbound messages (stored events) and the messages sent through the domain
+// (accepted, delivered and failed events), a message is returned once however many of its
+// events match. The To filter also matches the recipient of the accepted, delivered and failed
+// events, which may be a Bcc recipient missing from the To header.
+//
+//	matches, err := mg.SearchStoredMessages(ctx, &mailgun.StoredMessageQuery{
+//		Begin: time.Now().Add(-48 * time.Hour),
+//		To:    "customer@example.com",
+//	})
+func (mg *MailgunImpl) SearchStoredMessages(ctx context.Context, q *StoredMessageQuery) ([]StoredMessageMatch, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	end := q.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	it := mg.ListEvents(&ListEventOptions{
+		Begin:          q.Begin,
+		End:            end,
+		ForceAscending: true,
+		Limit:          MaxEventsLimit,
+		Filter:         map[string]string{"event": storedMessageEvents},
+	})
+
+	var matches []StoredMessageMatch
+	// The keys of the messages already matched, and the result of the header check per key
+	found := make(map[string]bool)
+	headersMatch := make(map[string]bool)
+	var page []Event
+	for it.Next(ctx, &page) {
+		for _, e := range page {
+			stored, ok := storedMessageOf(e)
+			if !ok || found[stored.storage.Key] {
+				continue
+			}
+			headers := stored.headers
+			if !containsFold(headers.Subject, q.Subject) ||
+				!containsFold(headers.From, q.From) ||
+				!(containsFold(headers.To, q.To) || stored.recipient != "" && containsFold(stored.recipient, q.To)) {
+				continue
+			}
+			if len(q.Headers) != 0 {
+				match, checked := headersMatch[stored.storage.Key]
+				if !checked {
+					msg, err := mg.GetStoredMessageForURL(ctx, stored.storage.URL)
+					if err != nil {
+						return matches, err
+					}
+					match = matchHeaders(msg.MessageHeaders, q.Headers)
+					headersMatch[stored.storage.Key] = match
+				}
+				if !match {
+					continue
+				}
+			}
+
+			found[stored.storage.Key] = true
+			matches = append(matches, StoredMessageMatch{
+				Key:       stored.storage.Key,
+				URL:       stored.storage.URL,
+				Timestamp: e.GetTimestamp(),
+				From:      headers.From,
+				To:        headers.To,
+				Subject:   headers.Subject,
+				MessageID: headers.MessageID,
+			})
+			if q.Limit != 0 && len(matches) == q.Limit {
+				return matches, nil
+			}
+		}
+	}
+	return matches, it.Err()
+}
+
+// storedMessageEvents filters the events which refer to a stored message
+var storedMessageEvents = strings.Join([]string{
+	events.EventStored, events.EventAccepted, events.EventDelivered, events.EventFailed,
+}, " OR ")
+
+// storedMessage is the stored message an event refers to
+type storedMessage struct {
+	storage events.Storage
+	headers events.MessageHeaders
+	// The recipient of the event, empty for stored events
+	recipient string
+	tags      []string
+}
+
+// storedMessageOf returns the stored message an event refers to, the events of the messages
+// sent through the domain carry a storage once Mailgun stored the message.
+func storedMessageOf(e Event) (storedMessage, bool) {
+	var m storedMessage
+	switch e := e.(type) {
+	case *events.Stored:
+		m = storedMessage{storage: e.Storage, headers: e.Message.Headers, tags: e.Tags}
+	case *events.Accepted:
+		m = storedMessage{e.Storage, e.Message.Headers, e.Recipient, e.Tags}
+	case *events.Delivered:
+		m = storedMessage{e.Storage, e.Message.Headers, e.Recipient, e.Tags}
+	case *events.Failed:
+		m = storedMessage{e.Storage, e.Message.Headers, e.Recipient, e.Tags}
+	}
+	return m, m.storage.Key != ""
+}
+
+// matchHeaders returns true if every header of want is present in headers with a value
+// containing the wanted value
+func matchHeaders(headers [][]string, want map[string]string) bool {
+	for name, value := range want {
+		found := false
+		for _, h := range headers {
+			if len(h) == 2 && strings.EqualFold(h[0], name) && containsFold(h[1], value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
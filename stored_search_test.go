@@ -0,0 +1,89 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestSearchStoredMessages(t *testing.T) {
+	var base string
+	var fetched int
+	messageEvent := func(event, key, to, recipient, subject string) string {
+		return fmt.Sprintf(`{"event": %q, "timestamp": 1545000000, "recipient": %q,
+			"storage": {"key": %q, "url": "%s/messages/%s"},
+			"message": {"headers": {"to": %q, "from": "support@example.com", "subject": %q, "message-id": "%s@example.com"}}}`,
+			event, recipient, key, base, key, to, subject, key)
+	}
+	storedEvent := func(key, to, subject string) string {
+		return messageEvent("stored", key, to, "", subject)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/"+exampleDomain+"/events" && req.FormValue("page") == "":
+			ensure.DeepEqual(t, req.FormValue("event"), "stored OR accepted OR delivered OR failed")
+			ensure.DeepEqual(t, req.FormValue("ascending"), "yes")
+			fmt.Fprintf(w, `{"items": [%s, %s, %s, %s, %s, %s], "paging": {"next": "%s/%s/events?page=2"}}`,
+				storedEvent("key-1", "Customer <customer@example.com>", "Your invoice"),
+				storedEvent("key-2", "other@example.com", "Your invoice"),
+				storedEvent("key-3", "customer@example.com", "Password reset"),
+				// A sent message stored once, the customer is a Bcc recipient
+				messageEvent("accepted", "key-4", "other@example.com", "other@example.com", "Newsletter"),
+				messageEvent("accepted", "key-4", "other@example.com", "customer@example.com", "Newsletter"),
+				messageEvent("delivered", "key-4", "other@example.com", "customer@example.com", "Newsletter"),
+				base, exampleDomain)
+		case req.URL.Path == "/"+exampleDomain+"/events":
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+		case strings.HasPrefix(req.URL.Path, "/messages/"):
+			fetched++
+			ticket := "1234"
+			if req.URL.Path == "/messages/key-3" {
+				ticket = "5678"
+			}
+			fmt.Fprintf(w, `{"message-headers": [["X-Ticket-Id", %q]]}`, ticket)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	base = srv.URL
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	_, err := mg.SearchStoredMessages(ctx, &StoredMessageQuery{To: "customer@example.com"})
+	ensure.DeepEqual(t, err, invalidOption("Begin", "the start of the time range is required"))
+
+	begin := time.Now().Add(-24 * time.Hour)
+	matches, err := mg.SearchStoredMessages(ctx, &StoredMessageQuery{Begin: begin, To: "CUSTOMER@example.com"})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(matches), 3)
+	ensure.DeepEqual(t, matches[0].Key, "key-1")
+	ensure.DeepEqual(t, matches[0].URL, srv.URL+"/messages/key-1")
+	ensure.DeepEqual(t, matches[0].MessageID, "key-1@example.com")
+	ensure.DeepEqual(t, matches[1].Key, "key-3")
+	ensure.DeepEqual(t, matches[2].Key, "key-4")
+
+	matches, err = mg.SearchStoredMessages(ctx, &StoredMessageQuery{
+		Begin:   begin,
+		To:      "customer@example.com",
+		Headers: map[string]string{"x-ticket-id": "5678"},
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(matches), 1)
+	ensure.DeepEqual(t, matches[0].Subject, "Password reset")
+	// The headers of each message are retrieved once
+	ensure.DeepEqual(t, fetched, 3)
+
+	matches, err = mg.SearchStoredMessages(ctx, &StoredMessageQuery{Begin: begin, Subject: "invoice", Limit: 1})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(matches), 1)
+	ensure.DeepEqual(t, matches[0].Key, "key-1")
+}
@@ -0,0 +1,189 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// SuppressionMode controls how Send() treats recipients which are on the bounces,
+// unsubscribes or complaints lists of the sending domain. See Message.SetSuppressionMode().
+type SuppressionMode int
+
+const (
+	// SuppressionIgnore sends the message to all recipients without checking the suppression
+	// lists, leaving it to Mailgun to drop suppressed recipients. This is the default.
+	SuppressionIgnore SuppressionMode = iota
+	// SuppressionStrip sends the message without its suppressed recipients. The message itself
+	// keeps them, so it can be sent again once they are removed from the suppression lists.
+	SuppressionStrip
+	// SuppressionReject fails Send() with a *SuppressedError if any recipient is suppressed.
+	SuppressionReject
+)
+
+// The suppression lists reported by SuppressedRecipient.List
+const (
+	SuppressionBounces      = "bounces"
+	SuppressionUnsubscribes = "unsubscribes"
+	SuppressionComplaints   = "complaints"
+)
+
+// SuppressedRecipient is a recipient which Send() skipped because it is on a suppression list
+type SuppressedRecipient struct {
+	// The recipient as it was added to the message
+	Address string
+	// The suppression list the address was found on
+	List string
+}
+
+// SuppressedError is returned by Send() when the SuppressionMode of the message is SuppressionReject
+// and some recipients are suppressed, or when it is SuppressionStrip and all recipients are suppressed.
+type SuppressedError struct {
+	Recipients []SuppressedRecipient
+}
+
+func (e *SuppressedError) Error() string {
+	var addresses []string
+	for _, r := range e.Recipients {
+		addresses = append(addresses, fmt.Sprintf("%s (%s)", r.Address, r.List))
+	}
+	return "suppressed recipients: " + strings.Join(addresses, ", ")
+}
+
+// SetSuppressionMode makes Send() check every To:, Cc: and Bcc: recipient against the bounces,
+// unsubscribes and complaints lists of the sending domain before the message is sent.
+// Unsubscribes only suppress a recipient if they apply to all tags or to a tag of the message.
+//
+// This costs up to three requests per recipient, so it is intended for resends and
+// small batches rather than bulk sending.
+func (m *Message) SetSuppressionMode(mode SuppressionMode) {
+	m.suppressionMode = mode
+}
+
+// SkippedRecipients returns the recipients which the last Send() of the message skipped,
+// or rejected, because they are suppressed.
+func (m *Message) SkippedRecipients() []SuppressedRecipient {
+	return m.skipped
+}
+
+// filterSuppressed checks the recipients of the message against the suppression lists
+// of the domain and applies the SuppressionMode of the message. It returns the message to
+// send, a copy of m without the suppressed recipients if there are any, so m can be sent again.
+func (mg *MailgunImpl) filterSuppressed(ctx context.Context, m *Message) (*Message, error) {
+	// Suppression lists belong to the domain the message is sent from
+	dmg := *mg
+	if m.domain != "" {
+		dmg.domain = m.domain
+	}
+
+	m.skipped = nil
+	suppressed := make(map[string]bool)
+	check := func(recipients []string) error {
+		for _, r := range recipients {
+			list, err := dmg.suppressionList(ctx, r, m.tags)
+			if err != nil {
+				return err
+			}
+			if list != "" {
+				suppressed[r] = true
+				m.skipped = append(m.skipped, SuppressedRecipient{Address: r, List: list})
+			}
+		}
+		return nil
+	}
+
+	if err := check(m.to); err != nil {
+		return nil, err
+	}
+	pm, isPlain := m.specific.(*plainMessage)
+	if isPlain {
+		if err := check(pm.cc); err != nil {
+			return nil, err
+		}
+		if err := check(pm.bcc); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.skipped) == 0 {
+		return m, nil
+	}
+	if m.suppressionMode == SuppressionReject {
+		return nil, &SuppressedError{Recipients: m.skipped}
+	}
+
+	c := *m
+	c.to = withoutSuppressed(m.to, suppressed)
+	if isPlain {
+		pc := *pm
+		pc.cc = withoutSuppressed(pm.cc, suppressed)
+		pc.bcc = withoutSuppressed(pm.bcc, suppressed)
+		c.specific = &pc
+	}
+	if m.recipientVariables != nil {
+		c.recipientVariables = make(map[string]map[string]interface{}, len(m.recipientVariables))
+		for r, vars := range m.recipientVariables {
+			if !suppressed[r] {
+				c.recipientVariables[r] = vars
+			}
+		}
+	}
+	// Mailgun requires at least one To: recipient
+	if len(c.to) == 0 {
+		return nil, &SuppressedError{Recipients: m.skipped}
+	}
+	return &c, nil
+}
+
+// suppressionList returns the name of the suppression list the recipient is on, or an empty
+// string if the recipient is not suppressed
+func (mg *MailgunImpl) suppressionList(ctx context.Context, recipient string, tags []string) (string, error) {
+	address := recipient
+	if addr, err := mail.ParseAddress(recipient); err == nil {
+		address = addr.Address
+	}
+
+	if _, err := mg.GetBounce(ctx, address); err == nil {
+		return SuppressionBounces, nil
+	} else if GetStatusFromErr(err) != http.StatusNotFound {
+		return "", err
+	}
+
+	if _, err := mg.GetComplaint(ctx, address); err == nil {
+		return SuppressionComplaints, nil
+	} else if GetStatusFromErr(err) != http.StatusNotFound {
+		return "", err
+	}
+
+	unsubscribe, err := mg.GetUnsubscribe(ctx, address)
+	if err == nil {
+		for _, t := range unsubscribe.Tags {
+			if t == "*" || containsString(tags, t) {
+				return SuppressionUnsubscribes, nil
+			}
+		}
+	} else if GetStatusFromErr(err) != http.StatusNotFound {
+		return "", err
+	}
+	return "", nil
+}
+
+func withoutSuppressed(recipients []string, suppressed map[string]bool) []string {
+	var result []string
+	for _, r := range recipients {
+		if !suppressed[r] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
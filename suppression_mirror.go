@@ -0,0 +1,106 @@
+package mailgun
+
+import (
+	"context"
+	"errors"
+)
+
+// MirrorOptions configures MirrorSuppressions()
+type MirrorOptions struct {
+	// The domains the suppressions of the client domain are copied to
+	Domains []string
+	// Set to skip copying unsubscribes or complaints
+	SkipUnsubscribes bool
+	SkipComplaints   bool
+	// Set to report what would be copied without changing the target domains
+	DryRun bool
+	// The number of addresses added per request, defaults to MaxSuppressionBatch
+	ChunkSize int
+}
+
+// Validate returns an *OptionError if the options are invalid.
+func (o *MirrorOptions) Validate() error {
+	if len(o.Domains) == 0 {
+		return invalidOption("Domains", "at least one target domain is required")
+	}
+	if o.ChunkSize < 0 || o.ChunkSize > MaxSuppressionBatch {
+		return invalidOption("ChunkSize", "must be between 0 and 1000")
+	}
+	return nil
+}
+
+// MirrorReport lists the addresses MirrorSuppressions() copied to each target domain, or
+// would have copied in a dry run.
+type MirrorReport struct {
+	DryRun bool
+	// Unsubscribed addresses added, by target domain
+	Unsubscribes map[string][]string
+	// Complained addresses added, by target domain
+	Complaints map[string][]string
+}
+
+// MirrorSuppressions copies the unsubscribes and complaints of the client domain to a set of
+// sibling domains, for audiences which are mailed from several domains. Only addresses which
+// are missing from a target domain are added, in batches of opts.ChunkSize addresses. It is a
+// shortcut for a SuppressionSync which only adds unsubscribes and complaints.
+//
+//	report, err := mg.MirrorSuppressions(ctx, &mailgun.MirrorOptions{
+//		Domains: []string{"news.example.com", "billing.example.com"},
+//		DryRun:  true,
+//	})
+//
+// An error copying to a domain doesn't stop the other domains, the first error is returned and
+// the report lists the addresses copied before the error.
+func (mg *MailgunImpl) MirrorSuppressions(ctx context.Context, opts *MirrorOptions) (MirrorReport, error) {
+	report := MirrorReport{
+		Unsubscribes: make(map[string][]string),
+		Complaints:   make(map[string][]string),
+	}
+	if opts == nil {
+		return report, errors.New("MirrorSuppressions requires MirrorOptions")
+	}
+	if err := opts.Validate(); err != nil {
+		return report, err
+	}
+	report.DryRun = opts.DryRun
+
+	s := NewSuppressionSync(mg, mirrorSource{mg: mg, opts: opts}, opts.Domains...)
+	s.DryRun = opts.DryRun
+	s.ChunkSize = opts.ChunkSize
+	results, err := s.Run(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, r := range results {
+		for _, u := range r.Added.Unsubscribes {
+			report.Unsubscribes[r.Domain] = append(report.Unsubscribes[r.Domain], u.Address)
+		}
+		for _, c := range r.Added.Complaints {
+			report.Complaints[r.Domain] = append(report.Complaints[r.Domain], c.Address)
+		}
+		if r.Err != nil && err == nil {
+			err = r.Err
+		}
+	}
+	return report, err
+}
+
+// mirrorSource reads the suppressions MirrorSuppressions() copies from the domain of mg
+type mirrorSource struct {
+	mg   Mailgun
+	opts *MirrorOptions
+}
+
+func (m mirrorSource) Suppressions(ctx context.Context) (SuppressionSet, error) {
+	var set SuppressionSet
+	var err error
+	if !m.opts.SkipUnsubscribes {
+		if set.Unsubscribes, err = allUnsubscribes(ctx, m.mg); err != nil {
+			return set, err
+		}
+	}
+	if !m.opts.SkipComplaints {
+		set.Complaints, err = allComplaints(ctx, m.mg)
+	}
+	return set, err
+}
@@ -0,0 +1,104 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+// suppressionServer serves the unsubscribes and complaints lists of several domains
+type suppressionServer struct {
+	mu      sync.Mutex
+	lists   map[string][]unsubscribeEntry
+	batches int
+}
+
+func (ss *suppressionServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	switch req.Method {
+	case http.MethodGet:
+		// The second page is always empty
+		if req.FormValue("page") == "next" {
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+			return
+		}
+		next := fmt.Sprintf("http://%s/%s?page=next", req.Host, key)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":  ss.lists[key],
+			"paging": Paging{Next: next},
+		})
+	case http.MethodPost:
+		var batch []unsubscribeEntry
+		if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ss.batches++
+		ss.lists[key] = append(ss.lists[key], batch...)
+		fmt.Fprint(w, `{"message": "Addresses have been added"}`)
+	}
+}
+
+func TestMirrorSuppressions(t *testing.T) {
+	ss := &suppressionServer{lists: map[string][]unsubscribeEntry{
+		"example.com/unsubscribes": {
+			{Address: "a@example.com", Tags: []string{"*"}},
+			{Address: "b@example.com", Tags: []string{"newsletter"}},
+			{Address: "c@example.com", Tags: []string{"*"}},
+		},
+		"example.com/complaints": {
+			{Address: "spam@example.com"},
+		},
+		"news.example.com/unsubscribes": {
+			{Address: "b@example.com", Tags: []string{"newsletter"}},
+		},
+	}}
+	srv := httptest.NewServer(ss)
+	defer srv.Close()
+
+	mg := NewMailgun("example.com", exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	_, err := mg.MirrorSuppressions(ctx, &MirrorOptions{})
+	ensure.NotNil(t, err)
+
+	opts := &MirrorOptions{
+		Domains:   []string{"news.example.com", "billing.example.com"},
+		ChunkSize: 1,
+		DryRun:    true,
+	}
+	report, err := mg.MirrorSuppressions(ctx, opts)
+	ensure.Nil(t, err)
+	ensure.True(t, report.DryRun)
+	ensure.DeepEqual(t, report.Unsubscribes["news.example.com"], []string{"a@example.com", "c@example.com"})
+	ensure.DeepEqual(t, report.Unsubscribes["billing.example.com"],
+		[]string{"a@example.com", "b@example.com", "c@example.com"})
+	ensure.DeepEqual(t, report.Complaints["news.example.com"], []string{"spam@example.com"})
+	ensure.DeepEqual(t, ss.batches, 0)
+
+	opts.DryRun = false
+	report, err = mg.MirrorSuppressions(ctx, opts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(report.Unsubscribes["billing.example.com"]), 3)
+	// One request per address with a chunk size of 1
+	ensure.DeepEqual(t, ss.batches, 7)
+	ensure.DeepEqual(t, ss.lists["billing.example.com/unsubscribes"][1],
+		unsubscribeEntry{Address: "b@example.com", Tags: []string{"newsletter"}})
+
+	// Nothing is left to copy
+	report, err = mg.MirrorSuppressions(ctx, opts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(report.Unsubscribes), 0)
+	ensure.DeepEqual(t, len(report.Complaints), 0)
+}
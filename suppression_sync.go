@@ -0,0 +1,428 @@
+package mailgun
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SuppressionSet holds the entries of the bounces, unsubscribes and complaints lists of a domain.
+// A SuppressionSet is also a SuppressionSource, so suppressions kept outside of Mailgun can
+// be replicated with SuppressionSync.
+type SuppressionSet struct {
+	Bounces      []Bounce
+	Unsubscribes []Unsubscribe
+	Complaints   []Complaint
+}
+
+// Suppressions returns the set itself.
+func (s SuppressionSet) Suppressions(ctx context.Context) (SuppressionSet, error) {
+	return s, nil
+}
+
+// SuppressionSource provides the suppressions SuppressionSync replicates
+type SuppressionSource interface {
+	Suppressions(ctx context.Context) (SuppressionSet, error)
+}
+
+// DomainSuppressions returns a SuppressionSource which reads the suppression lists of the domain of mg.
+func DomainSuppressions(mg Mailgun) SuppressionSource {
+	return domainSuppressions{mg: mg}
+}
+
+type domainSuppressions struct {
+	mg Mailgun
+}
+
+func (d domainSuppressions) Suppressions(ctx context.Context) (SuppressionSet, error) {
+	return listSuppressions(ctx, d.mg)
+}
+
+// MaxSuppressionBatch is the largest number of addresses Mailgun accepts in a single
+// request when adding bounces, unsubscribes or complaints.
+const MaxSuppressionBatch = 1000
+
+const (
+	// DefaultSyncConcurrency is the number of domains SuppressionSync updates at the same time
+	DefaultSyncConcurrency = 4
+	// DefaultSyncRetries is the number of times SuppressionSync retries a rate limited request
+	DefaultSyncRetries = 5
+	// DefaultMinAttemptTimeout is the shortest timeout SuppressionSync gives a request when
+	// splitting the deadline of its context across retries
+	DefaultMinAttemptTimeout = time.Second
+)
+
+// SuppressionSync replicates the suppressions of a source, such as another domain, to a set of
+// domains. Suppressions missing from a domain are added and, if Remove is set, suppressions which
+// are not in the source are removed. Domains are updated concurrently, and requests rejected
+// with 429 Too Many Requests or 503 Service Unavailable are retried after the delay requested
+// by Mailgun.
+//
+//	sync := mailgun.NewSuppressionSync(mg, mailgun.DomainSuppressions(mg),
+//		"news.example.com", "billing.example.com")
+//	sync.Remove = true
+//	results, err := sync.Run(ctx)
+type SuppressionSync struct {
+	// Remove deletes suppressions from the domains which are not in the source
+	Remove bool
+	// DryRun reports the changes in the results without making them
+	DryRun bool
+	// The number of domains updated at the same time, defaults to DefaultSyncConcurrency
+	Concurrency int
+	// The number of times a rate limited or timed out request is retried, defaults to DefaultSyncRetries
+	MaxRetries int
+	// If the context of Run() has a deadline, the time left is split evenly across a request and
+	// its remaining retries, so the first attempt can't use the whole budget. MinAttemptTimeout
+	// is the shortest timeout given to an attempt, defaults to DefaultMinAttemptTimeout.
+	MinAttemptTimeout time.Duration
+	// The number of addresses added per request, defaults to and is capped at MaxSuppressionBatch
+	ChunkSize int
+
+	mg      Mailgun
+	source  SuppressionSource
+	domains []string
+	// The delay before the first retry when Mailgun doesn't request one, doubled for each retry
+	backoff time.Duration
+}
+
+// SuppressionSyncResult reports the changes SuppressionSync made to a domain, or would make in
+// a dry run. If an error occurred the result lists the changes made before the error.
+type SuppressionSyncResult struct {
+	Domain  string
+	Added   SuppressionSet
+	Removed SuppressionSet
+	Err     error
+}
+
+// NewSuppressionSync creates a SuppressionSync which replicates the suppressions of source to
+// the given domains, using the credentials of mg.
+func NewSuppressionSync(mg Mailgun, source SuppressionSource, domains ...string) *SuppressionSync {
+	return &SuppressionSync{
+		mg:      mg,
+		source:  source,
+		domains: domains,
+		backoff: time.Second,
+	}
+}
+
+// Run reads the source and updates the domains. It returns a result for every domain, in the
+// order the domains were given. An error updating a domain is reported in its result and does
+// not stop the other domains, Run only returns an error if the source could not be read.
+func (s *SuppressionSync) Run(ctx context.Context) ([]SuppressionSyncResult, error) {
+	var source SuppressionSet
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		source, err = s.source.Suppressions(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+	results := make([]SuppressionSyncResult, len(s.domains))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, domain := range s.domains {
+		wg.Add(1)
+		go func(i int, domain string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = s.syncDomain(ctx, source, domain)
+		}(i, domain)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (s *SuppressionSync) syncDomain(ctx context.Context, source SuppressionSet, domain string) SuppressionSyncResult {
+	result := SuppressionSyncResult{Domain: domain}
+	dmg := forDomain(s.mg, domain)
+
+	var existing SuppressionSet
+	result.Err = s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		existing, err = listSuppressions(ctx, dmg)
+		return err
+	})
+	if result.Err != nil {
+		return result
+	}
+
+	// Bounces
+	var bounces []bounceEntry
+	present := bounceAddresses(existing.Bounces)
+	for _, b := range source.Bounces {
+		if !present[b.Address] {
+			bounces = append(bounces, bounceEntry{Address: b.Address, Code: b.Code, Error: b.Error})
+			result.Added.Bounces = append(result.Added.Bounces, b)
+		}
+	}
+	// Unsubscribes
+	var unsubscribes []unsubscribeEntry
+	present = unsubscribeAddresses(existing.Unsubscribes)
+	for _, u := range source.Unsubscribes {
+		if !present[u.Address] {
+			unsubscribes = append(unsubscribes, unsubscribeEntry{Address: u.Address, Tags: u.Tags})
+			result.Added.Unsubscribes = append(result.Added.Unsubscribes, u)
+		}
+	}
+	// Complaints
+	var complaints []complaintEntry
+	present = complaintAddresses(existing.Complaints)
+	for _, c := range source.Complaints {
+		if !present[c.Address] {
+			complaints = append(complaints, complaintEntry{Address: c.Address})
+			result.Added.Complaints = append(result.Added.Complaints, c)
+		}
+	}
+
+	if s.Remove {
+		wanted := bounceAddresses(source.Bounces)
+		for _, b := range existing.Bounces {
+			if !wanted[b.Address] {
+				result.Removed.Bounces = append(result.Removed.Bounces, b)
+			}
+		}
+		wanted = unsubscribeAddresses(source.Unsubscribes)
+		for _, u := range existing.Unsubscribes {
+			if !wanted[u.Address] {
+				result.Removed.Unsubscribes = append(result.Removed.Unsubscribes, u)
+			}
+		}
+		wanted = complaintAddresses(source.Complaints)
+		for _, c := range existing.Complaints {
+			if !wanted[c.Address] {
+				result.Removed.Complaints = append(result.Removed.Complaints, c)
+			}
+		}
+	}
+	if s.DryRun {
+		return result
+	}
+
+	// Apply the changes, trimming the result to what was done if an error occurs
+	added, removed := result.Added, result.Removed
+	result.Added, result.Removed = SuppressionSet{}, SuppressionSet{}
+	chunk := s.ChunkSize
+	if chunk <= 0 || chunk > MaxSuppressionBatch {
+		chunk = MaxSuppressionBatch
+	}
+
+	for start := 0; start < len(bounces); start += chunk {
+		end := minInt(start+chunk, len(bounces))
+		if result.Err = s.retry(ctx, func(ctx context.Context) error {
+			return addSuppressions(ctx, dmg, bouncesEndpoint, bounces[start:end])
+		}); result.Err != nil {
+			return result
+		}
+		result.Added.Bounces = append(result.Added.Bounces, added.Bounces[start:end]...)
+	}
+	for start := 0; start < len(unsubscribes); start += chunk {
+		end := minInt(start+chunk, len(unsubscribes))
+		if result.Err = s.retry(ctx, func(ctx context.Context) error {
+			return addSuppressions(ctx, dmg, unsubscribesEndpoint, unsubscribes[start:end])
+		}); result.Err != nil {
+			return result
+		}
+		result.Added.Unsubscribes = append(result.Added.Unsubscribes, added.Unsubscribes[start:end]...)
+	}
+	for start := 0; start < len(complaints); start += chunk {
+		end := minInt(start+chunk, len(complaints))
+		if result.Err = s.retry(ctx, func(ctx context.Context) error {
+			return addSuppressions(ctx, dmg, complaintsEndpoint, complaints[start:end])
+		}); result.Err != nil {
+			return result
+		}
+		result.Added.Complaints = append(result.Added.Complaints, added.Complaints[start:end]...)
+	}
+
+	for _, b := range removed.Bounces {
+		if result.Err = s.retry(ctx, func(ctx context.Context) error { return dmg.DeleteBounce(ctx, b.Address) }); result.Err != nil {
+			return result
+		}
+		result.Removed.Bounces = append(result.Removed.Bounces, b)
+	}
+	for _, u := range removed.Unsubscribes {
+		if result.Err = s.retry(ctx, func(ctx context.Context) error { return dmg.DeleteUnsubscribe(ctx, u.Address) }); result.Err != nil {
+			return result
+		}
+		result.Removed.Unsubscribes = append(result.Removed.Unsubscribes, u)
+	}
+	for _, c := range removed.Complaints {
+		if result.Err = s.retry(ctx, func(ctx context.Context) error { return dmg.DeleteComplaint(ctx, c.Address) }); result.Err != nil {
+			return result
+		}
+		result.Removed.Complaints = append(result.Removed.Complaints, c)
+	}
+	return result
+}
+
+// retry runs op until it succeeds, fails with an error other than a rate limit or a timeout of
+// the attempt, or the retries are exhausted
+func (s *SuppressionSync) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	retries := s.MaxRetries
+	if retries <= 0 {
+		retries = DefaultSyncRetries
+	}
+	floor := s.MinAttemptTimeout
+	if floor <= 0 {
+		floor = DefaultMinAttemptTimeout
+	}
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := attemptContext(ctx, retries-attempt+1, floor)
+		err := op(attemptCtx)
+		timedOut := err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+		if err == nil || attempt == retries || !(timedOut || rateLimited(err)) {
+			return err
+		}
+		delay := RetryAfter(err, backoff)
+		s.mg.ClientEvents().publishRetry(RetryEvent{Attempt: attempt + 1, Delay: delay, Err: err})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// attemptContext returns the context of an attempt of a retried operation. If ctx has a deadline,
+// the time left is split evenly across the attempts left, but not below floor.
+func attemptContext(ctx context.Context, attemptsLeft int, floor time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || attemptsLeft <= 1 {
+		return context.WithCancel(ctx)
+	}
+	timeout := time.Until(deadline) / time.Duration(attemptsLeft)
+	if timeout < floor {
+		timeout = floor
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func rateLimited(err error) bool {
+	status := GetStatusFromErr(err)
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// forDomain returns a client with the credentials of mg for another domain
+func forDomain(mg Mailgun, domain string) Mailgun {
+	if impl, ok := mg.(*MailgunImpl); ok {
+		c := *impl
+		c.domain = domain
+		return &c
+	}
+	c := NewMailgun(domain, mg.APIKey())
+	c.SetAPIBase(mg.APIBase())
+	c.SetClient(mg.Client())
+	return c
+}
+
+type bounceEntry struct {
+	Address string `json:"address"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type unsubscribeEntry struct {
+	Address string   `json:"address"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+type complaintEntry struct {
+	Address string `json:"address"`
+}
+
+func listSuppressions(ctx context.Context, mg Mailgun) (SuppressionSet, error) {
+	var set SuppressionSet
+	var err error
+	if set.Bounces, err = allBounces(ctx, mg); err != nil {
+		return set, err
+	}
+	if set.Unsubscribes, err = allUnsubscribes(ctx, mg); err != nil {
+		return set, err
+	}
+	set.Complaints, err = allComplaints(ctx, mg)
+	return set, err
+}
+
+func allBounces(ctx context.Context, mg Mailgun) ([]Bounce, error) {
+	var result, page []Bounce
+	it := mg.ListBounces(&ListOptions{Limit: 1000})
+	for it.Next(ctx, &page) {
+		result = append(result, page...)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result, it.Err()
+}
+
+func bounceAddresses(bounces []Bounce) map[string]bool {
+	addresses := make(map[string]bool, len(bounces))
+	for _, b := range bounces {
+		addresses[b.Address] = true
+	}
+	return addresses
+}
+
+func unsubscribeAddresses(unsubscribes []Unsubscribe) map[string]bool {
+	addresses := make(map[string]bool, len(unsubscribes))
+	for _, u := range unsubscribes {
+		addresses[u.Address] = true
+	}
+	return addresses
+}
+
+func complaintAddresses(complaints []Complaint) map[string]bool {
+	addresses := make(map[string]bool, len(complaints))
+	for _, c := range complaints {
+		addresses[c.Address] = true
+	}
+	return addresses
+}
+
+// addSuppressions adds a batch of entries to a suppression list with a single request
+func addSuppressions(ctx context.Context, mg Mailgun, endpoint string, entries interface{}) error {
+	r := newHTTPRequest(generateApiUrl(mg, endpoint))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	_, err := makePostRequest(ctx, r, newJSONEncodedPayload(entries))
+	return err
+}
+
+func allUnsubscribes(ctx context.Context, mg Mailgun) ([]Unsubscribe, error) {
+	var result, page []Unsubscribe
+	it := mg.ListUnsubscribes(&ListOptions{Limit: 1000})
+	for it.Next(ctx, &page) {
+		result = append(result, page...)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result, it.Err()
+}
+
+func allComplaints(ctx context.Context, mg Mailgun) ([]Complaint, error) {
+	var result, page []Complaint
+	it := mg.ListComplaints(&ListOptions{Limit: 1000})
+	for it.Next(ctx, &page) {
+		result = append(result, page...)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result, it.Err()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
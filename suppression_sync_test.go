@@ -0,0 +1,184 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+// syncServer serves the suppression lists of several domains, rate limiting the first write
+type syncServer struct {
+	mu          sync.Mutex
+	lists       map[string][]map[string]interface{}
+	rateLimited bool
+	writes      int
+}
+
+func (ss *syncServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Paths are /<domain>/<list> or /<domain>/<list>/<address>
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 3)
+	key := parts[0] + "/" + parts[1]
+	switch req.Method {
+	case http.MethodGet:
+		if req.FormValue("page") == "next" {
+			fmt.Fprint(w, `{"items": [], "paging": {}}`)
+			return
+		}
+		items := ss.lists[key]
+		if items == nil {
+			items = []map[string]interface{}{}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":  items,
+			"paging": Paging{Next: fmt.Sprintf("http://%s/%s?page=next", req.Host, key)},
+		})
+	case http.MethodPost:
+		if !ss.rateLimited {
+			ss.rateLimited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var batch []map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ss.writes++
+		ss.lists[key] = append(ss.lists[key], batch...)
+		fmt.Fprint(w, `{"message": "Addresses have been added"}`)
+	case http.MethodDelete:
+		var kept []map[string]interface{}
+		for _, item := range ss.lists[key] {
+			if item["address"] != parts[2] {
+				kept = append(kept, item)
+			}
+		}
+		ss.writes++
+		ss.lists[key] = kept
+		fmt.Fprint(w, `{"message": "Address has been removed"}`)
+	}
+}
+
+func (ss *syncServer) addresses(key string) []string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	var addresses []string
+	for _, item := range ss.lists[key] {
+		addresses = append(addresses, item["address"].(string))
+	}
+	return addresses
+}
+
+func TestSuppressionSync(t *testing.T) {
+	ss := &syncServer{lists: map[string][]map[string]interface{}{
+		"example.com/bounces": {
+			{"address": "bounced@example.com", "code": "550", "error": "No such mailbox"},
+		},
+		"example.com/unsubscribes": {
+			{"address": "unsubscribed@example.com", "tags": []string{"*"}},
+		},
+		"news.example.com/complaints": {
+			{"address": "old@example.com"},
+		},
+	}}
+	srv := httptest.NewServer(ss)
+	defer srv.Close()
+
+	mg := NewMailgun("example.com", exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	s := NewSuppressionSync(mg, DomainSuppressions(mg), "news.example.com", "billing.example.com")
+	s.backoff = time.Millisecond
+	s.Remove = true
+	s.DryRun = true
+
+	results, err := s.Run(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(results), 2)
+	ensure.DeepEqual(t, results[0].Domain, "news.example.com")
+	ensure.Nil(t, results[0].Err)
+	ensure.DeepEqual(t, len(results[0].Added.Bounces), 1)
+	ensure.DeepEqual(t, results[0].Added.Unsubscribes[0].Address, "unsubscribed@example.com")
+	ensure.DeepEqual(t, results[0].Removed.Complaints[0].Address, "old@example.com")
+	ensure.DeepEqual(t, ss.writes, 0)
+
+	// The first write is rate limited and retried
+	s.DryRun = false
+	results, err = s.Run(ctx)
+	ensure.Nil(t, err)
+	for _, r := range results {
+		ensure.Nil(t, r.Err)
+	}
+	ensure.True(t, ss.rateLimited)
+	ensure.DeepEqual(t, ss.addresses("billing.example.com/bounces"), []string{"bounced@example.com"})
+	ensure.DeepEqual(t, ss.addresses("news.example.com/unsubscribes"), []string{"unsubscribed@example.com"})
+	ensure.DeepEqual(t, len(ss.addresses("news.example.com/complaints")), 0)
+	ensure.DeepEqual(t, ss.lists["billing.example.com/bounces"][0]["code"], "550")
+
+	// A local source, which removes everything it doesn't list
+	local := SuppressionSet{Unsubscribes: []Unsubscribe{{Address: "unsubscribed@example.com"}}}
+	s = NewSuppressionSync(mg, local, "billing.example.com")
+	s.Remove = true
+	results, err = s.Run(ctx)
+	ensure.Nil(t, err)
+	ensure.Nil(t, results[0].Err)
+	ensure.DeepEqual(t, len(results[0].Added.Unsubscribes), 0)
+	ensure.DeepEqual(t, len(results[0].Removed.Bounces), 1)
+	ensure.DeepEqual(t, len(ss.addresses("billing.example.com/bounces")), 0)
+}
+
+func TestSuppressionSyncDeadlineSplitting(t *testing.T) {
+	s := NewSuppressionSync(NewMailgun(exampleDomain, exampleAPIKey), SuppressionSet{})
+	s.backoff = time.Millisecond
+	s.MaxRetries = 3
+	s.MinAttemptTimeout = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+
+	// The first attempt hangs until its share of the deadline expires, leaving time to retry
+	var timeouts []time.Duration
+	start := time.Now()
+	err := s.retry(ctx, func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		ensure.True(t, ok)
+		timeouts = append(timeouts, time.Until(deadline))
+		if len(timeouts) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(timeouts), 2)
+	ensure.True(t, timeouts[0] <= 200*time.Millisecond, timeouts[0])
+	ensure.True(t, time.Since(start) < 400*time.Millisecond)
+
+	// Attempts get at least the floor, but never more than the deadline of the caller
+	attemptCtx, cancelAttempt := attemptContext(ctx, 1000, time.Hour)
+	defer cancelAttempt()
+	attemptDeadline, _ := attemptCtx.Deadline()
+	deadline, _ := ctx.Deadline()
+	ensure.DeepEqual(t, attemptDeadline, deadline)
+
+	// Errors other than timeouts and rate limits are not retried
+	var attempts int
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errors.New("boom")
+	})
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, attempts, 1)
+}
@@ -0,0 +1,113 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func newSuppressionServer(t *testing.T, sent *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/" + exampleDomain + "/bounces/bounced@example.com":
+			fmt.Fprint(w, `{"address": "bounced@example.com", "code": "550"}`)
+		case "/" + exampleDomain + "/complaints/complained@example.com":
+			fmt.Fprint(w, `{"address": "complained@example.com"}`)
+		case "/" + exampleDomain + "/unsubscribes/unsubscribed@example.com":
+			fmt.Fprint(w, `{"address": "unsubscribed@example.com", "tags": ["*"]}`)
+		case "/" + exampleDomain + "/unsubscribes/newsletter@example.com":
+			fmt.Fprint(w, `{"address": "newsletter@example.com", "tags": ["newsletter"]}`)
+		case "/" + exampleDomain + "/messages":
+			ensure.Nil(t, req.ParseMultipartForm(1<<20))
+			*sent = append(req.MultipartForm.Value["to"], req.MultipartForm.Value["cc"]...)
+			fmt.Fprint(w, `{"message": "Queued", "id": "<id@example.com>"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Address not found"}`)
+		}
+	}))
+}
+
+func TestSendSuppressionStrip(t *testing.T) {
+	var sent []string
+	srv := newSuppressionServer(t, &sent)
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText,
+		"ok@example.com", "Bounced <bounced@example.com>", "newsletter@example.com")
+	m.AddCC("unsubscribed@example.com")
+	m.AddCC("complained@example.com")
+	m.SetSuppressionMode(SuppressionStrip)
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	// Tag specific unsubscribes only apply to messages with the tag
+	ensure.DeepEqual(t, sent, []string{"ok@example.com", "newsletter@example.com"})
+	ensure.DeepEqual(t, m.SkippedRecipients(), []SuppressedRecipient{
+		{Address: "Bounced <bounced@example.com>", List: SuppressionBounces},
+		{Address: "unsubscribed@example.com", List: SuppressionUnsubscribes},
+		{Address: "complained@example.com", List: SuppressionComplaints},
+	})
+
+	// The recipients are stripped from a copy, sending the message again sends it to them as well
+	ensure.DeepEqual(t, len(m.to), 3)
+	ensure.DeepEqual(t, len(m.specific.(*plainMessage).cc), 2)
+	_, _, err = mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sent, []string{"ok@example.com", "newsletter@example.com"})
+
+	// The recipient variables of the suppressed recipients are not sent
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText)
+	ensure.Nil(t, m.AddRecipientAndVariables("ok@example.com", map[string]interface{}{"id": 1}))
+	ensure.Nil(t, m.AddRecipientAndVariables("bounced@example.com", map[string]interface{}{"id": 2}))
+	m.SetSuppressionMode(SuppressionStrip)
+	c, err := mg.filterSuppressed(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, c.to, []string{"ok@example.com"})
+	ensure.DeepEqual(t, c.recipientVariables, map[string]map[string]interface{}{"ok@example.com": {"id": 1}})
+	ensure.DeepEqual(t, len(m.recipientVariables), 2)
+
+	// All To: recipients suppressed
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "newsletter@example.com")
+	ensure.Nil(t, m.AddTag("newsletter"))
+	m.SetSuppressionMode(SuppressionStrip)
+	_, _, err = mg.Send(context.Background(), m)
+	ensure.DeepEqual(t, err, &SuppressedError{Recipients: []SuppressedRecipient{
+		{Address: "newsletter@example.com", List: SuppressionUnsubscribes},
+	}})
+}
+
+func TestSendSuppressionReject(t *testing.T) {
+	var sent []string
+	srv := newSuppressionServer(t, &sent)
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+
+	m := mg.NewMessage(fromUser, exampleSubject, exampleText, "ok@example.com", "bounced@example.com")
+	m.SetSuppressionMode(SuppressionReject)
+
+	_, _, err := mg.Send(context.Background(), m)
+	ensure.NotNil(t, err)
+	suppressedErr, ok := err.(*SuppressedError)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, suppressedErr.Recipients, []SuppressedRecipient{
+		{Address: "bounced@example.com", List: SuppressionBounces},
+	})
+	ensure.DeepEqual(t, len(sent), 0)
+
+	// Nothing is rejected when no recipient is suppressed
+	m = mg.NewMessage(fromUser, exampleSubject, exampleText, "ok@example.com")
+	m.SetSuppressionMode(SuppressionReject)
+	_, _, err = mg.Send(context.Background(), m)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sent, []string{"ok@example.com"})
+}
@@ -2,7 +2,9 @@ package mailgun
 
 import (
 	"context"
+	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
 	"time"
 )
@@ -26,10 +28,21 @@ type ListTagOptions struct {
 	Prefix string
 }
 
+// Validate returns an *OptionError if the options are invalid. A nil *ListTagOptions is valid.
+func (o *ListTagOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Limit < 0 {
+		return invalidOption("Limit", "must not be negative")
+	}
+	return nil
+}
+
 // DeleteTag removes all counters for a particular tag, including the tag itself.
 func (mg *MailgunImpl) DeleteTag(ctx context.Context, tag string) error {
 	r := newHTTPRequest(generateApiUrl(mg, tagsEndpoint) + "/" + tag)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -38,7 +51,7 @@ func (mg *MailgunImpl) DeleteTag(ctx context.Context, tag string) error {
 // GetTag retrieves metadata about the tag from the api
 func (mg *MailgunImpl) GetTag(ctx context.Context, tag string) (Tag, error) {
 	r := newHTTPRequest(generateApiUrl(mg, tagsEndpoint) + "/" + tag)
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var tagItem Tag
 	return tagItem, getResponseFromJSON(ctx, r, &tagItem)
@@ -58,6 +71,9 @@ func (mg *MailgunImpl) GetTag(ctx context.Context, tag string) (Tag, error) {
 func (mg *MailgunImpl) ListTags(opts *ListTagOptions) *TagIterator {
 	req := newHTTPRequest(generateApiUrl(mg, tagsEndpoint))
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &TagIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			req.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -159,7 +175,7 @@ func (ti *TagIterator) Err() error {
 
 func (ti *TagIterator) fetch(ctx context.Context, url string) error {
 	req := newHTTPRequest(url)
-	req.setClient(ti.mg.Client())
+	req.setClient(ti.mg)
 	req.setBasicAuth(basicAuthUser, ti.mg.APIKey())
 	return getResponseFromJSON(ctx, req, &ti.tagsResponse)
 }
@@ -181,3 +197,20 @@ func canFetchPage(slug string) bool {
 	// If tags has no value, there are no more pages to fetch
 	return len(value) == 0
 }
+
+// A TagValidator returns an error if a tag does not follow the naming policy of
+// an organization. See SetTagValidator().
+type TagValidator func(tag string) error
+
+// TagPattern returns a TagValidator which only accepts tags matching the given regular expression.
+//
+//	// Require lowercase tags prefixed with the team name
+//	mg.SetTagValidator(mailgun.TagPattern(regexp.MustCompile(`^billing-[a-z0-9-]+$`)))
+func TagPattern(pattern *regexp.Regexp) TagValidator {
+	return func(tag string) error {
+		if !pattern.MatchString(tag) {
+			return fmt.Errorf("tag '%s' does not match the pattern '%s'", tag, pattern)
+		}
+		return nil
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,3 +90,25 @@ func waitForTag(mg mailgun.Mailgun, tag string) error {
 	}
 	return errors.Errorf("Waited to long for tag '%s' to show up", tag)
 }
+
+func TestTagValidator(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetTagValidator(mailgun.TagPattern(regexp.MustCompile(`^billing-[a-z0-9-]+$`)))
+
+	m := mg.NewMessage("from@example.com", "Subject", "Text", "to@example.com")
+	ensure.Nil(t, m.AddTag("billing-invoice"))
+	ensure.NotNil(t, m.AddTag("billing-receipt", "Billing Receipt"))
+	ensure.NotNil(t, m.AddTag("marketing-newsletter"))
+
+	mg.SetTagValidator(func(tag string) error {
+		if strings.ToLower(tag) != tag {
+			return errors.New("tags must be lowercase")
+		}
+		return nil
+	})
+	ensure.DeepEqual(t, m.AddTag("Newsletter").Error(), "tags must be lowercase")
+	ensure.Nil(t, m.AddTag("newsletter"))
+
+	mg.SetTagValidator(nil)
+	ensure.Nil(t, m.AddTag("Anything Goes"))
+}
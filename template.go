@@ -16,15 +16,14 @@ const (
 )
 
 type Template struct {
-	Id          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	CreatedAt   RFC2822Time `json:"createdAt"`
-	Version   TemplateVersion `json:"version,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	CreatedAt   RFC2822Time     `json:"createdAt"`
+	Version     TemplateVersion `json:"version,omitempty"`
 }
 
 type templateResp struct {
-	Item    Template `json:"item"`
+	Item    Template `json:"template"`
 	Message string   `json:"message"`
 }
 
@@ -35,15 +34,17 @@ type templateListResp struct {
 
 // Create a new template which can be used to attach template versions to
 func (mg *MailgunImpl) CreateTemplate(ctx context.Context, template *Template) error {
+	if template.Name == "" {
+		return errors.New("CreateTemplate() Template.Name cannot be empty")
+	}
+
 	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
+	payload.addValue("name", template.Name)
 
-	if template.Name != "" {
-		payload.addValue("name", template.Name)
-	}
 	if template.Description != "" {
 		payload.addValue("description", template.Description)
 	}
@@ -57,6 +58,9 @@ func (mg *MailgunImpl) CreateTemplate(ctx context.Context, template *Template) e
 	if template.Version.Comment != "" {
 		payload.addValue("comment", template.Version.Comment)
 	}
+	if template.Version.Tag != "" {
+		payload.addValue("tag", template.Version.Tag)
+	}
 
 	var resp templateResp
 	if err := postResponseFromJSON(ctx, r, payload, &resp); err != nil {
@@ -66,10 +70,11 @@ func (mg *MailgunImpl) CreateTemplate(ctx context.Context, template *Template) e
 	return nil
 }
 
-// Get a template given the template id
-func (mg *MailgunImpl) GetTemplate(ctx context.Context, id string) (Template, error) {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+// Get a template given the template name. The active version of the
+// template is included in Template.Version
+func (mg *MailgunImpl) GetTemplate(ctx context.Context, name string) (Template, error) {
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + name)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	r.addParameter("active", "yes")
 
@@ -81,20 +86,17 @@ func (mg *MailgunImpl) GetTemplate(ctx context.Context, id string) (Template, er
 	return resp.Item, nil
 }
 
-// Update the name and description of a template
+// Update the description of a template
 func (mg *MailgunImpl) UpdateTemplate(ctx context.Context, template *Template) error {
-	if template.Id == "" {
-		return errors.New("UpdateTemplate() Template.Id cannot be empty")
+	if template.Name == "" {
+		return errors.New("UpdateTemplate() Template.Name cannot be empty")
 	}
 
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + template.Id)
-	r.setClient(mg.Client())
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + template.Name)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 
-	if template.Name != "" {
-		p.addValue("name", template.Name)
-	}
 	if template.Description != "" {
 		p.addValue("description", template.Description)
 	}
@@ -108,10 +110,10 @@ func (mg *MailgunImpl) UpdateTemplate(ctx context.Context, template *Template) e
 	return nil
 }
 
-// Delete a template given a template id
-func (mg *MailgunImpl) DeleteTemplate(ctx context.Context, id string) error {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + id)
-	r.setClient(mg.Client())
+// Delete a template given a template name
+func (mg *MailgunImpl) DeleteTemplate(ctx context.Context, name string) error {
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + name)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -126,9 +128,12 @@ type TemplatesIterator struct {
 // List all available templates
 func (mg *MailgunImpl) ListTemplates(opts *ListOptions) *TemplatesIterator {
 	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &TemplatesIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -226,7 +231,7 @@ func (ti *TemplatesIterator) Previous(ctx context.Context, items *[]Template) bo
 
 func (ti *TemplatesIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(ti.mg.Client())
+	r.setClient(ti.mg)
 	r.setBasicAuth(basicAuthUser, ti.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &ti.templateListResp)
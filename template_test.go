@@ -3,29 +3,23 @@ package mailgun_test
 import (
 	"context"
 	"testing"
-	"time"
 
 	"github.com/facebookgo/ensure"
 	"github.com/mailgun/mailgun-go"
-	"github.com/pkg/errors"
 )
 
 func TestTemplateCRUD(t *testing.T) {
-	if reason := mailgun.SkipNetworkTest(); reason != "" {
-		t.Skip(reason)
-	}
-
-	mg, err := mailgun.NewMailgunFromEnv()
-	ensure.Nil(t, err)
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
 	ctx := context.Background()
 
-	findTemplate := func(id string) bool {
+	findTemplate := func(name string) bool {
 		it := mg.ListTemplates(nil)
 
 		var page []mailgun.Template
 		for it.Next(ctx, &page) {
 			for _, template := range page {
-				if template.Id == id {
+				if template.Name == name {
 					return true
 				}
 			}
@@ -35,57 +29,73 @@ func TestTemplateCRUD(t *testing.T) {
 	}
 
 	const (
-		Name        = "Mailgun-Go TestTemplateCRUD"
 		Description = "Mailgun-Go Test Template Description"
 		UpdatedDesc = "Mailgun-Go Test Updated Description"
 	)
 
+	name := randomString(10, "Mailgun-go-TestTemplateCRUD-")
+
 	tmpl := mailgun.Template{
-		Name:        Name,
+		Name:        name,
 		Description: Description,
 	}
 
 	// Create a template
 	ensure.Nil(t, mg.CreateTemplate(ctx, &tmpl))
-	ensure.True(t, tmpl.Id != "")
+	ensure.DeepEqual(t, tmpl.Name, name)
 	ensure.DeepEqual(t, tmpl.Description, Description)
-	ensure.DeepEqual(t, tmpl.Name, Name)
-
-	// Wait the template to show up
-	ensure.Nil(t, waitForTemplate(mg, tmpl.Id))
 
 	// Ensure the template is in the list
-	ensure.True(t, findTemplate(tmpl.Id))
+	ensure.True(t, findTemplate(name))
 
 	// Update the description
 	tmpl.Description = UpdatedDesc
 	ensure.Nil(t, mg.UpdateTemplate(ctx, &tmpl))
 
 	// Ensure update took
-	updated, err := mg.GetTemplate(ctx, tmpl.Id)
-
-	ensure.DeepEqual(t, updated.Id, tmpl.Id)
+	updated, err := mg.GetTemplate(ctx, name)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, updated.Name, name)
 	ensure.DeepEqual(t, updated.Description, UpdatedDesc)
 
 	// Delete the template
-	ensure.Nil(t, mg.DeleteTemplate(ctx, tmpl.Id))
+	ensure.Nil(t, mg.DeleteTemplate(ctx, name))
+	ensure.False(t, findTemplate(name))
+
+	_, err = mg.GetTemplate(ctx, name)
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, mailgun.GetStatusFromErr(err), 404)
 }
 
-func waitForTemplate(mg mailgun.Mailgun, id string) error {
+func TestCreateTemplateWithVersion(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
 	ctx := context.Background()
-	var attempts int
-	for attempts <= 5 {
-		_, err := mg.GetTemplate(ctx, id)
-		if err != nil {
-			if mailgun.GetStatusFromErr(err) == 404 {
-				time.Sleep(time.Second * 2)
-				attempts += 1
-				continue
-			}
-			return err
-		}
-		return nil
 
+	tmpl := mailgun.Template{
+		Name: randomString(10, "Mailgun-go-TestCreateTemplateWithVersion-"),
+		Version: mailgun.TemplateVersion{
+			Template: `'<div class="entry"> <h1>{{.title}}</h1> <div class="body"> {{.body}} </div> </div>'`,
+			Engine:   mailgun.TemplateEngineGo,
+			Comment:  "initial version",
+		},
 	}
-	return errors.Errorf("Waited to long for template '%s' to show up", id)
+	ensure.Nil(t, mg.CreateTemplate(ctx, &tmpl))
+	defer func() {
+		ensure.Nil(t, mg.DeleteTemplate(ctx, tmpl.Name))
+	}()
+
+	got, err := mg.GetTemplate(ctx, tmpl.Name)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, got.Version.Engine, mailgun.TemplateEngineGo)
+	ensure.DeepEqual(t, got.Version.Comment, "initial version")
+	ensure.True(t, got.Version.Active)
+}
+
+func TestCreateTemplateRequiresName(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+
+	err := mg.CreateTemplate(context.Background(), &mailgun.Template{Description: "no name"})
+	ensure.NotNil(t, err)
 }
@@ -2,34 +2,41 @@ package mailgun
 
 import (
 	"context"
+	"errors"
 	"strconv"
 )
 
 type TemplateVersion struct {
-	Id        string         `json:"id"`
+	Tag       string         `json:"tag"`
 	Template  string         `json:"template,omitempty"`
 	Engine    TemplateEngine `json:"engine"`
 	CreatedAt RFC2822Time    `json:"createdAt"`
-	Comment string `json:"comment"`
-	Active  bool   `json:"active"`
+	Comment   string         `json:"comment"`
+	Active    bool           `json:"active"`
 }
 
 type templateVersionListResp struct {
-	Item struct {
+	Template struct {
 		Template
 		Versions []TemplateVersion `json:"versions,omitempty"`
-	} `json:"item"`
+	} `json:"template"`
 	Paging Paging `json:"paging"`
 }
 
-// Add a template version to a template
-func (mg *MailgunImpl) AddTemplateVersion(ctx context.Context, templateId string, version *TemplateVersion) error {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateId + "/versions")
-	r.setClient(mg.Client())
+// Add a template version to a template. If version.Active is true the new
+// version becomes the active version of the template
+func (mg *MailgunImpl) AddTemplateVersion(ctx context.Context, templateName string, version *TemplateVersion) error {
+	if version.Tag == "" {
+		return errors.New("AddTemplateVersion() TemplateVersion.Tag cannot be empty")
+	}
+
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateName + "/versions")
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	payload := newUrlEncodedPayload()
 	payload.addValue("template", version.Template)
+	payload.addValue("tag", version.Tag)
 
 	if version.Engine != "" {
 		payload.addValue("engine", string(version.Engine))
@@ -50,9 +57,9 @@ func (mg *MailgunImpl) AddTemplateVersion(ctx context.Context, templateId string
 }
 
 // Get a specific version of a template
-func (mg *MailgunImpl) GetTemplateVersion(ctx context.Context, templateId, versionId string) (TemplateVersion, error) {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateId + "/versions/" + versionId)
-	r.setClient(mg.Client())
+func (mg *MailgunImpl) GetTemplateVersion(ctx context.Context, templateName, tag string) (TemplateVersion, error) {
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateName + "/versions/" + tag)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 
 	var resp templateResp
@@ -63,13 +70,20 @@ func (mg *MailgunImpl) GetTemplateVersion(ctx context.Context, templateId, versi
 	return resp.Item.Version, nil
 }
 
-// Update the comment and mark a version of a template active
-func (mg *MailgunImpl) UpdateTemplateVersion(ctx context.Context, templateId string, version *TemplateVersion) error {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateId + "/versions/" + version.Id)
-	r.setClient(mg.Client())
+// Update the template, comment and active flag of a version of a template
+func (mg *MailgunImpl) UpdateTemplateVersion(ctx context.Context, templateName string, version *TemplateVersion) error {
+	if version.Tag == "" {
+		return errors.New("UpdateTemplateVersion() TemplateVersion.Tag cannot be empty")
+	}
+
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateName + "/versions/" + version.Tag)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 
+	if version.Template != "" {
+		p.addValue("template", version.Template)
+	}
 	if version.Comment != "" {
 		p.addValue("comment", version.Comment)
 	}
@@ -86,10 +100,16 @@ func (mg *MailgunImpl) UpdateTemplateVersion(ctx context.Context, templateId str
 	return nil
 }
 
+// Mark a version of a template as the active version. The active version is
+// used when sending messages which reference the template
+func (mg *MailgunImpl) ActivateTemplateVersion(ctx context.Context, templateName, tag string) error {
+	return mg.UpdateTemplateVersion(ctx, templateName, &TemplateVersion{Tag: tag, Active: true})
+}
+
 // Delete a specific version of a template
-func (mg *MailgunImpl) DeleteTemplateVersion(ctx context.Context, templateId, versionId string) error {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateId + "/versions/" + versionId)
-	r.setClient(mg.Client())
+func (mg *MailgunImpl) DeleteTemplateVersion(ctx context.Context, templateName, tag string) error {
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateName + "/versions/" + tag)
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -102,11 +122,14 @@ type TemplateVersionsIterator struct {
 }
 
 // List all the versions of a specific template
-func (mg *MailgunImpl) ListTemplateVersions(templateId string, opts *ListOptions) *TemplateVersionsIterator {
-	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateId + "/versions")
-	r.setClient(mg.Client())
+func (mg *MailgunImpl) ListTemplateVersions(templateName string, opts *ListOptions) *TemplateVersionsIterator {
+	r := newHTTPRequest(generateApiUrl(mg, templatesEndpoint) + "/" + templateName + "/versions")
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &TemplateVersionsIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -135,10 +158,10 @@ func (li *TemplateVersionsIterator) Next(ctx context.Context, items *[]TemplateV
 	if li.err != nil {
 		return false
 	}
-	cpy := make([]TemplateVersion, len(li.Item.Versions))
-	copy(cpy, li.Item.Versions)
+	cpy := make([]TemplateVersion, len(li.Template.Versions))
+	copy(cpy, li.Template.Versions)
 	*items = cpy
-	if len(li.Item.Versions) == 0 {
+	if len(li.Template.Versions) == 0 {
 		return false
 	}
 	return true
@@ -155,8 +178,8 @@ func (li *TemplateVersionsIterator) First(ctx context.Context, items *[]Template
 	if li.err != nil {
 		return false
 	}
-	cpy := make([]TemplateVersion, len(li.Item.Versions))
-	copy(cpy, li.Item.Versions)
+	cpy := make([]TemplateVersion, len(li.Template.Versions))
+	copy(cpy, li.Template.Versions)
 	*items = cpy
 	return true
 }
@@ -173,8 +196,8 @@ func (li *TemplateVersionsIterator) Last(ctx context.Context, items *[]TemplateV
 	if li.err != nil {
 		return false
 	}
-	cpy := make([]TemplateVersion, len(li.Item.Versions))
-	copy(cpy, li.Item.Versions)
+	cpy := make([]TemplateVersion, len(li.Template.Versions))
+	copy(cpy, li.Template.Versions)
 	*items = cpy
 	return true
 }
@@ -193,10 +216,10 @@ func (li *TemplateVersionsIterator) Previous(ctx context.Context, items *[]Templ
 	if li.err != nil {
 		return false
 	}
-	cpy := make([]TemplateVersion, len(li.Item.Versions))
-	copy(cpy, li.Item.Versions)
+	cpy := make([]TemplateVersion, len(li.Template.Versions))
+	copy(cpy, li.Template.Versions)
 	*items = cpy
-	if len(li.Item.Versions) == 0 {
+	if len(li.Template.Versions) == 0 {
 		return false
 	}
 	return true
@@ -204,8 +227,10 @@ func (li *TemplateVersionsIterator) Previous(ctx context.Context, items *[]Templ
 
 func (li *TemplateVersionsIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(li.mg.Client())
+	r.setClient(li.mg)
 	r.setBasicAuth(basicAuthUser, li.mg.APIKey())
 
+	// The versions field is omitted when empty, don't keep the previous page around
+	li.Template.Versions = nil
 	return getResponseFromJSON(ctx, r, &li.templateVersionListResp)
 }
@@ -9,21 +9,17 @@ import (
 )
 
 func TestTemplateVersionsCRUD(t *testing.T) {
-	if reason := mailgun.SkipNetworkTest(); reason != "" {
-		t.Skip(reason)
-	}
-
-	mg, err := mailgun.NewMailgunFromEnv()
-	ensure.Nil(t, err)
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
 	ctx := context.Background()
 
-	findVersion := func(templateId, versionId string) bool {
-		it := mg.ListTemplateVersions(templateId, nil)
+	findVersion := func(templateName, tag string) bool {
+		it := mg.ListTemplateVersions(templateName, nil)
 
 		var page []mailgun.TemplateVersion
 		for it.Next(ctx, &page) {
 			for _, v := range page {
-				if v.Id == versionId {
+				if v.Tag == tag {
 					return true
 				}
 			}
@@ -39,13 +35,14 @@ func TestTemplateVersionsCRUD(t *testing.T) {
 	)
 
 	tmpl := mailgun.Template{
-		Name: "Mailgun-go TestTemplateVersionsCRUD",
+		Name: randomString(10, "Mailgun-go-TestTemplateVersionsCRUD-"),
 	}
 
 	// Create a template
 	ensure.Nil(t, mg.CreateTemplate(ctx, &tmpl))
 
 	version := mailgun.TemplateVersion{
+		Tag:      "v1",
 		Comment:  Comment,
 		Template: Template,
 		Active:   true,
@@ -53,42 +50,57 @@ func TestTemplateVersionsCRUD(t *testing.T) {
 	}
 
 	// Add a version version
-	ensure.Nil(t, mg.AddTemplateVersion(ctx, tmpl.Id, &version))
-	ensure.True(t, version.Id != "")
+	ensure.Nil(t, mg.AddTemplateVersion(ctx, tmpl.Name, &version))
+	ensure.DeepEqual(t, version.Tag, "v1")
 	ensure.DeepEqual(t, version.Comment, Comment)
 	ensure.DeepEqual(t, version.Engine, mailgun.TemplateEngineGo)
 
 	// Ensure the version is in the list
-	ensure.True(t, findVersion(tmpl.Id, version.Id))
+	ensure.True(t, findVersion(tmpl.Name, version.Tag))
 
 	// Update the Comment
 	version.Comment = UpdatedComment
-	ensure.Nil(t, mg.UpdateTemplateVersion(ctx, tmpl.Id, &version))
+	ensure.Nil(t, mg.UpdateTemplateVersion(ctx, tmpl.Name, &version))
 
 	// Ensure update took
-	updated, err := mg.GetTemplateVersion(ctx, tmpl.Id, version.Id)
-
-	ensure.DeepEqual(t, version.Id, updated.Id)
+	updated, err := mg.GetTemplateVersion(ctx, tmpl.Name, version.Tag)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, updated.Tag, version.Tag)
 	ensure.DeepEqual(t, updated.Comment, UpdatedComment)
 
-	// Add a new active Version
+	// Add a new inactive version
 	version2 := mailgun.TemplateVersion{
+		Tag:      "v2",
 		Comment:  Comment,
 		Template: Template,
-		Active:   true,
 		Engine:   mailgun.TemplateEngineGo,
 	}
-	ensure.Nil(t, mg.AddTemplateVersion(ctx, tmpl.Id, &version2))
+	ensure.Nil(t, mg.AddTemplateVersion(ctx, tmpl.Name, &version2))
+	ensure.False(t, version2.Active)
 
 	// Ensure the version is in the list
-	ensure.True(t, findVersion(tmpl.Id, version2.Id))
+	ensure.True(t, findVersion(tmpl.Name, version2.Tag))
+
+	// Activate the new version
+	ensure.Nil(t, mg.ActivateTemplateVersion(ctx, tmpl.Name, version2.Tag))
+	active, err := mg.GetTemplate(ctx, tmpl.Name)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, active.Version.Tag, version2.Tag)
 
 	// Delete the first version
-	ensure.Nil(t, mg.DeleteTemplateVersion(ctx, tmpl.Id, version.Id))
+	ensure.Nil(t, mg.DeleteTemplateVersion(ctx, tmpl.Name, version.Tag))
 
 	// Ensure version was deleted
-	ensure.False(t, findVersion(tmpl.Id, version.Id))
+	ensure.False(t, findVersion(tmpl.Name, version.Tag))
 
 	// Delete the template
-	ensure.Nil(t, mg.DeleteTemplate(ctx, tmpl.Id))
+	ensure.Nil(t, mg.DeleteTemplate(ctx, tmpl.Name))
+}
+
+func TestAddTemplateVersionRequiresTag(t *testing.T) {
+	mg := mailgun.NewMailgun(testDomain, testKey)
+	mg.SetAPIBase(server.URL())
+
+	err := mg.AddTemplateVersion(context.Background(), "template1", &mailgun.TemplateVersion{Template: "{{.Name}}"})
+	ensure.NotNil(t, err)
 }
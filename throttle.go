@@ -0,0 +1,178 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainThrottle limits the rate of messages sent to recipient domains, such as at most 100
+// messages a minute to yahoo.com, as large providers throttle aggressively and the deferrals
+// slow down the delivery of all the messages of the domain. Install it with SetDomainThrottle()
+// to have Send() wait until the message may be sent.
+//
+//	throttle := mailgun.NewDomainThrottle()
+//	if err := throttle.Limit("yahoo.*", 100, time.Minute); err != nil {
+//		return err
+//	}
+//	mg.SetDomainThrottle(throttle)
+//
+// Each recipient of a message counts against the limit of its domain, the To, Cc and Bcc
+// recipients of plain messages and the To recipients of MIME messages.
+type DomainThrottle struct {
+	mu    sync.Mutex
+	rules []*throttleRule
+	now   func() time.Time
+}
+
+// throttleRule is a sliding window of the sends to the domains matching pattern, including the
+// sends reserved in the future by the messages waiting for the window
+type throttleRule struct {
+	pattern string
+	n       int
+	per     time.Duration
+	sends   []time.Time
+}
+
+// reservation is a send reserved for a recipient of a message
+type reservation struct {
+	rule *throttleRule
+	at   time.Time
+}
+
+// NewDomainThrottle creates a throttle without limits.
+func NewDomainThrottle() *DomainThrottle {
+	return &DomainThrottle{now: time.Now}
+}
+
+// Limit allows at most n messages per duration to the recipient domains matching pattern, a
+// pattern in the syntax of path.Match() such as "yahoo.com" or "*.yahoo.*". Domains matching
+// the same pattern share the limit. A domain matching several patterns is limited by the
+// pattern added first.
+func (t *DomainThrottle) Limit(pattern string, n int, per time.Duration) error {
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return invalidOption("pattern", fmt.Sprintf("'%s' is not a valid pattern", pattern))
+	}
+	if n <= 0 {
+		return invalidOption("n", "must be positive")
+	}
+	if per <= 0 {
+		return invalidOption("per", "must be positive")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = append(t.rules, &throttleRule{pattern: pattern, n: n, per: per})
+	return nil
+}
+
+// Wait blocks until the message may be sent to all its recipients, or ctx is done. If ctx is
+// done first, the sends reserved for the message are released for the messages which follow.
+func (t *DomainThrottle) Wait(ctx context.Context, m *Message) error {
+	t.mu.Lock()
+	now := t.now()
+	release := now
+	var reserved []reservation
+	for _, address := range m.recipients() {
+		rule := t.rule(recipientDomain(address))
+		if rule == nil {
+			continue
+		}
+		at := rule.reserve(now)
+		reserved = append(reserved, reservation{rule: rule, at: at})
+		if at.After(release) {
+			release = at
+		}
+	}
+	t.mu.Unlock()
+
+	if !release.After(now) {
+		return nil
+	}
+	timer := time.NewTimer(release.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		for _, r := range reserved {
+			r.rule.cancel(r.at)
+		}
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// rule returns the first rule matching domain, or nil
+func (t *DomainThrottle) rule(domain string) *throttleRule {
+	for _, r := range t.rules {
+		if ok, _ := path.Match(r.pattern, domain); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// reserve records a send at the earliest time at or after now the window allows, and returns it.
+// Sends are granted in the order they are reserved, which keeps the sends sorted.
+func (r *throttleRule) reserve(now time.Time) time.Time {
+	// Forget the sends which left the window
+	i := 0
+	for i < len(r.sends) && !r.sends[i].After(now.Add(-r.per)) {
+		i++
+	}
+	r.sends = r.sends[i:]
+
+	at := now
+	if len(r.sends) != 0 && r.sends[len(r.sends)-1].After(at) {
+		at = r.sends[len(r.sends)-1]
+	}
+	if len(r.sends) >= r.n {
+		if next := r.sends[len(r.sends)-r.n].Add(r.per); next.After(at) {
+			at = next
+		}
+	}
+	r.sends = append(r.sends, at)
+	return at
+}
+
+// cancel removes a send reserved at the given time. The sends reserved after it keep their
+// time, they are not brought forward.
+func (r *throttleRule) cancel(at time.Time) {
+	for i := len(r.sends) - 1; i >= 0; i-- {
+		if r.sends[i].Equal(at) {
+			r.sends = append(r.sends[:i], r.sends[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetDomainThrottle installs a throttle which Send() waits on before sending each message.
+// Pass nil to remove the throttle.
+func (mg *MailgunImpl) SetDomainThrottle(t *DomainThrottle) {
+	mg.throttle = t
+}
+
+// recipients returns the addresses the message is sent to
+func (m *Message) recipients() []string {
+	recipients := append([]string(nil), m.to...)
+	if pm, ok := m.specific.(*plainMessage); ok {
+		recipients = append(recipients, pm.cc...)
+		recipients = append(recipients, pm.bcc...)
+	}
+	return recipients
+}
+
+// recipientDomain returns the lower case domain of an address, which may include a display name
+func recipientDomain(address string) string {
+	if a, err := mail.ParseAddress(address); err == nil {
+		address = a.Address
+	}
+	return strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+}
@@ -0,0 +1,79 @@
+package mailgun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestDomainThrottleLimit(t *testing.T) {
+	throttle := NewDomainThrottle()
+	ensure.Nil(t, throttle.Limit("yahoo.*", 2, time.Minute))
+	ensure.NotNil(t, throttle.Limit("[yahoo", 2, time.Minute))
+	ensure.NotNil(t, throttle.Limit("yahoo.com", 0, time.Minute))
+	ensure.NotNil(t, throttle.Limit("yahoo.com", 2, 0))
+}
+
+func TestDomainThrottleWait(t *testing.T) {
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	throttle := NewDomainThrottle()
+	throttle.now = func() time.Time { return now }
+	ensure.Nil(t, throttle.Limit("yahoo.*", 2, time.Minute))
+	ensure.Nil(t, throttle.Limit("*", 100, time.Minute))
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	yahoo := mg.NewMessage(fromUser, exampleSubject, exampleText, "Joe <joe@Yahoo.com>")
+	yahoo.AddCC("jane@yahoo.fr")
+	ctx := context.Background()
+
+	// Both recipients fit in the window, domains matching a pattern share its limit
+	ensure.Nil(t, throttle.Wait(ctx, yahoo))
+
+	// Other domains are limited by their own rule
+	gmail := mg.NewMessage(fromUser, exampleSubject, exampleText, "joe@gmail.com")
+	ensure.Nil(t, throttle.Wait(ctx, gmail))
+
+	// The window is full until a minute after the first sends
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	ensure.DeepEqual(t, throttle.Wait(ctx, yahoo), context.DeadlineExceeded)
+
+	// The sends reserved by the cancelled wait are released, the window is free a minute later
+	now = now.Add(time.Minute)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ensure.Nil(t, throttle.Wait(ctx, yahoo))
+
+	now = now.Add(3 * time.Minute)
+	ensure.Nil(t, throttle.Wait(context.Background(), yahoo))
+}
+
+func TestSendDomainThrottle(t *testing.T) {
+	var sent []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sent = append(sent, time.Now())
+		fmt.Fprint(w, `{"message":"Queued", "id":"<id@example.com>"}`)
+	}))
+	defer srv.Close()
+
+	throttle := NewDomainThrottle()
+	ensure.Nil(t, throttle.Limit("yahoo.com", 1, 100*time.Millisecond))
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	mg.SetDomainThrottle(throttle)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		m := mg.NewMessage(fromUser, exampleSubject, exampleText, "joe@yahoo.com")
+		_, _, err := mg.Send(context.Background(), m)
+		ensure.Nil(t, err)
+	}
+	ensure.DeepEqual(t, len(sent), 3)
+	ensure.True(t, sent[2].Sub(start) >= 200*time.Millisecond)
+}
@@ -20,9 +20,12 @@ type unsubscribesResponse struct {
 // Fetches the list of unsubscribes
 func (mg *MailgunImpl) ListUnsubscribes(opts *ListOptions) *UnsubscribesIterator {
 	r := newHTTPRequest(generateApiUrl(mg, unsubscribesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return &UnsubscribesIterator{mg: mg, err: err}
+		}
 		if opts.Limit != 0 {
 			r.addParameter("limit", strconv.Itoa(opts.Limit))
 		}
@@ -126,7 +129,7 @@ func (ci *UnsubscribesIterator) Previous(ctx context.Context, items *[]Unsubscri
 
 func (ci *UnsubscribesIterator) fetch(ctx context.Context, url string) error {
 	r := newHTTPRequest(url)
-	r.setClient(ci.mg.Client())
+	r.setClient(ci.mg)
 	r.setBasicAuth(basicAuthUser, ci.mg.APIKey())
 
 	return getResponseFromJSON(ctx, r, &ci.unsubscribesResponse)
@@ -134,25 +137,27 @@ func (ci *UnsubscribesIterator) fetch(ctx context.Context, url string) error {
 
 // Retreives a single unsubscribe record. Can be used to check if a given address is present in the list of unsubscribed users.
 func (mg *MailgunImpl) GetUnsubscribe(ctx context.Context, address string) (Unsubscribe, error) {
-	// TODO: Test this method!
 	r := newHTTPRequest(generateApiUrlWithTarget(mg, unsubscribesEndpoint, address))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
-	var envelope struct {
-		Unsubscribe Unsubscribe `json:"unsubscribe"`
-	}
-	err := getResponseFromJSON(ctx, r, &envelope)
-	return envelope.Unsubscribe, err
+
+	var response Unsubscribe
+	err := getResponseFromJSON(ctx, r, &response)
+	return response, err
 }
 
 // Unsubscribe adds an e-mail address to the domain's unsubscription table.
+// The address is only unsubscribed from messages with the given tag, pass an empty tag
+// or "*" to unsubscribe it from all messages.
 func (mg *MailgunImpl) CreateUnsubscribe(ctx context.Context, address, tag string) error {
 	r := newHTTPRequest(generateApiUrl(mg, unsubscribesEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	p.addValue("address", address)
-	p.addValue("tag", tag)
+	if tag != "" {
+		p.addValue("tag", tag)
+	}
 	_, err := makePostRequest(ctx, r, p)
 	return err
 }
@@ -162,7 +167,7 @@ func (mg *MailgunImpl) CreateUnsubscribe(ctx context.Context, address, tag strin
 // with the given ID will be removed.
 func (mg *MailgunImpl) DeleteUnsubscribe(ctx context.Context, address string) error {
 	r := newHTTPRequest(generateApiUrlWithTarget(mg, unsubscribesEndpoint, address))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
@@ -173,7 +178,7 @@ func (mg *MailgunImpl) DeleteUnsubscribe(ctx context.Context, address string) er
 // with the given ID will be removed.
 func (mg *MailgunImpl) DeleteUnsubscribeWithTag(ctx context.Context, a, t string) error {
 	r := newHTTPRequest(generateApiUrlWithTarget(mg, unsubscribesEndpoint, a))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	r.addParameter("tag", t)
 	_, err := makeDeleteRequest(ctx, r)
@@ -2,7 +2,11 @@ package mailgun
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/facebookgo/ensure"
@@ -87,3 +91,76 @@ func TestCreateDestroyUnsubscription(t *testing.T) {
 	// Destroy the unsubscription record
 	ensure.Nil(t, mg.DeleteUnsubscribe(ctx, email))
 }
+
+func TestUnsubscribesRequests(t *testing.T) {
+	unsubscribes := map[string]Unsubscribe{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		prefix := "/" + exampleDomain + "/unsubscribes"
+		address := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		u, found := unsubscribes[address]
+		switch {
+		case req.Method == http.MethodPost && address == "":
+			tag := req.FormValue("tag")
+			if tag == "" {
+				tag = "*"
+			}
+			u := unsubscribes[req.FormValue("address")]
+			u.Address = req.FormValue("address")
+			u.Tags = append(u.Tags, tag)
+			unsubscribes[u.Address] = u
+			json.NewEncoder(w).Encode(map[string]string{"message": "Address has been added to the unsubscribes table"})
+		case req.Method == http.MethodGet && address == "":
+			var items []Unsubscribe
+			for _, u := range unsubscribes {
+				items = append(items, u)
+			}
+			json.NewEncoder(w).Encode(unsubscribesResponse{Items: items})
+		case req.Method == http.MethodGet && found:
+			json.NewEncoder(w).Encode(u)
+		case req.Method == http.MethodDelete && found:
+			if tag := req.FormValue("tag"); tag != "" {
+				var tags []string
+				for _, t := range u.Tags {
+					if t != tag {
+						tags = append(tags, t)
+					}
+				}
+				u.Tags = tags
+				unsubscribes[address] = u
+			} else {
+				delete(unsubscribes, address)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"message": "Unsubscribe event has been removed"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Address not found in unsubscribers table"})
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	ensure.Nil(t, mg.CreateUnsubscribe(ctx, "user@example.com", ""))
+	ensure.Nil(t, mg.CreateUnsubscribe(ctx, "user@example.com", "newsletter"))
+
+	u, err := mg.GetUnsubscribe(ctx, "user@example.com")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u.Address, "user@example.com")
+	ensure.DeepEqual(t, u.Tags, []string{"*", "newsletter"})
+
+	var page []Unsubscribe
+	it := mg.ListUnsubscribes(nil)
+	ensure.True(t, it.First(ctx, &page))
+	ensure.DeepEqual(t, len(page), 1)
+
+	ensure.Nil(t, mg.DeleteUnsubscribeWithTag(ctx, "user@example.com", "newsletter"))
+	u, err = mg.GetUnsubscribe(ctx, "user@example.com")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u.Tags, []string{"*"})
+
+	ensure.Nil(t, mg.DeleteUnsubscribe(ctx, "user@example.com"))
+	_, err = mg.GetUnsubscribe(ctx, "user@example.com")
+	ensure.DeepEqual(t, GetStatusFromErr(err), http.StatusNotFound)
+}
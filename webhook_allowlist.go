@@ -0,0 +1,130 @@
+package mailgun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrWebhookIPNotAllowed is returned by VerifyWebhookRequest() when the request did not
+// originate from an address in the WebhookIPAllowlist installed on the client.
+var ErrWebhookIPNotAllowed = errors.New("webhook request did not originate from an allowed address")
+
+// WebhookIPSource returns the current list of webhook egress ranges, in CIDR notation
+// or as single IP addresses. Use it to load the ranges published by Mailgun.
+type WebhookIPSource func(ctx context.Context) ([]string, error)
+
+// WebhookIPAllowlist checks the source address of webhook requests against a list of
+// IP ranges. It is an additional check to signature verification, not a replacement.
+// It is safe for concurrent use, so the ranges can be refreshed while requests are served.
+//
+//	allowlist, err := mailgun.NewWebhookIPAllowlist("198.51.100.0/24", "203.0.113.7")
+//	mg.SetWebhookIPAllowlist(allowlist)
+//
+//	verified, err := mg.VerifyWebhookRequest(req)
+type WebhookIPAllowlist struct {
+	mu     sync.RWMutex
+	nets   []*net.IPNet
+	source WebhookIPSource
+}
+
+// NewWebhookIPAllowlist creates an allowlist of the given ranges.
+func NewWebhookIPAllowlist(ranges ...string) (*WebhookIPAllowlist, error) {
+	a := &WebhookIPAllowlist{}
+	if err := a.SetRanges(ranges); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetRanges replaces the ranges of the allowlist. Returns an error, leaving the
+// allowlist unchanged, if any of the ranges can not be parsed.
+func (a *WebhookIPAllowlist) SetRanges(ranges []string) error {
+	nets := make([]*net.IPNet, 0, len(ranges))
+	for _, r := range ranges {
+		n, err := parseIPRange(r)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, n)
+	}
+	a.mu.Lock()
+	a.nets = nets
+	a.mu.Unlock()
+	return nil
+}
+
+// SetSource sets the source Refresh() loads the ranges from.
+func (a *WebhookIPAllowlist) SetSource(source WebhookIPSource) {
+	a.mu.Lock()
+	a.source = source
+	a.mu.Unlock()
+}
+
+// Refresh replaces the ranges with those returned by the source. If the source fails
+// the current ranges are kept, so a failed refresh never opens or empties the allowlist.
+func (a *WebhookIPAllowlist) Refresh(ctx context.Context) error {
+	a.mu.RLock()
+	source := a.source
+	a.mu.RUnlock()
+
+	if source == nil {
+		return errors.New("no source set, call SetSource() before Refresh()")
+	}
+	ranges, err := source(ctx)
+	if err != nil {
+		return err
+	}
+	return a.SetRanges(ranges)
+}
+
+// Allowed returns true if the ip is within one of the ranges.
+func (a *WebhookIPAllowlist) Allowed(ip net.IP) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedRequest returns true if the remote address of the request is within one of
+// the ranges. Headers such as X-Forwarded-For are not consulted, if your webhook handler
+// is behind a proxy, extract the client address yourself and call Allowed().
+func (a *WebhookIPAllowlist) AllowedRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return a.Allowed(ip)
+}
+
+func parseIPRange(r string) (*net.IPNet, error) {
+	r = strings.TrimSpace(r)
+	if strings.Contains(r, "/") {
+		_, n, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	ip := net.ParseIP(r)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address or range '%s'", r)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
@@ -0,0 +1,233 @@
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultWebhookCheckInterval is how often a WebhookChecker checks the webhooks of a domain
+const DefaultWebhookCheckInterval = 5 * time.Minute
+
+// WebhookCheckVariable is the user variable set to "true" on the synthetic events sent by a
+// WebhookChecker, so receivers can recognize and discard them.
+const WebhookCheckVariable = "mailgun-webhook-check"
+
+// WebhookCheck is the result of checking a single webhook url
+type WebhookCheck struct {
+	Kind WebhookKind
+	URL  string
+	// The HTTP status code returned by the url, 0 if no response was received
+	Code int
+	// Not nil if the url did not respond with a 2xx status code
+	Err  error
+	Time time.Time
+}
+
+// WebhookChecker periodically sends synthetic, signed webhook requests to the webhook urls of
+// the domain and reports urls which stop accepting them, so a broken receiver is noticed before
+// real events are lost.
+//
+//	checker := mailgun.NewWebhookChecker(mg)
+//	checker.OnFailure = func(c mailgun.WebhookCheck) {
+//		log.Printf("webhook %s at %s is failing: %s", c.Kind, c.URL, c.Err)
+//	}
+//	err := checker.Run(ctx)
+//
+// By default the checker posts the requests itself, signed as Mailgun signs them with the
+// webhook signing key of the account, see GetWebhookSigningKey(), or the API key. Set UseTestAPI
+// to have Mailgun send its test payload with TestWebhook() instead. Synthetic events carry the
+// WebhookCheckVariable user variable.
+type WebhookChecker struct {
+	// How often the webhooks are checked, defaults to DefaultWebhookCheckInterval
+	Interval time.Duration
+	// How long a url has to respond, defaults to 10 seconds
+	Timeout time.Duration
+	// Check the webhooks with the test-fire API instead of posting to the urls directly
+	UseTestAPI bool
+	// Called when a url fails a check, after passing the previous check
+	OnFailure func(WebhookCheck)
+	// Called when a failing url passes a check again
+	OnRecovery func(WebhookCheck)
+	// Called by Run() when the webhooks of the domain could not be listed
+	OnError func(error)
+
+	mg      Mailgun
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+// NewWebhookChecker creates a checker for the webhooks of the domain of mg.
+func NewWebhookChecker(mg Mailgun) *WebhookChecker {
+	return &WebhookChecker{
+		mg:      mg,
+		failing: make(map[string]bool),
+	}
+}
+
+// Run checks the webhooks every Interval until the context is cancelled.
+func (wc *WebhookChecker) Run(ctx context.Context) error {
+	interval := wc.Interval
+	if interval == 0 {
+		interval = DefaultWebhookCheckInterval
+	}
+	for {
+		if _, err := wc.Check(ctx); err != nil && wc.OnError != nil && ctx.Err() == nil {
+			wc.OnError(err)
+		}
+
+		tick := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			tick.Stop()
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// Check checks every webhook of the domain once, calling OnFailure and OnRecovery for urls
+// whose state changed since the previous check. Returns the result for each webhook url.
+func (wc *WebhookChecker) Check(ctx context.Context) ([]WebhookCheck, error) {
+	hooks, err := wc.mg.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make([]WebhookKind, 0, len(hooks))
+	for kind := range hooks {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	var results []WebhookCheck
+	for _, kind := range kinds {
+		for _, url := range hooks[kind].URLs {
+			check := WebhookCheck{Kind: kind, URL: url, Time: time.Now()}
+			if wc.UseTestAPI {
+				var result WebhookTestResult
+				result, check.Err = wc.mg.TestWebhook(ctx, kind, url)
+				check.Code = result.Code
+			} else {
+				check.Code, check.Err = wc.post(ctx, kind, check.URL)
+			}
+			wc.report(check)
+			results = append(results, check)
+		}
+	}
+	return results, nil
+}
+
+func (wc *WebhookChecker) report(check WebhookCheck) {
+	key := check.Kind.String() + " " + check.URL
+	wc.mu.Lock()
+	wasFailing := wc.failing[key]
+	wc.failing[key] = check.Err != nil
+	wc.mu.Unlock()
+
+	if check.Err != nil && !wasFailing && wc.OnFailure != nil {
+		wc.OnFailure(check)
+	}
+	if check.Err == nil && wasFailing && wc.OnRecovery != nil {
+		wc.OnRecovery(check)
+	}
+}
+
+// post sends a signed synthetic event for the webhook kind to url
+func (wc *WebhookChecker) post(ctx context.Context, kind WebhookKind, url string) (int, error) {
+	timeout := wc.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := newWebhookCheckPayload(webhookSigningKey(wc.mg), kind.String())
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wc.mg.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook '%s' responded with code %d: %s", kind, resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+// newWebhookCheckPayload renders a webhook request body for a synthetic event, signed with key
+func newWebhookCheckPayload(key, kind string) ([]byte, error) {
+	token := make([]byte, 25)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sig := Signature{
+		TimeStamp: strconv.FormatInt(now.Unix(), 10),
+		Token:     hex.EncodeToString(token),
+	}
+	sig.Signature = webhookSignature(key, sig.TimeStamp, sig.Token)
+
+	event := map[string]interface{}{
+		"id":             sig.Token[:22],
+		"timestamp":      TimeToFloat(now),
+		"event":          webhookEventName(kind),
+		"user-variables": map[string]string{WebhookCheckVariable: "true"},
+	}
+	switch kind {
+	case "permanent_fail":
+		event["severity"] = "permanent"
+	case "temporary_fail":
+		event["severity"] = "temporary"
+	}
+	return json.Marshal(map[string]interface{}{
+		"signature":  sig,
+		"event-data": event,
+	})
+}
+
+// webhookEventName returns the name of the event sent to webhooks of the given kind
+func webhookEventName(kind string) string {
+	if kind == "permanent_fail" || kind == "temporary_fail" {
+		return "failed"
+	}
+	return kind
+}
+
+// webhookSignature computes the signature Mailgun sends with webhook requests
+func webhookSignature(key, timestamp, token string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	io.WriteString(h, timestamp)
+	io.WriteString(h, token)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// webhookSigningKey returns the key webhook requests to mg are signed with
+func webhookSigningKey(mg Mailgun) string {
+	if impl, ok := mg.(*MailgunImpl); ok {
+		return impl.webhookSigningKey()
+	}
+	return mg.APIKey()
+}
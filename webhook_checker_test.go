@@ -0,0 +1,105 @@
+package mailgun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+func TestWebhookChecker(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	// The receiver verifies the signature and fails while broken is set
+	var broken bool
+	var received []map[string]interface{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			Signature Signature              `json:"signature"`
+			EventData map[string]interface{} `json:"event-data"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		verified, err := mg.VerifyWebhookSignature(payload.Signature)
+		if err != nil || !verified || broken {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		received = append(received, payload.EventData)
+	}))
+	defer receiver.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.URL.Path, fmt.Sprintf("/domains/%s/webhooks", exampleDomain))
+		fmt.Fprintf(w, `{"webhooks": {"permanent_fail": {"url": "%s"}}}`, receiver.URL)
+	}))
+	defer api.Close()
+	mg.SetAPIBase(api.URL)
+	ctx := context.Background()
+
+	var failures, recoveries []WebhookCheck
+	checker := NewWebhookChecker(mg)
+	checker.OnFailure = func(c WebhookCheck) { failures = append(failures, c) }
+	checker.OnRecovery = func(c WebhookCheck) { recoveries = append(recoveries, c) }
+
+	results, err := checker.Check(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(results), 1)
+	ensure.Nil(t, results[0].Err)
+	ensure.DeepEqual(t, results[0].Kind, WebhookPermanentFail)
+	ensure.DeepEqual(t, results[0].URL, receiver.URL)
+	ensure.DeepEqual(t, len(received), 1)
+	ensure.DeepEqual(t, received[0]["event"], "failed")
+	ensure.DeepEqual(t, received[0]["severity"], "permanent")
+	ensure.DeepEqual(t, received[0]["user-variables"], map[string]interface{}{WebhookCheckVariable: "true"})
+
+	// Failures are reported once, until the url recovers
+	broken = true
+	for i := 0; i < 2; i++ {
+		results, err = checker.Check(ctx)
+		ensure.Nil(t, err)
+		ensure.NotNil(t, results[0].Err)
+		ensure.DeepEqual(t, results[0].Code, http.StatusNotAcceptable)
+	}
+	ensure.DeepEqual(t, len(failures), 1)
+	ensure.DeepEqual(t, len(recoveries), 0)
+
+	broken = false
+	_, err = checker.Check(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(failures), 1)
+	ensure.DeepEqual(t, len(recoveries), 1)
+}
+
+func TestWebhookCheckPayloadParses(t *testing.T) {
+	for _, kind := range webhookKinds {
+		payload, err := newWebhookCheckPayload(exampleAPIKey, string(kind))
+		ensure.Nil(t, err)
+
+		var p WebhookPayload
+		ensure.Nil(t, json.Unmarshal(payload, &p))
+		e, err := ParseEvent(p.EventData)
+		ensure.Nil(t, err, kind)
+		ensure.DeepEqual(t, e.GetName(), webhookEventName(string(kind)))
+
+		var vars map[string]string
+		switch e := e.(type) {
+		case *events.Opened:
+			vars = e.UserVariables
+		case *events.Clicked:
+			vars = e.UserVariables
+		case *events.Unsubscribed:
+			vars = e.UserVariables
+		default:
+			continue
+		}
+		ensure.DeepEqual(t, vars[WebhookCheckVariable], "true")
+	}
+}
@@ -0,0 +1,157 @@
+package mailgun
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// WebhookDispatcher verifies the requests Mailgun sends to webhooks like WebhookHandler(), and
+// calls the callback registered for their event.
+//
+//	d := mailgun.NewWebhookDispatcher(mg)
+//	d.OnDelivered(func(ctx context.Context, e *events.Delivered) error {
+//		return db.MarkDelivered(ctx, e.Message.Headers.MessageID)
+//	})
+//	d.OnPermanentFail(func(ctx context.Context, e *events.Failed) error {
+//		return db.Suppress(ctx, e.Recipient)
+//	})
+//	http.Handle("/webhooks", d)
+//
+// Requests are answered with 200 OK once their callback returns, or 500 Internal Server Error
+// if it returns an error, so Mailgun retries them later. Events without a callback are answered
+// with 200 OK. Register the callbacks before serving requests.
+type WebhookDispatcher struct {
+	// Verifies the requests, its MaxAge may be changed
+	WebhookMiddleware
+
+	handlers map[string]func(ctx context.Context, e *WebhookEvent) error
+	other    func(ctx context.Context, e *WebhookEvent) error
+}
+
+// NewWebhookDispatcher creates a dispatcher which verifies requests with the signing key of mg.
+func NewWebhookDispatcher(mg Mailgun) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		WebhookMiddleware: *WebhookHandler(mg, nil),
+		handlers:          make(map[string]func(ctx context.Context, e *WebhookEvent) error),
+	}
+}
+
+// OnAccepted calls f for each accepted event
+func (d *WebhookDispatcher) OnAccepted(f func(ctx context.Context, e *events.Accepted) error) {
+	d.handle(events.EventAccepted, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Accepted))
+	})
+}
+
+// OnRejected calls f for each rejected event
+func (d *WebhookDispatcher) OnRejected(f func(ctx context.Context, e *events.Rejected) error) {
+	d.handle(events.EventRejected, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Rejected))
+	})
+}
+
+// OnDelivered calls f for each delivered event
+func (d *WebhookDispatcher) OnDelivered(f func(ctx context.Context, e *events.Delivered) error) {
+	d.handle(events.EventDelivered, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Delivered))
+	})
+}
+
+// OnPermanentFail calls f for each failed event with a permanent severity
+func (d *WebhookDispatcher) OnPermanentFail(f func(ctx context.Context, e *events.Failed) error) {
+	d.handle(failedHandler(events.SeverityPermanent), func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Failed))
+	})
+}
+
+// OnTemporaryFail calls f for each failed event with a temporary severity, Mailgun retries
+// delivery of the message later
+func (d *WebhookDispatcher) OnTemporaryFail(f func(ctx context.Context, e *events.Failed) error) {
+	d.handle(failedHandler(events.SeverityTemporary), func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Failed))
+	})
+}
+
+// OnStored calls f for each stored event
+func (d *WebhookDispatcher) OnStored(f func(ctx context.Context, e *events.Stored) error) {
+	d.handle(events.EventStored, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Stored))
+	})
+}
+
+// OnOpened calls f for each opened event
+func (d *WebhookDispatcher) OnOpened(f func(ctx context.Context, e *events.Opened) error) {
+	d.handle(events.EventOpened, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Opened))
+	})
+}
+
+// OnClicked calls f for each clicked event
+func (d *WebhookDispatcher) OnClicked(f func(ctx context.Context, e *events.Clicked) error) {
+	d.handle(events.EventClicked, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Clicked))
+	})
+}
+
+// OnUnsubscribed calls f for each unsubscribed event
+func (d *WebhookDispatcher) OnUnsubscribed(f func(ctx context.Context, e *events.Unsubscribed) error) {
+	d.handle(events.EventUnsubscribed, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Unsubscribed))
+	})
+}
+
+// OnComplained calls f for each complained event
+func (d *WebhookDispatcher) OnComplained(f func(ctx context.Context, e *events.Complained) error) {
+	d.handle(events.EventComplained, func(ctx context.Context, e *WebhookEvent) error {
+		return f(ctx, e.Event.(*events.Complained))
+	})
+}
+
+// OnOther calls f for the events without a callback, including the events the codec couldn't
+// decode, whose Event is nil
+func (d *WebhookDispatcher) OnOther(f func(ctx context.Context, e *WebhookEvent) error) {
+	d.other = f
+}
+
+func (d *WebhookDispatcher) handle(name string, f func(ctx context.Context, e *WebhookEvent) error) {
+	d.handlers[name] = f
+}
+
+func (d *WebhookDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e, ok := d.verify(w, r)
+	if !ok {
+		return
+	}
+	handler, ok := d.handlers[webhookHandlerName(e.Event)]
+	if !ok {
+		handler = d.other
+	}
+	if handler != nil {
+		ctx := context.WithValue(r.Context(), webhookEventKey{}, e)
+		if err := handler(ctx, e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// failedHandler returns the name failed events of the given severity are dispatched by
+func failedHandler(severity events.Severity) string {
+	return events.EventFailed + ":" + string(severity)
+}
+
+// webhookHandlerName returns the name an event is dispatched by, "" for the events decoded into
+// other structs than the events package types, which only OnOther() receives
+func webhookHandlerName(event Event) string {
+	switch e := event.(type) {
+	case *events.Failed:
+		return failedHandler(e.Severity)
+	case *events.Accepted, *events.Rejected, *events.Delivered, *events.Stored, *events.Opened,
+		*events.Clicked, *events.Unsubscribed, *events.Complained:
+		return event.GetName()
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	d := NewWebhookDispatcher(mg)
+	var dispatched []string
+	d.OnDelivered(func(ctx context.Context, e *events.Delivered) error {
+		_, ok := WebhookEventFromContext(ctx)
+		ensure.True(t, ok)
+		dispatched = append(dispatched, "delivered")
+		return nil
+	})
+	d.OnPermanentFail(func(ctx context.Context, e *events.Failed) error {
+		ensure.DeepEqual(t, e.Severity, events.SeverityPermanent)
+		dispatched = append(dispatched, "permanent_fail")
+		return nil
+	})
+	d.OnComplained(func(ctx context.Context, e *events.Complained) error {
+		return errors.New("database is down")
+	})
+	serve := func(kind, key string) int {
+		payload, err := newWebhookCheckPayload(key, kind)
+		ensure.Nil(t, err)
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(payload)))
+		return w.Code
+	}
+
+	ensure.DeepEqual(t, serve("delivered", exampleAPIKey), http.StatusOK)
+	ensure.DeepEqual(t, serve("permanent_fail", exampleAPIKey), http.StatusOK)
+	// Events without a callback are acknowledged
+	ensure.DeepEqual(t, serve("temporary_fail", exampleAPIKey), http.StatusOK)
+	ensure.DeepEqual(t, serve("accepted", exampleAPIKey), http.StatusOK)
+	ensure.DeepEqual(t, dispatched, []string{"delivered", "permanent_fail"})
+
+	// Failed callbacks are retried by Mailgun
+	ensure.DeepEqual(t, serve("complained", exampleAPIKey), http.StatusInternalServerError)
+	// Forged requests are rejected before dispatching
+	ensure.DeepEqual(t, serve("delivered", "another-key"), http.StatusNotAcceptable)
+	ensure.DeepEqual(t, len(dispatched), 2)
+
+	var other []*WebhookEvent
+	d.OnOther(func(ctx context.Context, e *WebhookEvent) error {
+		other = append(other, e)
+		return nil
+	})
+	ensure.DeepEqual(t, serve("accepted", exampleAPIKey), http.StatusOK)
+	ensure.DeepEqual(t, serve("escalated", exampleAPIKey), http.StatusOK)
+	ensure.DeepEqual(t, len(other), 2)
+	_, ok := other[0].Event.(*events.Accepted)
+	ensure.True(t, ok)
+	ensure.Nil(t, other[1].Event)
+}
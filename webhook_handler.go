@@ -0,0 +1,119 @@
+package mailgun
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mailgun/mailgun-go/events"
+)
+
+// DefaultWebhookMaxAge is how old the timestamp of a webhook request accepted by
+// WebhookHandler() may be
+const DefaultWebhookMaxAge = 5 * time.Minute
+
+// maxWebhookBody is the largest webhook request body WebhookHandler() reads
+const maxWebhookBody = 1 << 20
+
+// WebhookEvent is a webhook request verified by WebhookHandler()
+type WebhookEvent struct {
+	Signature Signature
+	// The event decoded with DefaultEventCodec, nil if the codec couldn't decode it
+	Event Event
+	// The JSON of the event
+	Raw events.RawJSON
+}
+
+type webhookEventKey struct{}
+
+// WebhookEventFromContext returns the event of a request passed on by WebhookHandler().
+func WebhookEventFromContext(ctx context.Context) (*WebhookEvent, bool) {
+	e, ok := ctx.Value(webhookEventKey{}).(*WebhookEvent)
+	return e, ok
+}
+
+// WebhookMiddleware verifies webhook requests before passing them to the next handler, see
+// WebhookHandler().
+type WebhookMiddleware struct {
+	// How old the timestamp of a request may be, defaults to DefaultWebhookMaxAge
+	MaxAge time.Duration
+
+	mg   Mailgun
+	next http.Handler
+	now  func() time.Time
+}
+
+// WebhookHandler verifies the JSON requests Mailgun sends to webhooks, and passes the verified
+// requests to next with their event in the context of the request. Requests are rejected if
+// their signature is invalid, their timestamp is older than MaxAge, or their sender is not in
+// the WebhookIPAllowlist of mg, if any.
+//
+//	http.Handle("/webhooks", mailgun.WebhookHandler(mg, http.HandlerFunc(
+//		func(w http.ResponseWriter, r *http.Request) {
+//			e, _ := mailgun.WebhookEventFromContext(r.Context())
+//			switch event := e.Event.(type) {
+//			case *events.Delivered:
+//				...
+//			}
+//		})))
+//
+// Rejected requests are answered with 406 Not Acceptable, which stops Mailgun from retrying
+// them, and requests which can't be decoded with 400 Bad Request.
+func WebhookHandler(mg Mailgun, next http.Handler) *WebhookMiddleware {
+	return &WebhookMiddleware{mg: mg, next: next, now: time.Now}
+}
+
+func (m *WebhookMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if e, ok := m.verify(w, r); ok {
+		m.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webhookEventKey{}, e)))
+	}
+}
+
+// verify returns the event of a verified request, or answers the request and returns false
+func (m *WebhookMiddleware) verify(w http.ResponseWriter, r *http.Request) (*WebhookEvent, bool) {
+	if impl, ok := m.mg.(*MailgunImpl); ok && impl.webhookIPs != nil && !impl.webhookIPs.AllowedRequest(r) {
+		http.Error(w, ErrWebhookIPNotAllowed.Error(), http.StatusNotAcceptable)
+		return nil, false
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "while reading webhook request: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "while decoding webhook request: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	sig := payload.Signature
+	expected := webhookSignature(webhookSigningKey(m.mg), sig.TimeStamp, sig.Token)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(sig.Signature)) != 1 {
+		http.Error(w, "invalid webhook signature", http.StatusNotAcceptable)
+		return nil, false
+	}
+	timestamp, err := strconv.ParseInt(sig.TimeStamp, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid webhook timestamp", http.StatusNotAcceptable)
+		return nil, false
+	}
+	maxAge := m.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultWebhookMaxAge
+	}
+	if age := m.now().Sub(time.Unix(timestamp, 0)); age > maxAge || age < -maxAge {
+		http.Error(w, "stale webhook timestamp", http.StatusNotAcceptable)
+		return nil, false
+	}
+
+	// Events the codec doesn't know, such as new event types, are passed on undecoded
+	e := &WebhookEvent{Signature: sig, Raw: payload.EventData}
+	e.Event, _ = ParseEvent(payload.EventData)
+	return e, true
+}
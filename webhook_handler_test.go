@@ -0,0 +1,53 @@
+package mailgun
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	var received []*WebhookEvent
+	handler := WebhookHandler(mg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, ok := WebhookEventFromContext(r.Context())
+		ensure.True(t, ok)
+		received = append(received, e)
+	}))
+	serve := func(body []byte) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body)))
+		return w.Code
+	}
+
+	payload, err := newWebhookCheckPayload(exampleAPIKey, "delivered")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, serve(payload), http.StatusOK)
+	ensure.DeepEqual(t, len(received), 1)
+	_, ok := received[0].Event.(*events.Delivered)
+	ensure.True(t, ok)
+	ensure.True(t, len(received[0].Raw) != 0)
+
+	// Requests signed with another key, stale requests and invalid bodies are rejected
+	forged, err := newWebhookCheckPayload("another-key", "delivered")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, serve(forged), http.StatusNotAcceptable)
+	handler.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	ensure.DeepEqual(t, serve(payload), http.StatusNotAcceptable)
+	handler.MaxAge = time.Hour
+	ensure.DeepEqual(t, serve(payload), http.StatusOK)
+	ensure.DeepEqual(t, serve([]byte("not json")), http.StatusBadRequest)
+	ensure.DeepEqual(t, len(received), 2)
+
+	// Events the codec doesn't know are passed on undecoded
+	payload, err = newWebhookCheckPayload(exampleAPIKey, "escalated")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, serve(payload), http.StatusOK)
+	ensure.Nil(t, received[2].Event)
+	ensure.StringContains(t, string(received[2].Raw), `"escalated"`)
+}
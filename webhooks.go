@@ -6,41 +6,112 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sync"
 
 	"github.com/mailgun/mailgun-go/events"
 )
 
-// ListWebhooks returns the complete set of webhooks configured for your domain.
+// WebhookKind is the kind of events a webhook receives
+type WebhookKind string
+
+const (
+	WebhookDelivered     WebhookKind = "delivered"
+	WebhookOpened        WebhookKind = "opened"
+	WebhookClicked       WebhookKind = "clicked"
+	WebhookComplained    WebhookKind = "complained"
+	WebhookUnsubscribed  WebhookKind = "unsubscribed"
+	WebhookPermanentFail WebhookKind = "permanent_fail"
+	WebhookTemporaryFail WebhookKind = "temporary_fail"
+)
+
+var webhookKinds = []WebhookKind{
+	WebhookDelivered, WebhookOpened, WebhookClicked, WebhookComplained, WebhookUnsubscribed,
+	WebhookPermanentFail, WebhookTemporaryFail,
+}
+
+// IsValid returns true if k is one of the WebhookKind constants
+func (k WebhookKind) IsValid() bool {
+	for _, v := range webhookKinds {
+		if k == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (k WebhookKind) String() string {
+	return string(k)
+}
+
+// validateWebhookKind rejects unknown kinds, which the API answers with 404 Not Found
+func validateWebhookKind(kind WebhookKind) error {
+	if !kind.IsValid() {
+		return invalidOption("kind", fmt.Sprintf("unknown webhook kind '%s'", kind))
+	}
+	return nil
+}
+
+// MaxWebhookURLs is the number of urls Mailgun accepts per webhook kind
+const MaxWebhookURLs = 3
+
+// Webhook is the configuration of a webhook kind, such as "delivered"
+type Webhook struct {
+	// The urls Mailgun posts the events of the kind to
+	URLs []string `json:"urls"`
+}
+
+// UnmarshalJSON also decodes the single url of webhooks returned by older versions of the API
+func (w *Webhook) UnmarshalJSON(b []byte) error {
+	var v struct {
+		URL  string   `json:"url"`
+		URLs []string `json:"urls"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	w.URLs = v.URLs
+	if len(w.URLs) == 0 && v.URL != "" {
+		w.URLs = []string{v.URL}
+	}
+	return nil
+}
+
+// ListWebhooks returns the complete set of webhooks configured for your domain, keyed by kind.
 // Note that a zero-length mapping is not an error.
-func (mg *MailgunImpl) ListWebhooks(ctx context.Context) (map[string]string, error) {
+func (mg *MailgunImpl) ListWebhooks(ctx context.Context) (map[WebhookKind]Webhook, error) {
 	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var envelope struct {
-		Webhooks map[string]interface{} `json:"webhooks"`
+		Webhooks map[WebhookKind]Webhook `json:"webhooks"`
 	}
-	err := getResponseFromJSON(ctx, r, &envelope)
-	hooks := make(map[string]string, 0)
-	if err != nil {
-		return hooks, err
+	if err := getResponseFromJSON(ctx, r, &envelope); err != nil {
+		return make(map[WebhookKind]Webhook), err
 	}
-	for k, v := range envelope.Webhooks {
-		object := v.(map[string]interface{})
-		url := object["url"]
-		hooks[k] = url.(string)
+	if envelope.Webhooks == nil {
+		envelope.Webhooks = make(map[WebhookKind]Webhook)
 	}
-	return hooks, nil
+	return envelope.Webhooks, nil
 }
 
-// CreateWebhook installs a new webhook for your domain.
-func (mg *MailgunImpl) CreateWebhook(ctx context.Context, t string, urls []string) error {
+// CreateWebhook installs a new webhook for your domain, posting to up to MaxWebhookURLs urls.
+func (mg *MailgunImpl) CreateWebhook(ctx context.Context, kind WebhookKind, urls []string) error {
+	if err := validateWebhookKind(kind); err != nil {
+		return err
+	}
+	if err := validateWebhookURLs(urls); err != nil {
+		return err
+	}
 	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint))
-	r.setClient(mg.Client())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
-	p.addValue("id", t)
+	p.addValue("id", kind.String())
 	for _, url := range urls {
 		p.addValue("url", url)
 	}
@@ -49,32 +120,42 @@ func (mg *MailgunImpl) CreateWebhook(ctx context.Context, t string, urls []strin
 }
 
 // DeleteWebhook removes the specified webhook from your domain's configuration.
-func (mg *MailgunImpl) DeleteWebhook(ctx context.Context, t string) error {
-	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + t)
-	r.setClient(mg.Client())
+func (mg *MailgunImpl) DeleteWebhook(ctx context.Context, kind WebhookKind) error {
+	if err := validateWebhookKind(kind); err != nil {
+		return err
+	}
+	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + kind.String())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	_, err := makeDeleteRequest(ctx, r)
 	return err
 }
 
-// GetWebhook retrieves the currently assigned webhook URL associated with the provided type of webhook.
-func (mg *MailgunImpl) GetWebhook(ctx context.Context, t string) (string, error) {
-	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + t)
-	r.setClient(mg.Client())
+// GetWebhook retrieves the urls currently assigned to the provided type of webhook.
+func (mg *MailgunImpl) GetWebhook(ctx context.Context, kind WebhookKind) (Webhook, error) {
+	if err := validateWebhookKind(kind); err != nil {
+		return Webhook{}, err
+	}
+	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + kind.String())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	var envelope struct {
-		Webhook struct {
-			Url string `json:"url"`
-		} `json:"webhook"`
+		Webhook Webhook `json:"webhook"`
 	}
 	err := getResponseFromJSON(ctx, r, &envelope)
-	return envelope.Webhook.Url, err
+	return envelope.Webhook, err
 }
 
-// UpdateWebhook replaces one webhook setting for another.
-func (mg *MailgunImpl) UpdateWebhook(ctx context.Context, t string, urls []string) error {
-	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + t)
-	r.setClient(mg.Client())
+// UpdateWebhook replaces the urls of a webhook, up to MaxWebhookURLs urls.
+func (mg *MailgunImpl) UpdateWebhook(ctx context.Context, kind WebhookKind, urls []string) error {
+	if err := validateWebhookKind(kind); err != nil {
+		return err
+	}
+	if err := validateWebhookURLs(urls); err != nil {
+		return err
+	}
+	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + kind.String())
+	r.setClient(mg)
 	r.setBasicAuth(basicAuthUser, mg.APIKey())
 	p := newUrlEncodedPayload()
 	for _, url := range urls {
@@ -84,6 +165,105 @@ func (mg *MailgunImpl) UpdateWebhook(ctx context.Context, t string, urls []strin
 	return err
 }
 
+func validateWebhookURLs(urls []string) error {
+	if len(urls) == 0 {
+		return invalidOption("urls", "at least one url is required")
+	}
+	if len(urls) > MaxWebhookURLs {
+		return invalidOption("urls", fmt.Sprintf("at most %d urls are allowed per webhook", MaxWebhookURLs))
+	}
+	return nil
+}
+
+// Represents the result of firing a test payload at a webhook
+type WebhookTestResult struct {
+	// The HTTP status code returned by the webhook url
+	Code int `json:"code"`
+	// The response body or error message returned by the webhook url
+	Message string `json:"message"`
+}
+
+// TestWebhook asks mailgun to send a test payload for the webhook kind to url, or to the url
+// configured for the kind if url is empty, e.g. to check connectivity during deploys. Returns an
+// error if the webhook url did not respond with a 2xx status code.
+func (mg *MailgunImpl) TestWebhook(ctx context.Context, kind WebhookKind, url string) (WebhookTestResult, error) {
+	if err := validateWebhookKind(kind); err != nil {
+		return WebhookTestResult{}, err
+	}
+	r := newHTTPRequest(generateDomainApiUrl(mg, webhooksEndpoint) + "/" + kind.String() + "/test")
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	p := newUrlEncodedPayload()
+	if url != "" {
+		p.addValue("url", url)
+	}
+
+	var result WebhookTestResult
+	if err := putResponseFromJSON(ctx, r, p, &result); err != nil {
+		return result, err
+	}
+	if result.Code < 200 || result.Code > 299 {
+		return result, fmt.Errorf("webhook '%s' responded with code %d: %s", kind, result.Code, result.Message)
+	}
+	return result, nil
+}
+
+// signingKeyCache holds the webhook signing key of the account, shared by the copies of a client
+type signingKeyCache struct {
+	mu  sync.Mutex
+	key string
+}
+
+type signingKeyResponse struct {
+	Message string `json:"message"`
+	Key     string `json:"http_signing_key"`
+}
+
+// GetWebhookSigningKey returns the HTTP webhook signing key of the account, which Mailgun signs
+// webhook requests with. The key is fetched once and cached on the client, VerifyWebhookSignature()
+// and VerifyWebhookRequest() then verify signatures with it instead of the API key. Call it at
+// startup to configure webhook verification from the API key alone.
+func (mg *MailgunImpl) GetWebhookSigningKey(ctx context.Context) (string, error) {
+	mg.signingKey.mu.Lock()
+	defer mg.signingKey.mu.Unlock()
+	if mg.signingKey.key != "" {
+		return mg.signingKey.key, nil
+	}
+
+	r := newHTTPRequest(generateV5ApiUrl(mg, signingKeyEndpoint))
+	r.setClient(mg)
+	r.setBasicAuth(basicAuthUser, mg.APIKey())
+	var resp signingKeyResponse
+	if err := getResponseFromJSON(ctx, r, &resp); err != nil {
+		return "", err
+	}
+	if resp.Key == "" {
+		return "", fmt.Errorf("no webhook signing key in response: %s", resp.Message)
+	}
+	mg.signingKey.key = resp.Key
+	return resp.Key, nil
+}
+
+// SetWebhookSigningKey sets the HTTP webhook signing key of the account, which Mailgun signs
+// webhook requests with, so they are verified without fetching it with GetWebhookSigningKey().
+// The key is found on the webhooks page of the Mailgun control panel.
+func (mg *MailgunImpl) SetWebhookSigningKey(key string) {
+	mg.signingKey.mu.Lock()
+	defer mg.signingKey.mu.Unlock()
+	mg.signingKey.key = key
+}
+
+// webhookSigningKey returns the key webhook requests are signed with, the signing key of the
+// account if it was set or fetched, otherwise the API key
+func (mg *MailgunImpl) webhookSigningKey() string {
+	mg.signingKey.mu.Lock()
+	defer mg.signingKey.mu.Unlock()
+	if mg.signingKey.key != "" {
+		return mg.signingKey.key
+	}
+	return mg.APIKey()
+}
+
 // Represents the signature portion of the webhook POST body
 type Signature struct {
 	TimeStamp string `json:"timestamp"`
@@ -99,7 +279,16 @@ type WebhookPayload struct {
 
 // Use this method to parse the webhook signature given as JSON in the webhook response
 func (mg *MailgunImpl) VerifyWebhookSignature(sig Signature) (verified bool, err error) {
-	h := hmac.New(sha256.New, []byte(mg.APIKey()))
+	return VerifySignature(mg.webhookSigningKey(), sig)
+}
+
+// VerifySignature verifies the signature of a webhook request with the HTTP webhook signing key
+// of the account, for services which handle webhooks without making API calls and so have no
+// use for a client.
+//
+//	verified, err := mailgun.VerifySignature(os.Getenv("MG_WEBHOOK_SIGNING_KEY"), payload.Signature)
+func VerifySignature(signingKey string, sig Signature) (verified bool, err error) {
+	h := hmac.New(sha256.New, []byte(signingKey))
 	io.WriteString(h, sig.TimeStamp)
 	io.WriteString(h, sig.Token)
 
@@ -115,10 +304,50 @@ func (mg *MailgunImpl) VerifyWebhookSignature(sig Signature) (verified bool, err
 	return subtle.ConstantTimeCompare(signature, calculatedSignature) == 1, nil
 }
 
+// ErrInvalidWebhookSignature is returned by ParseWebhookPayload() when the signature of the
+// payload doesn't match the webhook signing key.
+var ErrInvalidWebhookSignature = errors.New("webhook payload has an invalid signature")
+
+// ParseWebhookPayload verifies the signature embedded in the JSON body Mailgun posts to
+// webhooks, and decodes its event-data into the events package type of the event.
+//
+//	body, err := ioutil.ReadAll(r.Body)
+//	...
+//	event, err := mg.ParseWebhookPayload(body)
+//	if err == mailgun.ErrInvalidWebhookSignature {
+//		w.WriteHeader(http.StatusNotAcceptable)
+//		return
+//	}
+//	switch e := event.(type) {
+//	case *events.Delivered:
+//		...
+//	}
+func (mg *MailgunImpl) ParseWebhookPayload(raw []byte) (Event, error) {
+	var payload WebhookPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("while decoding webhook payload: %s", err)
+	}
+	if len(payload.EventData) == 0 {
+		return nil, errors.New("webhook payload is missing 'event-data'")
+	}
+	verified, err := mg.VerifyWebhookSignature(payload.Signature)
+	if err != nil || !verified {
+		return nil, ErrInvalidWebhookSignature
+	}
+	return ParseEvent(payload.EventData)
+}
+
 // Deprecated: Please use the VerifyWebhookSignature() to parse the latest
 // version of WebHooks from mailgun
+//
+// If a WebhookIPAllowlist was installed with SetWebhookIPAllowlist(), requests from
+// addresses outside the allowlist are rejected with ErrWebhookIPNotAllowed.
 func (mg *MailgunImpl) VerifyWebhookRequest(req *http.Request) (verified bool, err error) {
-	h := hmac.New(sha256.New, []byte(mg.APIKey()))
+	if mg.webhookIPs != nil && !mg.webhookIPs.AllowedRequest(req) {
+		return false, ErrWebhookIPNotAllowed
+	}
+
+	h := hmac.New(sha256.New, []byte(mg.webhookSigningKey()))
 	io.WriteString(h, req.FormValue("timestamp"))
 	io.WriteString(h, req.FormValue("token"))
 
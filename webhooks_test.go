@@ -6,14 +6,20 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/facebookgo/ensure"
+	"github.com/mailgun/mailgun-go/events"
 )
 
 func TestWebhookCRUD(t *testing.T) {
@@ -34,9 +40,9 @@ func TestWebhookCRUD(t *testing.T) {
 	hookCount := countHooks()
 
 	domainURL := "http://api.mailgun.net"
-	ensure.Nil(t, mg.CreateWebhook(ctx, "deliver", []string{domainURL}))
+	ensure.Nil(t, mg.CreateWebhook(ctx, WebhookDelivered, []string{domainURL}))
 	defer func() {
-		ensure.Nil(t, mg.DeleteWebhook(ctx, "deliver"))
+		ensure.Nil(t, mg.DeleteWebhook(ctx, WebhookDelivered))
 		newCount := countHooks()
 		ensure.DeepEqual(t, newCount, hookCount)
 	}()
@@ -44,17 +50,50 @@ func TestWebhookCRUD(t *testing.T) {
 	newCount := countHooks()
 	ensure.False(t, newCount <= hookCount)
 
-	theURL, err := mg.GetWebhook(ctx, "deliver")
+	hook, err := mg.GetWebhook(ctx, WebhookDelivered)
 	ensure.Nil(t, err)
-	ensure.DeepEqual(t, theURL, domainURL)
+	ensure.DeepEqual(t, hook.URLs, []string{domainURL})
 
 	updatedDomainURL := "http://api.mailgun.net/messages"
-	ensure.Nil(t, mg.UpdateWebhook(ctx, "deliver", []string{updatedDomainURL}))
+	ensure.Nil(t, mg.UpdateWebhook(ctx, WebhookDelivered, []string{updatedDomainURL}))
 
 	hooks, err := mg.ListWebhooks(ctx)
 	ensure.Nil(t, err)
 
-	ensure.DeepEqual(t, hooks["deliver"], updatedDomainURL)
+	ensure.DeepEqual(t, hooks[WebhookDelivered].URLs, []string{updatedDomainURL})
+}
+
+func TestTestWebhook(t *testing.T) {
+	var code int
+	var testedURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ensure.DeepEqual(t, req.Method, http.MethodPut)
+		ensure.DeepEqual(t, req.URL.Path, fmt.Sprintf("/domains/%s/webhooks/clicked/test", exampleDomain))
+		ensure.DeepEqual(t, req.FormValue("url"), testedURL)
+		fmt.Fprintf(w, `{"code": %d, "message": "test message"}`, code)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL)
+	ctx := context.Background()
+
+	code = http.StatusOK
+	result, err := mg.TestWebhook(ctx, "clicked", "")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, result.Code, http.StatusOK)
+	ensure.DeepEqual(t, result.Message, "test message")
+
+	code = http.StatusForbidden
+	result, err = mg.TestWebhook(ctx, "clicked", "")
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, result.Code, http.StatusForbidden)
+
+	// A url other than the configured urls can be tested
+	code = http.StatusOK
+	testedURL = "https://staging.example.com/clicked"
+	_, err = mg.TestWebhook(ctx, "clicked", testedURL)
+	ensure.Nil(t, err)
 }
 
 var signedTests = []bool{
@@ -82,6 +121,46 @@ func TestVerifyWebhookSignature(t *testing.T) {
 	}
 }
 
+func TestVerifySignature(t *testing.T) {
+	for _, v := range signedTests {
+		fields := getSignatureFields(exampleAPIKey, v)
+		sig := Signature{
+			TimeStamp: fields["timestamp"],
+			Token:     fields["token"],
+			Signature: fields["signature"],
+		}
+
+		verified, err := VerifySignature(exampleAPIKey, sig)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, verified, v)
+	}
+
+	_, err := VerifySignature(exampleAPIKey, Signature{TimeStamp: "1", Token: "2", Signature: "not hex"})
+	ensure.NotNil(t, err)
+}
+
+func TestParseWebhookPayload(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+
+	payload, err := newWebhookCheckPayload(exampleAPIKey, "permanent_fail")
+	ensure.Nil(t, err)
+	event, err := mg.ParseWebhookPayload(payload)
+	ensure.Nil(t, err)
+	failed, ok := event.(*events.Failed)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, failed.Severity, events.SeverityPermanent)
+
+	forged, err := newWebhookCheckPayload("another-key", "delivered")
+	ensure.Nil(t, err)
+	_, err = mg.ParseWebhookPayload(forged)
+	ensure.DeepEqual(t, err, ErrInvalidWebhookSignature)
+
+	_, err = mg.ParseWebhookPayload([]byte(`{"signature": {}}`))
+	ensure.StringContains(t, err.Error(), "missing 'event-data'")
+	_, err = mg.ParseWebhookPayload([]byte("timestamp=1&token=2"))
+	ensure.StringContains(t, err.Error(), "while decoding webhook payload")
+}
+
 func TestVerifyWebhookRequest_Form(t *testing.T) {
 	mg := NewMailgun(exampleDomain, exampleAPIKey)
 
@@ -164,3 +243,177 @@ func getSignatureFields(key string, signed bool) map[string]string {
 
 	return fields
 }
+
+func TestVerifyWebhookRequest_IPAllowlist(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	allowlist, err := NewWebhookIPAllowlist("198.51.100.0/24", "2001:db8::1")
+	ensure.Nil(t, err)
+	mg.SetWebhookIPAllowlist(allowlist)
+
+	fields := getSignatureFields(mg.APIKey(), true)
+	for addr, allowed := range map[string]bool{
+		"198.51.100.23:4321": true,
+		"[2001:db8::1]:4321": true,
+		"203.0.113.7:4321":   false,
+		"[2001:db8::2]:4321": false,
+	} {
+		req := buildFormRequest(fields)
+		req.RemoteAddr = addr
+
+		verified, err := mg.VerifyWebhookRequest(req)
+		ensure.DeepEqual(t, verified, allowed)
+		if !allowed {
+			ensure.DeepEqual(t, err, ErrWebhookIPNotAllowed)
+		}
+	}
+
+	// Refresh from a source, keeping the current ranges if the source fails
+	allowlist.SetSource(func(ctx context.Context) ([]string, error) {
+		return []string{"203.0.113.0/24"}, nil
+	})
+	ensure.Nil(t, allowlist.Refresh(context.Background()))
+	ensure.True(t, allowlist.Allowed(net.ParseIP("203.0.113.7")))
+	ensure.False(t, allowlist.Allowed(net.ParseIP("198.51.100.23")))
+
+	allowlist.SetSource(func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("unavailable")
+	})
+	ensure.NotNil(t, allowlist.Refresh(context.Background()))
+	ensure.True(t, allowlist.Allowed(net.ParseIP("203.0.113.7")))
+
+	_, err = NewWebhookIPAllowlist("not-an-ip")
+	ensure.NotNil(t, err)
+}
+
+func TestGetWebhookSigningKey(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		ensure.DeepEqual(t, req.URL.Path, "/v5/accounts/http_signing_key")
+		fmt.Fprint(w, `{"message": "success", "http_signing_key": "signing-key"}`)
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL + "/v3")
+	ctx := context.Background()
+
+	// Signatures are verified with the API key until the signing key is fetched
+	fields := getSignatureFields("signing-key", true)
+	sig := Signature{TimeStamp: fields["timestamp"], Token: fields["token"], Signature: fields["signature"]}
+	verified, err := mg.VerifyWebhookSignature(sig)
+	ensure.Nil(t, err)
+	ensure.False(t, verified)
+
+	for i := 0; i < 2; i++ {
+		key, err := mg.GetWebhookSigningKey(ctx)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, key, "signing-key")
+	}
+	ensure.DeepEqual(t, requests, 1)
+
+	verified, err = mg.VerifyWebhookSignature(sig)
+	ensure.Nil(t, err)
+	ensure.True(t, verified)
+	verified, err = mg.VerifyWebhookRequest(buildFormRequest(fields))
+	ensure.Nil(t, err)
+	ensure.True(t, verified)
+}
+
+func TestSetWebhookSigningKey(t *testing.T) {
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	fields := getSignatureFields("signing-key", true)
+	sig := Signature{
+		TimeStamp: fields["timestamp"],
+		Token:     fields["token"],
+		Signature: fields["signature"],
+	}
+
+	verified, err := mg.VerifyWebhookSignature(sig)
+	ensure.Nil(t, err)
+	ensure.False(t, verified)
+
+	mg.SetWebhookSigningKey("signing-key")
+	verified, err = mg.VerifyWebhookSignature(sig)
+	ensure.Nil(t, err)
+	ensure.True(t, verified)
+	// The key is not fetched once set
+	key, err := mg.GetWebhookSigningKey(context.Background())
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, key, "signing-key")
+
+	for name, value := range map[string]string{
+		"MG_API_KEY":             exampleAPIKey,
+		"MG_DOMAIN":              exampleDomain,
+		"MG_WEBHOOK_SIGNING_KEY": "signing-key",
+	} {
+		previous, set := os.LookupEnv(name)
+		os.Setenv(name, value)
+		defer func(name string) {
+			if set {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name)
+	}
+	mg, err = NewMailgunFromEnv()
+	ensure.Nil(t, err)
+	verified, err = mg.VerifyWebhookSignature(sig)
+	ensure.Nil(t, err)
+	ensure.True(t, verified)
+}
+
+func TestWebhookURLs(t *testing.T) {
+	var created []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/v3/domains/"+exampleDomain+"/webhooks":
+			fmt.Fprint(w, `{"webhooks": {
+				"clicked": {"urls": ["https://a.example.com/clicked", "https://b.example.com/clicked"]},
+				"opened": {"url": "https://a.example.com/opened"}
+			}}`)
+		case req.Method == http.MethodGet:
+			fmt.Fprint(w, `{"webhook": {"urls": ["https://a.example.com/clicked", "https://b.example.com/clicked"]}}`)
+		case req.Method == http.MethodPost:
+			ensure.Nil(t, req.ParseForm())
+			created = req.PostForm["url"]
+			fmt.Fprint(w, `{"message": "Webhook has been created"}`)
+		}
+	}))
+	defer srv.Close()
+
+	mg := NewMailgun(exampleDomain, exampleAPIKey)
+	mg.SetAPIBase(srv.URL + "/v3")
+	ctx := context.Background()
+
+	hooks, err := mg.ListWebhooks(ctx)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hooks, map[WebhookKind]Webhook{
+		WebhookClicked: {URLs: []string{"https://a.example.com/clicked", "https://b.example.com/clicked"}},
+		// Webhooks with a single url are returned by older versions of the API
+		WebhookOpened: {URLs: []string{"https://a.example.com/opened"}},
+	})
+
+	hook, err := mg.GetWebhook(ctx, "clicked")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, hook.URLs, []string{"https://a.example.com/clicked", "https://b.example.com/clicked"})
+
+	urls := []string{"https://a.example.com/delivered", "https://b.example.com/delivered"}
+	ensure.Nil(t, mg.CreateWebhook(ctx, "delivered", urls))
+	ensure.DeepEqual(t, created, urls)
+
+	err = mg.CreateWebhook(ctx, "delivered", append(urls, "https://c.example.com", "https://d.example.com"))
+	ensure.StringContains(t, err.Error(), "at most 3 urls")
+	err = mg.UpdateWebhook(ctx, "delivered", nil)
+	ensure.StringContains(t, err.Error(), "at least one url")
+
+	// Unknown kinds are rejected without a request
+	err = mg.CreateWebhook(ctx, "deliver", urls)
+	ensure.StringContains(t, err.Error(), "unknown webhook kind 'deliver'")
+	_, err = mg.GetWebhook(ctx, "click")
+	ensure.NotNil(t, err)
+	_, err = mg.TestWebhook(ctx, "bounce", "")
+	ensure.NotNil(t, err)
+	ensure.True(t, WebhookTemporaryFail.IsValid())
+}